@@ -16,6 +16,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/IUAD1IY7/opa/cmd/internal/env"
+	"github.com/IUAD1IY7/opa/internal/bundle/diff"
 	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/bundle"
 	"github.com/IUAD1IY7/opa/v1/compile"
@@ -49,6 +50,8 @@ type buildParams struct {
 	v1Compatible       bool
 	followSymlinks     bool
 	wasmIncludePrint   bool
+	provenance         bool
+	deltaFrom          string
 }
 
 func newBuildParams() buildParams {
@@ -193,6 +196,37 @@ containing optional claims.
 For more information on the format of the ".signatures.json" file
 see https://www.openpolicyagent.org/docs/latest/management-bundles/#signature-format.
 
+Provenance
+----------
+
+The --provenance flag makes the 'build' command attach signed in-toto attestations
+to the output bundle, in a ".attestations.json" file:
+
+* a SLSA provenance statement recording the build parameters, the modules, data and
+  capabilities the bundle was built from, and the OPA version that built it
+* an inventory of the modules, data, capabilities, built-in functions and language
+  features the bundle's policies use
+
+The statements are signed with the key specified by the --signing-key flag, the same
+way as the bundle files, and the ".signatures.json" file covers the attestations. OPA
+verifies the attestations when reading a bundle with a verification config. For more
+information see https://www.openpolicyagent.org/docs/latest/management-bundles/#attestations.
+
+Delta Bundles
+-------------
+
+The --delta-from flag makes the 'build' command output a delta bundle instead of a
+snapshot bundle. The delta bundle patches the data and policies of the snapshot
+bundle given to the flag into those of the bundle being built:
+
+    $ opa build --bundle ./policies --delta-from bundle-v1.tar.gz -o delta.tar.gz
+
+Changed data is patched with "upsert", "replace" and "remove" operations on the
+data paths. Added or changed policies are patched with "upsert" operations and
+removed policies with "remove" operations on the module paths. Both bundles must
+have the same manifest roots and wasm resolvers, and neither may contain wasm
+modules or plans. Delta bundles cannot be signed.
+
 Capabilities
 ------------
 
@@ -271,6 +305,8 @@ against OPA v0.22.0:
 	addSigningKeyFlag(buildCommand.Flags(), &buildParams.key)
 	addSigningPluginFlag(buildCommand.Flags(), &buildParams.plugin)
 	addClaimsFileFlag(buildCommand.Flags(), &buildParams.claimsFile)
+	buildCommand.Flags().BoolVar(&buildParams.provenance, "provenance", false, "generate signed provenance and inventory attestations for the output bundle")
+	buildCommand.Flags().StringVarP(&buildParams.deltaFrom, "delta-from", "", "", "build a delta bundle against the given snapshot bundle")
 
 	addV0CompatibleFlag(buildCommand.Flags(), &buildParams.v0Compatible, false)
 	addV1CompatibleFlag(buildCommand.Flags(), &buildParams.v1Compatible, false)
@@ -297,6 +333,14 @@ func dobuild(params buildParams, args []string) error {
 		return errors.New("enable bundle mode (ie. --bundle) to verify or sign bundle files or directories")
 	}
 
+	if params.provenance && bsc == nil {
+		return errors.New("specify the signing key (ie. --signing-key) to generate provenance attestations")
+	}
+
+	if params.deltaFrom != "" && bsc != nil {
+		return errors.New("delta bundles cannot be signed, remove the signing key (ie. --signing-key)")
+	}
+
 	capabilities := params.capabilities.C
 	if capabilities == nil {
 		// ensure custom builtins are properly captured
@@ -316,6 +360,7 @@ func dobuild(params buildParams, args []string) error {
 		WithFilter(buildCommandLoaderFilter(params.bundleMode, params.ignore)).
 		WithBundleVerificationConfig(bvc).
 		WithBundleSigningConfig(bsc).
+		WithProvenance(params.provenance).
 		WithPartialNamespace(params.ns).
 		WithFollowSymlinks(params.followSymlinks)
 
@@ -329,6 +374,14 @@ func dobuild(params buildParams, args []string) error {
 		compiler = compiler.WithDebug(os.Stderr)
 	}
 
+	if params.deltaFrom != "" {
+		base, err := diff.Load(params.deltaFrom, params.regoVersion())
+		if err != nil {
+			return err
+		}
+		compiler = compiler.WithDeltaBase(base)
+	}
+
 	if params.claimsFile == "" {
 		compiler = compiler.WithBundleVerificationKeyID(params.pubKeyID)
 	}
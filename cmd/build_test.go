@@ -18,6 +18,7 @@ import (
 
 	"github.com/IUAD1IY7/opa/internal/file/archive"
 	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
 	"github.com/IUAD1IY7/opa/v1/loader"
 	"github.com/IUAD1IY7/opa/v1/util"
 	"github.com/IUAD1IY7/opa/v1/util/test"
@@ -379,6 +380,129 @@ func TestBuildSigningConfigError(t *testing.T) {
 	}
 }
 
+func TestBuildProvenance(t *testing.T) {
+
+	files := map[string]string{
+		"test.rego": `
+			package test
+
+			p := 1
+		`,
+	}
+
+	test.WithTempFS(files, func(root string) {
+		params := newBuildParams()
+		params.outputFile = path.Join(root, "bundle.tar.gz")
+		params.bundleMode = true
+		params.key = "secret"
+		params.algorithm = "HS256"
+		params.pubKeyID = defaultPublicKeyID
+		params.provenance = true
+
+		if err := dobuild(params, []string{root}); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(params.outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		vc := bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{defaultPublicKeyID: {Key: "secret", Algorithm: "HS256"}}, "", "", nil)
+		vc.RequireAttestations = true
+
+		b, err := bundle.NewReader(f).WithBundleVerificationConfig(vc).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(b.Attestations.Statements) != 2 {
+			t.Fatalf("expected provenance and inventory statements but got: %+v", b.Attestations.Statements)
+		}
+	})
+}
+
+func TestBuildProvenanceWithoutSigningKey(t *testing.T) {
+
+	params := newBuildParams()
+	params.bundleMode = true
+	params.provenance = true
+
+	err := dobuild(params, []string{"."})
+	if err == nil || !strings.Contains(err.Error(), "--signing-key") {
+		t.Fatalf("expected error but got: %v", err)
+	}
+}
+
+func TestBuildDeltaFrom(t *testing.T) {
+
+	files := map[string]string{
+		"policies/a.rego": `
+			package a
+
+			p := 1
+		`,
+		"policies/b.rego": `
+			package b
+
+			p := 1
+		`,
+	}
+
+	test.WithTempFS(files, func(root string) {
+		policies := path.Join(root, "policies")
+
+		params := newBuildParams()
+		params.outputFile = path.Join(root, "base.tar.gz")
+		params.bundleMode = true
+
+		if err := dobuild(params, []string{policies}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Remove(path.Join(policies, "b.rego")); err != nil {
+			t.Fatal(err)
+		}
+
+		params.outputFile = path.Join(root, "delta.tar.gz")
+		params.deltaFrom = path.Join(root, "base.tar.gz")
+
+		if err := dobuild(params, []string{policies}); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(params.outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		b, err := bundle.NewReader(f).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if b.Type() != bundle.DeltaBundleType || len(b.Patch.Modules) != 1 || b.Patch.Modules[0].Op != "remove" {
+			t.Fatalf("expected delta bundle removing b.rego but got: %+v", b.Patch)
+		}
+	})
+}
+
+func TestBuildDeltaFromWithSigningKey(t *testing.T) {
+
+	params := newBuildParams()
+	params.bundleMode = true
+	params.key = "secret"
+	params.algorithm = "HS256"
+	params.deltaFrom = "base.tar.gz"
+
+	err := dobuild(params, []string{"."})
+	if err == nil || !strings.Contains(err.Error(), "delta bundles cannot be signed") {
+		t.Fatalf("expected error but got: %v", err)
+	}
+}
+
 func TestBuildPlanWithPruneUnused(t *testing.T) {
 
 	files := map[string]string{
@@ -0,0 +1,215 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/IUAD1IY7/opa/cmd/formats"
+	"github.com/IUAD1IY7/opa/cmd/internal/env"
+	"github.com/IUAD1IY7/opa/internal/bundle/diff"
+	pr "github.com/IUAD1IY7/opa/internal/presentation"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/util"
+
+	"github.com/spf13/cobra"
+)
+
+type bundleDiffCommandParams struct {
+	outputFormat *util.EnumFlag
+	v0Compatible bool
+	v1Compatible bool
+}
+
+func (p *bundleDiffCommandParams) regoVersion() ast.RegoVersion {
+	if p.v0Compatible {
+		return ast.RegoV0
+	}
+	if p.v1Compatible {
+		return ast.RegoV1
+	}
+	return ast.DefaultRegoVersion
+}
+
+func newBundleDiffCommandParams() bundleDiffCommandParams {
+	return bundleDiffCommandParams{
+		outputFormat: formats.Flag(formats.Pretty, formats.JSON),
+	}
+}
+
+func init() {
+
+	var bundleCommand = &cobra.Command{
+		Use:   "bundle",
+		Short: "Work with OPA bundles",
+		Long:  "Work with OPA bundles.",
+	}
+
+	params := newBundleDiffCommandParams()
+
+	var bundleDiffCommand = &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "Compare two OPA bundles",
+		Long: `Compare two OPA bundles.
+
+The 'bundle diff' command reads two bundles, given as bundle tarballs or bundle
+directories, and reports how the new bundle differs from the old one:
+
+* manifest changes to the revision, roots, Rego version, metadata and Wasm resolvers
+* added, removed and modified modules, with the rules that changed in them
+* data changes, as JSON Patch style operations
+
+Signatures are not verified.
+
+Example:
+
+    $ opa bundle diff bundle-r1.tar.gz bundle-r2.tar.gz
+
+Rules are compared by the document they define. A document is modified if any
+of the rules defining it changed, ignoring formatting and comments.
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("specify exactly two OPA bundles")
+			}
+			return env.CmdFlags.CheckEnvironmentVariables(cmd)
+		},
+		Run: func(_ *cobra.Command, args []string) {
+			if err := doBundleDiff(params, args[0], args[1], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addOutputFormat(bundleDiffCommand.Flags(), params.outputFormat)
+	addV0CompatibleFlag(bundleDiffCommand.Flags(), &params.v0Compatible, false)
+	addV1CompatibleFlag(bundleDiffCommand.Flags(), &params.v1Compatible, false)
+
+	bundleCommand.AddCommand(bundleDiffCommand)
+	RootCommand.AddCommand(bundleCommand)
+}
+
+func doBundleDiff(params bundleDiffCommandParams, oldPath, newPath string, out io.Writer) error {
+	old, err := diff.Load(oldPath, params.regoVersion())
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", oldPath, err)
+	}
+
+	new, err := diff.Load(newPath, params.regoVersion())
+	if err != nil {
+		return fmt.Errorf("failed to load %v: %w", newPath, err)
+	}
+
+	result := diff.Bundles(old, new)
+
+	switch params.outputFormat.String() {
+	case formats.JSON:
+		return pr.JSON(out, result)
+	default:
+		return printBundleDiff(out, result)
+	}
+}
+
+func printBundleDiff(out io.Writer, result *diff.Result) error {
+	if result.Empty() {
+		fmt.Fprintln(out, "No differences.")
+		return nil
+	}
+
+	if m := result.Manifest; m != nil {
+		fmt.Fprintln(out, "MANIFEST:")
+		printChange(out, "revision", m.Revision)
+		printChange(out, "rego_version", m.RegoVersion)
+		printChange(out, "metadata", m.Metadata)
+		printSetDiff(out, "  ", "roots", m.Roots)
+		printSetDiff(out, "  ", "wasm", m.WasmResolvers)
+		printSetDiff(out, "  ", "file_rego_versions", m.FileRegoVersions)
+		fmt.Fprintln(out)
+	}
+
+	if len(result.Modules) > 0 {
+		fmt.Fprintln(out, "MODULES:")
+		for _, m := range result.Modules {
+			fmt.Fprintf(out, "  %v %v\n", statusMarker(m.Status), m.Path)
+			printChange(out, "  package", m.Package)
+			printSetDiff(out, "    ", "imports", m.Imports)
+			for _, r := range m.Rules {
+				fmt.Fprintf(out, "    %v %v\n", statusMarker(r.Status), r.Ref)
+				if r.Status == diff.Modified {
+					printSources(out, "-", r.Old)
+					printSources(out, "+", r.New)
+				}
+			}
+		}
+		fmt.Fprintln(out)
+	}
+
+	if len(result.Data) > 0 {
+		fmt.Fprintln(out, "DATA:")
+		for _, c := range result.Data {
+			if c.Op == diff.OpRemove {
+				fmt.Fprintf(out, "  %v %v\n", c.Op, c.Path)
+				continue
+			}
+			value, err := json.Marshal(c.Value)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "  %v %v: %v\n", c.Op, c.Path, truncateTableStr(string(value)))
+		}
+		fmt.Fprintln(out)
+	}
+
+	return nil
+}
+
+func statusMarker(status string) string {
+	switch status {
+	case diff.Added:
+		return "+"
+	case diff.Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+func printChange(out io.Writer, name string, c *diff.Change) {
+	if c == nil {
+		return
+	}
+	old, _ := json.Marshal(c.Old)
+	new, _ := json.Marshal(c.New)
+	fmt.Fprintf(out, "  %v: %v -> %v\n", name, truncateTableStr(string(old)), truncateTableStr(string(new)))
+}
+
+func printSetDiff(out io.Writer, indent, name string, s *diff.SetDiff) {
+	if s == nil {
+		return
+	}
+	for _, x := range s.Added {
+		fmt.Fprintf(out, "%v%v: + %v\n", indent, name, x)
+	}
+	for _, x := range s.Removed {
+		fmt.Fprintf(out, "%v%v: - %v\n", indent, name, x)
+	}
+	for _, x := range s.Modified {
+		fmt.Fprintf(out, "%v%v: ~ %v\n", indent, name, x)
+	}
+}
+
+func printSources(out io.Writer, marker string, sources []string) {
+	for _, src := range sources {
+		for _, line := range strings.Split(src, "\n") {
+			fmt.Fprintf(out, "        %v %v\n", marker, line)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/cmd/formats"
+	"github.com/IUAD1IY7/opa/internal/file/archive"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+func TestDoBundleDiff(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	oldBundle := filepath.Join(dir, "old.tar.gz")
+	writeBundleDiffTestBundle(t, oldBundle, [][2]string{
+		{"/.manifest", `{"revision": "r1", "roots": ["authz"]}`},
+		{"/authz/policy.rego", "package authz\n\nallow if input.admin"},
+		{"/authz/data.json", `{"users": {"alice": "admin"}}`},
+	})
+
+	newBundle := filepath.Join(dir, "new.tar.gz")
+	writeBundleDiffTestBundle(t, newBundle, [][2]string{
+		{"/.manifest", `{"revision": "r2", "roots": ["authz"]}`},
+		{"/authz/policy.rego", "package authz\n\nallow if {\n\tinput.admin\n\tinput.mfa\n}"},
+		{"/authz/data.json", `{"users": {"alice": "admin", "bob": "viewer"}}`},
+	})
+
+	tests := []struct {
+		note   string
+		format string
+		exp    string
+	}{
+		{
+			note:   "pretty",
+			format: formats.Pretty,
+			exp: `MANIFEST:
+  revision: "r1" -> "r2"
+
+MODULES:
+  ~ /authz/policy.rego
+    ~ data.authz.allow
+        - allow if input.admin
+        + allow if {
+        + 	input.admin
+        + 	input.mfa
+        + }
+
+DATA:
+  add /authz/users/bob: "viewer"
+
+`,
+		},
+		{
+			note:   "json",
+			format: formats.JSON,
+			exp: `{
+  "manifest": {"revision": {"old": "r1", "new": "r2"}},
+  "modules": [
+    {
+      "path": "/authz/policy.rego",
+      "status": "modified",
+      "rules": [
+        {
+          "ref": "data.authz.allow",
+          "status": "modified",
+          "old": ["allow if input.admin"],
+          "new": ["allow if {\n\tinput.admin\n\tinput.mfa\n}"]
+        }
+      ]
+    }
+  ],
+  "data": [{"op": "add", "path": "/authz/users/bob", "value": "viewer"}]
+}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			params := newBundleDiffCommandParams()
+			if err := params.outputFormat.Set(tc.format); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := doBundleDiff(params, oldBundle, newBundle, &out); err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.format == formats.JSON {
+				exp := util.MustUnmarshalJSON([]byte(tc.exp))
+				result := util.MustUnmarshalJSON(out.Bytes())
+				if !reflect.DeepEqual(exp, result) {
+					t.Fatalf("expected output:\n\n%v\n\ngot:\n\n%v", tc.exp, out.String())
+				}
+			} else if out.String() != tc.exp {
+				t.Fatalf("expected output:\n\n%v\n\ngot:\n\n%v", tc.exp, out.String())
+			}
+		})
+	}
+}
+
+func TestDoBundleDiffNoDifferences(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	writeBundleDiffTestBundle(t, path, [][2]string{
+		{"/authz/policy.rego", "package authz\n\nallow if input.admin"},
+	})
+
+	var out bytes.Buffer
+	if err := doBundleDiff(newBundleDiffCommandParams(), path, path, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "No differences.\n" {
+		t.Fatalf("unexpected output: %v", out.String())
+	}
+}
+
+func writeBundleDiffTestBundle(t *testing.T, path string, files [][2]string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, archive.MustWriteTarGz(files).Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
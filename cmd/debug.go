@@ -0,0 +1,254 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/IUAD1IY7/opa/cmd/internal/env"
+	"github.com/IUAD1IY7/opa/internal/dap"
+	internal_logging "github.com/IUAD1IY7/opa/internal/logging"
+	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+type debugCommandParams struct {
+	address           string
+	server            string
+	token             string
+	tlsCACertFile     string
+	tlsCertFile       string
+	tlsPrivateKeyFile string
+	logLevel          *util.EnumFlag
+}
+
+func newDebugCommandParams() debugCommandParams {
+	return debugCommandParams{
+		logLevel: util.NewEnumFlag("info", []string{"debug", "info", "error"}),
+	}
+}
+
+// stdio is the connection to a debug adapter client over stdin and stdout.
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func init() {
+
+	params := newDebugCommandParams()
+
+	var debugCommand = &cobra.Command{
+		Use:   "debug",
+		Short: "Debug Rego policies with a Debug Adapter Protocol client",
+		Long: `Debug Rego policies with a Debug Adapter Protocol client.
+
+The 'debug' command starts a debug adapter speaking the Debug Adapter Protocol
+(DAP), which editors and other DAP clients can use to debug the evaluation of
+queries and tests step by step. By default, the debug adapter serves a single
+client over stdin and stdout:
+
+    $ opa debug
+
+With the --address flag, the debug adapter listens for clients over TCP
+instead, serving each of them separately:
+
+    $ opa debug --address localhost:4711
+
+Clients launch a debug session with the "launch" request, whose arguments
+select the command to debug:
+
+    {
+      "command": "eval",
+      "query": "data.example.allow",
+      "inputPath": "input.json",
+      "dataPaths": ["policy.rego", "data.json"],
+      "bundlePaths": [],
+      "stopOnEntry": false,
+      "stopOnResult": false,
+      "enablePrint": true
+    }
+
+    {
+      "command": "test",
+      "run": "^data.example.test_allow$",
+      "dataPaths": ["."],
+      "stopOnFail": true
+    }
+
+With the --server flag, the debug adapter client is connected to the Debug API
+of a running OPA instead, started with 'opa run --server --debug-api'. Clients
+attach to the next decision matching a path and input filter with the
+"attach" request, and step through it with the policies and a snapshot of the
+data it was made with:
+
+    $ opa debug --server https://opa.example.com:8181 --token "$TOKEN"
+
+    {
+      "path": "example/allow",
+      "inputFilter": "input.user == \"alice\"",
+      "timeout": 300
+    }
+
+Breakpoints may have a condition, a Rego expression evaluated with the local
+variables, input and data at the breakpoint's location. Evaluation only stops
+at the breakpoint if the condition is true, e.g. 'x > 10' or
+'input.user == "alice"'.
+
+Logs are written to stderr.
+`,
+		PreRunE: func(cmd *cobra.Command, _ []string) error {
+			return env.CmdFlags.CheckEnvironmentVariables(cmd)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cmd.SilenceUsage = true
+			if params.server != "" {
+				return attachDebug(params, os.Stdin, os.Stdout)
+			}
+			return runDebug(params, os.Stdin, os.Stdout)
+		},
+	}
+
+	debugCommand.Flags().StringVarP(&params.address, "address", "a", "", "set listening address of the debug adapter (e.g., [ip]:<port>) instead of serving stdin and stdout")
+	debugCommand.Flags().StringVar(&params.server, "server", "", "connect to the Debug API of the OPA server at the given URL instead of debugging locally")
+	debugCommand.Flags().StringVar(&params.token, "token", "", "set bearer token for authenticating with the OPA server")
+	debugCommand.Flags().StringVar(&params.tlsCACertFile, "tls-ca-cert-file", "", "set path of TLS CA cert file for verifying the OPA server")
+	debugCommand.Flags().StringVar(&params.tlsCertFile, "tls-cert-file", "", "set path of TLS certificate file for authenticating with the OPA server")
+	debugCommand.Flags().StringVar(&params.tlsPrivateKeyFile, "tls-private-key-file", "", "set path of TLS private key file for authenticating with the OPA server")
+	debugCommand.Flags().VarP(params.logLevel, "log-level", "l", "set log level")
+	debugCommand.MarkFlagsMutuallyExclusive("address", "server")
+
+	RootCommand.AddCommand(debugCommand)
+}
+
+func runDebug(params debugCommandParams, stdin io.Reader, stdout io.Writer) error {
+	level, err := internal_logging.GetLevel(params.logLevel.String())
+	if err != nil {
+		return err
+	}
+	logger := logging.New()
+	logger.SetLevel(level)
+
+	if params.address == "" {
+		return dap.NewServer(stdio{stdin, stdout}, logger).Serve(context.Background())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", params.address)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	logger.Info("Listening for debug adapter clients on %v.", listener.Addr())
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		logger.Info("Serving debug adapter client %v.", conn.RemoteAddr())
+
+		go func() {
+			defer conn.Close()
+			if err := dap.NewServer(conn, logger).Serve(ctx); err != nil {
+				logger.Error("Failed to serve debug adapter client %v: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// attachDebug connects the debug adapter client over stdin and stdout to the
+// Debug API of an OPA server.
+func attachDebug(params debugCommandParams, stdin io.Reader, stdout io.Writer) error {
+	tlsConfig := &tls.Config{}
+
+	if params.tlsCACertFile != "" {
+		pem, err := os.ReadFile(params.tlsCACertFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("failed to parse CA cert file %v", params.tlsCACertFile)
+		}
+	}
+
+	if params.tlsCertFile != "" || params.tlsPrivateKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(params.tlsCertFile, params.tlsPrivateKeyFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	// Setting the TLS config keeps the client on HTTP/1.1, which connections
+	// can be upgraded from.
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(params.server, "/")+"/v1/debug", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "dap")
+	if params.token != "" {
+		req.Header.Set("Authorization", "Bearer "+params.token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("failed to connect to debug API: %v: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	conn, ok := resp.Body.(io.ReadWriter)
+	if !ok {
+		return errors.New("failed to connect to debug API: connection not upgraded")
+	}
+
+	go func() {
+		_, _ = io.Copy(conn, stdin)
+		resp.Body.Close()
+	}()
+
+	_, err = io.Copy(stdout, conn)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDebugStdio(t *testing.T) {
+	t.Parallel()
+
+	var stdin bytes.Buffer
+	for _, req := range []string{
+		`{"seq": 1, "type": "request", "command": "initialize", "arguments": {"adapterID": "opa"}}`,
+		`{"seq": 2, "type": "request", "command": "threads"}`,
+		`{"seq": 3, "type": "request", "command": "disconnect"}`,
+		`{"seq": 4, "type": "request", "command": "threads"}`,
+	} {
+		fmt.Fprintf(&stdin, "Content-Length: %d\r\n\r\n%s", len(req), req)
+	}
+
+	params := newDebugCommandParams()
+	var stdout bytes.Buffer
+	if err := runDebug(params, &stdin, &stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, exp := range []string{
+		`"request_seq":1,"success":true,"command":"initialize","body":{"supportsConfigurationDoneRequest":true,"supportsConditionalBreakpoints":true`,
+		`"request_seq":2,"success":false,"command":"threads","message":"no active debug session"`,
+		`"request_seq":3,"success":true,"command":"disconnect"`,
+	} {
+		if !strings.Contains(stdout.String(), exp) {
+			t.Fatalf("expected output to contain %q but got:\n\n%v", exp, stdout.String())
+		}
+	}
+
+	// Requests after disconnecting aren't handled.
+	if strings.Contains(stdout.String(), `"request_seq":4`) {
+		t.Fatalf("expected no response after disconnecting but got:\n\n%v", stdout.String())
+	}
+}
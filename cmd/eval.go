@@ -27,6 +27,7 @@ import (
 	"github.com/IUAD1IY7/opa/v1/bundle"
 	"github.com/IUAD1IY7/opa/v1/compile"
 	"github.com/IUAD1IY7/opa/v1/cover"
+	"github.com/IUAD1IY7/opa/v1/filter"
 	"github.com/IUAD1IY7/opa/v1/loader"
 	"github.com/IUAD1IY7/opa/v1/metrics"
 	"github.com/IUAD1IY7/opa/v1/profiler"
@@ -101,6 +102,9 @@ func newEvalCommandParams() evalCommandParams {
 			formats.Source,
 			formats.Raw,
 			formats.Discard,
+			formats.UCAST,
+			formats.LCOV,
+			formats.Cobertura,
 		),
 		explain:         newExplainFlag([]string{explainModeOff, explainModeFull, explainModeNotes, explainModeFails, explainModeDebug}),
 		target:          util.NewEnumFlag(compile.TargetRego, []string{compile.TargetRego, compile.TargetWasm}),
@@ -130,10 +134,12 @@ func validateEvalParams(p *evalCommandParams, cmdArgs []string) error {
 		return errors.New("specify --fail or --fail-defined but not both")
 	}
 	of := p.outputFormat.String()
-	if p.partial && of != formats.Pretty && of != formats.JSON && of != formats.Source {
+	if p.partial && of != formats.Pretty && of != formats.JSON && of != formats.Source && of != formats.UCAST {
 		return errors.New("invalid output format for partial evaluation")
-	} else if !p.partial && of == formats.Source {
+	} else if !p.partial && (of == formats.Source || of == formats.UCAST) {
 		return errors.New("invalid output format for evaluation")
+	} else if !p.coverage && (of == formats.LCOV || of == formats.Cobertura) {
+		return fmt.Errorf("output format %s requires coverage (--coverage)", of)
 	}
 
 	// check if illegal arguments is passed with unknowns flag
@@ -256,6 +262,9 @@ Set the output format with the --format flag.
     --format=source    : output partial evaluation results in a source format
     --format=raw       : output the values from query results in a scripting friendly format
     --format=discard   : output the result field as "discarded" when non-nil
+    --format=ucast     : output partial evaluation results as a UCAST condition tree
+    --format=lcov      : output the coverage report in the LCOV tracefile format (requires --coverage)
+    --format=cobertura : output the coverage report in the Cobertura XML format (requires --coverage)
 
 Schema
 ------
@@ -430,6 +439,12 @@ func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
 		err = pr.Raw(w, result)
 	case formats.Discard:
 		err = pr.Discard(w, result)
+	case formats.UCAST:
+		err = ucast(w, result)
+	case formats.LCOV:
+		err = coverage(w, result, ectx.modules, cover.WriteLCOV)
+	case formats.Cobertura:
+		err = coverage(w, result, ectx.modules, cover.WriteCobertura)
 	default:
 		err = pr.JSON(w, result)
 	}
@@ -477,8 +492,17 @@ func evalOnce(ctx context.Context, ectx *evalContext) pr.Output {
 		pq, resultErr = r.PrepareForPartial(ctx)
 		if resultErr == nil {
 			parsedModules = pq.Modules()
+			if ectx.params.outputFormat.String() == formats.UCAST {
+				resultErr = checkFilter(ectx.params, ectx.query, parsedModules)
+			}
+		}
+		if resultErr == nil {
 			result.Partial, resultErr = pq.Partial(ctx, ectx.evalArgs...)
-			resetExprLocations(result.Partial)
+			// Keep the original locations so that translation errors point
+			// at the policy.
+			if ectx.params.outputFormat.String() != formats.UCAST {
+				resetExprLocations(result.Partial)
+			}
 		}
 	}
 
@@ -519,6 +543,7 @@ func evalOnce(ctx context.Context, ectx *evalContext) pr.Output {
 	if ectx.params.coverage {
 		report := ectx.cover.Report(parsedModules)
 		result.Coverage = &report
+		ectx.modules = parsedModules
 	}
 
 	return result
@@ -526,9 +551,11 @@ func evalOnce(ctx context.Context, ectx *evalContext) pr.Output {
 
 type evalContext struct {
 	params           evalCommandParams
+	query            string
 	metrics          metrics.Metrics
 	profiler         *resettableProfiler
 	cover            *cover.Cover
+	modules          map[string]*ast.Module // modules of the last evaluation, for coverage reports
 	tracer           *topdown.BufferTracer
 	regoArgs         []func(*rego.Rego)
 	evalArgs         []rego.EvalOption
@@ -696,6 +723,7 @@ func setupEval(args []string, params evalCommandParams) (*evalContext, error) {
 
 	evalCtx := &evalContext{
 		params:           params,
+		query:            query,
 		metrics:          m,
 		profiler:         &rp,
 		cover:            c,
@@ -810,6 +838,81 @@ func (f *intFlag) isFlagSet() bool {
 	return f.isSet
 }
 
+// ucast writes the partial evaluation result as a UCAST condition tree. If the
+// result contains errors or cannot be translated, the errors are written
+// instead.
+func ucast(w io.Writer, result pr.Output) error {
+	if len(result.Errors) > 0 || result.Partial == nil {
+		return pr.JSON(w, result)
+	}
+
+	f, err := filter.Translate(result.Partial)
+	if err != nil {
+		return err
+	}
+
+	return pr.JSON(w, f.UCAST())
+}
+
+// checkFilter reports the calls in the rules that the query depends on that
+// cannot be translated into data filters, like the /v1/compile API does.
+func checkFilter(params evalCommandParams, query string, modules map[string]*ast.Module) error {
+	popts := ast.ParserOptions{RegoVersion: params.regoVersion()}
+
+	compiler := ast.NewCompiler().WithDefaultRegoVersion(params.regoVersion())
+	if params.capabilities.C != nil {
+		compiler = compiler.WithCapabilities(params.capabilities.C)
+	}
+	if compiler.Compile(modules); compiler.Failed() {
+		return compiler.Errors
+	}
+
+	qctx := ast.NewQueryContext()
+	if params.pkg != "" {
+		pkg, err := ast.ParsePackage("package " + params.pkg)
+		if err != nil {
+			return err
+		}
+		qctx = qctx.WithPackage(pkg)
+	}
+	if len(params.imports.v) > 0 {
+		imports, err := ast.ParseImports("import " + strings.Join(params.imports.v, "\nimport "))
+		if err != nil {
+			return err
+		}
+		qctx = qctx.WithImports(imports)
+	}
+
+	body, err := ast.ParseBodyWithOpts(query, popts)
+	if err != nil {
+		return err
+	}
+	body, err = compiler.QueryCompiler().WithContext(qctx).Compile(body)
+	if err != nil {
+		return err
+	}
+
+	unknowns := make([]*ast.Term, 0, len(params.unknowns))
+	for _, u := range params.unknowns {
+		t, err := ast.ParseTerm(u)
+		if err != nil {
+			return err
+		}
+		unknowns = append(unknowns, t)
+	}
+
+	return filter.Check(compiler, body, unknowns)
+}
+
+// coverage writes the coverage report of the result with the given writer, or
+// the result as JSON if the evaluation failed.
+func coverage(w io.Writer, result pr.Output, modules map[string]*ast.Module, write func(io.Writer, cover.Report, map[string]*ast.Module) error) error {
+	if len(result.Errors) > 0 || result.Coverage == nil {
+		return pr.JSON(w, result)
+	}
+	return write(w, *result.Coverage, modules)
+}
+
 // resetExprLocations overwrites the row in the location info for every expression contained in pq.
 // The location on every expression is shallow copied to avoid mutating shared state. Overwriting
 // the rows ensures that the formatting package does not leave blank lines in between expressions (e.g.,
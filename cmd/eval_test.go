@@ -274,6 +274,65 @@ p = 1`,
 	})
 }
 
+func TestEvalWithCoverageFormats(t *testing.T) {
+	files := map[string]string{
+		"x.rego": `package x
+
+p := 1
+
+q if input.x
+`,
+	}
+
+	tests := []struct {
+		format string
+		exp    []string
+	}{
+		{
+			format: "lcov",
+			exp:    []string{"FNDA:1,data.x.p\n", "FNDA:0,data.x.q\n", "DA:3,1\n", "DA:5,0\n", "LH:1\n"},
+		},
+		{
+			format: "cobertura",
+			exp:    []string{`<class name="data.x"`, `<method name="data.x.q" signature="" line-rate="0"`, `<line number="3" hits="1">`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			test.WithTempFS(files, func(path string) {
+				// Relative paths, for the module to be reported under the same
+				// name as its locations.
+				t.Chdir(path)
+
+				params := newEvalCommandParams()
+				params.dataPaths = newrepeatedStringFlag([]string{"x.rego"})
+				if err := params.outputFormat.Set(tc.format); err != nil {
+					t.Fatal(err)
+				}
+
+				err := validateEvalParams(&params, []string{"data.x.p"})
+				if err == nil || !strings.Contains(err.Error(), "requires coverage") {
+					t.Fatalf("expected error but got: %v", err)
+				}
+
+				params.coverage = true
+
+				var buf bytes.Buffer
+				if _, err := eval([]string{"data.x.p"}, params, &buf); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, exp := range tc.exp {
+					if !strings.Contains(buf.String(), exp) {
+						t.Fatalf("expected output to contain %q but got:\n\n%s", exp, buf.String())
+					}
+				}
+			})
+		})
+	}
+}
+
 func TestEvalWithOptimizeErrors(t *testing.T) {
 	files := map[string]string{
 		"x.rego": `package x
@@ -1874,6 +1933,73 @@ time.clock(input.y, time.clock(input.x))
 	}
 }
 
+func TestEvalPartialUCASTOutput(t *testing.T) {
+	params := newEvalCommandParams()
+	params.partial = true
+	params.unknowns = []string{"input.fruits"}
+	_ = params.outputFormat.Set(formats.UCAST)
+
+	buf := new(bytes.Buffer)
+	_, err := eval([]string{`input.fruits.name == "apple"; input.fruits.price > 1`}, params, buf)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	expected := `{
+  "type": "compound",
+  "operator": "and",
+  "value": [
+    {
+      "type": "field",
+      "operator": "eq",
+      "field": "fruits.name",
+      "value": "apple"
+    },
+    {
+      "type": "field",
+      "operator": "gt",
+      "field": "fruits.price",
+      "value": 1
+    }
+  ]
+}
+`
+	if actual := buf.String(); actual != expected {
+		t.Errorf("expected output %q\ngot %q", expected, actual)
+	}
+
+	buf.Reset()
+	_, err = eval([]string{`upper(input.fruits.name) == "APPLE"`}, params, buf)
+	if err == nil || !strings.Contains(buf.String(), `"code": "data_filter_translation_error"`) || !strings.Contains(buf.String(), "call to upper cannot be translated") {
+		t.Fatalf("expected translation error but got %v: %v", err, buf.String())
+	}
+
+	// The rules are checked before partial evaluation, so calls are reported
+	// even if their residuals would have been discarded.
+	files := map[string]string{
+		"policy.rego": `package fruits
+
+allow if {
+	upper(input.fruits.name) == "APPLE"
+	data.fruits.enabled
+}`,
+	}
+	test.WithTempFS(files, func(path string) {
+		params.dataPaths = newrepeatedStringFlag([]string{path})
+		buf.Reset()
+		_, err = eval([]string{`data.fruits.allow`}, params, buf)
+		if err == nil || !strings.Contains(buf.String(), "call to upper cannot be translated") {
+			t.Fatalf("expected translation error but got %v: %v", err, buf.String())
+		}
+	})
+	params.dataPaths = newrepeatedStringFlag([]string{})
+
+	params.partial = false
+	if err := validateEvalParams(&params, []string{"data"}); err == nil {
+		t.Fatal("expected error for ucast output without partial evaluation")
+	}
+}
+
 func TestEvalPartialOutput_RegoVersion(t *testing.T) {
 	tests := []struct {
 		note                string
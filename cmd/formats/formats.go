@@ -11,14 +11,19 @@ import (
 type option = string
 
 const (
-	Pretty   option = "pretty"
-	JSON     option = "json"
-	GoBench  option = "gobench"
-	Values   option = "values"
-	Bindings option = "bindings"
-	Source   option = "source"
-	Raw      option = "raw"
-	Discard  option = "discard"
+	Pretty    option = "pretty"
+	JSON      option = "json"
+	GoBench   option = "gobench"
+	Values    option = "values"
+	Bindings  option = "bindings"
+	Source    option = "source"
+	Raw       option = "raw"
+	Discard   option = "discard"
+	UCAST     option = "ucast"
+	JUnit     option = "junit"
+	TAP       option = "tap"
+	LCOV      option = "lcov"
+	Cobertura option = "cobertura"
 )
 
 // Returns an enum flag for the given formats, where the first provided format
@@ -0,0 +1,299 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/IUAD1IY7/opa/cmd/formats"
+	"github.com/IUAD1IY7/opa/cmd/internal/env"
+	pr "github.com/IUAD1IY7/opa/internal/presentation"
+	"github.com/IUAD1IY7/opa/internal/replay"
+	initload "github.com/IUAD1IY7/opa/internal/runtime/init"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+type replayCommandParams struct {
+	dataPaths    repeatedStringFlag
+	bundlePaths  repeatedStringFlag
+	ignore       []string
+	outputFormat *util.EnumFlag
+	fail         bool
+	v0Compatible bool
+	v1Compatible bool
+}
+
+func (p *replayCommandParams) regoVersion() ast.RegoVersion {
+	if p.v0Compatible {
+		return ast.RegoV0
+	}
+	if p.v1Compatible {
+		return ast.RegoV1
+	}
+	return ast.DefaultRegoVersion
+}
+
+func newReplayCommandParams() replayCommandParams {
+	return replayCommandParams{
+		outputFormat: formats.Flag(formats.Pretty, formats.JSON),
+	}
+}
+
+func init() {
+
+	params := newReplayCommandParams()
+
+	var replayCommand = &cobra.Command{
+		Use:   "replay <path> [path [...]]",
+		Short: "Replay logged decisions against policies",
+		Long: `Replay logged decisions against policies.
+
+The 'replay' command reads decision log events from the given files and
+evaluates each decision again, with its recorded input, against the policies
+and data loaded with the --data and --bundle flags. It reports the decisions
+whose result changed, the decisions that now fail with an error, and how the
+evaluation times compare with the recorded ones:
+
+    $ opa replay --bundle bundle-r2.tar.gz decisions.log decisions.log.20250101T000000.000000000.gz
+
+Decision logs are read as written by the file and console sinks, one event per
+line, or as uploaded to decision log services, in JSON arrays of events.
+Compressed files are decompressed. Use '-' to read events from stdin.
+
+Results of non-deterministic built-in functions, e.g. time.now_ns or
+http.send, are reused when they were recorded in the events' nd_builtin_cache,
+so that the decisions are evaluated as they were made. Other calls are made
+anew. Enable the cache with the nd_builtin_cache configuration option.
+
+Decisions of ad-hoc queries and decisions whose input was erased or masked are
+skipped. Evaluation times are compared by the rego_query_eval timer, recorded
+in the events' metrics. As they were measured on another machine and under
+another load, they are only indicative.
+
+With the --fail flag, the command exits with a non-zero exit code if any
+decision changed or failed with a new error.
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("specify at least one decision log file")
+			}
+			if len(params.dataPaths.v) == 0 && len(params.bundlePaths.v) == 0 {
+				return errors.New("specify the policies to replay decisions against with --data or --bundle")
+			}
+			return env.CmdFlags.CheckEnvironmentVariables(cmd)
+		},
+		Run: func(_ *cobra.Command, args []string) {
+			differs, err := doReplay(params, args, os.Stdin, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(1)
+			}
+			if differs && params.fail {
+				os.Exit(2)
+			}
+		},
+	}
+
+	addDataFlag(replayCommand.Flags(), &params.dataPaths)
+	addBundleFlag(replayCommand.Flags(), &params.bundlePaths)
+	addIgnoreFlag(replayCommand.Flags(), &params.ignore)
+	addOutputFormat(replayCommand.Flags(), params.outputFormat)
+	replayCommand.Flags().BoolVar(&params.fail, "fail", false, "exits with non-zero exit code if any decision changed or failed with a new error")
+	addV0CompatibleFlag(replayCommand.Flags(), &params.v0Compatible, false)
+	addV1CompatibleFlag(replayCommand.Flags(), &params.v1Compatible, false)
+
+	RootCommand.AddCommand(replayCommand)
+}
+
+// doReplay replays the decisions logged in the files and writes the report.
+// It returns true if any decision changed or failed with a new error.
+func doReplay(params replayCommandParams, paths []string, stdin io.Reader, out io.Writer) (bool, error) {
+	ctx := context.Background()
+
+	compiler, store, err := loadReplayPolicies(ctx, params)
+	if err != nil {
+		return false, err
+	}
+
+	replayer := replay.New(compiler, store)
+	report := &replay.Report{}
+
+	for _, path := range paths {
+		if err := replayFile(ctx, replayer, report, path, stdin); err != nil {
+			return false, fmt.Errorf("%v: %w", path, err)
+		}
+	}
+
+	report.Summarize()
+
+	switch params.outputFormat.String() {
+	case formats.JSON:
+		err = pr.JSON(out, report)
+	default:
+		err = printReplayReport(out, report)
+	}
+
+	return report.Differs(), err
+}
+
+func replayFile(ctx context.Context, replayer *replay.Replayer, report *replay.Report, path string, stdin io.Reader) error {
+	r := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	dec, err := replay.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	for {
+		e, err := dec.Decode()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		report.Add(replayer.Replay(ctx, e))
+	}
+}
+
+// loadReplayPolicies loads the policies and data to replay decisions against
+// into a new store, and compiles the policies.
+func loadReplayPolicies(ctx context.Context, params replayCommandParams) (*ast.Compiler, storage.Store, error) {
+	filter := ignored(params.ignore).Apply
+
+	files, err := initload.LoadPathsForRegoVersion(params.regoVersion(), params.dataPaths.v, filter, false, nil, true, false, false, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bundles, err := initload.LoadPathsForRegoVersion(params.regoVersion(), params.bundlePaths.v, filter, true, nil, true, false, false, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loaded := map[string]*bundle.Bundle{}
+	maps.Copy(loaded, files.Bundles)
+	maps.Copy(loaded, bundles.Bundles)
+
+	store := inmem.New()
+
+	var compiler *ast.Compiler
+	err = storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		result, err := initload.InsertAndCompile(ctx, initload.InsertAndCompileOptions{
+			Store:         store,
+			Txn:           txn,
+			Files:         files.Files,
+			Bundles:       loaded,
+			MaxErrors:     -1,
+			ParserOptions: ast.ParserOptions{RegoVersion: params.regoVersion()},
+		})
+		if err != nil {
+			return err
+		}
+		compiler = result.Compiler
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return compiler, store, nil
+}
+
+func printReplayReport(out io.Writer, report *replay.Report) error {
+	for _, d := range report.Decisions {
+		fmt.Fprintf(out, "%v: %v (%v)\n", replayStatusLabel(d.Status), d.DecisionID, d.Path)
+
+		switch d.Status {
+		case replay.Skipped:
+			fmt.Fprintf(out, "  reason: %v\n", d.Reason)
+		case replay.NewError:
+			fmt.Fprintf(out, "  recorded: %v\n", replayValue(d.RecordedResult))
+			fmt.Fprintf(out, "  error: %v\n", d.ReplayedError)
+		case replay.ResolvedError:
+			errJSON, err := json.Marshal(d.RecordedError)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "  recorded error: %v\n", truncateTableStr(string(errJSON)))
+			fmt.Fprintf(out, "  replayed: %v\n", replayValue(d.ReplayedResult))
+		default:
+			fmt.Fprintf(out, "  recorded: %v\n", replayValue(d.RecordedResult))
+			fmt.Fprintf(out, "  replayed: %v\n", replayValue(d.ReplayedResult))
+		}
+	}
+	if len(report.Decisions) > 0 {
+		fmt.Fprintln(out)
+	}
+
+	s := report.Summary
+	fmt.Fprintf(out, "DECISIONS: %d\n", s.Decisions)
+	fmt.Fprintf(out, "UNCHANGED: %d\n", s.Unchanged)
+	fmt.Fprintf(out, "CHANGED: %d\n", s.Changed)
+	fmt.Fprintf(out, "NEW ERRORS: %d\n", s.NewErrors)
+	fmt.Fprintf(out, "RESOLVED ERRORS: %d\n", s.ResolvedErrors)
+	fmt.Fprintf(out, "SKIPPED: %d\n", s.Skipped)
+
+	if l := s.Latency; l != nil {
+		fmt.Fprintf(out, "\nLATENCY (%d decisions):\n", l.Decisions)
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  \tMEAN\tP50\tP90\tP99")
+		for _, row := range []struct {
+			name string
+			p    replay.Percentiles
+		}{{"recorded", l.Recorded}, {"replayed", l.Replayed}} {
+			fmt.Fprintf(w, "  %v\t%v\t%v\t%v\t%v\n", row.name, row.p.Mean, row.p.P50, row.p.P90, row.p.P99)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func replayStatusLabel(status string) string {
+	switch status {
+	case replay.Changed:
+		return "CHANGED"
+	case replay.NewError:
+		return "NEW ERROR"
+	case replay.ResolvedError:
+		return "RESOLVED ERROR"
+	default:
+		return "SKIPPED"
+	}
+}
+
+func replayValue(v *any) string {
+	if v == nil {
+		return "undefined"
+	}
+	bs, err := json.Marshal(*v)
+	if err != nil {
+		return fmt.Sprint(*v)
+	}
+	return truncateTableStr(string(bs))
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/cmd/formats"
+	"github.com/IUAD1IY7/opa/v1/util/test"
+)
+
+func TestDoReplay(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"policy/policy.rego": `package authz
+
+allow if input.user in data.admins`,
+		"policy/data.json": `{"admins": ["alice"]}`,
+		"decisions.log": `{"decision_id": "d1", "path": "authz/allow", "input": {"user": "alice"}, "result": true, "metrics": {"timer_rego_query_eval_ns": 1000}}
+{"decision_id": "d2", "path": "authz/allow", "input": {"user": "bob"}, "result": true, "metrics": {"timer_rego_query_eval_ns": 2000}}
+{"decision_id": "d3", "query": "data.authz.allow = x"}
+`,
+	}
+
+	tests := []struct {
+		note   string
+		format string
+		exp    []string
+	}{
+		{
+			note:   "pretty",
+			format: formats.Pretty,
+			exp: []string{
+				"CHANGED: d2 (authz/allow)\n  recorded: true\n  replayed: undefined\n",
+				"SKIPPED: d3 ()\n  reason: ad-hoc queries are not replayed\n",
+				"DECISIONS: 3\nUNCHANGED: 1\nCHANGED: 1\nNEW ERRORS: 0\nRESOLVED ERRORS: 0\nSKIPPED: 1\n",
+				"LATENCY (2 decisions):",
+			},
+		},
+		{
+			note:   "json",
+			format: formats.JSON,
+			exp: []string{
+				`"decision_id": "d2"`,
+				`"status": "changed"`,
+				`"recorded_latency_ns": 2000`,
+				`"changed": 1`,
+			},
+		},
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		for _, tc := range tests {
+			t.Run(tc.note, func(t *testing.T) {
+				params := newReplayCommandParams()
+				if err := params.outputFormat.Set(tc.format); err != nil {
+					t.Fatal(err)
+				}
+				params.dataPaths = newrepeatedStringFlag([]string{filepath.Join(rootDir, "policy")})
+				params.v1Compatible = true
+
+				var buf bytes.Buffer
+				differs, err := doReplay(params, []string{filepath.Join(rootDir, "decisions.log")}, nil, &buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !differs {
+					t.Fatal("expected decisions to differ")
+				}
+
+				for _, exp := range tc.exp {
+					if !strings.Contains(buf.String(), exp) {
+						t.Fatalf("expected output to contain:\n%v\n\ngot:\n%v", exp, buf.String())
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestDoReplayStdin(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"policy.rego": `package authz
+
+allow if input.user == "alice"`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		params := newReplayCommandParams()
+		params.dataPaths = newrepeatedStringFlag([]string{rootDir})
+		params.v1Compatible = true
+
+		stdin := strings.NewReader(`[{"decision_id": "d1", "path": "authz/allow", "input": {"user": "alice"}, "result": true}]`)
+
+		var buf bytes.Buffer
+		differs, err := doReplay(params, []string{"-"}, stdin, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if differs {
+			t.Fatalf("expected no differences but got:\n%v", buf.String())
+		}
+
+		if !strings.Contains(buf.String(), "UNCHANGED: 1\n") {
+			t.Fatalf("expected decision to be unchanged but got:\n%v", buf.String())
+		}
+	})
+}
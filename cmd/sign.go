@@ -30,6 +30,8 @@ type signCmdParams struct {
 	outputFilePath string
 	bundleMode     bool
 	plugin         string
+	keyID          string
+	appendToFile   bool
 }
 
 const (
@@ -129,6 +131,19 @@ exists in the actual bundle. The file content is hashed using SHA256.
 To include additional claims in the payload use the --claims-file flag to provide
 a JSON file containing optional claims.
 
+The --signing-key-id flag sets the "kid" header of the JWT, which tells OPA which
+of its configured keys verifies the signature.
+
+Bundles can be signed by multiple keys, to be verified against a threshold of
+trusted keys (see the "signing.threshold" bundle configuration option). The
+--append flag adds the signature to the existing ".signatures.json" file at the
+output location instead of overwriting it. A signature previously made with the
+same key ID is replaced. Appending requires a key ID, and fails if the existing
+signatures were made over different files.
+
+	$ opa sign --signing-key alice.pem --signing-key-id alice --bundle foo
+	$ opa sign --signing-key bob.pem --signing-key-id bob --append --bundle foo
+
 For more information on the format of the ".signatures.json" file see
 https://www.openpolicyagent.org/docs/latest/management-bundles/#signature-format.
 `,
@@ -156,6 +171,8 @@ https://www.openpolicyagent.org/docs/latest/management-bundles/#signature-format
 	addSigningPluginFlag(signCommand.Flags(), &cmdParams.plugin)
 
 	signCommand.Flags().StringVarP(&cmdParams.outputFilePath, "output-file-path", "o", ".", "set the location for the .signatures.json file")
+	signCommand.Flags().StringVarP(&cmdParams.keyID, "signing-key-id", "", "", "set the ID of the signing key (the JWT kid header)")
+	signCommand.Flags().BoolVarP(&cmdParams.appendToFile, "append", "", false, "add the signature to the existing .signatures.json file")
 
 	RootCommand.AddCommand(signCommand)
 }
@@ -181,11 +198,15 @@ func doSign(args []string, params signCmdParams) error {
 		return err
 	}
 
-	token, err := bundle.GenerateSignedToken(files, signingConfig, "")
+	token, err := bundle.GenerateSignedToken(files, signingConfig, params.keyID)
 	if err != nil {
 		return err
 	}
 
+	if params.appendToFile {
+		return appendTokenToFile(token, params.keyID, files, params.outputFilePath)
+	}
+
 	return writeTokenToFile(token, params.outputFilePath)
 }
 
@@ -257,19 +278,60 @@ func hashFileContent(h bundle.SignatureHasher, data []byte, path string) (bundle
 }
 
 func writeTokenToFile(token, fileLoc string) error {
+	return writeTokensToFile([]string{token}, fileLoc)
+}
+
+// appendTokenToFile adds the token to the signatures file at fileLoc, replacing
+// the signature previously made with the same key. The existing signatures
+// must have been made over the same files.
+func appendTokenToFile(token, keyID string, files []bundle.FileInfo, fileLoc string) error {
+	bs, err := os.ReadFile(signaturesFilePath(fileLoc))
+	if errors.Is(err, os.ErrNotExist) {
+		return writeTokenToFile(token, fileLoc)
+	} else if err != nil {
+		return err
+	}
+
+	var sc bundle.SignaturesConfig
+	if err := util.Unmarshal(bs, &sc); err != nil {
+		return fmt.Errorf("failed to parse %v: %w", signaturesFile, err)
+	}
+
+	tokens := make([]string, 0, len(sc.Signatures)+1)
+	for i, existing := range sc.Signatures {
+		kid, ds, err := bundle.DecodeSignature(existing)
+		if err != nil {
+			return fmt.Errorf("%v: signature %d: %w", signaturesFile, i, err)
+		}
+		if kid == keyID {
+			continue
+		}
+		if !bundle.SameFiles(files, ds.Files) {
+			return fmt.Errorf("%v: signature %d (key %v) was made over different files, sign the bundle again without --append", signaturesFile, i, kid)
+		}
+		tokens = append(tokens, existing)
+	}
+
+	return writeTokensToFile(append(tokens, token), fileLoc)
+}
+
+func writeTokensToFile(tokens []string, fileLoc string) error {
 	content := make(map[string]any)
-	content["signatures"] = []string{token}
+	content["signatures"] = tokens
 
 	bs, err := json.MarshalIndent(content, "", " ")
 	if err != nil {
 		return err
 	}
 
-	path := signaturesFile
-	if fileLoc != "" {
-		path = filepath.Join(fileLoc, path)
+	return os.WriteFile(signaturesFilePath(fileLoc), bs, 0644)
+}
+
+func signaturesFilePath(fileLoc string) string {
+	if fileLoc == "" {
+		return signaturesFile
 	}
-	return os.WriteFile(path, bs, 0644)
+	return filepath.Join(fileLoc, signaturesFile)
 }
 
 func validateSignParams(args []string, params signCmdParams) error {
@@ -284,5 +346,9 @@ func validateSignParams(args []string, params signCmdParams) error {
 	if !params.bundleMode {
 		return errors.New("enable bundle mode (ie. --bundle) to sign bundle files or directories")
 	}
+
+	if params.appendToFile && params.keyID == "" {
+		return errors.New("specify the signing key ID (ie. --signing-key-id) to append a signature")
+	}
 	return nil
 }
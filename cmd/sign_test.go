@@ -9,6 +9,8 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/IUAD1IY7/opa/internal/file/archive"
@@ -128,6 +130,75 @@ func TestBundleSignVerification(t *testing.T) {
 	})
 }
 
+func TestSignAppend(t *testing.T) {
+
+	files := map[string]string{
+		"/.manifest":            `{"revision": "quickbrownfaux"}`,
+		"/example/example.rego": `package example`,
+		"/data.json":            `{"x": true}`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		sign := func(key, keyID string) error {
+			return doSign([]string{rootDir}, signCmdParams{
+				algorithm:      "HS256",
+				key:            key,
+				keyID:          keyID,
+				outputFilePath: rootDir,
+				bundleMode:     true,
+				appendToFile:   true,
+			})
+		}
+
+		for _, signer := range [][2]string{{"alice-secret", "alice"}, {"bob-secret", "bob"}, {"alice-secret", "alice"}} {
+			if err := sign(signer[0], signer[1]); err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+		}
+
+		bs, err := os.ReadFile(filepath.Join(rootDir, ".signatures.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var sc bundle.SignaturesConfig
+		if err := json.Unmarshal(bs, &sc); err != nil {
+			t.Fatal(err)
+		}
+
+		var keyIDs []string
+		for _, token := range sc.Signatures {
+			kid, _, err := bundle.DecodeSignature(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keyIDs = append(keyIDs, kid)
+		}
+		if !reflect.DeepEqual(keyIDs, []string{"bob", "alice"}) {
+			t.Fatalf("Expected signatures by bob and alice but got %v", keyIDs)
+		}
+
+		bvc := bundle.NewVerificationConfig(map[string]*keys.Config{
+			"alice": {Key: "alice-secret", Algorithm: "HS256"},
+			"bob":   {Key: "bob-secret", Algorithm: "HS256"},
+		}, "", "", nil)
+		bvc.Threshold = 2
+
+		if _, err := bundle.VerifyBundleSignature(sc, bvc); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(rootDir, "data.json"), []byte(`{"x": false}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err = sign("carol-secret", "carol")
+		if err == nil || !strings.Contains(err.Error(), "signature 0 (key bob) was made over different files") {
+			t.Fatalf("Expected error but got %v", err)
+		}
+	})
+}
+
 func TestValidateSignParams(t *testing.T) {
 
 	tests := map[string]struct {
@@ -156,6 +227,11 @@ func TestValidateSignParams(t *testing.T) {
 			signCmdParams{key: "foo"},
 			true, errors.New("enable bundle mode (ie. --bundle) to sign bundle files or directories"),
 		},
+		"append_without_key_id": {
+			[]string{"foo"},
+			signCmdParams{key: "foo", bundleMode: true, appendToFile: true},
+			true, errors.New("specify the signing key ID (ie. --signing-key-id) to append a signature"),
+		},
 		"no_error": {
 			[]string{"foo"},
 			signCmdParams{key: "foo", bundleMode: true},
@@ -45,6 +45,7 @@ type testCommandParams struct {
 	outputFormat *util.EnumFlag
 	coverage     bool
 	threshold    float64
+	mergeCover   []string
 	timeout      time.Duration
 	ignore       []string
 	bundleMode   bool
@@ -68,7 +69,7 @@ type testCommandParams struct {
 
 func newTestCommandParams() testCommandParams {
 	return testCommandParams{
-		outputFormat: formats.Flag(formats.Pretty, formats.JSON, formats.GoBench),
+		outputFormat: formats.Flag(formats.Pretty, formats.JSON, formats.GoBench, formats.JUnit, formats.TAP, formats.LCOV, formats.Cobertura),
 		explain:      newExplainFlag([]string{explainModeFails, explainModeFull, explainModeNotes, explainModeDebug}),
 		target:       util.NewEnumFlag(compile.TargetRego, []string{compile.TargetRego, compile.TargetWasm}),
 		capabilities: newcapabilitiesFlag(),
@@ -101,6 +102,17 @@ func opaTest(args []string, testParams testCommandParams) int {
 		return 0
 	}
 
+	if !testParams.coverage && testParams.threshold == 0 {
+		switch of := testParams.outputFormat.String(); {
+		case of == formats.LCOV || of == formats.Cobertura:
+			_, _ = fmt.Fprintf(testParams.errOutput, "cannot use output format %s without reporting coverage (--coverage)\n", of)
+			return 1
+		case len(testParams.mergeCover) > 0:
+			_, _ = fmt.Fprintln(testParams.errOutput, "cannot merge coverage reports without reporting coverage (--coverage)")
+			return 1
+		}
+	}
+
 	if !isThresholdValid(testParams.threshold) {
 		_, _ = fmt.Fprintln(testParams.errOutput, "Code coverage threshold must be between 0 and 100")
 		return 1
@@ -415,6 +427,18 @@ func compileAndSetupTests(ctx context.Context, testParams testCommandParams, sto
 			reporter = tester.JSONReporter{
 				Output: testParams.output,
 			}
+		case formats.JUnit:
+			reporter = tester.JUnitReporter{
+				Output:    testParams.output,
+				Verbose:   testParams.verbose,
+				LocalVars: testParams.varValues,
+			}
+		case formats.TAP:
+			reporter = tester.TAPReporter{
+				Output:    testParams.output,
+				Verbose:   testParams.verbose,
+				LocalVars: testParams.varValues,
+			}
 		case formats.GoBench:
 			goBench = true
 			fallthrough
@@ -430,18 +454,50 @@ func compileAndSetupTests(ctx context.Context, testParams testCommandParams, sto
 			}
 		}
 	} else {
-		reporter = tester.JSONCoverageReporter{
+		merge, err := loadCoverageReports(testParams.mergeCover)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		coverageReporter := tester.JSONCoverageReporter{
 			Cover:     cov,
 			Modules:   modules,
 			Output:    testParams.output,
 			Threshold: testParams.threshold,
 			Verbose:   testParams.verbose,
+			Merge:     merge,
+		}
+
+		switch testParams.outputFormat.String() {
+		case formats.LCOV:
+			reporter = tester.LCOVCoverageReporter(coverageReporter)
+		case formats.Cobertura:
+			reporter = tester.CoberturaCoverageReporter(coverageReporter)
+		default:
+			reporter = coverageReporter
 		}
 	}
 
 	return runner, reporter, nil
 }
 
+// loadCoverageReports loads the JSON coverage reports of other test runs.
+func loadCoverageReports(paths []string) ([]cover.Report, error) {
+	reports := make([]cover.Report, 0, len(paths))
+	for _, path := range paths {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var report cover.Report
+		if err := util.UnmarshalJSON(bs, &report); err != nil {
+			return nil, fmt.Errorf("failed to load coverage report %v: %w", path, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
 func init() {
 	var testParams = newTestCommandParams()
 
@@ -513,6 +569,19 @@ Example benchmark run:
 
 The optional "gobench" output format conforms to the Go Benchmark Data Format.
 
+The optional "junit" and "tap" output formats report the results in the JUnit XML and
+TAP version 14 formats, for CI systems to show them. Each package is reported as a test
+suite, or a TAP subtest, with the duration, failure trace and print() output of its tests.
+
+When reporting coverage (--coverage), the report is output as JSON by default, or in the
+LCOV tracefile or Cobertura XML formats with the "lcov" and "cobertura" output formats.
+Rules are reported as functions, or methods, in both. The --merge-coverage flag merges the
+JSON coverage reports of other test runs into the report, e.g. to combine the coverage of
+tests run separately for several bundles:
+
+	$ opa test --coverage --format json ./a/ > a.json
+	$ opa test --coverage --format lcov --merge-coverage a.json ./b/ > lcov.info
+
 The --watch flag can be used to monitor policy and data file-system changes. When a change is detected, OPA reloads
 the policy and data and then re-runs the tests. Watching individual files (rather than directories) is generally not
 recommended as some updates might cause them to be dropped by OPA.
@@ -541,6 +610,7 @@ recommended as some updates might cause them to be dropped by OPA.
 	testCommand.Flags().DurationVar(&testParams.timeout, "timeout", 0, "set test timeout (default 5s, 30s when benchmarking)")
 	testCommand.Flags().BoolVarP(&testParams.coverage, "coverage", "c", false, "report coverage (overrides debug tracing)")
 	testCommand.Flags().Float64VarP(&testParams.threshold, "threshold", "", 0, "set coverage threshold and exit with non-zero status if coverage is less than threshold %")
+	testCommand.Flags().StringArrayVar(&testParams.mergeCover, "merge-coverage", []string{}, "merge the coverage report with the JSON coverage report of another test run. This flag can be repeated.")
 	testCommand.Flags().BoolVar(&testParams.benchmark, "bench", false, "benchmark the unit tests")
 	testCommand.Flags().StringVarP(&testParams.runRegex, "run", "r", "", "run only test cases matching the regular expression")
 	testCommand.Flags().BoolVarP(&testParams.watch, "watch", "w", false, "watch command line files for changes")
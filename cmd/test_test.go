@@ -2358,6 +2358,71 @@ func TestExitCode(t *testing.T) {
 	}
 }
 
+func TestCIOutputFormats(t *testing.T) {
+	files := map[string]string{
+		"test.rego": `package foo
+
+test_pass if { print("hello") }
+
+test_fail if { false }
+
+todo_test_skip if { true }
+`,
+	}
+
+	tests := []struct {
+		format string
+		exp    []string
+	}{
+		{
+			format: "junit",
+			exp: []string{
+				`<testsuite name="data.foo" tests="3" failures="1" errors="0" skipped="1"`,
+				`<system-out><![CDATA[hello`,
+				`<failure message="data.foo.test_fail: FAIL`,
+				`<skipped message="todo test (todo_test_ prefix)"></skipped>`,
+			},
+		},
+		{
+			format: "tap",
+			exp: []string{
+				"# Subtest: data.foo\n    1..3\n",
+				`output: "hello\n"`,
+				" - test_fail\n      ---\n",
+				"severity: fail",
+				" - todo_test_skip # SKIP todo test (todo_test_ prefix)\n",
+				"not ok 1 - data.foo\n",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			test.WithTempFS(files, func(path string) {
+				var buf bytes.Buffer
+
+				testParams := newTestCommandParams()
+				testParams.count = 1
+				testParams.errOutput = io.Discard
+				testParams.output = &buf
+				if err := testParams.outputFormat.Set(tc.format); err != nil {
+					t.Fatal(err)
+				}
+
+				if exitCode := opaTest([]string{path}, testParams); exitCode != 2 {
+					t.Fatalf("expected exit code 2 but got %d", exitCode)
+				}
+
+				for _, exp := range tc.exp {
+					if !strings.Contains(buf.String(), exp) {
+						t.Fatalf("expected output to contain %q but got:\n\n%s", exp, buf.String())
+					}
+				}
+			})
+		})
+	}
+}
+
 func TestCoverageThreshold(t *testing.T) {
 	testCases := []struct {
 		note              string
@@ -2480,6 +2545,80 @@ Lines not covered:
 	}
 }
 
+func TestCoverageOutputFormats(t *testing.T) {
+	files := map[string]string{
+		"policy.rego": `package foo
+
+allow if input.x == 1
+
+deny if input.x == 2
+`,
+		"allow_test.rego": `package foo
+
+test_allow if allow with input.x as 1
+`,
+		"deny_test.rego": `package foo
+
+test_deny if deny with input.x as 2
+`,
+	}
+
+	test.WithTempFS(files, func(root string) {
+		run := func(coverage bool, format string, merge []string, args ...string) (int, string, string) {
+			var buf, errBuf bytes.Buffer
+
+			testParams := newTestCommandParams()
+			testParams.count = 1
+			testParams.coverage = coverage
+			testParams.mergeCover = merge
+			testParams.output = &buf
+			testParams.errOutput = &errBuf
+			if err := testParams.outputFormat.Set(format); err != nil {
+				t.Fatal(err)
+			}
+
+			exitCode := opaTest(args, testParams)
+			return exitCode, buf.String(), errBuf.String()
+		}
+
+		policy := filepath.Join(root, "policy.rego")
+		report := filepath.Join(root, "report.json")
+
+		exitCode, output, _ := run(true, "json", nil, policy, filepath.Join(root, "allow_test.rego"))
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0 but got %d", exitCode)
+		}
+		if err := os.WriteFile(report, []byte(output), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		exitCode, output, _ = run(true, "lcov", nil, policy, filepath.Join(root, "deny_test.rego"))
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0 but got %d", exitCode)
+		}
+		for _, exp := range []string{"SF:" + policy + "\n", "FNDA:0,data.foo.allow\n", "FNDA:1,data.foo.deny\n", "DA:3,0\n", "DA:5,1\n"} {
+			if !strings.Contains(output, exp) {
+				t.Fatalf("expected output to contain %q but got:\n\n%s", exp, output)
+			}
+		}
+
+		exitCode, output, _ = run(true, "cobertura", []string{report}, policy, filepath.Join(root, "deny_test.rego"))
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0 but got %d", exitCode)
+		}
+		for _, exp := range []string{`<method name="data.foo.allow" signature="" line-rate="1"`, `<line number="3" hits="1">`, `<line number="5" hits="1">`} {
+			if !strings.Contains(output, exp) {
+				t.Fatalf("expected output to contain %q but got:\n\n%s", exp, output)
+			}
+		}
+
+		exitCode, _, errOutput := run(false, "lcov", nil, root)
+		if exitCode != 1 || !strings.Contains(errOutput, "cannot use output format lcov without reporting coverage") {
+			t.Fatalf("expected error but got exit code %d and: %v", exitCode, errOutput)
+		}
+	})
+}
+
 type loadType int
 
 const (
@@ -0,0 +1,84 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+// Patch operations of delta bundles.
+const (
+	patchUpsert  = "upsert"
+	patchReplace = "replace"
+	patchRemove  = "remove"
+)
+
+// Delta returns a delta bundle that patches the data and modules of the old
+// snapshot bundle into those of the new one. The delta bundle has the manifest
+// of the new bundle. Modules are compared by source, so that the modules stored
+// after activating the delta bundle match the new bundle exactly.
+func Delta(old, new *bundle.Bundle) (*bundle.Bundle, error) {
+	for _, b := range []*bundle.Bundle{old, new} {
+		if b.Type() != bundle.SnapshotBundleType {
+			return nil, errors.New("delta bundles can only be built between snapshot bundles")
+		}
+		if len(b.WasmModules) != 0 || len(b.PlanModules) != 0 {
+			return nil, errors.New("delta bundles cannot contain wasm modules or plans")
+		}
+	}
+
+	if d := manifests(&old.Manifest, &new.Manifest); d != nil && (d.Roots != nil || d.WasmResolvers != nil) {
+		return nil, errors.New("delta bundles cannot change the manifest roots or wasm resolvers")
+	}
+
+	var patch bundle.Patch
+
+	for _, c := range data(old.Data, new.Data) {
+		op := bundle.PatchOperation{Path: c.Path, Value: c.Value}
+		switch c.Op {
+		case OpAdd:
+			op.Op = patchUpsert
+		case OpReplace:
+			op.Op = patchReplace
+		case OpRemove:
+			op.Op = patchRemove
+		}
+		patch.Data = append(patch.Data, op)
+	}
+
+	oldByPath := moduleSources(old.Modules)
+	newByPath := moduleSources(new.Modules)
+
+	for _, path := range util.KeysSorted(newByPath) {
+		if src, ok := oldByPath[path]; !ok || !bytes.Equal(src, newByPath[path]) {
+			patch.Modules = append(patch.Modules, bundle.ModulePatchOperation{Op: patchUpsert, Path: path, Value: string(newByPath[path])})
+		}
+	}
+
+	for _, path := range util.KeysSorted(oldByPath) {
+		if _, ok := newByPath[path]; !ok {
+			patch.Modules = append(patch.Modules, bundle.ModulePatchOperation{Op: patchRemove, Path: path})
+		}
+	}
+
+	if len(patch.Data) == 0 && len(patch.Modules) == 0 {
+		return nil, errors.New("bundles have the same data and modules, no delta to build")
+	}
+
+	return &bundle.Bundle{Manifest: new.Manifest, Patch: patch}, nil
+}
+
+func moduleSources(modules []bundle.ModuleFile) map[string][]byte {
+	result := make(map[string][]byte, len(modules))
+	for _, m := range modules {
+		result["/"+strings.TrimLeft(m.Path, "/")] = m.Raw
+	}
+	return result
+}
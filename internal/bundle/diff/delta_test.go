@@ -0,0 +1,128 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/bundle"
+)
+
+func TestDelta(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		old     map[string]string
+		new     map[string]string
+		exp     bundle.Patch
+		wantErr string
+	}{
+		{
+			note: "data and modules",
+			old: map[string]string{
+				"/data.json":  `{"a": {"x": 1, "y": 2}}`,
+				"/a/foo.rego": "package a.foo\n\np := 1\n",
+				"/a/bar.rego": "package a.bar\n\np := 1\n",
+			},
+			new: map[string]string{
+				"/data.json":  `{"a": {"x": 2, "z": 3}}`,
+				"/a/foo.rego": "package a.foo\n\np := 2\n",
+				"/a/baz.rego": "package a.baz\n\np := 1\n",
+			},
+			exp: bundle.Patch{
+				Data: []bundle.PatchOperation{
+					{Op: "replace", Path: "/a/x", Value: json.Number("2")},
+					{Op: "upsert", Path: "/a/z", Value: json.Number("3")},
+					{Op: "remove", Path: "/a/y"},
+				},
+				Modules: []bundle.ModulePatchOperation{
+					{Op: "upsert", Path: "/a/baz.rego", Value: "package a.baz\n\np := 1\n"},
+					{Op: "upsert", Path: "/a/foo.rego", Value: "package a.foo\n\np := 2\n"},
+					{Op: "remove", Path: "/a/bar.rego"},
+				},
+			},
+		},
+		{
+			note: "modules only",
+			old: map[string]string{
+				"/a/foo.rego": "package a.foo\n\np := 1\n",
+			},
+			new: map[string]string{
+				"/a/foo.rego": "package a.foo\n\np := 1\n",
+				"/a/bar.rego": "package a.bar\n\np := 1\n",
+			},
+			exp: bundle.Patch{
+				Modules: []bundle.ModulePatchOperation{
+					{Op: "upsert", Path: "/a/bar.rego", Value: "package a.bar\n\np := 1\n"},
+				},
+			},
+		},
+		{
+			note: "no changes",
+			old: map[string]string{
+				"/data.json":  `{"a": 1}`,
+				"/a/foo.rego": "package a.foo\n\np := 1\n",
+			},
+			new: map[string]string{
+				"/data.json":  `{"a": 1}`,
+				"/a/foo.rego": "package a.foo\n\np := 1\n",
+			},
+			wantErr: "no delta to build",
+		},
+		{
+			note: "roots changed",
+			old: map[string]string{
+				"/.manifest": `{"roots": ["a"]}`,
+				"/data.json": `{"a": 1}`,
+			},
+			new: map[string]string{
+				"/.manifest": `{"roots": ["a", "b"]}`,
+				"/data.json": `{"a": 2}`,
+			},
+			wantErr: "cannot change the manifest roots",
+		},
+		{
+			note: "delta bundle",
+			old: map[string]string{
+				"/data.json": `{"a": 1}`,
+			},
+			new: map[string]string{
+				"/patch.json": `{"data": [{"op": "upsert", "path": "/a", "value": 2}]}`,
+			},
+			wantErr: "can only be built between snapshot bundles",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			old := loadTestBundle(t, tc.old, false)
+			new := loadTestBundle(t, tc.new, false)
+
+			result, err := Delta(old, new)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "":
+				if result.Type() != bundle.DeltaBundleType {
+					t.Fatalf("expected delta bundle but got %v", result.Type())
+				}
+				for i := range result.Patch.Modules {
+					result.Patch.Modules[i].Parsed = nil
+				}
+				if !reflect.DeepEqual(result.Patch, tc.exp) {
+					t.Fatalf("expected patch:\n\n%+v\n\ngot:\n\n%+v", tc.exp, result.Patch)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package diff compares bundle revisions.
+package diff
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+// Statuses of modules and rules.
+const (
+	Added    = "added"
+	Removed  = "removed"
+	Modified = "modified"
+)
+
+// Operations of data changes, named after their JSON Patch counterparts.
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Result describes the differences between two bundles.
+type Result struct {
+	Manifest *ManifestDiff `json:"manifest,omitempty"`
+	Modules  []ModuleDiff  `json:"modules,omitempty"`
+	Data     []DataChange  `json:"data,omitempty"`
+}
+
+// Empty returns true if the bundles don't differ.
+func (r *Result) Empty() bool {
+	return r.Manifest == nil && len(r.Modules) == 0 && len(r.Data) == 0
+}
+
+// Change is a value that changed between bundles. Old or New is nil if the
+// value is unset in the respective bundle.
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// ManifestDiff describes the differences between bundle manifests.
+type ManifestDiff struct {
+	Revision         *Change  `json:"revision,omitempty"`
+	RegoVersion      *Change  `json:"rego_version,omitempty"`
+	Metadata         *Change  `json:"metadata,omitempty"`
+	Roots            *SetDiff `json:"roots,omitempty"`
+	WasmResolvers    *SetDiff `json:"wasm_resolvers,omitempty"`
+	FileRegoVersions *SetDiff `json:"file_rego_versions,omitempty"`
+}
+
+// SetDiff lists the elements added to, removed from or modified in a set.
+type SetDiff struct {
+	Added    []string `json:"added,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	Modified []string `json:"modified,omitempty"`
+}
+
+func (s *SetDiff) empty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0 && len(s.Modified) == 0
+}
+
+// ModuleDiff describes an added, removed or modified module. The rules of
+// modified modules are compared by the documents they define.
+type ModuleDiff struct {
+	Path    string     `json:"path"`
+	Status  string     `json:"status"`
+	Package *Change    `json:"package,omitempty"`
+	Imports *SetDiff   `json:"imports,omitempty"`
+	Rules   []RuleDiff `json:"rules,omitempty"`
+}
+
+// RuleDiff describes the rules defining a document that were added, removed
+// or modified. Old and New hold the source of the rules in each bundle.
+type RuleDiff struct {
+	Ref    string   `json:"ref"`
+	Status string   `json:"status"`
+	Old    []string `json:"old,omitempty"`
+	New    []string `json:"new,omitempty"`
+}
+
+// DataChange is a JSON Patch style operation on the bundle data. Path is a
+// JSON pointer into the data.
+type DataChange struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Load reads the bundle tarball or directory at path. Signatures aren't
+// verified.
+func Load(path string, regoVersion ast.RegoVersion) (*bundle.Bundle, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader *bundle.Reader
+	if info.IsDir() {
+		reader = bundle.NewCustomReader(bundle.NewDirectoryLoader(path))
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		reader = bundle.NewReader(f)
+	}
+
+	b, err := reader.
+		WithSkipBundleVerification(true).
+		WithRegoVersion(regoVersion).
+		Read()
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// Bundles returns the differences between the old and new bundle.
+func Bundles(old, new *bundle.Bundle) *Result {
+	return &Result{
+		Manifest: manifests(&old.Manifest, &new.Manifest),
+		Modules:  modules(old.Modules, new.Modules),
+		Data:     data(old.Data, new.Data),
+	}
+}
+
+func manifests(old, new *bundle.Manifest) *ManifestDiff {
+	var d ManifestDiff
+	changed := false
+
+	if old.Revision != new.Revision {
+		d.Revision = &Change{Old: old.Revision, New: new.Revision}
+		changed = true
+	}
+
+	if !reflect.DeepEqual(old.RegoVersion, new.RegoVersion) {
+		d.RegoVersion = &Change{Old: intOrNil(old.RegoVersion), New: intOrNil(new.RegoVersion)}
+		changed = true
+	}
+
+	if !reflect.DeepEqual(old.Metadata, new.Metadata) {
+		d.Metadata = &Change{Old: old.Metadata, New: new.Metadata}
+		changed = true
+	}
+
+	if s := sets(roots(old), roots(new)); !s.empty() {
+		d.Roots = s
+		changed = true
+	}
+
+	if s := sets(wasmResolvers(old), wasmResolvers(new)); !s.empty() {
+		d.WasmResolvers = s
+		changed = true
+	}
+
+	if s := sets(fileRegoVersions(old), fileRegoVersions(new)); !s.empty() {
+		d.FileRegoVersions = s
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return &d
+}
+
+func intOrNil(x *int) any {
+	if x == nil {
+		return nil
+	}
+	return *x
+}
+
+func roots(m *bundle.Manifest) map[string]string {
+	result := map[string]string{}
+	if m.Roots != nil {
+		for _, r := range *m.Roots {
+			result[r] = r
+		}
+	}
+	return result
+}
+
+// wasmResolvers returns the modules of the resolvers by entrypoint.
+func wasmResolvers(m *bundle.Manifest) map[string]string {
+	result := make(map[string]string, len(m.WasmResolvers))
+	for _, wr := range m.WasmResolvers {
+		result[wr.Entrypoint] = wr.Module
+	}
+	return result
+}
+
+func fileRegoVersions(m *bundle.Manifest) map[string]string {
+	result := make(map[string]string, len(m.FileRegoVersions))
+	for path, v := range m.FileRegoVersions {
+		result[path] = ast.RegoVersionFromInt(v).String()
+	}
+	return result
+}
+
+// sets compares sets of keys, whose values are modified if they differ.
+func sets[V comparable](old, new map[string]V) *SetDiff {
+	var d SetDiff
+
+	for _, k := range util.KeysSorted(new) {
+		if v, ok := old[k]; !ok {
+			d.Added = append(d.Added, k)
+		} else if v != new[k] {
+			d.Modified = append(d.Modified, k)
+		}
+	}
+
+	for _, k := range util.KeysSorted(old) {
+		if _, ok := new[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+
+	return &d
+}
+
+func modules(old, new []bundle.ModuleFile) []ModuleDiff {
+	oldByPath := make(map[string]*ast.Module, len(old))
+	for _, m := range old {
+		oldByPath[m.Path] = m.Parsed
+	}
+	newByPath := make(map[string]*ast.Module, len(new))
+	for _, m := range new {
+		newByPath[m.Path] = m.Parsed
+	}
+
+	var result []ModuleDiff
+
+	for _, path := range util.KeysSorted(newByPath) {
+		m := newByPath[path]
+		o, ok := oldByPath[path]
+		switch {
+		case !ok:
+			result = append(result, ModuleDiff{Path: path, Status: Added, Rules: rules(nil, m)})
+		case !o.Equal(m):
+			result = append(result, moduleDiff(path, o, m))
+		}
+	}
+
+	for _, path := range util.KeysSorted(oldByPath) {
+		if _, ok := newByPath[path]; !ok {
+			result = append(result, ModuleDiff{Path: path, Status: Removed, Rules: rules(oldByPath[path], nil)})
+		}
+	}
+
+	return result
+}
+
+func moduleDiff(path string, old, new *ast.Module) ModuleDiff {
+	d := ModuleDiff{Path: path, Status: Modified}
+
+	if !old.Package.Equal(new.Package) {
+		d.Package = &Change{Old: old.Package.Path.String(), New: new.Package.Path.String()}
+	}
+
+	if s := sets(imports(old), imports(new)); !s.empty() {
+		d.Imports = s
+	}
+
+	d.Rules = rules(old, new)
+
+	return d
+}
+
+func imports(m *ast.Module) map[string]string {
+	result := make(map[string]string, len(m.Imports))
+	for _, imp := range m.Imports {
+		result[imp.String()] = imp.String()
+	}
+	return result
+}
+
+// rules compares the rules of two modules, grouped by the document they
+// define. Either module may be nil.
+func rules(old, new *ast.Module) []RuleDiff {
+	oldByRef := rulesByRef(old)
+	newByRef := rulesByRef(new)
+
+	var result []RuleDiff
+
+	for _, ref := range util.KeysSorted(newByRef) {
+		n := newByRef[ref]
+		o, ok := oldByRef[ref]
+		switch {
+		case !ok:
+			result = append(result, RuleDiff{Ref: ref, Status: Added, New: sources(n)})
+		case !slices.EqualFunc(o, n, (*ast.Rule).Equal):
+			result = append(result, RuleDiff{Ref: ref, Status: Modified, Old: sources(o), New: sources(n)})
+		}
+	}
+
+	for _, ref := range util.KeysSorted(oldByRef) {
+		if _, ok := newByRef[ref]; !ok {
+			result = append(result, RuleDiff{Ref: ref, Status: Removed, Old: sources(oldByRef[ref])})
+		}
+	}
+
+	return result
+}
+
+func rulesByRef(m *ast.Module) map[string][]*ast.Rule {
+	result := map[string][]*ast.Rule{}
+	if m == nil {
+		return result
+	}
+
+	for _, rule := range m.Rules {
+		ref := rule.Ref().GroundPrefix().String()
+		result[ref] = append(result[ref], rule)
+	}
+
+	return result
+}
+
+// sources returns the source of the rules, or their string representation if
+// the source isn't known.
+func sources(rules []*ast.Rule) []string {
+	result := make([]string, len(rules))
+	for i, rule := range rules {
+		if rule.Location != nil && len(rule.Location.Text) > 0 {
+			result[i] = strings.TrimSpace(string(rule.Location.Text))
+		} else {
+			result[i] = rule.String()
+		}
+	}
+	return result
+}
+
+// data compares data documents, descending into objects. Other values are
+// replaced as a whole.
+func data(old, new map[string]any) []DataChange {
+	var result []DataChange
+	diffObjects("", old, new, &result)
+	return result
+}
+
+func diffObjects(prefix string, old, new map[string]any, result *[]DataChange) {
+	for _, k := range util.KeysSorted(new) {
+		path := prefix + "/" + escapePointer(k)
+		n := new[k]
+		o, ok := old[k]
+		if !ok {
+			*result = append(*result, DataChange{Op: OpAdd, Path: path, Value: n})
+			continue
+		}
+
+		oo, oldIsObj := o.(map[string]any)
+		no, newIsObj := n.(map[string]any)
+		switch {
+		case oldIsObj && newIsObj:
+			diffObjects(path, oo, no, result)
+		case !reflect.DeepEqual(o, n):
+			*result = append(*result, DataChange{Op: OpReplace, Path: path, Value: n})
+		}
+	}
+
+	for _, k := range util.KeysSorted(old) {
+		if _, ok := new[k]; !ok {
+			*result = append(*result, DataChange{Op: OpRemove, Path: prefix + "/" + escapePointer(k)})
+		}
+	}
+}
+
+// escapePointer escapes a JSON pointer reference token (RFC 6901).
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
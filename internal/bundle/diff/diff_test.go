@@ -0,0 +1,159 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/internal/file/archive"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+)
+
+func TestBundles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note string
+		old  map[string]string
+		new  map[string]string
+		exp  *Result
+	}{
+		{
+			note: "no differences",
+			old: map[string]string{
+				"/.manifest":       `{"revision": "r1"}`,
+				"/authz.rego":      "package authz\n\nallow if input.admin",
+				"/authz/data.json": `{"users": ["alice"]}`,
+			},
+			new: map[string]string{
+				"/.manifest":       `{"revision": "r1"}`,
+				"/authz.rego":      "package authz\n\n# reformatted\nallow if {\n\tinput.admin\n}",
+				"/authz/data.json": `{"users": ["alice"]}`,
+			},
+			exp: &Result{},
+		},
+		{
+			note: "manifest",
+			old: map[string]string{
+				"/.manifest":     `{"revision": "r1", "roots": ["a", "b"], "wasm": [{"entrypoint": "a/allow", "module": "/a/policy.wasm"}, {"entrypoint": "a/deny", "module": "/a/policy.wasm"}]}`,
+				"/a/policy.wasm": "a",
+			},
+			new: map[string]string{
+				"/.manifest":     `{"revision": "r2", "roots": ["a", "c"], "rego_version": 1, "wasm": [{"entrypoint": "a/allow", "module": "/b/policy.wasm"}]}`,
+				"/b/policy.wasm": "b",
+			},
+			exp: &Result{
+				Manifest: &ManifestDiff{
+					Revision:      &Change{Old: "r1", New: "r2"},
+					RegoVersion:   &Change{Old: nil, New: 1},
+					Roots:         &SetDiff{Added: []string{"c"}, Removed: []string{"b"}},
+					WasmResolvers: &SetDiff{Removed: []string{"a/deny"}, Modified: []string{"a/allow"}},
+				},
+			},
+		},
+		{
+			note: "modules",
+			old: map[string]string{
+				"/authz.rego": "package authz\n\nimport data.users\n\nallow if input.admin\n\ndeny contains \"x\" if input.x",
+				"/old.rego":   "package old\n\np := 1",
+			},
+			new: map[string]string{
+				"/authz.rego": "package authz\n\nallow if input.admin\n\nallow if input.owner\n\nreasons contains \"y\" if input.y",
+				"/new.rego":   "package new\n\nq := 2",
+			},
+			exp: &Result{
+				Modules: []ModuleDiff{
+					{
+						Path:    "/authz.rego",
+						Status:  Modified,
+						Imports: &SetDiff{Removed: []string{"import data.users"}},
+						Rules: []RuleDiff{
+							{
+								Ref:    "data.authz.allow",
+								Status: Modified,
+								Old:    []string{"allow if input.admin"},
+								New:    []string{"allow if input.admin", "allow if input.owner"},
+							},
+							{Ref: "data.authz.reasons", Status: Added, New: []string{`reasons contains "y" if input.y`}},
+							{Ref: "data.authz.deny", Status: Removed, Old: []string{`deny contains "x" if input.x`}},
+						},
+					},
+					{Path: "/new.rego", Status: Added, Rules: []RuleDiff{{Ref: "data.new.q", Status: Added, New: []string{"q := 2"}}}},
+					{Path: "/old.rego", Status: Removed, Rules: []RuleDiff{{Ref: "data.old.p", Status: Removed, Old: []string{"p := 1"}}}},
+				},
+			},
+		},
+		{
+			note: "data",
+			old: map[string]string{
+				"/data.json": `{"a": {"b": 1, "c": [1, 2], "d/e": true}, "f": "x"}`,
+			},
+			new: map[string]string{
+				"/data.json": `{"a": {"b": 1, "c": [1, 3], "g": {"h": null}}, "f": {"x": 1}}`,
+			},
+			exp: &Result{
+				Data: []DataChange{
+					{Op: OpReplace, Path: "/a/c", Value: []any{json.Number("1"), json.Number("3")}},
+					{Op: OpAdd, Path: "/a/g", Value: map[string]any{"h": nil}},
+					{Op: OpRemove, Path: "/a/d~1e"},
+					{Op: OpReplace, Path: "/f", Value: map[string]any{"x": json.Number("1")}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			old := loadTestBundle(t, tc.old, false)
+			new := loadTestBundle(t, tc.new, true)
+
+			result := Bundles(old, new)
+			if !reflect.DeepEqual(result, tc.exp) {
+				exp, _ := json.MarshalIndent(tc.exp, "", "  ")
+				got, _ := json.MarshalIndent(result, "", "  ")
+				t.Fatalf("expected:\n\n%s\n\ngot:\n\n%s", exp, got)
+			}
+		})
+	}
+}
+
+// loadTestBundle loads the files as a bundle tarball or directory.
+func loadTestBundle(t *testing.T, files map[string]string, asDir bool) *bundle.Bundle {
+	t.Helper()
+
+	var path string
+	if asDir {
+		path = t.TempDir()
+		for name, content := range files {
+			p := filepath.Join(path, name)
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	} else {
+		var tarFiles [][2]string
+		for name, content := range files {
+			tarFiles = append(tarFiles, [2]string{name, content})
+		}
+		path = filepath.Join(t.TempDir(), "bundle.tar.gz")
+		if err := os.WriteFile(path, archive.MustWriteTarGz(tarFiles).Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := Load(path, ast.RegoV1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
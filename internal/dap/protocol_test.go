@@ -0,0 +1,69 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package dap
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		input   string
+		exp     string
+		wantErr bool
+	}{
+		{
+			note:  "valid",
+			input: "Content-Length: 2\r\n\r\n{}",
+			exp:   "{}",
+		},
+		{
+			note:    "missing length",
+			input:   "\r\n{}",
+			wantErr: true,
+		},
+		{
+			note:    "negative length",
+			input:   "Content-Length: -1\r\n\r\n",
+			wantErr: true,
+		},
+		{
+			note:    "length too large",
+			input:   fmt.Sprintf("Content-Length: %d\r\n\r\n{}", maxMessageLength+1),
+			wantErr: true,
+		},
+		{
+			note:    "truncated content",
+			input:   "Content-Length: 10\r\n\r\n{}",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			content, err := readMessage(bufio.NewReader(strings.NewReader(tc.input)))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error but got content %q", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tc.exp {
+				t.Fatalf("expected %q but got %q", tc.exp, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,612 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/IUAD1IY7/opa/v1/ast/location"
+	"github.com/IUAD1IY7/opa/v1/debug"
+	"github.com/IUAD1IY7/opa/v1/logging"
+)
+
+// Launch commands.
+const (
+	evalCommand = "eval"
+	testCommand = "test"
+)
+
+// SessionArguments are the arguments of launch and attach requests controlling
+// the debug session.
+type SessionArguments struct {
+	StopOnEntry         bool `json:"stopOnEntry"`
+	StopOnFail          bool `json:"stopOnFail"`
+	StopOnResult        bool `json:"stopOnResult"`
+	EnablePrint         bool `json:"enablePrint"`
+	RuleIndexing        bool `json:"ruleIndexing"`
+	StrictBuiltinErrors bool `json:"strictBuiltinErrors"`
+}
+
+// LaunchProperties returns the launch properties of the debug session.
+func (a SessionArguments) LaunchProperties() debug.LaunchProperties {
+	return debug.LaunchProperties{
+		StopOnResult:        a.StopOnResult,
+		StopOnEntry:         a.StopOnEntry,
+		StopOnFail:          a.StopOnFail,
+		EnablePrint:         a.EnablePrint,
+		StrictBuiltinErrors: a.StrictBuiltinErrors,
+		RuleIndexing:        a.RuleIndexing,
+	}
+}
+
+// LaunchArguments are the arguments of the launch request, following the
+// launch configurations of the Rego debug adapters.
+type LaunchArguments struct {
+	SessionArguments
+	Command     string   `json:"command"`
+	Query       string   `json:"query"`
+	Input       any      `json:"input"`
+	InputPath   string   `json:"inputPath"`
+	BundlePaths []string `json:"bundlePaths"`
+	DataPaths   []string `json:"dataPaths"`
+	Run         string   `json:"run"`
+}
+
+func (a LaunchArguments) properties() debug.LaunchProperties {
+	props := a.LaunchProperties()
+	props.BundlePaths = a.BundlePaths
+	props.DataPaths = a.DataPaths
+	return props
+}
+
+// AttachArguments are the arguments of the attach request, selecting the
+// decision of a running OPA to debug.
+type AttachArguments struct {
+	SessionArguments
+
+	// Path is the path of the decision, e.g. "example/allow". Any decision
+	// matches if empty.
+	Path string `json:"path"`
+
+	// InputFilter is a Rego expression the input of the decision must satisfy,
+	// e.g. 'input.user == "alice"'.
+	InputFilter string `json:"inputFilter"`
+
+	// Timeout is the number of seconds to wait for a matching decision.
+	Timeout int `json:"timeout"`
+}
+
+// AttachFunc attaches the debugger to a decision matching the arguments,
+// returning the debug session once there is one.
+type AttachFunc func(ctx context.Context, d debug.Debugger, args AttachArguments) (debug.Session, error)
+
+// Server serves a single client of the Debug Adapter Protocol, debugging the
+// sessions it launches.
+type Server struct {
+	r        *bufio.Reader
+	w        io.Writer
+	logger   logging.Logger
+	debugger debug.Debugger
+	session  debug.Session
+	attach   AttachFunc
+
+	// Breakpoints are set per source by the client, replacing the previous
+	// ones.
+	breakpoints map[string][]debug.BreakpointID
+
+	// Lines and columns are 1-based unless the client says otherwise.
+	lineOffset   int
+	columnOffset int
+
+	mtx      sync.Mutex // guards the fields below, and writes
+	seq      int
+	handling bool    // whether a request is being handled
+	closed   bool    // whether the client has disconnected
+	pending  []Event // events to send once the request has been responded to
+}
+
+// NewServer returns a new server for the client reading and writing the
+// Debug Adapter Protocol messages on rw.
+func NewServer(rw io.ReadWriter, logger logging.Logger) *Server {
+	s := &Server{
+		r:           bufio.NewReader(rw),
+		w:           rw,
+		logger:      logger,
+		breakpoints: map[string][]debug.BreakpointID{},
+	}
+	s.debugger = debug.NewDebugger(debug.SetLogger(logger), debug.SetEventHandler(s.handleEvent))
+	return s
+}
+
+// WithAttach sets the function attaching the debugger to decisions, enabling
+// the attach request.
+func (s *Server) WithAttach(f AttachFunc) *Server {
+	s.attach = f
+	return s
+}
+
+// Serve handles the requests of the client until it disconnects, and
+// terminates the debug session.
+func (s *Server) Serve(ctx context.Context) error {
+	defer s.terminate()
+
+	for {
+		content, err := readMessage(s.r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req Request
+		if err := json.Unmarshal(content, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+		if req.Type != requestType {
+			s.logger.Warn("Ignoring %v message from client.", req.Type)
+			continue
+		}
+
+		if done, err := s.handleRequest(ctx, req); err != nil || done {
+			return err
+		}
+	}
+}
+
+func (s *Server) handleRequest(ctx context.Context, req Request) (bool, error) {
+	s.mtx.Lock()
+	s.handling = true
+	s.mtx.Unlock()
+
+	s.logger.Debug("Handling %v request.", req.Command)
+
+	var body any
+	var err error
+	var events []Event
+	done := false
+
+	switch req.Command {
+	case "initialize":
+		body, err = s.initialize(req.Arguments)
+	case "launch":
+		err = s.launch(ctx, req.Arguments)
+		if err == nil {
+			// The client is ready to be configured once the session is
+			// launched, stopped until the configuration is done.
+			events = append(events, Event{Event: "initialized"})
+		}
+	case "attach":
+		err = s.attachSession(ctx, req.Arguments)
+		if err == nil {
+			events = append(events, Event{Event: "initialized"})
+		}
+	case "setBreakpoints":
+		body, err = s.setBreakpoints(req.Arguments)
+	case "setExceptionBreakpoints":
+		body = map[string]any{"breakpoints": []Breakpoint{}}
+	case "configurationDone":
+		err = s.withSession(func(session debug.Session) error {
+			return session.ResumeAll()
+		})
+	case "threads":
+		body, err = s.threads()
+	case "stackTrace":
+		body, err = s.stackTrace(req.Arguments)
+	case "scopes":
+		body, err = s.scopes(req.Arguments)
+	case "variables":
+		body, err = s.variables(req.Arguments)
+	case "continue":
+		body, err = s.step(req.Arguments, debug.Session.Resume)
+		if err == nil {
+			body = map[string]any{"allThreadsContinued": false}
+		}
+	case "next":
+		_, err = s.step(req.Arguments, debug.Session.StepOver)
+	case "stepIn":
+		_, err = s.step(req.Arguments, debug.Session.StepIn)
+	case "stepOut":
+		_, err = s.step(req.Arguments, debug.Session.StepOut)
+	case "terminate":
+		err = s.withSession(debug.Session.Terminate)
+	case "disconnect":
+		s.terminate()
+		done = true
+	default:
+		err = fmt.Errorf("unsupported command: %v", req.Command)
+	}
+
+	resp := Response{
+		RequestSeq: req.Seq,
+		Success:    err == nil,
+		Command:    req.Command,
+		Body:       body,
+	}
+	if err != nil {
+		s.logger.Debug("Failed to handle %v request: %v", req.Command, err)
+		resp.Message = err.Error()
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.handling = false
+	events = append(events, s.pending...)
+	s.pending = nil
+
+	if err := s.write(&resp); err != nil {
+		return true, err
+	}
+	if done {
+		// The client doesn't expect any events once disconnected.
+		s.closed = true
+		return true, nil
+	}
+	for _, e := range events {
+		if err := s.write(&e); err != nil {
+			return true, err
+		}
+	}
+
+	return false, nil
+}
+
+// write writes the response or event to the client, in sequence. It must be
+// called with the mutex held.
+func (s *Server) write(msg any) error {
+	s.seq++
+	switch msg := msg.(type) {
+	case *Response:
+		msg.Seq, msg.Type = s.seq, responseType
+	case *Event:
+		msg.Seq, msg.Type = s.seq, eventType
+	}
+	return writeMessage(s.w, msg)
+}
+
+// sendEvent sends the event to the client, after the response to the request
+// being handled, if any.
+func (s *Server) sendEvent(event string, body any) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	e := Event{Event: event, Body: body}
+	if s.closed {
+		return
+	}
+	if s.handling {
+		s.pending = append(s.pending, e)
+		return
+	}
+	if err := s.write(&e); err != nil {
+		s.logger.Error("Failed to send %v event: %v", event, err)
+	}
+}
+
+func (s *Server) handleEvent(e debug.Event) {
+	switch e.Type {
+	case debug.StoppedEventType:
+		s.sendEvent("stopped", map[string]any{
+			"reason":            e.Message,
+			"threadId":          e.Thread,
+			"allThreadsStopped": false,
+		})
+	case debug.ExceptionEventType:
+		s.sendEvent("stopped", map[string]any{
+			"reason":            "exception",
+			"description":       e.Message,
+			"threadId":          e.Thread,
+			"allThreadsStopped": false,
+		})
+	case debug.StdoutEventType:
+		s.sendEvent("output", map[string]any{
+			"category": "stdout",
+			"output":   e.Message + "\n",
+		})
+	case debug.ThreadEventType:
+		s.sendEvent("thread", map[string]any{
+			"reason":   e.Message,
+			"threadId": e.Thread,
+		})
+	case debug.TerminatedEventType:
+		s.sendEvent("terminated", nil)
+	}
+}
+
+func (s *Server) initialize(args json.RawMessage) (any, error) {
+	var a struct {
+		LinesStartAt1   *bool `json:"linesStartAt1"`
+		ColumnsStartAt1 *bool `json:"columnsStartAt1"`
+	}
+	if err := unmarshalArguments(args, &a); err != nil {
+		return nil, err
+	}
+
+	if a.LinesStartAt1 != nil && !*a.LinesStartAt1 {
+		s.lineOffset = -1
+	}
+	if a.ColumnsStartAt1 != nil && !*a.ColumnsStartAt1 {
+		s.columnOffset = -1
+	}
+
+	return Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsConditionalBreakpoints:   true,
+		SupportsTerminateRequest:         true,
+	}, nil
+}
+
+func (s *Server) launch(ctx context.Context, args json.RawMessage) error {
+	if s.session != nil {
+		return errors.New("debug session already launched")
+	}
+
+	var a LaunchArguments
+	if err := unmarshalArguments(args, &a); err != nil {
+		return err
+	}
+
+	var session debug.Session
+	var err error
+
+	switch a.Command {
+	case evalCommand:
+		session, err = s.debugger.LaunchEval(ctx, debug.LaunchEvalProperties{
+			LaunchProperties: a.properties(),
+			Query:            a.Query,
+			Input:            a.Input,
+			InputPath:        a.InputPath,
+		})
+	case testCommand:
+		launcher, ok := s.debugger.(debug.TestLauncher)
+		if !ok {
+			return errors.New("debugging tests is not supported by the debugger")
+		}
+		session, err = launcher.LaunchTest(ctx, debug.LaunchTestProperties{
+			LaunchProperties: a.properties(),
+			Run:              a.Run,
+		})
+	default:
+		return fmt.Errorf("unsupported launch command %q, expected %q or %q", a.Command, evalCommand, testCommand)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.session = session
+	return nil
+}
+
+func (s *Server) attachSession(ctx context.Context, args json.RawMessage) error {
+	if s.attach == nil {
+		return errors.New("attach is not supported, launch a debug session instead")
+	}
+	if s.session != nil {
+		return errors.New("debug session already launched")
+	}
+
+	var a AttachArguments
+	if err := unmarshalArguments(args, &a); err != nil {
+		return err
+	}
+
+	session, err := s.attach(ctx, s.debugger, a)
+	if err != nil {
+		return err
+	}
+
+	s.session = session
+	return nil
+}
+
+func (s *Server) setBreakpoints(args json.RawMessage) (any, error) {
+	var a struct {
+		Source      Source             `json:"source"`
+		Breakpoints []SourceBreakpoint `json:"breakpoints"`
+	}
+	if err := unmarshalArguments(args, &a); err != nil {
+		return nil, err
+	}
+
+	var breakpoints []Breakpoint
+	err := s.withSession(func(session debug.Session) error {
+		path := filepath.Clean(a.Source.Path)
+
+		for _, id := range s.breakpoints[path] {
+			if _, err := session.RemoveBreakpoint(id); err != nil {
+				return err
+			}
+		}
+		delete(s.breakpoints, path)
+
+		for _, sbp := range a.Breakpoints {
+			line := sbp.Line - s.lineOffset
+			bp, err := addBreakpoint(session, location.Location{File: path, Row: line}, sbp.Condition)
+			if err != nil {
+				breakpoints = append(breakpoints, Breakpoint{Verified: false, Message: err.Error(), Source: &a.Source, Line: sbp.Line})
+				continue
+			}
+			s.breakpoints[path] = append(s.breakpoints[path], bp.ID())
+			breakpoints = append(breakpoints, Breakpoint{ID: int(bp.ID()), Verified: true, Source: &a.Source, Line: sbp.Line})
+		}
+
+		return nil
+	})
+
+	return map[string]any{"breakpoints": breakpoints}, err
+}
+
+// addBreakpoint adds a breakpoint to the session, which must support
+// conditional breakpoints if a condition is given.
+func addBreakpoint(session debug.Session, loc location.Location, condition string) (debug.Breakpoint, error) {
+	if condition == "" {
+		return session.AddBreakpoint(loc)
+	}
+	cs, ok := session.(debug.ConditionalBreakpointSession)
+	if !ok {
+		return nil, errors.New("conditional breakpoints are not supported by the debug session")
+	}
+	return cs.AddConditionalBreakpoint(loc, condition)
+}
+
+func (s *Server) threads() (any, error) {
+	threads := []Thread{}
+	err := s.withSession(func(session debug.Session) error {
+		ts, err := session.Threads()
+		for _, t := range ts {
+			threads = append(threads, Thread{ID: int(t.ID()), Name: t.Name()})
+		}
+		return err
+	})
+	return map[string]any{"threads": threads}, err
+}
+
+func (s *Server) stackTrace(args json.RawMessage) (any, error) {
+	var a struct {
+		ThreadID   int `json:"threadId"`
+		StartFrame int `json:"startFrame"`
+		Levels     int `json:"levels"`
+	}
+	if err := unmarshalArguments(args, &a); err != nil {
+		return nil, err
+	}
+
+	frames := []StackFrame{}
+	var total int
+	err := s.withSession(func(session debug.Session) error {
+		trace, err := session.StackTrace(debug.ThreadID(a.ThreadID))
+		if err != nil {
+			return err
+		}
+		total = len(trace)
+
+		trace = trace[min(a.StartFrame, len(trace)):]
+		if a.Levels > 0 {
+			trace = trace[:min(a.Levels, len(trace))]
+		}
+
+		for _, f := range trace {
+			frame := StackFrame{ID: int(f.ID()), Name: f.Name()}
+			if loc := f.Location(); loc != nil {
+				frame.Source = s.source(loc)
+				frame.Line = loc.Row + s.lineOffset
+				frame.Column = loc.Col + s.columnOffset
+			}
+			frames = append(frames, frame)
+		}
+		return nil
+	})
+
+	return map[string]any{"stackFrames": frames, "totalFrames": total}, err
+}
+
+func (s *Server) scopes(args json.RawMessage) (any, error) {
+	var a struct {
+		FrameID int `json:"frameId"`
+	}
+	if err := unmarshalArguments(args, &a); err != nil {
+		return nil, err
+	}
+
+	scopes := []Scope{}
+	err := s.withSession(func(session debug.Session) error {
+		ss, err := session.Scopes(debug.FrameID(a.FrameID))
+		for _, sc := range ss {
+			scope := Scope{
+				Name:               sc.Name(),
+				VariablesReference: int(sc.VariablesReference()),
+				NamedVariables:     sc.NamedVariables(),
+			}
+			if loc := sc.Location(); loc != nil {
+				scope.Source = s.source(loc)
+				scope.Line = loc.Row + s.lineOffset
+			}
+			scopes = append(scopes, scope)
+		}
+		return err
+	})
+
+	return map[string]any{"scopes": scopes}, err
+}
+
+func (s *Server) variables(args json.RawMessage) (any, error) {
+	var a struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := unmarshalArguments(args, &a); err != nil {
+		return nil, err
+	}
+
+	variables := []Variable{}
+	err := s.withSession(func(session debug.Session) error {
+		vs, err := session.Variables(debug.VarRef(a.VariablesReference))
+		for _, v := range vs {
+			variables = append(variables, Variable{
+				Name:               v.Name(),
+				Value:              v.Value(),
+				Type:               v.Type(),
+				VariablesReference: int(v.VariablesReference()),
+			})
+		}
+		return err
+	})
+
+	return map[string]any{"variables": variables}, err
+}
+
+func (s *Server) step(args json.RawMessage, f func(debug.Session, debug.ThreadID) error) (any, error) {
+	var a struct {
+		ThreadID int `json:"threadId"`
+	}
+	if err := unmarshalArguments(args, &a); err != nil {
+		return nil, err
+	}
+
+	return nil, s.withSession(func(session debug.Session) error {
+		return f(session, debug.ThreadID(a.ThreadID))
+	})
+}
+
+func (s *Server) withSession(f func(debug.Session) error) error {
+	if s.session == nil {
+		return errors.New("no active debug session")
+	}
+	return f(s.session)
+}
+
+func (s *Server) terminate() {
+	if s.session == nil {
+		return
+	}
+	if err := s.session.Terminate(); err != nil {
+		s.logger.Error("Failed to terminate debug session: %v", err)
+	}
+	s.session = nil
+}
+
+func (*Server) source(loc *location.Location) *Source {
+	if loc.File == "" {
+		return nil
+	}
+	return &Source{Name: filepath.Base(loc.File), Path: loc.File}
+}
+
+func unmarshalArguments(args json.RawMessage, v any) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
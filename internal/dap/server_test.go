@@ -0,0 +1,327 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/debug"
+	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util/test"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+}
+
+type testMessage struct {
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func newTestClient(t *testing.T, attach AttachFunc) *testClient {
+	t.Helper()
+
+	client, server := net.Pipe()
+	done := make(chan error)
+
+	go func() {
+		done <- NewServer(server, logging.NewNoOpLogger()).WithAttach(attach).Serve(context.Background())
+		server.Close()
+	}()
+
+	t.Cleanup(func() {
+		client.Close()
+		if err := <-done; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	return &testClient{t: t, conn: client, r: bufio.NewReader(client)}
+}
+
+func (c *testClient) send(command string, args any) int {
+	c.t.Helper()
+
+	c.seq++
+	bs, err := json.Marshal(args)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req := Request{
+		ProtocolMessage: ProtocolMessage{Seq: c.seq, Type: requestType},
+		Command:         command,
+		Arguments:       bs,
+	}
+	if err := writeMessage(c.conn, req); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.seq
+}
+
+func (c *testClient) read() testMessage {
+	c.t.Helper()
+
+	if err := c.conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		c.t.Fatal(err)
+	}
+	content, err := readMessage(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var msg testMessage
+	if err := json.Unmarshal(content, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// request sends the request and returns the body of its response, skipping
+// any events sent before it.
+func (c *testClient) request(command string, args any, body any) testMessage {
+	c.t.Helper()
+
+	seq := c.send(command, args)
+	for {
+		msg := c.read()
+		if msg.Type != responseType {
+			continue
+		}
+		if msg.RequestSeq != seq || msg.Command != command {
+			c.t.Fatalf("expected response to %v request %d but got: %+v", command, seq, msg)
+		}
+		if body != nil && msg.Success {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return msg
+	}
+}
+
+// expectEvent returns the body of the next event of the given type, skipping
+// any other events.
+func (c *testClient) expectEvent(event string, body any) {
+	c.t.Helper()
+
+	for {
+		msg := c.read()
+		if msg.Type != eventType || msg.Event != event {
+			continue
+		}
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+func TestServerEval(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"policy.rego": `package test
+
+allow if {
+	x := input.x
+	x == 1
+}`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		c := newTestClient(t, nil)
+		policy := filepath.Join(rootDir, "policy.rego")
+
+		var caps Capabilities
+		if resp := c.request("initialize", map[string]any{"adapterID": "opa"}, &caps); !resp.Success || !caps.SupportsConditionalBreakpoints {
+			t.Fatalf("unexpected initialize response: %+v, %+v", resp, caps)
+		}
+
+		resp := c.request("launch", LaunchArguments{
+			Command:   evalCommand,
+			Query:     "data.test.allow",
+			Input:     map[string]any{"x": 1},
+			DataPaths: []string{rootDir},
+		}, nil)
+		if !resp.Success {
+			t.Fatalf("failed to launch: %v", resp.Message)
+		}
+		c.expectEvent("initialized", nil)
+
+		var bps struct {
+			Breakpoints []Breakpoint `json:"breakpoints"`
+		}
+		c.request("setBreakpoints", map[string]any{
+			"source":      Source{Path: policy},
+			"breakpoints": []SourceBreakpoint{{Line: 5, Condition: "x == 1"}, {Line: 4, Condition: "x ="}},
+		}, &bps)
+		if len(bps.Breakpoints) != 2 || !bps.Breakpoints[0].Verified || bps.Breakpoints[1].Verified {
+			t.Fatalf("expected first breakpoint to be verified but got: %+v", bps.Breakpoints)
+		}
+		if !strings.Contains(bps.Breakpoints[1].Message, "invalid breakpoint condition") {
+			t.Fatalf("expected invalid condition but got: %v", bps.Breakpoints[1].Message)
+		}
+
+		c.request("configurationDone", nil, nil)
+
+		var stopped struct {
+			Reason   string `json:"reason"`
+			ThreadID int    `json:"threadId"`
+		}
+		c.expectEvent("stopped", &stopped)
+		if stopped.Reason != "breakpoint" {
+			t.Fatalf("expected to stop on breakpoint but got: %+v", stopped)
+		}
+
+		var trace struct {
+			StackFrames []StackFrame `json:"stackFrames"`
+		}
+		c.request("stackTrace", map[string]any{"threadId": stopped.ThreadID, "levels": 1}, &trace)
+		if len(trace.StackFrames) != 1 || trace.StackFrames[0].Line != 5 || trace.StackFrames[0].Source.Path != policy {
+			t.Fatalf("expected to stop on line 5 of %v but got: %+v", policy, trace.StackFrames)
+		}
+
+		var scopes struct {
+			Scopes []Scope `json:"scopes"`
+		}
+		c.request("scopes", map[string]any{"frameId": trace.StackFrames[0].ID}, &scopes)
+		if len(scopes.Scopes) == 0 || scopes.Scopes[0].Name != "Locals" {
+			t.Fatalf("expected local scope but got: %+v", scopes.Scopes)
+		}
+
+		var vars struct {
+			Variables []Variable `json:"variables"`
+		}
+		c.request("variables", map[string]any{"variablesReference": scopes.Scopes[0].VariablesReference}, &vars)
+		var found bool
+		for _, v := range vars.Variables {
+			if v.Name == "x" && v.Value == "1" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("expected local x = 1 but got: %+v", vars.Variables)
+		}
+
+		c.request("continue", map[string]any{"threadId": stopped.ThreadID}, nil)
+		c.expectEvent("terminated", nil)
+
+		c.request("disconnect", nil, nil)
+	})
+}
+
+func TestServerAttach(t *testing.T) {
+	t.Parallel()
+
+	compiler := ast.MustCompileModulesWithOpts(map[string]string{
+		"policy.rego": `package test
+
+allow if input.user == "alice"`,
+	}, ast.CompileOpts{ParserOptions: ast.ParserOptions{RegoVersion: ast.RegoV1}})
+
+	var args AttachArguments
+	c := newTestClient(t, func(ctx context.Context, d debug.Debugger, a AttachArguments) (debug.Session, error) {
+		args = a
+		return d.(debug.DecisionLauncher).LaunchDecision(ctx, debug.LaunchDecisionProperties{
+			LaunchProperties: a.LaunchProperties(),
+			Query:            "data." + strings.ReplaceAll(a.Path, "/", "."),
+			Input:            ast.MustParseTerm(`{"user": "alice"}`).Value,
+			Compiler:         compiler,
+			Store:            inmem.New(),
+		})
+	})
+
+	resp := c.request("attach", map[string]any{"path": "test/allow", "inputFilter": `input.user == "alice"`, "stopOnResult": true}, nil)
+	if !resp.Success {
+		t.Fatalf("failed to attach: %v", resp.Message)
+	}
+	if args.Path != "test/allow" || args.InputFilter != `input.user == "alice"` || !args.StopOnResult {
+		t.Fatalf("unexpected attach arguments: %+v", args)
+	}
+	c.expectEvent("initialized", nil)
+
+	c.request("configurationDone", nil, nil)
+
+	var output struct {
+		Output string `json:"output"`
+	}
+	c.expectEvent("output", &output)
+	if !strings.Contains(output.Output, `"value": true`) {
+		t.Fatalf("expected true result but got: %v", output.Output)
+	}
+
+	var stopped struct {
+		Reason string `json:"reason"`
+	}
+	c.expectEvent("stopped", &stopped)
+	if stopped.Reason != "result" {
+		t.Fatalf("expected to stop on result but got: %+v", stopped)
+	}
+
+	c.request("disconnect", nil, nil)
+}
+
+func TestServerErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		command string
+		args    any
+		wantErr string
+	}{
+		{
+			note:    "no session",
+			command: "threads",
+			wantErr: "no active debug session",
+		},
+		{
+			note:    "unsupported launch command",
+			command: "launch",
+			args:    map[string]any{"command": "build"},
+			wantErr: `unsupported launch command "build"`,
+		},
+		{
+			note:    "attach unsupported",
+			command: "attach",
+			wantErr: "attach is not supported",
+		},
+		{
+			note:    "unsupported command",
+			command: "evaluate",
+			wantErr: "unsupported command: evaluate",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := newTestClient(t, nil)
+
+			resp := c.request(tc.command, tc.args, nil)
+			if resp.Success || !strings.Contains(resp.Message, tc.wantErr) {
+				t.Fatalf("expected error containing %q but got: %+v", tc.wantErr, resp)
+			}
+		})
+	}
+}
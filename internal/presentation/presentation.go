@@ -21,6 +21,7 @@ import (
 
 	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/cover"
+	"github.com/IUAD1IY7/opa/v1/filter"
 	"github.com/IUAD1IY7/opa/v1/format"
 	"github.com/IUAD1IY7/opa/v1/loader"
 	"github.com/IUAD1IY7/opa/v1/metrics"
@@ -155,6 +156,13 @@ func NewOutputErrors(err error) []OutputError {
 				Location: typedErr.Location,
 				err:      typedErr,
 			}}
+		case *filter.Error:
+			errs = []OutputError{{
+				Code:     typedErr.Code,
+				Message:  typedErr.Message,
+				Location: typedErr.Location,
+				err:      typedErr,
+			}}
 		case *storage.Error:
 			errs = []OutputError{{
 				Code:    typedErr.Code,
@@ -176,6 +184,12 @@ func NewOutputErrors(err error) []OutputError {
 					errs = append(errs, NewOutputErrors(e)...)
 				}
 			}
+		case filter.Errors:
+			for _, e := range typedErr {
+				if e != nil {
+					errs = append(errs, NewOutputErrors(e)...)
+				}
+			}
 		case loader.Errors:
 			for _, e := range typedErr {
 				if e != nil {
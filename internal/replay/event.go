@@ -0,0 +1,174 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/topdown/builtins"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+// Event is a decision log event, as written by the decision log plugin. Only
+// the fields needed to replay the decision are read.
+type Event struct {
+	DecisionID     string         `json:"decision_id"`
+	Path           string         `json:"path,omitempty"`
+	Query          string         `json:"query,omitempty"`
+	Input          *any           `json:"input,omitempty"`
+	Result         *any           `json:"result,omitempty"`
+	NDBuiltinCache *any           `json:"nd_builtin_cache,omitempty"`
+	Erased         []string       `json:"erased,omitempty"`
+	Masked         []string       `json:"masked,omitempty"`
+	Error          any            `json:"error,omitempty"`
+	Timestamp      time.Time      `json:"timestamp"`
+	Metrics        map[string]any `json:"metrics,omitempty"`
+}
+
+// inputRedacted returns true if the input of the event was erased or masked,
+// in which case it cannot be replayed.
+func (e *Event) inputRedacted() bool {
+	return slices.ContainsFunc(slices.Concat(e.Erased, e.Masked), func(p string) bool {
+		return p == "/input" || strings.HasPrefix(p, "/input/")
+	})
+}
+
+// latency returns the recorded evaluation time of the decision, or zero if it
+// was not recorded.
+func (e *Event) latency() time.Duration {
+	v, ok := e.Metrics["timer_"+metrics.RegoQueryEval+"_ns"]
+	if !ok {
+		return 0
+	}
+	switch v := v.(type) {
+	case json.Number:
+		n, _ := v.Int64()
+		return time.Duration(n)
+	case float64:
+		return time.Duration(v)
+	}
+	return 0
+}
+
+// ndbCache returns the recorded results of non-deterministic built-in
+// functions. The arguments of the calls are the keys of the cache, which were
+// turned into JSON strings when the event was logged, so they are parsed back
+// into values for the calls to be found again.
+func (e *Event) ndbCache() (builtins.NDBCache, error) {
+	if e.NDBuiltinCache == nil {
+		return nil, nil
+	}
+
+	entries, ok := (*e.NDBuiltinCache).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected nd_builtin_cache of type %T", *e.NDBuiltinCache)
+	}
+
+	cache := builtins.NDBCache{}
+	for name, calls := range entries {
+		calls, ok := calls.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unexpected nd_builtin_cache entry for %v of type %T", name, calls)
+		}
+		for args, result := range calls {
+			key, err := parseArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			value, err := ast.InterfaceToValue(result)
+			if err != nil {
+				return nil, err
+			}
+			cache.Put(name, key, value)
+		}
+	}
+
+	return cache, nil
+}
+
+func parseArgs(s string) (ast.Value, error) {
+	var args any
+	if err := util.UnmarshalJSON([]byte(s), &args); err != nil {
+		return ast.String(s), nil
+	}
+	return ast.InterfaceToValue(args)
+}
+
+// Decoder reads decision log events from newline-delimited JSON, as written
+// by the file and console sinks, or from JSON arrays of events, as uploaded to
+// decision log services. Gzip compressed input is decompressed.
+type Decoder struct {
+	dec     *json.Decoder
+	pending []*Event
+	n       int
+}
+
+// NewDecoder returns a new Decoder reading events from r.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var src io.Reader = br
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		if src, err = gzip.NewReader(br); err != nil {
+			return nil, err
+		}
+	}
+
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+
+	return &Decoder{dec: dec}, nil
+}
+
+// Decode returns the next event, or io.EOF once all events were read. Records
+// without a decision ID, e.g. other log messages of the console sink, are
+// skipped.
+func (d *Decoder) Decode() (*Event, error) {
+	for len(d.pending) == 0 {
+		var raw json.RawMessage
+		if err := d.dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("invalid decision log record %d: %w", d.n+1, err)
+		}
+		d.n++
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			if err := util.UnmarshalJSON(raw, &d.pending); err != nil {
+				return nil, fmt.Errorf("invalid decision log record %d: %w", d.n, err)
+			}
+		} else {
+			var e Event
+			if err := util.UnmarshalJSON(raw, &e); err != nil {
+				return nil, fmt.Errorf("invalid decision log record %d: %w", d.n, err)
+			}
+			d.pending = append(d.pending, &e)
+		}
+
+		d.pending = slices.DeleteFunc(d.pending, func(e *Event) bool { return e == nil || e.DecisionID == "" })
+	}
+
+	e := d.pending[0]
+	d.pending = d.pending[1:]
+	return e, nil
+}
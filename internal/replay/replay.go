@@ -0,0 +1,281 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package replay re-evaluates logged decisions against other policies and
+// reports how they would have been decided.
+package replay
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/IUAD1IY7/opa/internal/ref"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/rego"
+	"github.com/IUAD1IY7/opa/v1/storage"
+)
+
+// Statuses of replayed decisions.
+const (
+	Unchanged     = "unchanged"
+	Changed       = "changed"
+	NewError      = "new_error"
+	ResolvedError = "resolved_error"
+	Skipped       = "skipped"
+)
+
+// Result describes a replayed decision. Results are nil if the decision was
+// undefined, and latencies are zero if they were not recorded.
+type Result struct {
+	DecisionID      string        `json:"decision_id"`
+	Path            string        `json:"path,omitempty"`
+	Status          string        `json:"status"`
+	Reason          string        `json:"reason,omitempty"`
+	RecordedResult  *any          `json:"recorded_result,omitempty"`
+	ReplayedResult  *any          `json:"replayed_result,omitempty"`
+	RecordedError   any           `json:"recorded_error,omitempty"`
+	ReplayedError   string        `json:"replayed_error,omitempty"`
+	RecordedLatency time.Duration `json:"recorded_latency_ns,omitempty"`
+	ReplayedLatency time.Duration `json:"replayed_latency_ns,omitempty"`
+}
+
+// Replayer evaluates logged decisions against a compiler and store.
+type Replayer struct {
+	compiler *ast.Compiler
+	store    storage.Store
+	queries  map[string]rego.PreparedEvalQuery
+}
+
+// New returns a new Replayer evaluating decisions against the policies of the
+// compiler and the data of the store.
+func New(compiler *ast.Compiler, store storage.Store) *Replayer {
+	return &Replayer{
+		compiler: compiler,
+		store:    store,
+		queries:  map[string]rego.PreparedEvalQuery{},
+	}
+}
+
+// Replay evaluates the decision of the event again and compares the result with
+// the recorded one. Results of non-deterministic built-in functions recorded
+// in the event are reused, other calls are made anew. Ad-hoc queries and
+// decisions whose input was erased or masked are skipped.
+func (r *Replayer) Replay(ctx context.Context, e *Event) Result {
+	result := Result{
+		DecisionID:      e.DecisionID,
+		Path:            e.Path,
+		RecordedResult:  e.Result,
+		RecordedError:   e.Error,
+		RecordedLatency: e.latency(),
+	}
+
+	switch {
+	case e.Query != "":
+		return result.skip("ad-hoc queries are not replayed")
+	case e.inputRedacted():
+		return result.skip("input was erased or masked")
+	}
+
+	pq, err := r.prepare(ctx, e.Path)
+	if err != nil {
+		return result.skip(err.Error())
+	}
+
+	m := metrics.New()
+	opts := []rego.EvalOption{rego.EvalMetrics(m)}
+
+	if e.Input != nil {
+		input, err := ast.InterfaceToValue(*e.Input)
+		if err != nil {
+			return result.skip(err.Error())
+		}
+		opts = append(opts, rego.EvalParsedInput(input))
+	}
+
+	cache, err := e.ndbCache()
+	if err != nil {
+		return result.skip(err.Error())
+	}
+	if cache != nil {
+		opts = append(opts, rego.EvalNDBuiltinCache(cache))
+	}
+
+	rs, err := pq.Eval(ctx, opts...)
+	result.ReplayedLatency = time.Duration(m.Timer(metrics.RegoQueryEval).Int64())
+
+	if err != nil {
+		result.ReplayedError = err.Error()
+		result.Status = Unchanged
+		if e.Error == nil {
+			result.Status = NewError
+		}
+		return result
+	}
+
+	if len(rs) > 0 {
+		x := rs[0].Expressions[0].Value
+		result.ReplayedResult = &x
+	}
+
+	switch {
+	case e.Error != nil:
+		result.Status = ResolvedError
+	case !equalResults(e.Result, result.ReplayedResult):
+		result.Status = Changed
+	default:
+		result.Status = Unchanged
+	}
+
+	return result
+}
+
+func (r *Result) skip(reason string) Result {
+	r.Status = Skipped
+	r.Reason = reason
+	return *r
+}
+
+func (r *Replayer) prepare(ctx context.Context, path string) (rego.PreparedEvalQuery, error) {
+	if pq, ok := r.queries[path]; ok {
+		return pq, nil
+	}
+
+	dataRef, err := ref.ParseDataPath(path)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, err
+	}
+
+	pq, err := rego.New(
+		rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(dataRef)))),
+		rego.Compiler(r.compiler),
+		rego.Store(r.store),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, err
+	}
+
+	r.queries[path] = pq
+	return pq, nil
+}
+
+func equalResults(a, b *any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	x, err := ast.InterfaceToValue(*a)
+	if err != nil {
+		return false
+	}
+	y, err := ast.InterfaceToValue(*b)
+	if err != nil {
+		return false
+	}
+
+	return x.Compare(y) == 0
+}
+
+// Report summarizes replayed decisions. Only the decisions that did not replay
+// unchanged are listed.
+type Report struct {
+	Summary   Summary  `json:"summary"`
+	Decisions []Result `json:"decisions,omitempty"`
+
+	recorded []time.Duration
+	replayed []time.Duration
+}
+
+// Summary counts the replayed decisions by status, and compares the latencies
+// of the decisions whose latency was recorded.
+type Summary struct {
+	Decisions      int      `json:"decisions"`
+	Unchanged      int      `json:"unchanged"`
+	Changed        int      `json:"changed"`
+	NewErrors      int      `json:"new_errors"`
+	ResolvedErrors int      `json:"resolved_errors"`
+	Skipped        int      `json:"skipped"`
+	Latency        *Latency `json:"latency,omitempty"`
+}
+
+// Latency compares the recorded and replayed evaluation times of decisions.
+type Latency struct {
+	Decisions int         `json:"decisions"`
+	Recorded  Percentiles `json:"recorded"`
+	Replayed  Percentiles `json:"replayed"`
+}
+
+// Percentiles of evaluation times.
+type Percentiles struct {
+	Mean time.Duration `json:"mean_ns"`
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P99  time.Duration `json:"p99_ns"`
+}
+
+// Add adds the replayed decision to the report.
+func (r *Report) Add(result Result) {
+	r.Summary.Decisions++
+
+	switch result.Status {
+	case Unchanged:
+		r.Summary.Unchanged++
+	case Changed:
+		r.Summary.Changed++
+	case NewError:
+		r.Summary.NewErrors++
+	case ResolvedError:
+		r.Summary.ResolvedErrors++
+	case Skipped:
+		r.Summary.Skipped++
+	}
+
+	if result.Status != Unchanged {
+		r.Decisions = append(r.Decisions, result)
+	}
+
+	if result.RecordedLatency > 0 && result.ReplayedLatency > 0 {
+		r.recorded = append(r.recorded, result.RecordedLatency)
+		r.replayed = append(r.replayed, result.ReplayedLatency)
+	}
+}
+
+// Differs returns true if any decision changed or replayed with a new error.
+func (r *Report) Differs() bool {
+	return r.Summary.Changed > 0 || r.Summary.NewErrors > 0
+}
+
+// Summarize compares the latencies of the decisions added so far.
+func (r *Report) Summarize() {
+	if len(r.recorded) == 0 {
+		return
+	}
+	r.Summary.Latency = &Latency{
+		Decisions: len(r.recorded),
+		Recorded:  percentiles(r.recorded),
+		Replayed:  percentiles(r.replayed),
+	}
+}
+
+func percentiles(ds []time.Duration) Percentiles {
+	sorted := slices.Clone(ds)
+	slices.Sort(sorted)
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+
+	at := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1))]
+	}
+
+	return Percentiles{
+		Mean: sum / time.Duration(len(sorted)),
+		P50:  at(0.5),
+		P90:  at(0.9),
+		P99:  at(0.99),
+	}
+}
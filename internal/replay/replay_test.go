@@ -0,0 +1,222 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package replay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+)
+
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+
+	ndjson := `{"decision_id": "1", "path": "a"}
+{"level": "info", "msg": "Starting server."}
+{"decision_id": "2", "path": "b", "input": {"x": 1}}
+`
+
+	tests := []struct {
+		note    string
+		input   string
+		exp     []string
+		wantErr string
+	}{
+		{
+			note:  "newline-delimited",
+			input: ndjson,
+			exp:   []string{"1", "2"},
+		},
+		{
+			note:  "arrays",
+			input: `[{"decision_id": "1"}, {"decision_id": "2"}] [{"decision_id": "3"}]`,
+			exp:   []string{"1", "2", "3"},
+		},
+		{
+			note:  "compressed",
+			input: gzipString(t, ndjson),
+			exp:   []string{"1", "2"},
+		},
+		{
+			note:  "empty",
+			input: "",
+		},
+		{
+			note:    "invalid",
+			input:   `{"decision_id": "1"}` + "\nnot json",
+			exp:     []string{"1"},
+			wantErr: "invalid decision log record 2",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := NewDecoder(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for {
+				e, err := dec.Decode()
+				if errors.Is(err, io.EOF) {
+					break
+				} else if err != nil {
+					if tc.wantErr == "" || !strings.Contains(err.Error(), tc.wantErr) {
+						t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+					}
+					break
+				}
+				ids = append(ids, e.DecisionID)
+			}
+
+			if !slices.Equal(ids, tc.exp) {
+				t.Fatalf("expected decisions %v but got %v", tc.exp, ids)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	compiler := ast.MustCompileModulesWithOpts(map[string]string{
+		"policy.rego": `package test
+
+allow if input.user in data.admins
+
+expired if time.now_ns() > input.expires
+
+conflict := 1 if input.x
+
+conflict := 2 if input.x`,
+	}, ast.CompileOpts{ParserOptions: ast.ParserOptions{RegoVersion: ast.RegoV1}})
+
+	store := inmem.NewFromObject(map[string]any{"admins": []any{"alice"}})
+
+	tests := []struct {
+		note   string
+		event  string
+		status string
+		reason string
+	}{
+		{
+			note:   "unchanged",
+			event:  `{"decision_id": "1", "path": "test/allow", "input": {"user": "alice"}, "result": true}`,
+			status: Unchanged,
+		},
+		{
+			note:   "unchanged undefined",
+			event:  `{"decision_id": "1", "path": "test/allow", "input": {"user": "bob"}}`,
+			status: Unchanged,
+		},
+		{
+			note:   "changed",
+			event:  `{"decision_id": "1", "path": "test/allow", "input": {"user": "bob"}, "result": true}`,
+			status: Changed,
+		},
+		{
+			note:   "non-deterministic built-in replayed",
+			event:  `{"decision_id": "1", "path": "test/expired", "input": {"expires": 100}, "nd_builtin_cache": {"time.now_ns": {"[]": 50}}}`,
+			status: Unchanged,
+		},
+		{
+			note:   "new error",
+			event:  `{"decision_id": "1", "path": "test/conflict", "input": {"x": true}, "result": 1}`,
+			status: NewError,
+		},
+		{
+			note:   "resolved error",
+			event:  `{"decision_id": "1", "path": "test/allow", "input": {"user": "alice"}, "error": {"code": "internal_error", "message": "boom"}}`,
+			status: ResolvedError,
+		},
+		{
+			note:   "ad-hoc query",
+			event:  `{"decision_id": "1", "query": "data.test.allow = x"}`,
+			status: Skipped,
+			reason: "ad-hoc queries are not replayed",
+		},
+		{
+			note:   "erased input",
+			event:  `{"decision_id": "1", "path": "test/allow", "erased": ["/input/password"]}`,
+			status: Skipped,
+			reason: "input was erased or masked",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := NewDecoder(strings.NewReader(tc.event))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e, err := dec.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := New(compiler, store).Replay(context.Background(), e)
+			if result.Status != tc.status || !strings.Contains(result.Reason, tc.reason) {
+				t.Fatalf("expected status %v (%v) but got: %+v", tc.status, tc.reason, result)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	var report Report
+	for i, status := range []string{Unchanged, Unchanged, Changed, NewError, Skipped} {
+		report.Add(Result{
+			DecisionID:      string(rune('a' + i)),
+			Status:          status,
+			RecordedLatency: time.Duration(i) * time.Millisecond,
+			ReplayedLatency: time.Duration(2*i) * time.Millisecond,
+		})
+	}
+	report.Summarize()
+
+	exp := Summary{Decisions: 5, Unchanged: 2, Changed: 1, NewErrors: 1, Skipped: 1}
+	latency := report.Summary.Latency
+	report.Summary.Latency = nil
+	if report.Summary != exp {
+		t.Fatalf("expected summary %+v but got %+v", exp, report.Summary)
+	}
+	if len(report.Decisions) != 3 || !report.Differs() {
+		t.Fatalf("expected 3 differing decisions but got: %+v", report.Decisions)
+	}
+
+	if latency == nil || latency.Decisions != 4 || latency.Recorded.P50 != 2*time.Millisecond || latency.Replayed.Mean != 5*time.Millisecond {
+		t.Fatalf("unexpected latency: %+v", latency)
+	}
+}
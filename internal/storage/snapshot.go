@@ -0,0 +1,64 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package storage contains utilities for working with OPA stores.
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+)
+
+// Snapshot copies the documents and policies in the store, as read in the
+// transaction, into a new in-memory store. Later writes to either store are
+// not seen by the other.
+func Snapshot(ctx context.Context, store storage.Store, txn storage.Transaction) (storage.Store, error) {
+	data, err := store.Read(ctx, txn, storage.Path{})
+	if err != nil {
+		return nil, err
+	}
+
+	if v, ok := data.(ast.Value); ok {
+		if data, err = ast.JSON(v); err != nil {
+			return nil, err
+		}
+	}
+
+	obj, ok := data.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected root document of type %T", data)
+	}
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		if policies[id], err = store.GetPolicy(ctx, txn, id); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := inmem.NewFromObjectWithOpts(obj, inmem.OptRoundTripOnWrite(true))
+
+	err = storage.Txn(ctx, snapshot, storage.WriteParams, func(txn storage.Transaction) error {
+		for id, bs := range policies {
+			if err := snapshot.UpsertPolicy(ctx, txn, id, bs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
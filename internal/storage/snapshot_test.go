@@ -0,0 +1,64 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]any{"a": map[string]any{"b": "c"}})
+
+	var snapshot storage.Store
+	err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := store.UpsertPolicy(ctx, txn, "p.rego", []byte("package p")); err != nil {
+			return err
+		}
+		var err error
+		snapshot, err = Snapshot(ctx, store, txn)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes to the store aren't seen by the snapshot.
+	err = storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.Write(ctx, txn, storage.ReplaceOp, storage.MustParsePath("/a/b"), "d")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := storage.ReadOne(ctx, snapshot, storage.Path{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := util.MustUnmarshalJSON([]byte(`{"a": {"b": "c"}}`)); util.Compare(v, exp) != 0 {
+		t.Fatalf("expected %v but got %v", exp, v)
+	}
+
+	err = storage.Txn(ctx, snapshot, storage.TransactionParams{}, func(txn storage.Transaction) error {
+		bs, err := snapshot.GetPolicy(ctx, txn, "p.rego")
+		if err != nil {
+			return err
+		}
+		if string(bs) != "package p" {
+			t.Fatalf("unexpected policy: %s", bs)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -33,6 +33,7 @@ const (
 const (
 	PromHandlerV0Data     = v1.PromHandlerV0Data
 	PromHandlerV1Data     = v1.PromHandlerV1Data
+	PromHandlerV1Batch    = v1.PromHandlerV1Batch
 	PromHandlerV1Query    = v1.PromHandlerV1Query
 	PromHandlerV1Policies = v1.PromHandlerV1Policies
 	PromHandlerV1Compile  = v1.PromHandlerV1Compile
@@ -0,0 +1,310 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+// Attestation statement and predicate types.
+const (
+	AttestationsFile        = ".attestations.json"
+	StatementType           = "https://in-toto.io/Statement/v1"
+	ProvenancePredicateType = "https://slsa.dev/provenance/v1"
+	InventoryPredicateType  = "https://openpolicyagent.org/bundle/inventory/v1"
+	subjectDigestAlg        = "sha256"
+)
+
+// Attestations holds in-toto statements about the files of a bundle, along
+// with a JWT signing them. The JWT payload lists the statements by predicate
+// type, with their hashes, just like bundle signatures list the bundle files.
+type Attestations struct {
+	Statements []Statement `json:"statements"`
+	Signatures []string    `json:"signatures,omitempty"`
+	Plugin     string      `json:"plugin,omitempty"`
+}
+
+// Statement is an in-toto attestation statement. The digests of its subjects
+// are computed the same way as the hashes of signed bundle files.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     any       `json:"predicate"`
+}
+
+// Subject is a bundle file a statement is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// NewStatement returns a statement with the given predicate. Its subjects are
+// set when the bundle attestations are generated.
+func NewStatement(predicateType string, predicate any) Statement {
+	return Statement{
+		Type:          StatementType,
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	}
+}
+
+// Provenance is a SLSA build provenance predicate.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs of a build.
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]any       `json:"externalParameters"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// ResourceDescriptor identifies a build input by name and digest.
+type ResourceDescriptor struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// RunDetails describes the builder that ran a build.
+type RunDetails struct {
+	Builder Builder `json:"builder"`
+}
+
+// Builder identifies the builder and its version.
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// Inventory is a predicate listing what a bundle was built from and what its
+// policies require.
+type Inventory struct {
+	OPAVersion   string                `json:"opa_version"`
+	Modules      []InventoryModule     `json:"modules"`
+	Data         []ResourceDescriptor  `json:"data"`
+	Capabilities InventoryCapabilities `json:"capabilities"`
+	Builtins     []string              `json:"builtins"`
+}
+
+// InventoryModule describes a module of a bundle.
+type InventoryModule struct {
+	Path    string            `json:"path"`
+	Package string            `json:"package"`
+	Digest  map[string]string `json:"digest"`
+}
+
+// InventoryCapabilities describes the capabilities a bundle was built against,
+// and the features its policies require.
+type InventoryCapabilities struct {
+	Digest   map[string]string `json:"digest"`
+	Features []string          `json:"features,omitempty"`
+}
+
+// Digest returns the digest of v, hashed the same way as signed bundle files.
+// Byte slices are hashed as-is, other values as JSON documents.
+func Digest(v any) (map[string]string, error) {
+	hash, err := NewSignatureHasher(SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := v.([]byte); !ok {
+		if v, err = jsonValue(v); err != nil {
+			return nil, err
+		}
+	}
+
+	bs, err := hash.HashFile(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{subjectDigestAlg: hex.EncodeToString(bs)}, nil
+}
+
+// GenerateAttestations sets the subjects of the statements to the files of the
+// bundle, signs them with the given signing config and attaches them to the
+// bundle. Attestations are generated before the bundle signature, which then
+// covers the attestations file as well.
+func (b *Bundle) GenerateAttestations(statements []Statement, signingConfig *SigningConfig, keyID string, useModulePath bool) error {
+
+	hash, err := NewSignatureHasher(HashingAlgorithm(defaultHashingAlg))
+	if err != nil {
+		return err
+	}
+
+	b.Attestations = nil
+
+	files, err := b.fileInfos(hash, useModulePath)
+	if err != nil {
+		return err
+	}
+
+	subjects := make([]Subject, len(files))
+	for i, f := range files {
+		subjects[i] = Subject{Name: f.Name, Digest: map[string]string{subjectDigestAlg: f.Hash}}
+	}
+
+	payload := make([]FileInfo, len(statements))
+	seen := make(map[string]struct{}, len(statements))
+
+	for i := range statements {
+		if _, ok := seen[statements[i].PredicateType]; ok {
+			return fmt.Errorf("multiple statements with predicate type %v", statements[i].PredicateType)
+		}
+		seen[statements[i].PredicateType] = struct{}{}
+
+		statements[i].Type = StatementType
+		statements[i].Subject = subjects
+
+		bs, err := hashStatement(hash, statements[i])
+		if err != nil {
+			return err
+		}
+		payload[i] = NewFile(statements[i].PredicateType, hex.EncodeToString(bs), defaultHashingAlg)
+	}
+
+	token, err := GenerateSignedToken(payload, signingConfig, keyID)
+	if err != nil {
+		return err
+	}
+
+	b.Attestations = &Attestations{
+		Statements: statements,
+		Signatures: []string{token},
+		Plugin:     signingConfig.Plugin,
+	}
+
+	return nil
+}
+
+func hashStatement(hash SignatureHasher, s Statement) ([]byte, error) {
+	v, err := jsonValue(s)
+	if err != nil {
+		return nil, err
+	}
+	return hash.HashFile(v)
+}
+
+// jsonValue returns v as a JSON document, so that it's hashed the same way as
+// when it's read from a file.
+func jsonValue(v any) (any, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var result any
+	if err := util.Unmarshal(bs, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyAttestations verifies the signature of the bundle attestations and
+// checks that the subjects of each statement match the digests of the bundle
+// files exactly. The digests are keyed by file path, without a leading slash.
+func VerifyAttestations(a *Attestations, bvc *VerificationConfig, digests map[string]string) error {
+	if len(a.Statements) == 0 {
+		return fmt.Errorf("%v: no statements", AttestationsFile)
+	}
+
+	files, err := VerifyBundleSignature(SignaturesConfig{Signatures: a.Signatures, Plugin: a.Plugin}, bvc)
+	if err != nil {
+		return fmt.Errorf("%v: %w", AttestationsFile, err)
+	}
+
+	for _, s := range a.Statements {
+		if err := verifyStatement(s, files, digests); err != nil {
+			return fmt.Errorf("%v: %v: %w", AttestationsFile, s.PredicateType, err)
+		}
+	}
+
+	if len(files) != 0 {
+		return fmt.Errorf("%v: statement(s) %v specified in signature but not found", AttestationsFile, util.KeysSorted(files))
+	}
+
+	return nil
+}
+
+func verifyStatement(s Statement, files map[string]FileInfo, digests map[string]string) error {
+	if s.Type != StatementType {
+		return fmt.Errorf("unsupported statement type %q", s.Type)
+	}
+
+	file, ok := files[s.PredicateType]
+	if !ok {
+		return errors.New("statement not included in signature")
+	}
+	delete(files, s.PredicateType)
+
+	hash, err := NewSignatureHasher(HashingAlgorithm(file.Algorithm))
+	if err != nil {
+		return err
+	}
+
+	bs, err := hashStatement(hash, s)
+	if err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(bs) != strings.ToLower(file.Hash) {
+		return fmt.Errorf("digest mismatch (want: %v, got: %x)", file.Hash, bs)
+	}
+
+	subjects := make(map[string]string, len(s.Subject))
+	for _, subject := range s.Subject {
+		subjects[subject.Name] = subject.Digest[subjectDigestAlg]
+	}
+
+	for _, path := range util.KeysSorted(digests) {
+		want, ok := subjects[path]
+		if !ok {
+			return fmt.Errorf("file %v not included in statement subjects", path)
+		}
+		if want != digests[path] {
+			return fmt.Errorf("%v: digest mismatch (want: %v, got: %v)", path, want, digests[path])
+		}
+		delete(subjects, path)
+	}
+
+	if len(subjects) != 0 {
+		return fmt.Errorf("file(s) %v specified in statement subjects but not found in the target bundle", util.KeysSorted(subjects))
+	}
+
+	return nil
+}
+
+// fileDigest returns the hex encoded digest of a bundle file, hashed the same
+// way as when verifying bundle signatures.
+func fileDigest(path string, data []byte) (string, error) {
+	hash, err := NewSignatureHasher(HashingAlgorithm(defaultHashingAlg))
+	if err != nil {
+		return "", err
+	}
+
+	var value any = data
+	if IsStructuredDoc(path) {
+		if err := util.Unmarshal(data, &value); err != nil {
+			return "", err
+		}
+	}
+
+	bs, err := hash.HashFile(value)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(bs), nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+)
+
+func TestReadWithAttestations(t *testing.T) {
+
+	tests := []struct {
+		note    string
+		tamper  func(*Bundle)
+		sign    bool
+		require bool
+		wantErr string
+	}{
+		{
+			note: "attested",
+		},
+		{
+			note: "attested and signed",
+			sign: true,
+		},
+		{
+			note: "modified module",
+			tamper: func(b *Bundle) {
+				b.Modules[0].Raw = []byte("package foo\n\np := 2\n")
+			},
+			wantErr: "foo.rego: digest mismatch",
+		},
+		{
+			note: "added module",
+			tamper: func(b *Bundle) {
+				b.Modules = append(b.Modules, ModuleFile{
+					URL:    "/bar.rego",
+					Path:   "/bar.rego",
+					Parsed: ast.MustParseModule("package bar"),
+					Raw:    []byte("package bar\n"),
+				})
+			},
+			wantErr: "file bar.rego not included in statement subjects",
+		},
+		{
+			note: "removed module",
+			tamper: func(b *Bundle) {
+				b.Modules = nil
+			},
+			wantErr: "file(s) [foo.rego] specified in statement subjects but not found in the target bundle",
+		},
+		{
+			note: "modified statement",
+			tamper: func(b *Bundle) {
+				b.Attestations.Statements[0].Predicate = map[string]any{"forged": true}
+			},
+			wantErr: "https://example.com/predicate/v1: digest mismatch",
+		},
+		{
+			note: "missing attestations",
+			tamper: func(b *Bundle) {
+				b.Attestations = nil
+			},
+			require: true,
+			wantErr: "bundle missing .attestations.json file",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			b := Bundle{
+				Data: map[string]any{"foo": "bar"},
+				Modules: []ModuleFile{
+					{
+						URL:    "/foo.rego",
+						Path:   "/foo.rego",
+						Parsed: ast.MustParseModule("package foo\n\np := 1"),
+						Raw:    []byte("package foo\n\np := 1\n"),
+					},
+				},
+				Manifest: Manifest{Revision: "r1"},
+			}
+			b.Manifest.Init()
+
+			sc := NewSigningConfig("secret", "HS256", "")
+			statements := []Statement{NewStatement("https://example.com/predicate/v1", map[string]any{"foo": "bar"})}
+
+			if err := b.GenerateAttestations(statements, sc, "foo", false); err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.tamper != nil {
+				tc.tamper(&b)
+			}
+
+			if tc.sign {
+				if err := b.GenerateSignature(sc, "foo", false); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := NewWriter(&buf).Write(b); err != nil {
+				t.Fatal(err)
+			}
+
+			vc := NewVerificationConfig(map[string]*KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}}, "", "", nil)
+			vc.RequireAttestations = tc.require
+
+			result, err := NewReader(&buf).WithBundleVerificationConfig(vc).Read()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "":
+				if !reflect.DeepEqual(result.Attestations.Signatures, b.Attestations.Signatures) {
+					t.Fatalf("expected attestations to be read but got: %+v", result.Attestations)
+				}
+			}
+		})
+	}
+}
+
+func TestReadWithAttestationsWrongKey(t *testing.T) {
+
+	b := Bundle{Data: map[string]any{}}
+	b.Manifest.Init()
+
+	statements := []Statement{NewStatement("https://example.com/predicate/v1", map[string]any{})}
+	if err := b.GenerateAttestations(statements, NewSigningConfig("secret", "HS256", ""), "foo", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// Attestations aren't verified without a verification config.
+	if _, err := NewReader(bytes.NewReader(buf.Bytes())).Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	vc := NewVerificationConfig(map[string]*KeyConfig{"foo": {Key: "other", Algorithm: "HS256"}}, "", "", nil)
+	_, err := NewReader(bytes.NewReader(buf.Bytes())).WithBundleVerificationConfig(vc).Read()
+	if err == nil || !strings.Contains(err.Error(), ".attestations.json: failed to verify message") {
+		t.Fatalf("expected verification error but got: %v", err)
+	}
+}
+
+func TestGenerateAttestationsDuplicatePredicateType(t *testing.T) {
+
+	b := Bundle{Data: map[string]any{}}
+	statements := []Statement{
+		NewStatement("https://example.com/predicate/v1", map[string]any{}),
+		NewStatement("https://example.com/predicate/v1", map[string]any{}),
+	}
+
+	err := b.GenerateAttestations(statements, NewSigningConfig("secret", "HS256", ""), "", false)
+	if err == nil || !strings.Contains(err.Error(), "multiple statements") {
+		t.Fatalf("expected error but got: %v", err)
+	}
+}
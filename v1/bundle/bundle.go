@@ -51,16 +51,17 @@ const (
 
 // Bundle represents a loaded bundle. The bundle can contain data and policies.
 type Bundle struct {
-	Signatures  SignaturesConfig
-	Manifest    Manifest
-	Data        map[string]any
-	Modules     []ModuleFile
-	Wasm        []byte // Deprecated. Use WasmModules instead
-	WasmModules []WasmModuleFile
-	PlanModules []PlanModuleFile
-	Patch       Patch
-	Etag        string
-	Raw         []Raw
+	Signatures   SignaturesConfig
+	Attestations *Attestations
+	Manifest     Manifest
+	Data         map[string]any
+	Modules      []ModuleFile
+	Wasm         []byte // Deprecated. Use WasmModules instead
+	WasmModules  []WasmModuleFile
+	PlanModules  []PlanModuleFile
+	Patch        Patch
+	Etag         string
+	Raw          []Raw
 
 	lazyLoadingMode bool
 	sizeLimitBytes  int64
@@ -74,9 +75,11 @@ type Raw struct {
 }
 
 // Patch contains an array of objects wherein each object represents the patch operation to be
-// applied to the bundle data.
+// applied to the bundle data, and an array of patch operations to be applied to the bundle's
+// policy modules.
 type Patch struct {
-	Data []PatchOperation `json:"data,omitempty"`
+	Data    []PatchOperation       `json:"data,omitempty"`
+	Modules []ModulePatchOperation `json:"modules,omitempty"`
 }
 
 // PatchOperation models a single patch operation against a document.
@@ -86,6 +89,16 @@ type PatchOperation struct {
 	Value any    `json:"value"`
 }
 
+// ModulePatchOperation models a single patch operation against a policy module. The path
+// is the path of the module in the bundle, and the value is its source. Modules are added
+// or replaced with the "upsert" operation, replaced with "replace" and removed with "remove".
+type ModulePatchOperation struct {
+	Op     string      `json:"op"`
+	Path   string      `json:"path"`
+	Value  string      `json:"value,omitempty"`
+	Parsed *ast.Module `json:"-"`
+}
+
 // SignaturesConfig represents an array of JWTs that encapsulate the signatures for the bundle.
 type SignaturesConfig struct {
 	Signatures []string `json:"signatures,omitempty"`
@@ -385,6 +398,20 @@ func (m *Manifest) validateAndInjectDefaults(b Bundle) error {
 		}
 	}
 
+	// Validate module patches in bundle.
+	for _, patch := range b.Patch.Modules {
+		if patch.Parsed == nil {
+			continue
+		}
+		found := false
+		if path, err := patch.Parsed.Package.Path.Ptr(); err == nil {
+			found = RootPathsContain(roots, path)
+		}
+		if !found {
+			return fmt.Errorf("manifest roots %v do not permit '%v' in module patch '%v'", roots, patch.Parsed.Package, patch.Path)
+		}
+	}
+
 	if b.lazyLoadingMode {
 		return nil
 	}
@@ -601,6 +628,12 @@ func (r *Reader) Read() (Bundle, error) {
 		bundle.Data = map[string]any{}
 	}
 
+	// digests of the files to check the bundle attestations against
+	var digests map[string]string
+	if bundle.Type() == SnapshotBundleType && !r.skipVerify && r.verificationConfig != nil {
+		digests = map[string]string{}
+	}
+
 	var modules []ModuleFile
 	for _, f := range descriptors {
 		buf, err := readFile(f, r.sizeLimitBytes)
@@ -626,10 +659,28 @@ func (r *Reader) Read() (Bundle, error) {
 			}
 		}
 
+		if digests != nil && filepath.Base(f.Path()) != AttestationsFile {
+			path := f.Path()
+			if r.baseDir != "" {
+				path = f.URL()
+			}
+			path = strings.TrimPrefix(path, "/")
+
+			if !r.isFileExcluded(path) {
+				if digests[path], err = fileDigest(path, buf.Bytes()); err != nil {
+					return bundle, err
+				}
+			}
+		}
+
 		// Normalize the paths to use `/` separators
 		path := filepath.ToSlash(f.Path())
 
-		if strings.HasSuffix(path, RegoExt) {
+		if filepath.Base(path) == AttestationsFile {
+			if err := util.NewJSONDecoder(&buf).Decode(&bundle.Attestations); err != nil {
+				return bundle, fmt.Errorf("bundle load failed on attestations decode: %w", err)
+			}
+		} else if strings.HasSuffix(path, RegoExt) {
 			fullPath := r.fullPath(path)
 			bs := buf.Bytes()
 
@@ -729,6 +780,12 @@ func (r *Reader) Read() (Bundle, error) {
 		bundle.Modules = append(bundle.Modules, mf)
 	}
 
+	for i := range bundle.Patch.Modules {
+		if err := parseModulePatch(&bundle, &bundle.Patch.Modules[i], popts, r.metrics); err != nil {
+			return bundle, err
+		}
+	}
+
 	if bundle.Type() == DeltaBundleType {
 		if len(bundle.Data) != 0 {
 			return bundle, errors.New("delta bundle expected to contain only patch file but data files found")
@@ -756,6 +813,12 @@ func (r *Reader) Read() (Bundle, error) {
 		return bundle, fmt.Errorf("file(s) %v specified in bundle signatures but not found in the target bundle", extra)
 	}
 
+	if digests != nil {
+		if err := r.checkAttestations(bundle.Attestations, digests); err != nil {
+			return bundle, err
+		}
+	}
+
 	if err := bundle.Manifest.validateAndInjectDefaults(bundle); err != nil {
 		return bundle, err
 	}
@@ -817,7 +880,7 @@ func (r *Reader) checkSignaturesAndDescriptors(signatures SignaturesConfig) erro
 		return nil
 	}
 
-	if signatures.isEmpty() && r.verificationConfig != nil && r.verificationConfig.KeyID != "" {
+	if signatures.isEmpty() && r.verificationConfig != nil && (r.verificationConfig.KeyID != "" || r.verificationConfig.Threshold > 0) {
 		return errors.New("bundle missing .signatures.json file")
 	}
 
@@ -834,6 +897,17 @@ func (r *Reader) checkSignaturesAndDescriptors(signatures SignaturesConfig) erro
 	return nil
 }
 
+func (r *Reader) checkAttestations(a *Attestations, digests map[string]string) error {
+	if a == nil {
+		if r.verificationConfig.RequireAttestations {
+			return fmt.Errorf("bundle missing %v file", AttestationsFile)
+		}
+		return nil
+	}
+
+	return VerifyAttestations(a, r.verificationConfig, digests)
+}
+
 func (r *Reader) verifyBundleSignature(sc SignaturesConfig) error {
 	var err error
 	r.files, err = VerifyBundleSignature(sc, r.verificationConfig)
@@ -924,6 +998,10 @@ func (w *Writer) Write(bundle Bundle) error {
 			return err
 		}
 
+		if err := writeAttestations(tw, bundle); err != nil {
+			return err
+		}
+
 		if err := w.writePlan(tw, bundle); err != nil {
 			return err
 		}
@@ -1023,6 +1101,20 @@ func writeSignatures(tw *tar.Writer, bundle Bundle) error {
 	return archive.WriteFile(tw, fmt.Sprintf(".%v", SignaturesFile), bs)
 }
 
+func writeAttestations(tw *tar.Writer, bundle Bundle) error {
+
+	if bundle.Attestations == nil {
+		return nil
+	}
+
+	bs, err := json.MarshalIndent(bundle.Attestations, "", " ")
+	if err != nil {
+		return err
+	}
+
+	return archive.WriteFile(tw, AttestationsFile, bs)
+}
+
 func hashBundleFiles(hash SignatureHasher, b *Bundle) ([]FileInfo, error) {
 
 	files := []FileInfo{}
@@ -1081,6 +1173,20 @@ func hashBundleFiles(hash SignatureHasher, b *Bundle) ([]FileInfo, error) {
 		files = append(files, NewFile(strings.TrimPrefix(ManifestExt, "/"), hex.EncodeToString(bs), defaultHashingAlg))
 	}
 
+	if b.Attestations != nil {
+		result, err := jsonValue(b.Attestations)
+		if err != nil {
+			return files, err
+		}
+
+		bs, err = hash.HashFile(result)
+		if err != nil {
+			return files, err
+		}
+
+		files = append(files, NewFile(AttestationsFile, hex.EncodeToString(bs), defaultHashingAlg))
+	}
+
 	return files, err
 }
 
@@ -1134,26 +1240,10 @@ func (b *Bundle) GenerateSignature(signingConfig *SigningConfig, keyID string, u
 		return err
 	}
 
-	files := []FileInfo{}
-
-	for _, module := range b.Modules {
-		bytes, err := hash.HashFile(module.Raw)
-		if err != nil {
-			return err
-		}
-
-		path := module.URL
-		if useModulePath {
-			path = module.Path
-		}
-		files = append(files, NewFile(strings.TrimPrefix(path, "/"), hex.EncodeToString(bytes), defaultHashingAlg))
-	}
-
-	result, err := hashBundleFiles(hash, b)
+	files, err := b.fileInfos(hash, useModulePath)
 	if err != nil {
 		return err
 	}
-	files = append(files, result...)
 
 	// generate signed token
 	token, err := GenerateSignedToken(files, signingConfig, keyID)
@@ -1174,6 +1264,32 @@ func (b *Bundle) GenerateSignature(signingConfig *SigningConfig, keyID string, u
 	return nil
 }
 
+// fileInfos returns the hashes of the files the bundle is written to.
+func (b *Bundle) fileInfos(hash SignatureHasher, useModulePath bool) ([]FileInfo, error) {
+
+	files := []FileInfo{}
+
+	for _, module := range b.Modules {
+		bytes, err := hash.HashFile(module.Raw)
+		if err != nil {
+			return files, err
+		}
+
+		path := module.URL
+		if useModulePath {
+			path = module.Path
+		}
+		files = append(files, NewFile(strings.TrimPrefix(path, "/"), hex.EncodeToString(bytes), defaultHashingAlg))
+	}
+
+	result, err := hashBundleFiles(hash, b)
+	if err != nil {
+		return files, err
+	}
+
+	return append(files, result...), nil
+}
+
 // ParsedModules returns a map of parsed modules with names that are
 // unique and human readable for the given a bundle name.
 func (b *Bundle) ParsedModules(bundleName string) map[string]*ast.Module {
@@ -1377,7 +1493,7 @@ func (b *Bundle) readData(key []string) *any {
 
 // Type returns the type of the bundle.
 func (b *Bundle) Type() string {
-	if len(b.Patch.Data) != 0 {
+	if len(b.Patch.Data) != 0 || len(b.Patch.Modules) != 0 {
 		return DeltaBundleType
 	}
 	return SnapshotBundleType
@@ -1653,7 +1769,8 @@ func modulePathWithPrefix(bundleName string, modulePath string) string {
 // IsStructuredDoc checks if the file name equals a structured file extension ex. ".json"
 func IsStructuredDoc(name string) bool {
 	return filepath.Base(name) == dataFile || filepath.Base(name) == yamlDataFile ||
-		filepath.Base(name) == SignaturesFile || filepath.Base(name) == ManifestExt
+		filepath.Base(name) == SignaturesFile || filepath.Base(name) == ManifestExt ||
+		filepath.Base(name) == AttestationsFile
 }
 
 func preProcessBundle(loader DirectoryLoader, skipVerify bool, sizeLimitBytes int64) (SignaturesConfig, Patch, []*Descriptor, error) {
@@ -1706,6 +1823,32 @@ func preProcessBundle(loader DirectoryLoader, skipVerify bool, sizeLimitBytes in
 	return signatures, patch, descriptors, nil
 }
 
+// parseModulePatch normalizes the path of the module patch and parses the
+// module it adds or replaces.
+func parseModulePatch(b *Bundle, patch *ModulePatchOperation, popts ast.ParserOptions, m metrics.Metrics) error {
+	patch.Path = "/" + strings.TrimLeft(filepath.ToSlash(patch.Path), "/")
+
+	switch patch.Op {
+	case "remove":
+		return nil
+	case "upsert", "replace":
+	default:
+		return fmt.Errorf("bad module patch operation: %v", patch.Op)
+	}
+
+	if regoVersion, err := b.RegoVersionForFile(patch.Path, popts.EffectiveRegoVersion()); err != nil {
+		return err
+	} else if regoVersion != ast.RegoUndefined {
+		popts.RegoVersion = regoVersion
+	}
+
+	var err error
+	m.Timer(metrics.RegoModuleParse).Start()
+	patch.Parsed, err = ast.ParseModuleWithOpts(patch.Path, patch.Value, popts)
+	m.Timer(metrics.RegoModuleParse).Stop()
+	return err
+}
+
 func readFile(f *Descriptor, sizeLimitBytes int64) (bytes.Buffer, error) {
 	// Case for pre-loaded byte buffers, like those from the tarballLoader.
 	if bb, ok := f.reader.(*bytes.Buffer); ok {
@@ -0,0 +1,458 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package keyless verifies bundles signed with short-lived certificates, in the
+// style of Sigstore, instead of keys distributed to OPA out-of-band.
+//
+// A keyless signature is a JWT with the same payload as the signatures of the
+// default verifier. Its header carries the certificate chain of the signer in
+// the standard "x5c" parameter, and an entry of a transparency log recording
+// that the certificate was used to sign the payload in the "tlog" parameter.
+// The log entry includes an inclusion proof and a signed checkpoint of the log,
+// so signatures are verified offline against a local trust root.
+package keyless
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/IUAD1IY7/opa/internal/jwx/jwa"
+	"github.com/IUAD1IY7/opa/internal/jwx/jws"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+)
+
+// VerifierID is the ID the Verifier is conventionally registered under, and
+// the plugin set in signatures files of keyless signed bundles.
+const VerifierID = "keyless"
+
+// Header parameters of keyless signatures.
+const (
+	certChainHeader = "x5c"
+	logEntryHeader  = "tlog"
+)
+
+// Certificate extensions holding the OIDC issuer of the signer's identity.
+var (
+	issuerOID   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	issuerV2OID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Config is the trust root of the verifier, and the identities trusted to sign
+// bundles.
+type Config struct {
+	// CertificateAuthorities is a list of PEM encoded certificates. Self-signed
+	// certificates are trusted as roots, others are used as intermediates.
+	CertificateAuthorities string            `json:"certificate_authorities"`
+	TransparencyLogs       []TransparencyLog `json:"transparency_logs"`
+	Identities             []Identity        `json:"identities"`
+}
+
+// TransparencyLog is a log trusted to record signatures. Its checkpoints are
+// verified with its PEM encoded public key.
+type TransparencyLog struct {
+	Origin    string `json:"origin"`
+	PublicKey string `json:"public_key"`
+}
+
+// Identity is a signer identity trusted to sign bundles. The subject is
+// matched against the email addresses and URIs of the signer's certificate.
+type Identity struct {
+	Issuer        string `json:"issuer"`
+	Subject       string `json:"subject,omitempty"`
+	SubjectRegExp string `json:"subject_regexp,omitempty"`
+}
+
+// LogEntry is the entry of a transparency log recording a signature, along
+// with the proof that it is included in the log.
+type LogEntry struct {
+	Origin         string `json:"origin"`
+	IntegratedTime int64  `json:"integrated_time"`
+	Index          int64  `json:"log_index"`
+	TreeSize       int64  `json:"tree_size"`
+	RootHash       []byte `json:"root_hash"`
+	// Hashes is the inclusion proof of the entry in the tree.
+	Hashes [][]byte `json:"hashes"`
+	// Signature is the signature of the log over the checkpoint of the tree.
+	Signature []byte `json:"checkpoint_signature"`
+}
+
+// LeafData returns the data recorded by the log for a signature: the digest of
+// the signed payload, the signer's certificate and the time the entry was
+// integrated into the log.
+func LeafData(payload []byte, cert *x509.Certificate, integratedTime int64) []byte {
+	digest := sha256.Sum256(payload)
+	bs, _ := json.Marshal(struct {
+		PayloadDigest  []byte `json:"payload_digest"`
+		Certificate    []byte `json:"certificate"`
+		IntegratedTime int64  `json:"integrated_time"`
+	}{digest[:], cert.Raw, integratedTime})
+	return bs
+}
+
+// LeafHash returns the hash of a leaf of the log's Merkle tree (RFC 6962).
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// NodeHash returns the hash of an interior node of the log's Merkle tree.
+func NodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Checkpoint returns the checkpoint of the log signed by its key.
+func Checkpoint(origin string, treeSize int64, rootHash []byte) []byte {
+	return fmt.Appendf(nil, "%s\n%d\n%s\n", origin, treeSize, base64.StdEncoding.EncodeToString(rootHash))
+}
+
+// Verifier verifies keyless bundle signatures. It's registered with
+// bundle.RegisterVerifier.
+type Verifier struct {
+	roots         *x509.CertPool
+	intermediates *x509.CertPool
+	logs          map[string]crypto.PublicKey
+	identities    []identity
+}
+
+type identity struct {
+	Identity
+	re *regexp.Regexp
+}
+
+// New returns a Verifier for the given configuration.
+func New(c Config) (*Verifier, error) {
+	v := &Verifier{
+		roots:         x509.NewCertPool(),
+		intermediates: x509.NewCertPool(),
+		logs:          make(map[string]crypto.PublicKey, len(c.TransparencyLogs)),
+	}
+
+	certs, err := parseCertificates([]byte(c.CertificateAuthorities))
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate authorities: %w", err)
+	}
+
+	var roots int
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			v.roots.AddCert(cert)
+			roots++
+		} else {
+			v.intermediates.AddCert(cert)
+		}
+	}
+
+	if roots == 0 {
+		return nil, errors.New("no root certificate authorities")
+	}
+
+	for _, l := range c.TransparencyLogs {
+		block, _ := pem.Decode([]byte(l.PublicKey))
+		if block == nil {
+			return nil, fmt.Errorf("transparency log %v: failed to parse PEM block containing the key", l.Origin)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("transparency log %v: %w", l.Origin, err)
+		}
+		v.logs[l.Origin] = key
+	}
+
+	if len(v.logs) == 0 {
+		return nil, errors.New("no transparency logs")
+	}
+
+	for _, id := range c.Identities {
+		if id.Issuer == "" {
+			return nil, errors.New("identity issuer is empty")
+		}
+
+		i := identity{Identity: id}
+		switch {
+		case id.Subject != "" && id.SubjectRegExp != "":
+			return nil, fmt.Errorf("identity %v: subject and subject_regexp cannot be used together", id.Issuer)
+		case id.SubjectRegExp != "":
+			if i.re, err = regexp.Compile(id.SubjectRegExp); err != nil {
+				return nil, fmt.Errorf("identity %v: %w", id.Issuer, err)
+			}
+		case id.Subject == "":
+			return nil, fmt.Errorf("identity %v: subject or subject_regexp must be set", id.Issuer)
+		}
+		v.identities = append(v.identities, i)
+	}
+
+	if len(v.identities) == 0 {
+		return nil, errors.New("no trusted identities")
+	}
+
+	return v, nil
+}
+
+type header struct {
+	Algorithm jwa.SignatureAlgorithm `json:"alg"`
+	CertChain [][]byte               `json:"x5c"`
+	LogEntry  *LogEntry              `json:"tlog"`
+}
+
+// VerifyBundleSignature verifies the keyless signature of a bundle and returns
+// the files listed in its payload. The signer's certificate must chain to a
+// trusted root at the time the signature was recorded in the log, and its
+// identity must match one of the trusted identities.
+func (v *Verifier) VerifyBundleSignature(sc bundle.SignaturesConfig, bvc *bundle.VerificationConfig) (map[string]bundle.FileInfo, error) {
+	files := make(map[string]bundle.FileInfo)
+
+	if len(sc.Signatures) != 1 {
+		return files, fmt.Errorf("%v: expected exactly one keyless signature, got %d", bundle.SignaturesFile, len(sc.Signatures))
+	}
+
+	ds, err := v.verify(sc.Signatures[0])
+	if err != nil {
+		return files, fmt.Errorf("%v: %w", bundle.SignaturesFile, err)
+	}
+
+	if bvc != nil && ds.Scope != bvc.Scope {
+		return files, fmt.Errorf("%v: scope mismatch", bundle.SignaturesFile)
+	}
+
+	for _, file := range ds.Files {
+		files[file.Name] = file
+	}
+	return files, nil
+}
+
+func (v *Verifier) verify(token string) (*bundle.DecodedSignature, error) {
+	parts, err := jws.SplitCompact(token)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64 decode JWT headers: %w", err)
+	}
+
+	var hdr header
+	if err := json.Unmarshal(bs, &hdr); err != nil {
+		return nil, fmt.Errorf("failed to parse JWT headers: %w", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hdr.CertChain) == 0 {
+		return nil, fmt.Errorf("missing %v header", certChainHeader)
+	}
+
+	if hdr.LogEntry == nil {
+		return nil, fmt.Errorf("missing %v header", logEntryHeader)
+	}
+
+	chain := make([]*x509.Certificate, len(hdr.CertChain))
+	for i, der := range hdr.CertChain {
+		if chain[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, fmt.Errorf("certificate %d: %w", i, err)
+		}
+	}
+	cert := chain[0]
+
+	if err := v.verifyLogEntry(hdr.LogEntry, LeafData(payload, cert, hdr.LogEntry.IntegratedTime)); err != nil {
+		return nil, fmt.Errorf("transparency log entry: %w", err)
+	}
+
+	if err := v.verifyCertificate(cert, chain[1:], time.Unix(hdr.LogEntry.IntegratedTime, 0)); err != nil {
+		return nil, fmt.Errorf("certificate: %w", err)
+	}
+
+	if err := v.verifyIdentity(cert); err != nil {
+		return nil, err
+	}
+
+	switch hdr.Algorithm {
+	case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512, jwa.ES256, jwa.ES384, jwa.ES512:
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm: %s", hdr.Algorithm)
+	}
+
+	if _, err := jws.Verify([]byte(token), hdr.Algorithm, cert.PublicKey); err != nil {
+		return nil, err
+	}
+
+	var ds bundle.DecodedSignature
+	if err := json.Unmarshal(payload, &ds); err != nil {
+		return nil, err
+	}
+
+	return &ds, nil
+}
+
+// verifyCertificate verifies that the certificate chains to a trusted root at
+// the given time. Certificates are short-lived, so they are verified at the
+// time the log recorded the signature, rather than the current time.
+func (v *Verifier) verifyCertificate(cert *x509.Certificate, chain []*x509.Certificate, at time.Time) error {
+	intermediates := v.intermediates.Clone()
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	return err
+}
+
+func (v *Verifier) verifyIdentity(cert *x509.Certificate) error {
+	issuer, err := certificateIssuer(cert)
+	if err != nil {
+		return err
+	}
+
+	subjects := slices.Clone(cert.EmailAddresses)
+	for _, u := range cert.URIs {
+		subjects = append(subjects, u.String())
+	}
+
+	for _, id := range v.identities {
+		if id.Issuer != issuer {
+			continue
+		}
+		for _, s := range subjects {
+			if (id.re != nil && id.re.MatchString(s)) || (id.re == nil && id.Subject == s) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("identity %v (issuer %v) not trusted", subjects, issuer)
+}
+
+// certificateIssuer returns the OIDC issuer of the signer's identity.
+func certificateIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(issuerV2OID):
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", fmt.Errorf("invalid issuer extension: %w", err)
+			}
+			return issuer, nil
+		case ext.Id.Equal(issuerOID):
+			return string(ext.Value), nil
+		}
+	}
+	return "", errors.New("certificate has no issuer extension")
+}
+
+// verifyLogEntry verifies the checkpoint signature of the log, and the proof
+// that the leaf is included in the tree of the checkpoint.
+func (v *Verifier) verifyLogEntry(e *LogEntry, leaf []byte) error {
+	key, ok := v.logs[e.Origin]
+	if !ok {
+		return fmt.Errorf("unknown log %v", e.Origin)
+	}
+
+	if err := verifyCheckpoint(key, Checkpoint(e.Origin, e.TreeSize, e.RootHash), e.Signature); err != nil {
+		return err
+	}
+
+	return VerifyInclusion(LeafHash(leaf), e.Index, e.TreeSize, e.Hashes, e.RootHash)
+}
+
+func verifyCheckpoint(key crypto.PublicKey, checkpoint, sig []byte) error {
+	digest := sha256.Sum256(checkpoint)
+
+	var ok bool
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(key, digest[:], sig)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, checkpoint, sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	default:
+		return fmt.Errorf("unsupported log key type %T", key)
+	}
+
+	if !ok {
+		return errors.New("invalid checkpoint signature")
+	}
+	return nil
+}
+
+// VerifyInclusion verifies the proof that the leaf hash is included at index in
+// a tree of the given size and root hash (RFC 9162, section 2.1.3.2).
+func VerifyInclusion(leafHash []byte, index, treeSize int64, proof [][]byte, rootHash []byte) error {
+	if index < 0 || index >= treeSize {
+		return fmt.Errorf("index %d out of range for tree size %d", index, treeSize)
+	}
+
+	fn, sn := index, treeSize-1
+	r := leafHash
+
+	for _, p := range proof {
+		if sn == 0 {
+			return errors.New("inclusion proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = NodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = NodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 || !bytes.Equal(r, rootHash) {
+		return errors.New("inclusion proof does not match root hash")
+	}
+	return nil
+}
+
+func parseCertificates(bs []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, bs = pem.Decode(bs)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
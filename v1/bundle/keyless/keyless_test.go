@@ -0,0 +1,508 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package keyless
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/internal/jwx/jwa"
+	"github.com/IUAD1IY7/opa/internal/jwx/jws"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+)
+
+const (
+	testOrigin  = "log.example.com"
+	testIssuer  = "https://accounts.example.com"
+	testSubject = "release@example.com"
+)
+
+func TestVerifyBundleSignature(t *testing.T) {
+
+	tests := []struct {
+		note    string
+		signer  func(*testSigner)
+		config  func(*Config)
+		wantErr string
+	}{
+		{
+			note: "valid",
+		},
+		{
+			note: "subject regexp",
+			config: func(c *Config) {
+				c.Identities = []Identity{{Issuer: testIssuer, SubjectRegExp: `^.*@example\.com$`}}
+			},
+		},
+		{
+			note: "legacy issuer extension",
+			signer: func(s *testSigner) {
+				s.issuerExt = pkix.Extension{Id: issuerOID, Value: []byte(testIssuer)}
+			},
+		},
+		{
+			note: "untrusted subject",
+			signer: func(s *testSigner) {
+				s.subject = "mallory@example.com"
+			},
+			wantErr: "identity [mallory@example.com] (issuer https://accounts.example.com) not trusted",
+		},
+		{
+			note: "untrusted issuer",
+			config: func(c *Config) {
+				c.Identities = []Identity{{Issuer: "https://other.example.com", Subject: testSubject}}
+			},
+			wantErr: "identity [release@example.com] (issuer https://accounts.example.com) not trusted",
+		},
+		{
+			note: "untrusted certificate authority",
+			signer: func(s *testSigner) {
+				s.ca, s.caKey = newCA(t)
+			},
+			wantErr: "certificate: x509: certificate signed by unknown authority",
+		},
+		{
+			note: "certificate expired when recorded",
+			signer: func(s *testSigner) {
+				s.integratedTime = s.integratedTime.Add(time.Hour)
+			},
+			wantErr: "certificate: x509: certificate has expired or is not yet valid",
+		},
+		{
+			note: "unknown log",
+			signer: func(s *testSigner) {
+				s.origin = "other.example.com"
+			},
+			wantErr: "transparency log entry: unknown log other.example.com",
+		},
+		{
+			note: "forged checkpoint",
+			signer: func(s *testSigner) {
+				s.logKey = newKey(t)
+			},
+			wantErr: "transparency log entry: invalid checkpoint signature",
+		},
+		{
+			note: "entry not included",
+			signer: func(s *testSigner) {
+				s.tamper = func(_ []byte, e *LogEntry) {
+					e.Index = 1
+				}
+			},
+			wantErr: "transparency log entry: inclusion proof does not match root hash",
+		},
+		{
+			note: "payload not recorded",
+			signer: func(s *testSigner) {
+				s.tamper = func(payload []byte, _ *LogEntry) {
+					copy(payload, `{"files":[]}`)
+				}
+			},
+			wantErr: "transparency log entry: inclusion proof does not match root hash",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			s := newTestSigner(t)
+			config := s.config(t)
+
+			if tc.signer != nil {
+				tc.signer(s)
+			}
+			if tc.config != nil {
+				tc.config(&config)
+			}
+
+			v, err := New(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			files := []bundle.FileInfo{bundle.NewFile("data.json", "abc", "SHA-256")}
+			token, err := s.GenerateSignedToken(files, nil, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := v.VerifyBundleSignature(bundle.SignaturesConfig{Signatures: []string{token}, Plugin: VerifierID}, nil)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "" && result["data.json"] != files[0]:
+				t.Fatalf("expected files to be verified but got: %v", result)
+			}
+		})
+	}
+}
+
+func TestReadKeylessSignedBundle(t *testing.T) {
+
+	s := newTestSigner(t)
+
+	v, err := New(s.config(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bundle.RegisterSigner(VerifierID, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.RegisterVerifier(VerifierID, v); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bundle.Bundle{
+		Data: map[string]any{"foo": "bar"},
+		Modules: []bundle.ModuleFile{
+			{
+				URL:    "/foo.rego",
+				Path:   "/foo.rego",
+				Parsed: ast.MustParseModule("package foo\n\np := 1"),
+				Raw:    []byte("package foo\n\np := 1\n"),
+			},
+		},
+	}
+	b.Manifest.Init()
+
+	if err := b.GenerateSignature(bundle.NewSigningConfig("", "", "").WithPlugin(VerifierID), "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	vc := bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{}, "", "", nil)
+
+	if _, err := bundle.NewReader(bytes.NewReader(buf.Bytes())).WithBundleVerificationConfig(vc).Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Modules[0].Raw = []byte("package foo\n\np := 2\n")
+	buf.Reset()
+	if err := bundle.NewWriter(&buf).Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = bundle.NewReader(&buf).WithBundleVerificationConfig(vc).Read()
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("expected verification error but got: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+
+	s := newTestSigner(t)
+
+	tests := []struct {
+		note    string
+		config  func(*Config)
+		wantErr string
+	}{
+		{
+			note: "valid",
+		},
+		{
+			note: "no roots",
+			config: func(c *Config) {
+				c.CertificateAuthorities = ""
+			},
+			wantErr: "no root certificate authorities",
+		},
+		{
+			note: "invalid log key",
+			config: func(c *Config) {
+				c.TransparencyLogs[0].PublicKey = "foo"
+			},
+			wantErr: "transparency log log.example.com: failed to parse PEM block containing the key",
+		},
+		{
+			note: "no logs",
+			config: func(c *Config) {
+				c.TransparencyLogs = nil
+			},
+			wantErr: "no transparency logs",
+		},
+		{
+			note: "no identities",
+			config: func(c *Config) {
+				c.Identities = nil
+			},
+			wantErr: "no trusted identities",
+		},
+		{
+			note: "no issuer",
+			config: func(c *Config) {
+				c.Identities = []Identity{{Subject: testSubject}}
+			},
+			wantErr: "identity issuer is empty",
+		},
+		{
+			note: "no subject",
+			config: func(c *Config) {
+				c.Identities = []Identity{{Issuer: testIssuer}}
+			},
+			wantErr: "identity https://accounts.example.com: subject or subject_regexp must be set",
+		},
+		{
+			note: "subject and regexp",
+			config: func(c *Config) {
+				c.Identities = []Identity{{Issuer: testIssuer, Subject: testSubject, SubjectRegExp: ".*"}}
+			},
+			wantErr: "subject and subject_regexp cannot be used together",
+		},
+		{
+			note: "invalid regexp",
+			config: func(c *Config) {
+				c.Identities = []Identity{{Issuer: testIssuer, SubjectRegExp: "("}}
+			},
+			wantErr: "identity https://accounts.example.com: error parsing regexp",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			config := s.config(t)
+			if tc.config != nil {
+				tc.config(&config)
+			}
+
+			_, err := New(config)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyInclusion(t *testing.T) {
+
+	for size := 1; size <= 9; size++ {
+		leaves := make([][]byte, size)
+		for i := range leaves {
+			leaves[i] = LeafHash([]byte{byte(i)})
+		}
+		root := merkleRoot(leaves)
+
+		for index := range size {
+			proof := merkleProof(leaves, index)
+			if err := VerifyInclusion(leaves[index], int64(index), int64(size), proof, root); err != nil {
+				t.Fatalf("leaf %d of %d: %v", index, size, err)
+			}
+			if err := VerifyInclusion(LeafHash([]byte("x")), int64(index), int64(size), proof, root); err == nil {
+				t.Fatalf("leaf %d of %d: expected error for wrong leaf", index, size)
+			}
+		}
+	}
+
+	if err := VerifyInclusion(LeafHash(nil), 1, 1, nil, LeafHash(nil)); err == nil {
+		t.Fatal("expected error for index out of range")
+	}
+}
+
+// testSigner issues short-lived certificates and records signatures in a test
+// transparency log, the way a keyless signing service would.
+type testSigner struct {
+	ca             *x509.Certificate
+	caKey          *ecdsa.PrivateKey
+	logKey         *ecdsa.PrivateKey
+	origin         string
+	subject        string
+	issuerExt      pkix.Extension
+	integratedTime time.Time
+	tamper         func(payload []byte, e *LogEntry)
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+
+	ca, caKey := newCA(t)
+	issuer, err := asn1.MarshalWithParams(testIssuer, "utf8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testSigner{
+		ca:             ca,
+		caKey:          caKey,
+		logKey:         newKey(t),
+		origin:         testOrigin,
+		subject:        testSubject,
+		issuerExt:      pkix.Extension{Id: issuerV2OID, Value: issuer},
+		integratedTime: time.Now().Add(-time.Hour),
+	}
+}
+
+func (s *testSigner) config(t *testing.T) Config {
+	t.Helper()
+
+	logKey, err := x509.MarshalPKIXPublicKey(&s.logKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Config{
+		CertificateAuthorities: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.ca.Raw})),
+		TransparencyLogs: []TransparencyLog{
+			{Origin: testOrigin, PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: logKey}))},
+		},
+		Identities: []Identity{{Issuer: testIssuer, Subject: testSubject}},
+	}
+}
+
+// GenerateSignedToken signs the files with a certificate valid for ten minutes
+// from an hour ago, when the signature is recorded in the log.
+func (s *testSigner) GenerateSignedToken(files []bundle.FileInfo, _ *bundle.SigningConfig, _ string) (string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	notBefore := time.Now().Add(-time.Hour - time.Minute)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       notBefore,
+		NotAfter:        notBefore.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{s.subject},
+		ExtraExtensions: []pkix.Extension{s.issuerExt},
+	}, s.ca, &key.PublicKey, s.caKey)
+	if err != nil {
+		return "", err
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(bundle.DecodedSignature{Files: files})
+	if err != nil {
+		return "", err
+	}
+
+	// The log holds other entries around the one recording the signature.
+	integratedTime := s.integratedTime.Unix()
+	leaves := [][]byte{LeafHash([]byte("a")), LeafHash([]byte("b")), LeafHash([]byte("c")), nil, LeafHash([]byte("d"))}
+	leaves[3] = LeafHash(LeafData(payload, cert, integratedTime))
+	root := merkleRoot(leaves)
+
+	checkpoint := sha256.Sum256(Checkpoint(testOrigin, int64(len(leaves)), root))
+	sig, err := ecdsa.SignASN1(rand.Reader, s.logKey, checkpoint[:])
+	if err != nil {
+		return "", err
+	}
+
+	entry := LogEntry{
+		Origin:         s.origin,
+		IntegratedTime: integratedTime,
+		Index:          3,
+		TreeSize:       int64(len(leaves)),
+		RootHash:       root,
+		Hashes:         merkleProof(leaves, 3),
+		Signature:      sig,
+	}
+
+	if s.tamper != nil {
+		s.tamper(payload, &entry)
+	}
+
+	hdr, err := json.Marshal(header{Algorithm: jwa.ES256, CertChain: [][]byte{cert.Raw}, LogEntry: &entry})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := jws.SignLiteral(payload, jwa.ES256, key, hdr, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return string(token), nil
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key := newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test root"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// merkleRoot returns the root hash of the tree of the leaf hashes (RFC 6962).
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return NodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merkleProof returns the inclusion proof of the leaf at index (RFC 6962).
+func merkleProof(leaves [][]byte, index int) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+	k := split(len(leaves))
+	if index < k {
+		return append(merkleProof(leaves[:k], index), merkleRoot(leaves[k:]))
+	}
+	return append(merkleProof(leaves[k:], index-k), merkleRoot(leaves[:k]))
+}
+
+// split returns the largest power of two smaller than n.
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
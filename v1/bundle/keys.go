@@ -7,6 +7,7 @@ package bundle
 
 import (
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
 
@@ -31,6 +32,13 @@ type VerificationConfig struct {
 	KeyID      string   `json:"keyid"`
 	Scope      string   `json:"scope"`
 	Exclude    []string `json:"exclude_files"`
+
+	// RequireAttestations rejects bundles without signed attestations.
+	RequireAttestations bool `json:"require_attestations"`
+
+	// Threshold is the number of signatures by distinct keys a bundle must
+	// carry. If zero, a bundle must carry exactly one signature.
+	Threshold int `json:"threshold"`
 }
 
 // NewVerificationConfig return a new VerificationConfig
@@ -60,6 +68,20 @@ func (vc *VerificationConfig) ValidateAndInjectDefaults(keys map[string]*KeyConf
 			return fmt.Errorf("key id %s not found", vc.KeyID)
 		}
 	}
+
+	if vc.Threshold < 0 {
+		return fmt.Errorf("invalid threshold %d", vc.Threshold)
+	}
+
+	if vc.Threshold > 0 {
+		if vc.KeyID != "" {
+			return errors.New("key id and threshold cannot be used together")
+		}
+		if vc.Threshold > len(keys) {
+			return fmt.Errorf("threshold %d exceeds the number of keys (%d)", vc.Threshold, len(keys))
+		}
+	}
+
 	return nil
 }
 
@@ -38,6 +38,26 @@ func TestValidateAndInjectDefaultsVerificationConfig(t *testing.T) {
 			NewVerificationConfig(map[string]*KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}}, "bar", "", nil),
 			true, errors.New("key id bar not found"),
 		},
+		"valid_config_with_threshold": {
+			map[string]*KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}, "bar": {Key: "secret", Algorithm: "HS256"}},
+			&VerificationConfig{Threshold: 2},
+			false, nil,
+		},
+		"invalid_config_threshold_exceeds_keys": {
+			map[string]*KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}},
+			&VerificationConfig{Threshold: 2},
+			true, errors.New("threshold 2 exceeds the number of keys (1)"),
+		},
+		"invalid_config_threshold_with_key": {
+			map[string]*KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}},
+			&VerificationConfig{KeyID: "foo", Threshold: 1},
+			true, errors.New("key id and threshold cannot be used together"),
+		},
+		"invalid_config_negative_threshold": {
+			map[string]*KeyConfig{},
+			&VerificationConfig{Threshold: -1},
+			true, errors.New("invalid threshold -1"),
+		},
 	}
 
 	for name, tc := range tests {
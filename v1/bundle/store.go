@@ -488,7 +488,14 @@ func activateBundles(opts *ActivateOpts) error {
 	maps.Copy(remainingAndExtra, remaining)
 	maps.Copy(remainingAndExtra, opts.ExtraModules)
 
-	err = compileModules(opts.Compiler, opts.Metrics, snapshotBundles, remainingAndExtra, opts.legacy, opts.AuthorizationDecisionRef)
+	// Modules removed by delta bundles may still be on the compiler, so only
+	// the modules remaining in the store are compiled if any were patched.
+	preserve := true
+	for _, b := range deltaBundles {
+		preserve = preserve && len(b.Patch.Modules) == 0
+	}
+
+	err = compileModules(opts.Compiler, opts.Metrics, snapshotBundles, remainingAndExtra, preserve, opts.legacy, opts.AuthorizationDecisionRef)
 	if err != nil {
 		return err
 	}
@@ -595,6 +602,10 @@ func activateDeltaBundles(opts *ActivateOpts, bundles map[string]*Bundle) error
 		}
 	}
 
+	if err := applyModulePatches(opts, bundles); err != nil {
+		return err
+	}
+
 	if err := ast.CheckPathConflicts(opts.Compiler, storage.NonEmpty(opts.Ctx, opts.Store, opts.Txn)); len(err) > 0 {
 		return err
 	}
@@ -612,6 +623,51 @@ func activateDeltaBundles(opts *ActivateOpts, bundles map[string]*Bundle) error
 	return nil
 }
 
+// applyModulePatches applies the module patches of the delta bundles to the
+// store. The patched modules are compiled along with the other modules in the
+// store once the bundles are activated.
+func applyModulePatches(opts *ActivateOpts, bundles map[string]*Bundle) error {
+	for name, b := range bundles {
+		for _, pat := range b.Patch.Modules {
+			id := pat.Path
+			if !opts.legacy {
+				id = modulePathWithPrefix(name, pat.Path)
+			}
+
+			switch pat.Op {
+			case "remove":
+				if err := opts.Store.DeletePolicy(opts.Ctx, opts.Txn, id); err != nil {
+					return err
+				}
+			case "replace":
+				if _, err := opts.Store.GetPolicy(opts.Ctx, opts.Txn, id); err != nil {
+					return err
+				}
+				fallthrough
+			case "upsert":
+				if err := opts.Store.UpsertPolicy(opts.Ctx, opts.Txn, id, []byte(pat.Value)); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("bad module patch operation: %v", pat.Op)
+			}
+
+			if err := eraseModuleRegoVersionsFromStore(opts.Ctx, opts.Store, opts.Txn, []string{id}); err != nil {
+				return err
+			}
+
+			if pat.Parsed != nil {
+				mf := ModuleFile{Path: pat.Path, Raw: []byte(pat.Value), Parsed: pat.Parsed}
+				if err := writeModuleRegoVersionToStore(opts.Ctx, opts.Store, opts.Txn, b, mf, id, opts.ParserOptions.RegoVersion); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func valueToManifest(v any) (Manifest, error) {
 	if astV, ok := v.(ast.Value); ok {
 		var err error
@@ -919,7 +975,7 @@ func writeData(ctx context.Context, store storage.Store, txn storage.Transaction
 	return nil
 }
 
-func compileModules(compiler *ast.Compiler, m metrics.Metrics, bundles map[string]*Bundle, extraModules map[string]*ast.Module, legacy bool, authorizationDecisionRef ast.Ref) error {
+func compileModules(compiler *ast.Compiler, m metrics.Metrics, bundles map[string]*Bundle, extraModules map[string]*ast.Module, preserve bool, legacy bool, authorizationDecisionRef ast.Ref) error {
 
 	m.Timer(metrics.RegoModuleCompile).Start()
 	defer m.Timer(metrics.RegoModuleCompile).Stop()
@@ -927,7 +983,9 @@ func compileModules(compiler *ast.Compiler, m metrics.Metrics, bundles map[strin
 	modules := map[string]*ast.Module{}
 
 	// preserve any modules already on the compiler
-	maps.Copy(modules, compiler.Modules)
+	if preserve {
+		maps.Copy(modules, compiler.Modules)
+	}
 
 	// preserve any modules passed in from the store
 	maps.Copy(modules, extraModules)
@@ -5873,6 +5873,188 @@ func TestDeltaBundleBadManifest(t *testing.T) {
 	mockStore.AssertValid(t)
 }
 
+func TestDeltaBundleModulePatches(t *testing.T) {
+
+	tests := []struct {
+		note    string
+		patches []ModulePatchOperation
+		exp     map[string]string
+		wantErr string
+	}{
+		{
+			note: "upsert and remove",
+			patches: []ModulePatchOperation{
+				{Op: "upsert", Path: "/a/x.rego", Value: "package a.x\n\np := 2\n"},
+				{Op: "upsert", Path: "a/z.rego", Value: "package a.z\n\nr := data.a.x.p\n"},
+				{Op: "remove", Path: "/a/y.rego"},
+			},
+			exp: map[string]string{
+				"bundle1/a/x.rego": "package a.x\n\np := 2\n",
+				"bundle1/a/z.rego": "package a.z\n\nr := data.a.x.p\n",
+			},
+		},
+		{
+			note: "replace",
+			patches: []ModulePatchOperation{
+				{Op: "replace", Path: "/a/y.rego", Value: "package a.y\n\nq := 2\n"},
+			},
+			exp: map[string]string{
+				"bundle1/a/x.rego": "package a.x\n\np := 1\n",
+				"bundle1/a/y.rego": "package a.y\n\nq := 2\n",
+			},
+		},
+		{
+			note: "replace missing module",
+			patches: []ModulePatchOperation{
+				{Op: "replace", Path: "/a/z.rego", Value: "package a.z\n"},
+			},
+			wantErr: "storage_not_found_error: policy id \"bundle1/a/z.rego\"",
+		},
+		{
+			note: "remove missing module",
+			patches: []ModulePatchOperation{
+				{Op: "remove", Path: "/a/z.rego"},
+			},
+			wantErr: "storage_not_found_error: policy id \"bundle1/a/z.rego\"",
+		},
+		{
+			note: "compile error",
+			patches: []ModulePatchOperation{
+				{Op: "remove", Path: "/a/x.rego"},
+				{Op: "upsert", Path: "/a/z.rego", Value: "package a.z\n\nr := undefined_function(1)\n"},
+			},
+			wantErr: "undefined function undefined_function",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx := context.Background()
+			store := inmem.New()
+			m := metrics.New()
+
+			snapshot := map[string]*Bundle{
+				"bundle1": {
+					Manifest: Manifest{Revision: "snapshot", Roots: &[]string{"a"}},
+					Data:     map[string]any{},
+					Modules: []ModuleFile{
+						{Path: "/a/x.rego", Raw: []byte("package a.x\n\np := 1\n"), Parsed: ast.MustParseModule("package a.x\n\np := 1\n")},
+						{Path: "/a/y.rego", Raw: []byte("package a.y\n\nq := 1\n"), Parsed: ast.MustParseModule("package a.y\n\nq := 1\n")},
+					},
+				},
+			}
+
+			compiler := ast.NewCompiler()
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				return Activate(&ActivateOpts{Ctx: ctx, Store: store, Txn: txn, Compiler: compiler, Metrics: m, Bundles: snapshot})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Round trip the delta bundle to parse the patched modules.
+			var buf bytes.Buffer
+			delta := Bundle{Manifest: Manifest{Revision: "delta", Roots: &[]string{"a"}}, Patch: Patch{Modules: tc.patches}}
+			if err := NewWriter(&buf).Write(delta); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := NewReader(&buf).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if b.Type() != DeltaBundleType {
+				t.Fatalf("expected delta bundle but got %v", b.Type())
+			}
+
+			// Delta bundles are activated with the compiler holding the current policies.
+			err = storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				return Activate(&ActivateOpts{Ctx: ctx, Store: store, Txn: txn, Compiler: compiler, Metrics: m, Bundles: map[string]*Bundle{"bundle1": &b}})
+			})
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			ids, err := store.ListPolicies(ctx, txn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			policies := map[string]string{}
+			for _, id := range ids {
+				bs, err := store.GetPolicy(ctx, txn, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				policies[id] = string(bs)
+			}
+
+			if !reflect.DeepEqual(policies, tc.exp) {
+				t.Fatalf("expected policies %v but got %v", tc.exp, policies)
+			}
+
+			if !reflect.DeepEqual(util.KeysSorted(compiler.Modules), util.KeysSorted(tc.exp)) {
+				t.Fatalf("expected compiled modules %v but got %v", util.KeysSorted(tc.exp), util.KeysSorted(compiler.Modules))
+			}
+
+			revision, err := ReadBundleRevisionFromStore(ctx, store, txn, "bundle1")
+			if err != nil || revision != "delta" {
+				t.Fatalf("expected delta revision but got %v (err: %v)", revision, err)
+			}
+		})
+	}
+}
+
+func TestReadDeltaBundleModulePatches(t *testing.T) {
+
+	tests := []struct {
+		note    string
+		patch   ModulePatchOperation
+		wantErr string
+	}{
+		{
+			note:    "bad operation",
+			patch:   ModulePatchOperation{Op: "add", Path: "/a/x.rego", Value: "package a"},
+			wantErr: "bad module patch operation: add",
+		},
+		{
+			note:    "outside roots",
+			patch:   ModulePatchOperation{Op: "upsert", Path: "/b/x.rego", Value: "package b"},
+			wantErr: "manifest roots [a] do not permit 'package b' in module patch '/b/x.rego'",
+		},
+		{
+			note:    "parse error",
+			patch:   ModulePatchOperation{Op: "upsert", Path: "/a/x.rego", Value: "package a\n\np :="},
+			wantErr: "/a/x.rego:3: rego_parse_error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var buf bytes.Buffer
+			delta := Bundle{Manifest: Manifest{Revision: "delta", Roots: &[]string{"a"}}, Patch: Patch{Modules: []ModulePatchOperation{tc.patch}}}
+			if err := NewWriter(&buf).Write(delta); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := NewReader(&buf).Read()
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestEraseData(t *testing.T) {
 	storeReadModes := []struct {
 		note    string
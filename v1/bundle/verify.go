@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/IUAD1IY7/opa/internal/jwx/jwa"
 	"github.com/IUAD1IY7/opa/internal/jwx/jws"
@@ -60,6 +61,10 @@ type DefaultVerifier struct{}
 func (*DefaultVerifier) VerifyBundleSignature(sc SignaturesConfig, bvc *VerificationConfig) (map[string]FileInfo, error) {
 	files := make(map[string]FileInfo)
 
+	if bvc != nil && bvc.Threshold > 0 {
+		return verifyThreshold(sc.Signatures, bvc)
+	}
+
 	if len(sc.Signatures) == 0 {
 		return files, errors.New(".signatures.json: missing JWT (expected exactly one)")
 	}
@@ -81,65 +86,194 @@ func (*DefaultVerifier) VerifyBundleSignature(sc SignaturesConfig, bvc *Verifica
 	return files, nil
 }
 
+// SignatureError describes why a bundle signature wasn't verified.
+type SignatureError struct {
+	Index int    // index of the signature in .signatures.json
+	KeyID string // ID of the key the signature claims, if known
+	Err   error
+}
+
+func (e *SignatureError) Error() string {
+	if e.KeyID == "" {
+		return fmt.Sprintf("signature %d: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("signature %d (key %v): %v", e.Index, e.KeyID, e.Err)
+}
+
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// ThresholdError is returned when fewer bundle signatures than the configured
+// threshold are verified. It lists why each of the other signatures failed.
+type ThresholdError struct {
+	Threshold int
+	Verified  int
+	Failures  []*SignatureError
+}
+
+func (e *ThresholdError) Error() string {
+	msg := fmt.Sprintf(".signatures.json: %d of %d required signatures verified", e.Verified, e.Threshold)
+	for _, f := range e.Failures {
+		msg += "; " + f.Error()
+	}
+	return msg
+}
+
+// verifyThreshold verifies that at least bvc.Threshold signatures are made by
+// distinct trusted keys over the same files. Signatures failing verification
+// are ignored as long as the threshold is met.
+func verifyThreshold(signatures []string, bvc *VerificationConfig) (map[string]FileInfo, error) {
+	files := make(map[string]FileInfo)
+	signers := map[string]struct{}{}
+	var failures []*SignatureError
+	var signed []FileInfo
+
+	for i, token := range signatures {
+		keyID, payload, err := verifyJWT(token, bvc, false)
+		if err == nil {
+			switch _, ok := signers[keyID]; {
+			case ok:
+				err = errors.New("duplicate signature by the same key")
+			case signed != nil && !SameFiles(signed, payload.Files):
+				err = errors.New("signed files differ from other signatures")
+			}
+		}
+
+		if err != nil {
+			failures = append(failures, &SignatureError{Index: i, KeyID: keyID, Err: err})
+			continue
+		}
+
+		signers[keyID] = struct{}{}
+		if signed == nil {
+			signed = payload.Files
+			for _, file := range payload.Files {
+				files[file.Name] = file
+			}
+		}
+	}
+
+	if len(signers) < bvc.Threshold {
+		return make(map[string]FileInfo), &ThresholdError{Threshold: bvc.Threshold, Verified: len(signers), Failures: failures}
+	}
+
+	return files, nil
+}
+
+// SameFiles returns true if both lists contain the same files, with the same
+// hashes, in any order.
+func SameFiles(a, b []FileInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	byName := make(map[string]FileInfo, len(a))
+	for _, f := range a {
+		byName[f.Name] = f
+	}
+
+	for _, f := range b {
+		if byName[f.Name] != f {
+			return false
+		}
+	}
+
+	return true
+}
+
 func verifyJWTSignature(token string, bvc *VerificationConfig) (*DecodedSignature, error) {
-	// decode JWT to check if the header specifies the key to use and/or if claims have the scope.
+	_, ds, err := verifyJWT(token, bvc, true)
+	return ds, err
+}
+
+// DecodeSignature decodes a bundle signature token without verifying it. It
+// returns the ID of the key the token refers to, which is empty if the token
+// doesn't specify one.
+func DecodeSignature(token string) (string, *DecodedSignature, error) {
+	hdr, ds, err := decodeJWT(token)
+	if err != nil {
+		return "", nil, err
+	}
+	if hdr.KeyID != "" {
+		return hdr.KeyID, ds, nil
+	}
+	// If header has no key id, check the deprecated key claim.
+	return ds.KeyID, ds, nil
+}
 
+func decodeJWT(token string) (*jws.StandardHeaders, *DecodedSignature, error) {
 	parts, err := jws.SplitCompact(token)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var decodedHeader []byte
 	if decodedHeader, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
-		return nil, fmt.Errorf("failed to base64 decode JWT headers: %w", err)
+		return nil, nil, fmt.Errorf("failed to base64 decode JWT headers: %w", err)
 	}
 
 	var hdr jws.StandardHeaders
 	if err := json.Unmarshal(decodedHeader, &hdr); err != nil {
-		return nil, fmt.Errorf("failed to parse JWT headers: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse JWT headers: %w", err)
 	}
 
 	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var ds DecodedSignature
 	if err := json.Unmarshal(payload, &ds); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	return &hdr, &ds, nil
+}
+
+// verifyJWT verifies the token with the key it refers to or, if useConfigKeyID
+// is set, the key configured for verification. It returns the ID of the key.
+func verifyJWT(token string, bvc *VerificationConfig, useConfigKeyID bool) (string, *DecodedSignature, error) {
+	// decode JWT to check if the header specifies the key to use and/or if claims have the scope.
+	tokenKeyID, ds, err := DecodeSignature(token)
+	if err != nil {
+		return "", nil, err
 	}
 
 	// check for the id of the key to use for JWT signature verification
 	// first in the OPA config. If not found, then check the JWT kid.
-	keyID := bvc.KeyID
-	if keyID == "" {
-		keyID = hdr.KeyID
+	var keyID string
+	if useConfigKeyID {
+		keyID = bvc.KeyID
 	}
 	if keyID == "" {
-		// If header has no key id, check the deprecated key claim.
-		keyID = ds.KeyID
+		keyID = tokenKeyID
 	}
 
 	if keyID == "" {
-		return nil, errors.New("verification key ID is empty")
+		return keyID, nil, errors.New("verification key ID is empty")
 	}
 
 	// now that we have the keyID, fetch the actual key
 	keyConfig, err := bvc.GetPublicKey(keyID)
 	if err != nil {
-		return nil, err
+		return keyID, nil, err
+	}
+
+	if err := keyConfig.ValidAt(time.Now()); err != nil {
+		return keyID, nil, err
 	}
 
 	// verify JWT signature
 	alg := jwa.SignatureAlgorithm(keyConfig.Algorithm)
 	key, err := verify.GetSigningKey(keyConfig.Key, alg)
 	if err != nil {
-		return nil, err
+		return keyID, nil, err
 	}
 
 	_, err = jws.Verify([]byte(token), alg, key)
 	if err != nil {
-		return nil, err
+		return keyID, nil, err
 	}
 
 	// verify the scope
@@ -149,9 +283,9 @@ func verifyJWTSignature(token string, bvc *VerificationConfig) (*DecodedSignatur
 	}
 
 	if ds.Scope != scope {
-		return nil, errors.New("scope mismatch")
+		return keyID, nil, errors.New("scope mismatch")
 	}
-	return &ds, nil
+	return keyID, ds, nil
 }
 
 // VerifyBundleFile verifies the hash of a file in the bundle matches to that provided in the bundle's signature
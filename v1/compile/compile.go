@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/internal/bundle/diff"
 	"github.com/IUAD1IY7/opa/internal/compiler/wasm"
 	"github.com/IUAD1IY7/opa/internal/debug"
 	"github.com/IUAD1IY7/opa/internal/planner"
@@ -32,6 +33,8 @@ import (
 	"github.com/IUAD1IY7/opa/v1/loader"
 	"github.com/IUAD1IY7/opa/v1/rego"
 	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/util"
+	"github.com/IUAD1IY7/opa/v1/version"
 )
 
 const (
@@ -49,6 +52,12 @@ const (
 	TargetPlan = "plan"
 )
 
+// BuildType and BuilderID identify bundle builds in provenance attestations.
+const (
+	BuildType = "https://openpolicyagent.org/bundle/build/v1"
+	BuilderID = "https://openpolicyagent.org/opa"
+)
+
 // Targets contains the list of targets supported by the compiler.
 var Targets = []string{
 	TargetRego,
@@ -85,7 +94,9 @@ type Compiler struct {
 	fsys                         fs.FS                      // file system to use when loading paths
 	ns                           string
 	regoVersion                  ast.RegoVersion
-	followSymlinks               bool // optionally follow symlinks in the bundle directory when building the bundle
+	followSymlinks               bool           // optionally follow symlinks in the bundle directory when building the bundle
+	provenance                   bool           // optionally generate signed provenance and inventory attestations
+	deltaBase                    *bundle.Bundle // optionally, the snapshot bundle to build a delta bundle from
 }
 
 // New returns a new compiler instance that can be invoked.
@@ -216,6 +227,23 @@ func (c *Compiler) WithBundleVerificationKeyID(keyID string) *Compiler {
 	return c
 }
 
+// WithProvenance enables the generation of signed in-toto attestations for the
+// output bundle: a SLSA provenance statement and an inventory of the modules,
+// data, capabilities and built-in functions the bundle was built from. The
+// attestations are signed with the bundle signing config.
+func (c *Compiler) WithProvenance(enabled bool) *Compiler {
+	c.provenance = enabled
+	return c
+}
+
+// WithDeltaBase sets the snapshot bundle to build a delta bundle from. The
+// output bundle is a delta bundle patching the data and modules of the base
+// bundle into those of the built bundle.
+func (c *Compiler) WithDeltaBase(b *bundle.Bundle) *Compiler {
+	c.deltaBase = b
+	return c
+}
+
 // WithCapabilities sets the capabilities to use while checking policies.
 func (c *Compiler) WithCapabilities(capabilities *ast.Capabilities) *Compiler {
 	c.capabilities = capabilities
@@ -311,10 +339,26 @@ func (c *Compiler) Build(ctx context.Context) error {
 		}
 	}
 
+	if c.provenance && c.bsc == nil {
+		return errors.New("bundle signing config required to generate provenance attestations")
+	}
+
+	if c.deltaBase != nil && c.bsc != nil {
+		return errors.New("delta bundles cannot be signed")
+	}
+
 	if err := c.initBundle(false); err != nil {
 		return err
 	}
 
+	var inventory *bundle.Inventory
+	if c.provenance {
+		var err error
+		if inventory, err = c.inventory(); err != nil {
+			return err
+		}
+	}
+
 	// Extract annotations, and generate new entrypoints as needed.
 	if c.useRegoAnnotationEntrypoints {
 		moduleList := make([]*ast.Module, 0, len(c.bundle.Modules))
@@ -391,6 +435,20 @@ func (c *Compiler) Build(ctx context.Context) error {
 		}
 	}
 
+	if c.deltaBase != nil {
+		delta, err := diff.Delta(c.deltaBase, c.bundle)
+		if err != nil {
+			return err
+		}
+		c.bundle = delta
+	}
+
+	if inventory != nil {
+		if err := c.generateAttestations(inventory); err != nil {
+			return err
+		}
+	}
+
 	if c.bsc != nil {
 		if err := c.bundle.GenerateSignature(c.bsc, c.keyID, false); err != nil {
 			return err
@@ -404,6 +462,123 @@ func (c *Compiler) Build(ctx context.Context) error {
 	return bundle.NewWriter(*c.output).Write(*c.bundle)
 }
 
+// inventory returns the inventory of the modules and data the bundle is built
+// from, before optimization. Without optimizations, the built-in functions
+// used are added once the policies are compiled for the bundle. Otherwise the
+// policies are compiled here, as they're not compiled before they're
+// optimized.
+func (c *Compiler) inventory() (*bundle.Inventory, error) {
+	inventory := &bundle.Inventory{
+		OPAVersion: version.Version,
+		Modules:    make([]bundle.InventoryModule, 0, len(c.bundle.Modules)),
+		Data:       make([]bundle.ResourceDescriptor, 0, len(c.bundle.Data)),
+		Builtins:   []string{},
+	}
+
+	for _, mf := range c.bundle.Modules {
+		digest, err := bundle.Digest(mf.Raw)
+		if err != nil {
+			return nil, err
+		}
+		inventory.Modules = append(inventory.Modules, bundle.InventoryModule{
+			Path:    mf.Path,
+			Package: mf.Parsed.Package.Path.String(),
+			Digest:  digest,
+		})
+	}
+
+	for _, k := range util.KeysSorted(c.bundle.Data) {
+		digest, err := bundle.Digest(c.bundle.Data[k])
+		if err != nil {
+			return nil, err
+		}
+		inventory.Data = append(inventory.Data, bundle.ResourceDescriptor{
+			Name:   ast.DefaultRootRef.Append(ast.StringTerm(k)).String(),
+			Digest: digest,
+		})
+	}
+
+	digest, err := bundle.Digest(c.capabilities)
+	if err != nil {
+		return nil, err
+	}
+	inventory.Capabilities.Digest = digest
+
+	if c.optimizationLevel > 0 {
+		compiler, err := compile(c.capabilities, c.bundle, c.debug, c.enablePrintStatements)
+		if err != nil {
+			return nil, err
+		}
+		addRequired(inventory, compiler)
+	}
+
+	return inventory, nil
+}
+
+// addRequired adds the built-in functions and features required by the
+// compiled policies to the inventory.
+func addRequired(inventory *bundle.Inventory, compiler *ast.Compiler) {
+	if compiler.Required == nil {
+		return
+	}
+	for _, bi := range compiler.Required.Builtins {
+		inventory.Builtins = append(inventory.Builtins, bi.Name)
+	}
+	inventory.Capabilities.Features = compiler.Required.Features
+}
+
+// generateAttestations signs the provenance and inventory statements and
+// attaches them to the bundle.
+func (c *Compiler) generateAttestations(inventory *bundle.Inventory) error {
+	if c.optimizationLevel <= 0 && c.compiler != nil {
+		addRequired(inventory, c.compiler)
+	}
+
+	dependencies := make([]bundle.ResourceDescriptor, 0, len(inventory.Modules)+len(inventory.Data)+1)
+	for _, m := range inventory.Modules {
+		dependencies = append(dependencies, bundle.ResourceDescriptor{Name: m.Path, Digest: m.Digest})
+	}
+	dependencies = append(dependencies, inventory.Data...)
+	dependencies = append(dependencies, bundle.ResourceDescriptor{Name: "capabilities", Digest: inventory.Capabilities.Digest})
+
+	entrypoints := make([]string, 0, len(c.entrypointrefs))
+	for _, ref := range c.entrypointrefs {
+		entrypoints = append(entrypoints, ref.String())
+	}
+
+	parameters := map[string]any{
+		"paths":        c.paths,
+		"target":       c.target,
+		"entrypoints":  entrypoints,
+		"optimize":     c.optimizationLevel,
+		"rego_version": c.regoVersion.String(),
+	}
+	if c.revision != nil {
+		parameters["revision"] = *c.revision
+	}
+
+	provenance := bundle.Provenance{
+		BuildDefinition: bundle.BuildDefinition{
+			BuildType:            BuildType,
+			ExternalParameters:   parameters,
+			ResolvedDependencies: dependencies,
+		},
+		RunDetails: bundle.RunDetails{
+			Builder: bundle.Builder{
+				ID:      BuilderID,
+				Version: map[string]string{"opa": version.Version},
+			},
+		},
+	}
+
+	statements := []bundle.Statement{
+		bundle.NewStatement(bundle.ProvenancePredicateType, provenance),
+		bundle.NewStatement(bundle.InventoryPredicateType, inventory),
+	}
+
+	return c.bundle.GenerateAttestations(statements, c.bsc, c.keyID, false)
+}
+
 func (c *Compiler) init() error {
 
 	if c.capabilities == nil {
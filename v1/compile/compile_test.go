@@ -2888,6 +2888,155 @@ func TestCompilerOutput(t *testing.T) {
 	}
 }
 
+func TestCompilerProvenance(t *testing.T) {
+	files := map[string]string{
+		"test.rego": `package test
+
+p if count(data.foo) > 1`,
+		"data.json": `{"foo": [1, 2, 3]}`,
+	}
+
+	for _, level := range []int{0, 1} {
+		t.Run(fmt.Sprintf("optimize=%d", level), func(t *testing.T) {
+			test.WithTestFS(files, false, func(root string, fsys fs.FS) {
+
+				buf := bytes.NewBuffer(nil)
+				compiler := New().
+					WithAsBundle(true).
+					WithFS(fsys).
+					WithPaths(root).
+					WithEntrypoints("test/p").
+					WithOptimizationLevel(level).
+					WithRevision("r1").
+					WithBundleSigningConfig(bundle.NewSigningConfig("secret", "HS256", "")).
+					WithBundleVerificationKeyID("default").
+					WithProvenance(true).
+					WithOutput(buf)
+				if err := compiler.Build(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+
+				vc := bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{"default": {Key: "secret", Algorithm: "HS256"}}, "", "", nil)
+				vc.RequireAttestations = true
+
+				result, err := bundle.NewReader(buf).WithBundleVerificationConfig(vc).Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				statements := result.Attestations.Statements
+				if len(statements) != 2 || statements[0].PredicateType != bundle.ProvenancePredicateType || statements[1].PredicateType != bundle.InventoryPredicateType {
+					t.Fatalf("expected provenance and inventory statements but got: %+v", statements)
+				}
+
+				var provenance bundle.Provenance
+				bs, _ := json.Marshal(statements[0].Predicate)
+				if err := util.UnmarshalJSON(bs, &provenance); err != nil {
+					t.Fatal(err)
+				}
+				if provenance.BuildDefinition.BuildType != BuildType || provenance.BuildDefinition.ExternalParameters["revision"] != "r1" {
+					t.Fatalf("unexpected build definition: %+v", provenance.BuildDefinition)
+				}
+				if len(provenance.BuildDefinition.ResolvedDependencies) != 3 {
+					t.Fatalf("expected module, data and capabilities dependencies but got: %+v", provenance.BuildDefinition.ResolvedDependencies)
+				}
+
+				var inventory bundle.Inventory
+				bs, _ = json.Marshal(statements[1].Predicate)
+				if err := util.UnmarshalJSON(bs, &inventory); err != nil {
+					t.Fatal(err)
+				}
+				if len(inventory.Modules) != 1 || inventory.Modules[0].Package != "data.test" {
+					t.Fatalf("unexpected modules: %+v", inventory.Modules)
+				}
+				if len(inventory.Data) != 1 || inventory.Data[0].Name != "data.foo" {
+					t.Fatalf("unexpected data: %+v", inventory.Data)
+				}
+				if !slices.Equal(inventory.Builtins, []string{"count", "eq", "gt"}) {
+					t.Fatalf("unexpected builtins: %v", inventory.Builtins)
+				}
+			})
+		})
+	}
+}
+
+func TestCompilerProvenanceRequiresSigning(t *testing.T) {
+	err := New().WithPaths("foo").WithProvenance(true).Build(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "signing config required") {
+		t.Fatalf("expected error but got: %v", err)
+	}
+}
+
+func TestCompilerDeltaBase(t *testing.T) {
+	files := map[string]string{
+		"a.rego":    "package a\n\np := 1\n",
+		"b.rego":    "package b\n\np := 1\n",
+		"data.json": `{"foo": 1}`,
+	}
+
+	test.WithTestFS(files, false, func(root string, _ fs.FS) {
+
+		build := func(base *bundle.Bundle) *bundle.Bundle {
+			buf := bytes.NewBuffer(nil)
+			compiler := New().
+				WithAsBundle(true).
+				WithPaths(root).
+				WithOutput(buf)
+			if base != nil {
+				compiler = compiler.WithDeltaBase(base)
+			}
+			if err := compiler.Build(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := bundle.NewReader(buf).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return &b
+		}
+
+		base := build(nil)
+
+		if err := os.WriteFile(filepath.Join(root, "a.rego"), []byte("package a\n\np := 2\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "data.json"), []byte(`{"foo": 1, "bar": 2}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(filepath.Join(root, "b.rego")); err != nil {
+			t.Fatal(err)
+		}
+
+		delta := build(base)
+
+		if delta.Type() != bundle.DeltaBundleType || len(delta.Modules) != 0 || delta.Data != nil {
+			t.Fatalf("expected delta bundle but got: %+v", delta)
+		}
+
+		if len(delta.Patch.Data) != 1 || delta.Patch.Data[0].Op != "upsert" || delta.Patch.Data[0].Path != "/bar" {
+			t.Fatalf("unexpected data patch: %+v", delta.Patch.Data)
+		}
+
+		if len(delta.Patch.Modules) != 2 ||
+			delta.Patch.Modules[0].Op != "upsert" || !strings.HasSuffix(delta.Patch.Modules[0].Path, "/a.rego") || delta.Patch.Modules[0].Parsed == nil ||
+			delta.Patch.Modules[1].Op != "remove" || !strings.HasSuffix(delta.Patch.Modules[1].Path, "/b.rego") {
+			t.Fatalf("unexpected module patch: %+v", delta.Patch.Modules)
+		}
+	})
+}
+
+func TestCompilerDeltaBaseRequiresNoSigning(t *testing.T) {
+	err := New().
+		WithPaths("foo").
+		WithDeltaBase(&bundle.Bundle{}).
+		WithBundleSigningConfig(bundle.NewSigningConfig("secret", "HS256", "")).
+		Build(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "delta bundles cannot be signed") {
+		t.Fatalf("expected error but got: %v", err)
+	}
+}
+
 func TestOptimizerNoops(t *testing.T) {
 	tests := []struct {
 		note        string
@@ -42,9 +42,11 @@ type Config struct {
 		Encoding json.RawMessage `json:"encoding,omitempty"`
 		Decoding json.RawMessage `json:"decoding,omitempty"`
 		Metrics  json.RawMessage `json:"metrics,omitempty"`
+		Changes  json.RawMessage `json:"changes,omitempty"`
 	} `json:"server,omitempty"`
 	Storage *struct {
-		Disk json.RawMessage `json:"disk,omitempty"`
+		Disk   json.RawMessage `json:"disk,omitempty"`
+		SQLite json.RawMessage `json:"sqlite,omitempty"`
 	} `json:"storage,omitempty"`
 	Extra map[string]json.RawMessage `json:"-"`
 }
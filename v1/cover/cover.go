@@ -84,10 +84,18 @@ func (c *Cover) Report(modules map[string]*ast.Module) (report Report) {
 		fr.NotCovered = sortedPositionSliceToRangeSlice(notCovered)
 	}
 
+	report.summarize()
+
+	return
+}
+
+// summarize computes the coverage of the files and the overall coverage of the
+// report.
+func (r *Report) summarize() {
 	var coveredLoc, notCoveredLoc int
 	var overallCoverage float64
 
-	for _, fr := range report.Files {
+	for _, fr := range r.Files {
 		fr.Coverage = fr.computeCoveragePercentage()
 		fr.CoveredLines = fr.locCovered()
 		fr.NotCoveredLines = fr.locNotCovered()
@@ -99,11 +107,9 @@ func (c *Cover) Report(modules map[string]*ast.Module) (report Report) {
 	if totalLoc != 0 {
 		overallCoverage = 100.0 * float64(coveredLoc) / float64(totalLoc)
 	}
-	report.CoveredLines = coveredLoc
-	report.NotCoveredLines = notCoveredLoc
-	report.Coverage = overallCoverage
-
-	return
+	r.CoveredLines = coveredLoc
+	r.NotCoveredLines = notCoveredLoc
+	r.Coverage = overallCoverage
 }
 
 // Trace updates the coverage state.
@@ -0,0 +1,339 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/util"
+	"github.com/IUAD1IY7/opa/v1/version"
+)
+
+// Merge merges coverage reports, e.g. of several test runs. A line is covered
+// if it's covered in any of the reports, and not covered if it isn't covered in
+// any of them.
+func Merge(reports ...Report) Report {
+	covered := map[string]map[int]struct{}{}
+	notCovered := map[string]map[int]struct{}{}
+
+	for _, r := range reports {
+		for file, fr := range r.Files {
+			addRows(covered, file, fr.Covered)
+			addRows(notCovered, file, fr.NotCovered)
+		}
+	}
+
+	result := Report{Files: map[string]*FileReport{}}
+
+	for file := range notCovered {
+		for row := range covered[file] {
+			delete(notCovered[file], row)
+		}
+	}
+
+	for _, rows := range []map[string]map[int]struct{}{covered, notCovered} {
+		for file := range rows {
+			if _, ok := result.Files[file]; !ok {
+				result.Files[file] = &FileReport{
+					Covered:    rowsToRanges(covered[file]),
+					NotCovered: rowsToRanges(notCovered[file]),
+				}
+			}
+		}
+	}
+
+	result.summarize()
+
+	return result
+}
+
+func addRows(rows map[string]map[int]struct{}, file string, ranges []Range) {
+	if _, ok := rows[file]; !ok {
+		rows[file] = map[int]struct{}{}
+	}
+	for _, r := range ranges {
+		for row := r.Start.Row; row <= r.End.Row; row++ {
+			rows[file][row] = struct{}{}
+		}
+	}
+}
+
+func rowsToRanges(rows map[int]struct{}) []Range {
+	positions := make(PositionSlice, 0, len(rows))
+	for row := range rows {
+		positions = append(positions, Position{Row: row})
+	}
+	positions.Sort()
+	return sortedPositionSliceToRangeSlice(positions)
+}
+
+// lineHit is the coverage of a line of a file.
+type lineHit struct {
+	row  int
+	hits int
+}
+
+// lines returns the coverage of the lines in the file report, ordered by row.
+func (fr *FileReport) lines() []lineHit {
+	var result []lineHit
+	for _, ranges := range []struct {
+		ranges []Range
+		hits   int
+	}{{fr.Covered, 1}, {fr.NotCovered, 0}} {
+		for _, r := range ranges.ranges {
+			for row := r.Start.Row; row <= r.End.Row; row++ {
+				result = append(result, lineHit{row: row, hits: ranges.hits})
+			}
+		}
+	}
+	slices.SortFunc(result, func(a, b lineHit) int {
+		return a.row - b.row
+	})
+	return result
+}
+
+// ruleHit is the coverage of a rule of a module, by the head of each of its
+// definitions. A rule is covered if any of its definitions is.
+type ruleHit struct {
+	name  string
+	heads []lineHit
+}
+
+func (rh ruleHit) row() int {
+	return rh.heads[0].row
+}
+
+func (rh ruleHit) hits() int {
+	for _, h := range rh.heads {
+		if h.hits > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// rules returns the coverage of the rules of the module in the file, ordered by
+// the row of their first definition. It returns nil if the module isn't known.
+func (r Report) rules(file string, module *ast.Module) []ruleHit {
+	if module == nil {
+		return nil
+	}
+
+	var result []ruleHit
+	index := map[string]int{}
+
+	for _, rule := range module.Rules {
+		if !hasFileLocation(rule.Head.Location) {
+			continue
+		}
+
+		name := module.Package.Path.Extend(rule.Head.Ref().GroundPrefix()).String()
+		head := lineHit{row: rule.Head.Location.Row}
+		if r.IsCovered(file, head.row) {
+			head.hits = 1
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(result)
+			index[name] = i
+			result = append(result, ruleHit{name: name})
+		}
+		result[i].heads = append(result[i].heads, head)
+	}
+
+	return result
+}
+
+// WriteLCOV writes the coverage report in the LCOV tracefile format. The rules
+// of the given modules are reported as functions.
+func WriteLCOV(w io.Writer, r Report, modules map[string]*ast.Module) error {
+	bw := bufio.NewWriter(w)
+
+	for _, file := range util.KeysSorted(r.Files) {
+		fr := r.Files[file]
+
+		fmt.Fprintln(bw, "TN:")
+		fmt.Fprintf(bw, "SF:%s\n", file)
+
+		rules := r.rules(file, modules[file])
+		var rulesHit int
+		for _, rule := range rules {
+			fmt.Fprintf(bw, "FN:%d,%s\n", rule.row(), rule.name)
+		}
+		for _, rule := range rules {
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", rule.hits(), rule.name)
+			if rule.hits() > 0 {
+				rulesHit++
+			}
+		}
+		if len(rules) > 0 {
+			fmt.Fprintf(bw, "FNF:%d\n", len(rules))
+			fmt.Fprintf(bw, "FNH:%d\n", rulesHit)
+		}
+
+		for _, line := range fr.lines() {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line.row, line.hits)
+		}
+		fmt.Fprintf(bw, "LF:%d\n", fr.locCovered()+fr.locNotCovered())
+		fmt.Fprintf(bw, "LH:%d\n", fr.locCovered())
+		fmt.Fprintln(bw, "end_of_record")
+	}
+
+	return bw.Flush()
+}
+
+const coberturaDocType = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        string             `xml:"line-rate,attr"`
+	BranchRate      string             `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      string             `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   string           `xml:"line-rate,attr"`
+	BranchRate string           `xml:"branch-rate,attr"`
+	Complexity string           `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+
+	covered, valid int
+}
+
+type coberturaClass struct {
+	Name       string            `xml:"name,attr"`
+	Filename   string            `xml:"filename,attr"`
+	LineRate   string            `xml:"line-rate,attr"`
+	BranchRate string            `xml:"branch-rate,attr"`
+	Complexity string            `xml:"complexity,attr"`
+	Methods    []coberturaMethod `xml:"methods>method"`
+	Lines      []coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name       string          `xml:"name,attr"`
+	Signature  string          `xml:"signature,attr"`
+	LineRate   string          `xml:"line-rate,attr"`
+	BranchRate string          `xml:"branch-rate,attr"`
+	Complexity string          `xml:"complexity,attr"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+// WriteCobertura writes the coverage report in the Cobertura XML format. Files
+// are reported as classes of the package of their directory, and the rules of
+// the given modules as their methods.
+func WriteCobertura(w io.Writer, r Report, modules map[string]*ast.Module) error {
+	return writeCobertura(w, r, modules, time.Now())
+}
+
+func writeCobertura(w io.Writer, r Report, modules map[string]*ast.Module, now time.Time) error {
+	report := coberturaCoverage{
+		LineRate:     rate(r.CoveredLines, r.CoveredLines+r.NotCoveredLines),
+		BranchRate:   "0",
+		LinesCovered: r.CoveredLines,
+		LinesValid:   r.CoveredLines + r.NotCoveredLines,
+		Complexity:   "0",
+		Version:      version.Version,
+		Timestamp:    now.UnixMilli(),
+		Sources:      []string{"."},
+	}
+
+	packages := map[string]*coberturaPackage{}
+	var names []string
+
+	for _, file := range util.KeysSorted(r.Files) {
+		fr := r.Files[file]
+		filename := filepath.ToSlash(file)
+		dir := path.Dir(filename)
+
+		pkg, ok := packages[dir]
+		if !ok {
+			pkg = &coberturaPackage{Name: dir, BranchRate: "0", Complexity: "0"}
+			packages[dir] = pkg
+			names = append(names, dir)
+		}
+
+		class := coberturaClass{
+			Name:       path.Base(filename),
+			Filename:   filename,
+			LineRate:   rate(fr.locCovered(), fr.locCovered()+fr.locNotCovered()),
+			BranchRate: "0",
+			Complexity: "0",
+			Methods:    []coberturaMethod{},
+			Lines:      []coberturaLine{},
+		}
+		if module, ok := modules[file]; ok {
+			class.Name = module.Package.Path.String()
+		}
+
+		for _, rule := range r.rules(file, modules[file]) {
+			method := coberturaMethod{
+				Name:       rule.name,
+				BranchRate: "0",
+				Complexity: "0",
+			}
+			var covered int
+			for _, head := range rule.heads {
+				method.Lines = append(method.Lines, coberturaLine{Number: head.row, Hits: head.hits})
+				covered += head.hits
+			}
+			method.LineRate = rate(covered, len(rule.heads))
+			class.Methods = append(class.Methods, method)
+		}
+
+		for _, line := range fr.lines() {
+			class.Lines = append(class.Lines, coberturaLine{Number: line.row, Hits: line.hits})
+		}
+
+		pkg.Classes = append(pkg.Classes, class)
+		pkg.covered += fr.locCovered()
+		pkg.valid += fr.locCovered() + fr.locNotCovered()
+	}
+
+	for _, name := range names {
+		pkg := packages[name]
+		pkg.LineRate = rate(pkg.covered, pkg.valid)
+		report.Packages = append(report.Packages, *pkg)
+	}
+
+	bs, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n%s\n", xml.Header, coberturaDocType, bs)
+	return err
+}
+
+func rate(covered, valid int) string {
+	if valid == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(covered)/float64(valid), 'f', -1, 64)
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cover
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/version"
+)
+
+const exportTestModule = `package test
+
+allow if {
+	input.x == 1
+}
+
+deny if {
+	input.x == 2
+}
+
+deny if {
+	input.x == 3
+}
+`
+
+func exportTestReport(t *testing.T) (Report, map[string]*ast.Module) {
+	t.Helper()
+
+	module := ast.MustParseModuleWithOpts(exportTestModule, ast.ParserOptions{RegoVersion: ast.RegoV1})
+	module.Package.Location.File = "test.rego"
+	for _, rule := range module.Rules {
+		rule.Head.Location.File = "test.rego"
+	}
+
+	report := Report{Files: map[string]*FileReport{
+		"test.rego": {
+			Covered:    []Range{{Start: Position{7}, End: Position{8}}},
+			NotCovered: []Range{{Start: Position{3}, End: Position{4}}, {Start: Position{11}, End: Position{12}}},
+		},
+		"other.rego": {
+			Covered: []Range{{Start: Position{1}, End: Position{1}}},
+		},
+	}}
+	report.summarize()
+
+	return report, map[string]*ast.Module{"test.rego": module}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	a := Report{Files: map[string]*FileReport{
+		"a.rego": {
+			Covered:    []Range{{Start: Position{1}, End: Position{2}}},
+			NotCovered: []Range{{Start: Position{3}, End: Position{5}}},
+		},
+	}}
+	b := Report{Files: map[string]*FileReport{
+		"a.rego": {
+			Covered:    []Range{{Start: Position{4}, End: Position{4}}},
+			NotCovered: []Range{{Start: Position{1}, End: Position{5}}},
+		},
+		"b.rego": {
+			NotCovered: []Range{{Start: Position{1}, End: Position{1}}},
+		},
+	}}
+
+	result := Merge(a, b)
+
+	exp := map[string]*FileReport{
+		"a.rego": {
+			Covered:    []Range{{Start: Position{1}, End: Position{2}}, {Start: Position{4}, End: Position{4}}},
+			NotCovered: []Range{{Start: Position{3}, End: Position{3}}, {Start: Position{5}, End: Position{5}}},
+		},
+		"b.rego": {
+			NotCovered: []Range{{Start: Position{1}, End: Position{1}}},
+		},
+	}
+	for file, fr := range exp {
+		if !reflect.DeepEqual(result.Files[file].Covered, fr.Covered) || !reflect.DeepEqual(result.Files[file].NotCovered, fr.NotCovered) {
+			t.Fatalf("expected %v to be %+v but got %+v", file, fr, result.Files[file])
+		}
+	}
+
+	if result.CoveredLines != 3 || result.NotCoveredLines != 3 || result.Coverage != 50 {
+		t.Fatalf("expected 3 of 6 lines to be covered but got: %+v", result)
+	}
+}
+
+func TestWriteLCOV(t *testing.T) {
+	t.Parallel()
+
+	report, modules := exportTestReport(t)
+
+	var buf bytes.Buffer
+	if err := WriteLCOV(&buf, report, modules); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `TN:
+SF:other.rego
+DA:1,1
+LF:1
+LH:1
+end_of_record
+TN:
+SF:test.rego
+FN:3,data.test.allow
+FN:7,data.test.deny
+FNDA:0,data.test.allow
+FNDA:1,data.test.deny
+FNF:2
+FNH:1
+DA:3,0
+DA:4,0
+DA:7,1
+DA:8,1
+DA:11,0
+DA:12,0
+LF:6
+LH:2
+end_of_record
+`
+
+	if buf.String() != exp {
+		t.Fatalf("expected:\n\n%v\n\ngot:\n\n%v", exp, buf.String())
+	}
+}
+
+func TestWriteCobertura(t *testing.T) {
+	t.Parallel()
+
+	report, modules := exportTestReport(t)
+
+	var buf bytes.Buffer
+	if err := writeCobertura(&buf, report, modules, time.UnixMilli(1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">
+<coverage line-rate="0.42857142857142855" branch-rate="0" lines-covered="3" lines-valid="7" branches-covered="0" branches-valid="0" complexity="0" version="VERSION" timestamp="1000">
+  <sources>
+    <source>.</source>
+  </sources>
+  <packages>
+    <package name="." line-rate="0.42857142857142855" branch-rate="0" complexity="0">
+      <classes>
+        <class name="other.rego" filename="other.rego" line-rate="1" branch-rate="0" complexity="0">
+          <methods></methods>
+          <lines>
+            <line number="1" hits="1"></line>
+          </lines>
+        </class>
+        <class name="data.test" filename="test.rego" line-rate="0.3333333333333333" branch-rate="0" complexity="0">
+          <methods>
+            <method name="data.test.allow" signature="" line-rate="0" branch-rate="0" complexity="0">
+              <lines>
+                <line number="3" hits="0"></line>
+              </lines>
+            </method>
+            <method name="data.test.deny" signature="" line-rate="0.5" branch-rate="0" complexity="0">
+              <lines>
+                <line number="7" hits="1"></line>
+                <line number="11" hits="0"></line>
+              </lines>
+            </method>
+          </methods>
+          <lines>
+            <line number="3" hits="0"></line>
+            <line number="4" hits="0"></line>
+            <line number="7" hits="1"></line>
+            <line number="8" hits="1"></line>
+            <line number="11" hits="0"></line>
+            <line number="12" hits="0"></line>
+          </lines>
+        </class>
+      </classes>
+    </package>
+  </packages>
+</coverage>
+`
+	exp = strings.Replace(exp, "VERSION", version.Version, 1)
+
+	if buf.String() != exp {
+		t.Fatalf("expected:\n\n%v\n\ngot:\n\n%v", exp, buf.String())
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/ast/location"
 )
 
@@ -19,9 +20,20 @@ type Breakpoint interface {
 	Location() location.Location
 }
 
+// ConditionalBreakpoint is implemented by Breakpoint(s) that may have a condition.
+type ConditionalBreakpoint interface {
+	Breakpoint
+
+	// Condition returns the Rego expression that must be true for the breakpoint to be hit.
+	// Unconditional breakpoints have an empty condition.
+	Condition() string
+}
+
 type breakpoint struct {
-	id       BreakpointID
-	location location.Location
+	id        BreakpointID
+	location  location.Location
+	condition string
+	query     ast.Body
 }
 
 func (b breakpoint) ID() BreakpointID {
@@ -32,7 +44,14 @@ func (b breakpoint) Location() location.Location {
 	return b.location
 }
 
+func (b breakpoint) Condition() string {
+	return b.condition
+}
+
 func (b breakpoint) String() string {
+	if b.condition != "" {
+		return fmt.Sprintf("<%d> %s:%d if %s", b.id, b.location.File, b.location.Row, b.condition)
+	}
 	return fmt.Sprintf("<%d> %s:%d", b.id, b.location.File, b.location.Row)
 }
 
@@ -72,14 +91,11 @@ func (bc *breakpointCollection) newID() BreakpointID {
 	return bc.idCounter
 }
 
-func (bc *breakpointCollection) add(location location.Location) Breakpoint {
+func (bc *breakpointCollection) add(bp breakpoint) ConditionalBreakpoint {
 	bc.mtx.Lock()
 	defer bc.mtx.Unlock()
 
-	bp := breakpoint{
-		id:       bc.newID(),
-		location: location,
-	}
+	bp.id = bc.newID()
 	bps := bc.breakpoints[bp.location.File]
 	bps = append(bps, bp)
 	bc.breakpoints[bp.location.File] = bps
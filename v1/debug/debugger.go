@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"slices"
 	"strings"
@@ -19,11 +20,16 @@ import (
 
 	"github.com/IUAD1IY7/opa/v1/storage/inmem"
 	fileurl "github.com/IUAD1IY7/opa/internal/file/url"
+	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/ast/location"
+	"github.com/IUAD1IY7/opa/v1/bundle"
 	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/metrics"
 	"github.com/IUAD1IY7/opa/v1/rego"
 	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/tester"
 	"github.com/IUAD1IY7/opa/v1/topdown"
+	"github.com/IUAD1IY7/opa/v1/topdown/builtins"
 	prnt "github.com/IUAD1IY7/opa/v1/topdown/print"
 	"github.com/IUAD1IY7/opa/v1/util"
 )
@@ -40,6 +46,23 @@ type Debugger interface {
 	LaunchEval(ctx context.Context, props LaunchEvalProperties, opts ...LaunchOption) (Session, error)
 }
 
+// TestLauncher is implemented by Debugger(s) that can debug tests, such as the one returned by NewDebugger.
+type TestLauncher interface {
+	// LaunchTest starts a new test debug session with the given LaunchTestProperties.
+	// The tests are run one at a time on a single thread, and the result of each test is sent as a stdout event.
+	// The returned session is in a stopped state, and must be resumed to start execution.
+	LaunchTest(ctx context.Context, props LaunchTestProperties) (Session, error)
+}
+
+// DecisionLauncher is implemented by Debugger(s) that can re-evaluate decisions, such as the one returned by
+// NewDebugger.
+type DecisionLauncher interface {
+	// LaunchDecision starts a new debug session re-evaluating a decision with the given LaunchDecisionProperties,
+	// e.g. a decision captured from a running OPA.
+	// The returned session is in a stopped state, and must be resumed to start execution.
+	LaunchDecision(ctx context.Context, props LaunchDecisionProperties) (Session, error)
+}
+
 type debugger struct {
 	logger       logging.Logger
 	printHook    *printHook
@@ -150,6 +173,16 @@ type Session interface {
 	Terminate() error
 }
 
+// ConditionalBreakpointSession is implemented by Session(s) that support conditional breakpoints, such as the
+// sessions launched by the Debugger returned by NewDebugger.
+type ConditionalBreakpointSession interface {
+	// AddConditionalBreakpoint sets a breakpoint at the given location that is only hit if the condition, a Rego
+	// expression such as `x > 10`, is true. The condition is evaluated with the local variables of the stack frame
+	// the breakpoint is hit in, and the input and data documents.
+	// An error is returned if the condition is invalid.
+	AddConditionalBreakpoint(loc location.Location, condition string) (ConditionalBreakpoint, error)
+}
+
 type printHook struct {
 	prnt.Hook
 	d *debugger
@@ -207,6 +240,20 @@ type LaunchTestProperties struct {
 	Run string
 }
 
+// LaunchDecisionProperties are the properties of a decision to re-evaluate. The data paths and bundle paths of the
+// LaunchProperties are ignored, the decision is evaluated with the given compiler and store instead.
+type LaunchDecisionProperties struct {
+	LaunchProperties
+	Query    string
+	Input    ast.Value
+	Compiler *ast.Compiler
+	Store    storage.Store
+
+	// NDBuiltinCache holds the results of non-deterministic built-in functions recorded when the decision was made,
+	// which are reused instead of calling the functions again.
+	NDBuiltinCache builtins.NDBCache
+}
+
 type LaunchProperties struct {
 	BundlePaths         []string
 	DataPaths           []string
@@ -312,6 +359,43 @@ func (d *debugger) LaunchEval(ctx context.Context, props LaunchEvalProperties, o
 		return nil, fmt.Errorf("failed to commit store transaction: %v", err)
 	}
 
+	return d.launchQuery(ctx, pq, store, nil, props.LaunchProperties)
+}
+
+func (d *debugger) LaunchDecision(ctx context.Context, props LaunchDecisionProperties) (Session, error) {
+	if props.Compiler == nil || props.Store == nil {
+		return nil, errors.New("decision requires a compiler and a store")
+	}
+
+	regoArgs := []func(*rego.Rego){
+		rego.Query(props.Query),
+		rego.Compiler(props.Compiler),
+		rego.Store(props.Store),
+		rego.StrictBuiltinErrors(props.StrictBuiltinErrors),
+	}
+
+	if props.SkipOps == nil {
+		props.SkipOps = []topdown.Op{topdown.IndexOp, topdown.RedoOp, topdown.SaveOp, topdown.UnifyOp}
+	}
+
+	if props.EnablePrint {
+		regoArgs = append(regoArgs, rego.EnablePrintStatements(true),
+			rego.PrintHook(d.printHook))
+	}
+
+	pq, err := rego.New(regoArgs...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare query for evaluation: %v", err)
+	}
+
+	return d.launchQuery(ctx, pq, props.Store, props.Compiler, props.LaunchProperties,
+		rego.EvalParsedInput(props.Input),
+		rego.EvalNDBuiltinCache(props.NDBuiltinCache))
+}
+
+// launchQuery launches a session evaluating the prepared query on a single thread. Breakpoint conditions are
+// evaluated with the given compiler, or one compiled from the modules of the query if nil.
+func (d *debugger) launchQuery(ctx context.Context, pq rego.PreparedEvalQuery, store storage.Store, compiler *ast.Compiler, props LaunchProperties, opts ...rego.EvalOption) (Session, error) {
 	tracer := newDebugTracer()
 
 	vc := topdown.NewVirtualCache()
@@ -323,11 +407,16 @@ func (d *debugger) LaunchEval(ctx context.Context, props LaunchEvalProperties, o
 		rego.EvalRuleIndexing(props.RuleIndexing),
 		rego.EvalVirtualCache(vc),
 	}
+	evalArgs = append(evalArgs, opts...)
 
 	varManager := newVariableManager()
 	// Threads are 1-indexed.
 	t := newThread(1, "main", tracer, varManager, vc, store, d.logger)
-	s := newSession(ctx, d, varManager, props.LaunchProperties, []*thread{t})
+	s := newSession(ctx, d, varManager, props, []*thread{t})
+	s.compiler = compiler
+	if compiler == nil {
+		s.modules = pq.Modules()
+	}
 
 	go func() {
 		defer func() { _ = tracer.Close() }()
@@ -351,6 +440,96 @@ func (d *debugger) LaunchEval(ctx context.Context, props LaunchEvalProperties, o
 	return s, nil
 }
 
+func (d *debugger) LaunchTest(ctx context.Context, props LaunchTestProperties) (Session, error) {
+	modules, store, err := tester.Load(props.DataPaths, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data paths: %v", err)
+	}
+
+	if len(props.BundlePaths) > 0 {
+		bundles, err := tester.LoadBundles(props.BundlePaths, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load bundles: %v", err)
+		}
+
+		// Bundles are activated here rather than by the test runner, so that their data is committed and can be
+		// inspected while tests are stopped.
+		if err := activateBundles(ctx, store, bundles, modules); err != nil {
+			return nil, fmt.Errorf("failed to activate bundles: %v", err)
+		}
+
+		for path, b := range bundles {
+			maps.Copy(modules, b.ParsedModules(path))
+		}
+	}
+
+	if props.SkipOps == nil {
+		props.SkipOps = []topdown.Op{topdown.IndexOp, topdown.RedoOp, topdown.SaveOp, topdown.UnifyOp}
+	}
+
+	tracer := newDebugTracer()
+	compiler := ast.NewCompiler().WithEnablePrintStatements(props.EnablePrint)
+
+	// Tests are run one at a time, without a timeout, as they are stepped through on a single thread.
+	runner := tester.NewRunner().
+		SetCompiler(compiler).
+		SetStore(store).
+		SetModules(modules).
+		Filter(props.Run).
+		SetParallel(1).
+		SetTimeout(0).
+		CapturePrintOutput(props.EnablePrint).
+		RaiseBuiltinErrors(props.StrictBuiltinErrors).
+		SetCoverageQueryTracer(tracer)
+
+	varManager := newVariableManager()
+	// Threads are 1-indexed.
+	t := newThread(1, "main", tracer, varManager, nil, store, d.logger)
+	s := newSession(ctx, d, varManager, props.LaunchProperties, []*thread{t})
+
+	ch, err := runner.RunTests(s.ctx, nil)
+	if err != nil {
+		s.cancel()
+		return nil, fmt.Errorf("failed to prepare tests: %v", err)
+	}
+	s.compiler = compiler
+
+	go func() {
+		defer func() { _ = tracer.Close() }()
+		for tr := range ch {
+			s.testResult(t, tr)
+		}
+	}()
+
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func activateBundles(ctx context.Context, store storage.Store, bundles map[string]*bundle.Bundle, modules map[string]*ast.Module) error {
+	txn, err := store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		return err
+	}
+
+	opts := &bundle.ActivateOpts{
+		Ctx:          ctx,
+		Store:        store,
+		Txn:          txn,
+		Compiler:     ast.NewCompiler(),
+		Metrics:      metrics.New(),
+		Bundles:      bundles,
+		ExtraModules: modules,
+	}
+	if err := bundle.Activate(opts); err != nil {
+		store.Abort(ctx, txn)
+		return err
+	}
+
+	return store.Commit(ctx, txn)
+}
+
 func readInput(path string) (any, error) {
 	path, err := fileurl.Clean(path)
 	if err != nil {
@@ -381,6 +560,13 @@ type session struct {
 	cancel         context.CancelFunc
 	varManager     *variableManager
 	mtx            sync.Mutex
+
+	// The compiler that breakpoint conditions are evaluated with, compiled from the session modules when the first
+	// condition is evaluated unless set when launching the session.
+	compiler     *ast.Compiler
+	compilerErr  error
+	compilerOnce sync.Once
+	modules      map[string]*ast.Module
 }
 
 func newSession(ctx context.Context, debugger *debugger, varManager *variableManager, props LaunchProperties, threads []*thread) *session {
@@ -687,7 +873,7 @@ func (s *session) handleEvent(t *thread, stackIndex int, e *topdown.Event, ts th
 
 	if e.Location != nil && e.Location.File != "" {
 		for _, bp := range s.breakpoints.allForFilePath(e.Location.File) {
-			if bp.Location().Row == e.Location.Row {
+			if bp.Location().Row == e.Location.Row && s.conditionMet(t, bp, e) {
 				// if the last event also caused a breakpoint AND we're still on the same line, skip this breakpoint.
 				s.d.logger.Info("Thread %d stopped at breakpoint: %s:%d", t.id, e.Location.File, e.Location.Row)
 				s.d.sendEvent(Event{Type: StoppedEventType, Thread: t.id, Message: "breakpoint", stackIndex: stackIndex, stackEvent: e})
@@ -699,6 +885,77 @@ func (s *session) handleEvent(t *thread, stackIndex int, e *topdown.Event, ts th
 	return nopAction, state, nil
 }
 
+// conditionMet returns true if the breakpoint is unconditional, or if its condition is true in the stack frame of
+// the event.
+func (s *session) conditionMet(t *thread, b Breakpoint, e *topdown.Event) bool {
+	bp, ok := b.(breakpoint)
+	if !ok || bp.query == nil {
+		return true
+	}
+
+	s.compilerOnce.Do(func() {
+		if s.compiler != nil {
+			return
+		}
+		s.compiler = ast.NewCompiler()
+		if s.compiler.Compile(s.modules); s.compiler.Failed() {
+			s.compilerErr = s.compiler.Errors
+		}
+	})
+	if s.compilerErr != nil {
+		s.d.logger.Warn("Failed to compile modules for breakpoint conditions: %v", s.compilerErr)
+		return false
+	}
+
+	// Local variables are bound to their values in the stack frame by their original names.
+	locals := map[ast.Var]ast.Value{}
+	e.Locals.Iter(func(k, v ast.Value) bool {
+		name := k.(ast.Var)
+		if meta, ok := e.LocalMetadata[name]; ok {
+			name = meta.Name
+		}
+		locals[name] = v
+		return false
+	})
+
+	query, err := ast.TransformVars(bp.query.Copy(), func(v ast.Var) (ast.Value, error) {
+		if value, ok := locals[v]; ok {
+			return value, nil
+		}
+		return v, nil
+	})
+	if err != nil {
+		s.d.logger.Warn("Failed to bind breakpoint condition %q: %v", bp.condition, err)
+		return false
+	}
+
+	regoArgs := []func(*rego.Rego){
+		rego.ParsedQuery(query.(ast.Body)),
+		rego.Compiler(s.compiler),
+	}
+	if t.store != nil {
+		regoArgs = append(regoArgs, rego.Store(t.store))
+	}
+	if input := e.Input(); input != nil {
+		regoArgs = append(regoArgs, rego.ParsedInput(input.Value))
+	}
+
+	rs, err := rego.New(regoArgs...).Eval(s.ctx)
+	if err != nil {
+		s.d.logger.Warn("Failed to evaluate breakpoint condition %q: %v", bp.condition, err)
+		return false
+	}
+
+	// As in rule bodies, the condition is true if none of its expressions is false or undefined.
+	for _, r := range rs {
+		if !slices.ContainsFunc(r.Expressions, func(ev *rego.ExpressionValue) bool { return ev.Value == false }) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *session) skipOp(op topdown.Op) bool {
 	return slices.Contains(s.properties.SkipOps, op)
 }
@@ -712,6 +969,18 @@ func (s *session) result(t *thread, rs rego.ResultSet) {
 	}
 }
 
+func (s *session) testResult(t *thread, tr *tester.Result) {
+	msg := strings.TrimSpace(tr.String())
+	if tr.Error != nil {
+		msg = fmt.Sprintf("%s: %v", msg, tr.Error)
+	}
+	if len(tr.Output) > 0 {
+		msg = fmt.Sprintf("%s\n%s", msg, strings.TrimRight(string(tr.Output), "\n"))
+	}
+	s.d.logger.Debug("Test result: %s", msg)
+	s.d.sendEvent(Event{Type: StdoutEventType, Thread: t.id, Message: msg})
+}
+
 func (s *session) StackTrace(threadID ThreadID) (StackTrace, error) {
 	if s == nil {
 		return nil, errors.New("no active debug session")
@@ -835,14 +1104,28 @@ func (s *session) Breakpoints() ([]Breakpoint, error) {
 }
 
 func (s *session) AddBreakpoint(loc location.Location) (Breakpoint, error) {
+	return s.AddConditionalBreakpoint(loc, "")
+}
+
+func (s *session) AddConditionalBreakpoint(loc location.Location, condition string) (ConditionalBreakpoint, error) {
 	if s == nil {
 		return nil, errors.New("no active debug session")
 	}
 
+	bp := breakpoint{location: loc, condition: condition}
+
+	if condition != "" {
+		query, err := ast.ParseBodyWithOpts(condition, ast.ParserOptions{RegoVersion: ast.RegoV1})
+		if err != nil {
+			return nil, fmt.Errorf("invalid breakpoint condition: %v", err)
+		}
+		bp.query = query
+	}
+
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	return s.breakpoints.add(loc), nil
+	return s.breakpoints.add(bp), nil
 }
 
 func (s *session) RemoveBreakpoint(id BreakpointID) (Breakpoint, error) {
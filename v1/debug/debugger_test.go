@@ -657,6 +657,315 @@ qux: d
 	}
 }
 
+func TestDebuggerConditionalBreakpoint(t *testing.T) {
+	files := map[string]string{
+		"test.rego": `package test
+
+q := 3
+
+p contains y if {
+	some x in [1, 2, 3]
+	y := x * 10
+}
+`,
+	}
+
+	tests := []struct {
+		note      string
+		condition string
+		exp       []string
+		wantErr   string
+	}{
+		{
+			note:      "local variable",
+			condition: "x == 2",
+			exp:       []string{"2"},
+		},
+		{
+			note:      "input",
+			condition: "x > input.min",
+			exp:       []string{"2", "3"},
+		},
+		{
+			note:      "virtual document",
+			condition: "x == data.test.q",
+			exp:       []string{"3"},
+		},
+		{
+			note:      "unsafe variable",
+			condition: "z == 1",
+		},
+		{
+			note:      "invalid condition",
+			condition: "x ==",
+			wantErr:   "invalid breakpoint condition",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+			defer cancel()
+
+			test.WithTempFS(files, func(rootDir string) {
+				eh := newTestEventHandler()
+				d := NewDebugger(SetEventHandler(eh.HandleEvent))
+
+				s, err := d.LaunchEval(ctx, LaunchEvalProperties{
+					LaunchProperties: LaunchProperties{
+						DataPaths: []string{rootDir},
+					},
+					Query: "x = data.test.p",
+					Input: map[string]any{"min": 1},
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error launching debug session: %v", err)
+				}
+
+				bp, err := s.(ConditionalBreakpointSession).AddConditionalBreakpoint(location.Location{File: path.Join(rootDir, "test.rego"), Row: 7}, tc.condition)
+				switch {
+				case tc.wantErr != "":
+					if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+						t.Fatalf("Expected error containing %q, got: %v", tc.wantErr, err)
+					}
+					return
+				case err != nil:
+					t.Fatalf("Unexpected error: %v", err)
+				case bp.Condition() != tc.condition:
+					t.Fatalf("Expected condition %q, got %q", tc.condition, bp.Condition())
+				}
+
+				if err := s.ResumeAll(); err != nil {
+					t.Fatalf("Unexpected error resuming threads: %v", err)
+				}
+
+				var hits []string
+				for {
+					e := eh.NextBlocking()
+					if e.Type == TerminatedEventType {
+						break
+					}
+					if e.Type != StoppedEventType {
+						continue
+					}
+
+					e.stackEvent.Locals.Iter(func(k, v ast.Value) bool {
+						if meta, ok := e.stackEvent.LocalMetadata[k.(ast.Var)]; ok && meta.Name == "x" {
+							if !slices.Contains(hits, v.String()) {
+								hits = append(hits, v.String())
+							}
+						}
+						return false
+					})
+
+					if err := s.Resume(e.Thread); err != nil {
+						t.Fatalf("Unexpected error resuming: %v", err)
+					}
+				}
+
+				if !slices.Equal(hits, tc.exp) {
+					t.Fatalf("Expected to stop with x in %v, got %v", tc.exp, hits)
+				}
+			})
+		})
+	}
+}
+
+func TestDebuggerLaunchTest(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer cancel()
+
+	files := map[string]string{
+		"policy.rego": `package test
+
+p if input.x == 1
+`,
+		"policy_test.rego": `package test
+
+test_p if {
+	print("testing p")
+	p with input.x as 1
+}
+
+test_q if {
+	p with input.x as 2
+}
+`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		eh := newTestEventHandler()
+		d := NewDebugger(SetEventHandler(eh.HandleEvent))
+
+		s, err := d.(TestLauncher).LaunchTest(ctx, LaunchTestProperties{
+			LaunchProperties: LaunchProperties{
+				DataPaths:   []string{rootDir},
+				EnablePrint: true,
+			},
+			Run: "test_p",
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error launching debug session: %v", err)
+		}
+
+		if _, err := s.AddBreakpoint(location.Location{File: path.Join(rootDir, "policy.rego"), Row: 3}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := s.ResumeAll(); err != nil {
+			t.Fatalf("Unexpected error resuming threads: %v", err)
+		}
+
+		e := eh.WaitFor(ctx, StoppedEventType)
+		if e == nil || e.stackEvent.Location.Row != 3 {
+			t.Fatalf("Expected to stop on row 3, got: %v", e)
+		}
+
+		eh.IgnoreAll(ctx)
+		stk, err := s.StackTrace(e.Thread)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if loc := stk[0].Location(); loc.File != path.Join(rootDir, "policy.rego") {
+			t.Fatalf("Expected top frame in policy.rego, got %v", loc)
+		}
+
+		if err := s.Terminate(); err != nil {
+			t.Fatalf("Unexpected error terminating session: %v", err)
+		}
+	})
+}
+
+func TestDebuggerLaunchTestResults(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer cancel()
+
+	files := map[string]string{
+		"policy_test.rego": `package test
+
+test_p if {
+	print("testing p")
+}
+
+test_q if {
+	false
+}
+`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		eh := newTestEventHandler()
+		d := NewDebugger(SetEventHandler(eh.HandleEvent))
+
+		s, err := d.(TestLauncher).LaunchTest(ctx, LaunchTestProperties{
+			LaunchProperties: LaunchProperties{
+				DataPaths:   []string{rootDir},
+				EnablePrint: true,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error launching debug session: %v", err)
+		}
+
+		if err := s.ResumeAll(); err != nil {
+			t.Fatalf("Unexpected error resuming threads: %v", err)
+		}
+
+		var output []string
+		for {
+			e := eh.NextBlocking()
+			if e.Type == TerminatedEventType {
+				break
+			}
+			if e.Type == StdoutEventType {
+				output = append(output, e.Message)
+			}
+		}
+		slices.Sort(output)
+
+		if len(output) != 2 ||
+			!strings.HasPrefix(output[0], "data.test.test_p: PASS") || !strings.HasSuffix(output[0], "\ntesting p") ||
+			!strings.HasPrefix(output[1], "data.test.test_q: FAIL") {
+			t.Fatalf("Expected test results, got: %q", output)
+		}
+	})
+}
+
+func TestDebuggerLaunchDecision(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(10*time.Second))
+	defer cancel()
+
+	module, err := ast.ParseModuleWithOpts("policy.rego", `package test
+
+allow if {
+	input.user in data.users
+	time.now_ns() == 42
+}`, ast.ParserOptions{RegoVersion: ast.RegoV1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compiler := ast.NewCompiler()
+	if compiler.Compile(map[string]*ast.Module{"policy.rego": module}); compiler.Failed() {
+		t.Fatal(compiler.Errors)
+	}
+
+	// The recorded result of time.now_ns() is used instead of calling it again.
+	ndbCache := builtins.NDBCache{}
+	ndbCache.Put("time.now_ns", ast.NewArray(), ast.Number("42"))
+
+	eh := newTestEventHandler()
+	d := NewDebugger(SetEventHandler(eh.HandleEvent))
+
+	s, err := d.(DecisionLauncher).LaunchDecision(ctx, LaunchDecisionProperties{
+		Query:          "data.test.allow",
+		Input:          ast.MustParseTerm(`{"user": "alice"}`).Value,
+		Compiler:       compiler,
+		Store:          inmem.NewFromObject(map[string]any{"users": []any{"alice"}}),
+		NDBuiltinCache: ndbCache,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error launching debug session: %v", err)
+	}
+
+	if _, err := s.(ConditionalBreakpointSession).AddConditionalBreakpoint(location.Location{File: "policy.rego", Row: 5}, `input.user == "alice"`); err != nil {
+		t.Fatalf("Unexpected error adding breakpoint: %v", err)
+	}
+
+	if err := s.ResumeAll(); err != nil {
+		t.Fatalf("Unexpected error resuming threads: %v", err)
+	}
+
+	var stops int
+	var result string
+	for e := eh.NextBlocking(); e.Type != TerminatedEventType; e = eh.NextBlocking() {
+		switch e.Type {
+		case StoppedEventType:
+			if f := topOfStack(t, s); e.Message != "breakpoint" || f.Location().Row != 5 {
+				t.Fatalf("Expected to stop at breakpoint on row 5, got: %v at %v", e, f.Location())
+			}
+			stops++
+			if err := s.Resume(e.Thread); err != nil {
+				t.Fatalf("Unexpected error resuming thread: %v", err)
+			}
+		case StdoutEventType:
+			result = e.Message
+		}
+	}
+
+	if stops == 0 {
+		t.Fatal("Expected to stop at breakpoint")
+	}
+
+	var rs rego.ResultSet
+	if err := json.Unmarshal([]byte(result), &rs); err != nil {
+		t.Fatalf("Unexpected error parsing result: %v", err)
+	}
+	if len(rs) != 1 || rs[0].Expressions[0].Value != true {
+		t.Fatalf("Expected true result, got: %v", result)
+	}
+}
+
 func topOfStack(t *testing.T, s Session) *stackFrame {
 	t.Helper()
 	stk, err := s.StackTrace(ThreadID(1))
@@ -32,8 +32,9 @@ type PollingConfig struct {
 
 // Config represents the configuration for the downloader.
 type Config struct {
-	Trigger *plugins.TriggerMode `json:"trigger,omitempty"`
-	Polling PollingConfig        `json:"polling"`
+	Trigger     *plugins.TriggerMode `json:"trigger,omitempty"`
+	Polling     PollingConfig        `json:"polling"`
+	Incremental bool                 `json:"incremental,omitempty"` // synchronize bundles from their file index if the server supports it
 }
 
 // ValidateAndInjectDefaults checks for configuration errors and ensures all
@@ -68,6 +68,7 @@ type Downloader struct {
 	lazyLoadingMode    bool
 	bundleName         string
 	bundleParserOpts   ast.ParserOptions
+	blobs              blobCache // file contents of the last bundle, for incremental sync
 }
 
 type downloaderResponse struct {
@@ -283,12 +284,23 @@ func (d *Downloader) oneShot(ctx context.Context) error {
 }
 
 func (d *Downloader) download(ctx context.Context, m metrics.Metrics) (*downloaderResponse, error) {
+	return d.request(ctx, m, d.config.Incremental)
+}
+
+// request downloads the bundle. If incremental is true, the bundle index is
+// requested and the bundle is synchronized from it if the server replies with
+// one. Otherwise, or if synchronizing fails, the full bundle is downloaded.
+func (d *Downloader) request(ctx context.Context, m metrics.Metrics, incremental bool) (*downloaderResponse, error) {
 	d.logger.Debug("Download starting.")
 
 	d.client = d.client.WithHeader("If-None-Match", d.etag)
 
 	preferences := []string{fmt.Sprintf("modes=%v,%v", defaultBundleMode, deltaBundleMode)}
 
+	if incremental {
+		preferences = append(preferences, syncIndexPreference)
+	}
+
 	if d.longPollingEnabled && d.config.Polling.LongPollingTimeoutSeconds != nil {
 		wait := "wait=" + strconv.FormatInt(*d.config.Polling.LongPollingTimeoutSeconds, 10)
 		preferences = append(preferences, wait)
@@ -319,19 +331,42 @@ func (d *Downloader) download(ctx context.Context, m metrics.Metrics) (*download
 	case http.StatusOK:
 		var buf bytes.Buffer
 		if resp.Body != nil {
-			d.logger.Debug("Download in progress.")
-			m.Timer(metrics.RegoLoadBundles).Start()
-			defer m.Timer(metrics.RegoLoadBundles).Stop()
 			baseURL := path.Join(d.client.Config().URL, d.path)
-
 			cnt := &count{}
-			r := io.TeeReader(resp.Body, cnt)
 
 			var loader bundle.DirectoryLoader
-			if d.persist {
-				tee := io.TeeReader(r, &buf)
-				loader = bundle.NewTarballLoaderWithBaseURL(tee, baseURL)
-			} else {
+
+			if incremental && resp.Header.Get("Content-Type") == IndexContentType {
+				d.logger.Debug("Bundle sync in progress.")
+				files, err := d.sync(ctx, resp.Body)
+				if err != nil {
+					d.logger.Warn("Incremental bundle sync failed, falling back to full download: %v", err)
+					util.Close(resp)
+					return d.request(ctx, m, false)
+				}
+
+				// Only bundles that are persisted need to be packed again.
+				if d.persist {
+					if err := writeTarball(&buf, files); err != nil {
+						return nil, err
+					}
+				}
+				for _, f := range files {
+					cnt.total += len(f.content)
+				}
+				loader = newBlobLoader(files, baseURL)
+			}
+
+			m.Timer(metrics.RegoLoadBundles).Start()
+			defer m.Timer(metrics.RegoLoadBundles).Stop()
+
+			if loader == nil {
+				d.logger.Debug("Download in progress.")
+
+				r := io.TeeReader(resp.Body, cnt)
+				if d.persist {
+					r = io.TeeReader(r, &buf)
+				}
 				loader = bundle.NewTarballLoaderWithBaseURL(r, baseURL)
 			}
 
@@ -0,0 +1,274 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/IUAD1IY7/opa/internal/file/archive"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/loader/filter"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+// IndexContentType is the content type of bundle file indexes. Servers that
+// support incremental bundle synchronization reply with an index instead of
+// the bundle tarball when the request carries the "sync=index" preference.
+const IndexContentType = "application/vnd.openpolicyagent.bundles.index+json"
+
+// syncIndexPreference is the preference included in the Prefer header of
+// bundle requests when incremental synchronization is enabled.
+const syncIndexPreference = "sync=index"
+
+// Index lists the files of a bundle along with the hashes of their raw
+// contents. The content of a file is served by the bundle server at
+// <resource>/blobs/<hash>, so that the downloader only has to fetch the files
+// it doesn't hold already.
+type Index struct {
+	Files []bundle.FileInfo `json:"files"`
+}
+
+// NewIndex returns the index of the bundle tarball read from r, using the
+// given hashing algorithm, and the contents of its files by hash. It can be
+// used by bundle servers to serve bundles incrementally.
+func NewIndex(r io.Reader, alg bundle.HashingAlgorithm) (*Index, map[string][]byte, error) {
+	files, err := readTarballFiles(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasher, err := bundle.NewSignatureHasher(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := &Index{Files: make([]bundle.FileInfo, 0, len(files))}
+	blobs := make(map[string][]byte, len(files))
+
+	for _, f := range files {
+		sum, err := hasher.HashFile(f.content)
+		if err != nil {
+			return nil, nil, err
+		}
+		hash := hex.EncodeToString(sum)
+		index.Files = append(index.Files, bundle.NewFile(f.name, hash, alg.String()))
+		blobs[hash] = f.content
+	}
+
+	return index, blobs, nil
+}
+
+type tarballFile struct {
+	name    string
+	content []byte
+}
+
+// readTarballFiles returns the regular files of a gzipped bundle tarball, in
+// the order they appear in it.
+func readTarballFiles(r io.Reader) ([]tarballFile, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	var files []tarballFile
+	tr := tar.NewReader(gr)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, tarballFile{name: "/" + strings.TrimLeft(hdr.Name, "/"), content: content})
+	}
+}
+
+// blobKey identifies the content of a file by its hash.
+type blobKey struct {
+	alg  bundle.HashingAlgorithm
+	hash string
+}
+
+// blobCache holds the file contents of the last bundle synchronized, so that
+// they don't have to be downloaded again when synchronizing the next one.
+type blobCache map[blobKey][]byte
+
+// sync reads the index of a bundle, downloads the files that aren't cached and
+// returns the files of the bundle in the order of the index. The cache is
+// replaced with the files of the bundle once all of them are available.
+func (d *Downloader) sync(ctx context.Context, r io.Reader) ([]tarballFile, error) {
+	var index Index
+	if err := util.NewJSONDecoder(r).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle index: %w", err)
+	}
+
+	files := make([]tarballFile, 0, len(index.Files))
+	blobs := make(blobCache, len(index.Files))
+
+	var downloaded int
+
+	for _, f := range index.Files {
+		key := blobKey{alg: bundle.HashingAlgorithm(f.Algorithm), hash: f.Hash}
+
+		if _, err := hex.DecodeString(f.Hash); err != nil || f.Hash == "" {
+			return nil, fmt.Errorf("invalid hash %q for %v", f.Hash, f.Name)
+		}
+
+		content, ok := blobs[key]
+		if !ok {
+			content, ok = d.blobs[key]
+		}
+		if !ok {
+			var err error
+			if content, err = d.downloadBlob(ctx, key.alg, key.hash); err != nil {
+				return nil, fmt.Errorf("failed to download %v: %w", f.Name, err)
+			}
+			downloaded++
+		}
+
+		blobs[key] = content
+		files = append(files, tarballFile{name: f.Name, content: content})
+	}
+
+	d.blobs = blobs
+
+	d.logger.Debug("Bundle synchronized, downloaded %d of %d files.", downloaded, len(index.Files))
+
+	return files, nil
+}
+
+// writeTarball writes the files as a gzipped bundle tarball.
+func writeTarball(w io.Writer, files []tarballFile) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	for _, f := range files {
+		if err := archive.WriteFile(tw, f.name, f.content); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// blobLoader is a bundle.DirectoryLoader for the files of a synchronized
+// bundle, so that they can be read without being packed into a tarball first.
+// Files are named like in the tarballs built by OPA, so that the bundle is
+// read the same way as a downloaded one.
+type blobLoader struct {
+	baseURL           string
+	files             []tarballFile
+	idx               int
+	maxSizeLimitBytes int64
+}
+
+func newBlobLoader(files []tarballFile, baseURL string) *blobLoader {
+	return &blobLoader{baseURL: baseURL, files: files}
+}
+
+// WithFilter is a no-op for blobLoader.
+func (l *blobLoader) WithFilter(filter.LoaderFilter) bundle.DirectoryLoader {
+	return l
+}
+
+// WithPathFormat is a no-op for blobLoader, file names are always slash
+// rooted.
+func (l *blobLoader) WithPathFormat(bundle.PathFormat) bundle.DirectoryLoader {
+	return l
+}
+
+// WithSizeLimitBytes specifies the maximum size of any file to read.
+func (l *blobLoader) WithSizeLimitBytes(sizeLimitBytes int64) bundle.DirectoryLoader {
+	l.maxSizeLimitBytes = sizeLimitBytes
+	return l
+}
+
+// WithFollowSymlinks is a no-op for blobLoader.
+func (l *blobLoader) WithFollowSymlinks(bool) bundle.DirectoryLoader {
+	return l
+}
+
+// NextFile returns a Descriptor for the next file of the bundle.
+func (l *blobLoader) NextFile() (*bundle.Descriptor, error) {
+	if l.idx >= len(l.files) {
+		return nil, io.EOF
+	}
+
+	f := l.files[l.idx]
+	l.idx++
+
+	if l.maxSizeLimitBytes > 0 && int64(len(f.content)) > l.maxSizeLimitBytes {
+		return nil, fmt.Errorf("bundle file %s size (%d bytes) exceeds configured size_limit_bytes (%d bytes)", f.name, len(f.content), l.maxSizeLimitBytes)
+	}
+
+	name := "/" + strings.TrimLeft(f.name, "/")
+	return bundle.NewDescriptor(path.Join(l.baseURL, name), name, bytes.NewReader(f.content)), nil
+}
+
+// downloadBlob downloads the file content with the given hash and checks that
+// the content matches it.
+func (d *Downloader) downloadBlob(ctx context.Context, alg bundle.HashingAlgorithm, hash string) ([]byte, error) {
+	hasher, err := bundle.NewSignatureHasher(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(ctx, "GET", path.Join(d.path, "blobs", hash))
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer util.Close(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, HTTPError{StatusCode: resp.StatusCode}
+	}
+
+	var body io.Reader = resp.Body
+	if d.sizeLimitBytes != nil {
+		body = io.LimitReader(resp.Body, *d.sizeLimitBytes+1)
+	}
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if d.sizeLimitBytes != nil && int64(len(content)) > *d.sizeLimitBytes {
+		return nil, fmt.Errorf("file exceeds the size limit of %d bytes", *d.sizeLimitBytes)
+	}
+
+	sum, err := hasher.HashFile(content)
+	if err != nil {
+		return nil, err
+	} else if hex.EncodeToString(sum) != hash {
+		return nil, errors.New("content does not match its hash")
+	}
+
+	return content, nil
+}
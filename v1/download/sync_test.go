@@ -0,0 +1,268 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+//go:build slow
+// +build slow
+
+package download
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/keys"
+	"github.com/IUAD1IY7/opa/v1/plugins/rest"
+)
+
+func TestIncrementalSync(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := newSyncTestServer(t)
+	server.publish(t, "r1", map[string]any{"large": strings.Repeat("x", 1024), "small": "a"})
+
+	d := server.downloader(t)
+
+	u := server.oneShot(ctx, t, d)
+	if u.Bundle.Manifest.Revision != "r1" || server.fullDownloads != 0 {
+		t.Fatalf("expected r1 to be synchronized but got %v after %d full downloads", u.Bundle.Manifest.Revision, server.fullDownloads)
+	}
+	if server.blobDownloads != len(server.index.Files) {
+		t.Fatalf("expected all %d files to be downloaded but got %d", len(server.index.Files), server.blobDownloads)
+	}
+
+	// Only the manifest, data and signatures change.
+	server.publish(t, "r2", map[string]any{"large": strings.Repeat("x", 1024), "small": "b"})
+	server.blobDownloads = 0
+
+	u = server.oneShot(ctx, t, d)
+	if u.Bundle.Manifest.Revision != "r2" || u.Bundle.Data["small"] != "b" {
+		t.Fatalf("expected r2 to be synchronized but got %v: %v", u.Bundle.Manifest.Revision, u.Bundle.Data)
+	}
+	if server.blobDownloads != 3 || server.fullDownloads != 0 {
+		t.Fatalf("expected 3 files to be downloaded but got %d and %d full downloads", server.blobDownloads, server.fullDownloads)
+	}
+
+	// Files of r1 that aren't in r2 are no longer cached.
+	if len(d.blobs) != len(server.index.Files) {
+		t.Fatalf("expected %d cached files but got %d", len(server.index.Files), len(d.blobs))
+	}
+	for _, f := range server.index.Files {
+		if _, ok := d.blobs[blobKey{alg: bundle.SHA256, hash: f.Hash}]; !ok {
+			t.Fatalf("expected %v to be cached", f.Name)
+		}
+	}
+}
+
+func TestIncrementalSyncPersist(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := newSyncTestServer(t)
+	server.publish(t, "r1", map[string]any{"small": "a"})
+
+	u := server.oneShot(ctx, t, server.downloader(t).WithBundlePersistence(true))
+	if server.fullDownloads != 0 {
+		t.Fatalf("expected r1 to be synchronized but got %d full downloads", server.fullDownloads)
+	}
+
+	b, err := bundle.NewReader(u.Raw).WithSkipBundleVerification(true).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Manifest.Revision != "r1" || b.Data["small"] != "a" {
+		t.Fatalf("expected persisted bundle to be r1 but got %v: %v", b.Manifest.Revision, b.Data)
+	}
+}
+
+func TestIncrementalSyncFallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		noIndex bool
+		noBlobs bool
+	}{
+		{
+			note:    "index not supported",
+			noIndex: true,
+		},
+		{
+			note:    "blobs not found",
+			noBlobs: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx := context.Background()
+			server := newSyncTestServer(t)
+			server.noIndex = tc.noIndex
+			server.noBlobs = tc.noBlobs
+			server.publish(t, "r1", map[string]any{"small": "a"})
+
+			u := server.oneShot(ctx, t, server.downloader(t))
+			if u.Bundle.Manifest.Revision != "r1" || server.fullDownloads != 1 {
+				t.Fatalf("expected r1 to be downloaded in full but got %v after %d full downloads", u.Bundle.Manifest.Revision, server.fullDownloads)
+			}
+		})
+	}
+}
+
+func TestIncrementalSyncVerification(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	server := newSyncTestServer(t)
+	server.publish(t, "r1", map[string]any{"small": "a"})
+
+	// Replace the data file in the index with content that matches its hash,
+	// but not the signed file list.
+	tampered := []byte(`{"small": "evil"}`)
+	hasher, _ := bundle.NewSignatureHasher(bundle.SHA256)
+	sum, _ := hasher.HashFile(tampered)
+	for i, f := range server.index.Files {
+		if f.Name == "/data.json" {
+			server.index.Files[i].Hash = fmt.Sprintf("%x", sum)
+			server.blobs[server.index.Files[i].Hash] = tampered
+		}
+	}
+
+	d := server.downloader(t)
+	err := d.oneShot(ctx)
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("expected verification error but got: %v", err)
+	}
+}
+
+type syncTestServer struct {
+	mtx           sync.Mutex
+	server        *httptest.Server
+	tarball       []byte
+	index         *Index
+	blobs         map[string][]byte
+	noIndex       bool
+	noBlobs       bool
+	fullDownloads int
+	blobDownloads int
+}
+
+func newSyncTestServer(t *testing.T) *syncTestServer {
+	s := &syncTestServer{}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *syncTestServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/bundles/test/blobs/"):
+		content, ok := s.blobs[path.Base(r.URL.Path)]
+		if s.noBlobs || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.blobDownloads++
+		_, _ = w.Write(content)
+	case r.URL.Path == "/bundles/test":
+		if !s.noIndex && getPreferHeaderField(r, "sync") == "index" {
+			w.Header().Set("Content-Type", IndexContentType)
+			_ = json.NewEncoder(w).Encode(s.index)
+			return
+		}
+		s.fullDownloads++
+		_, _ = w.Write(s.tarball)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// publish signs and serves a new revision of the bundle.
+func (s *syncTestServer) publish(t *testing.T, revision string, data map[string]any) {
+	t.Helper()
+
+	module := "package example\n\np := true"
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: revision},
+		Data:     data,
+		Modules: []bundle.ModuleFile{
+			{
+				URL:    "/example.rego",
+				Path:   "/example.rego",
+				Raw:    []byte(module),
+				Parsed: ast.MustParseModule(module),
+			},
+		},
+	}
+	b.Manifest.Init()
+
+	if err := b.GenerateSignature(bundle.NewSigningConfig("secret", "HS256", ""), "foo", false); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(b); err != nil {
+		t.Fatal(err)
+	}
+
+	index, blobs, err := NewIndex(bytes.NewReader(buf.Bytes()), bundle.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.tarball = buf.Bytes()
+	s.index = index
+	s.blobs = blobs
+}
+
+func (s *syncTestServer) downloader(t *testing.T) *Downloader {
+	t.Helper()
+
+	client, err := rest.New(fmt.Appendf(nil, `{"url": %q}`, s.server.URL), map[string]*keys.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{Incremental: true}
+	if err := config.ValidateAndInjectDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	vc := bundle.NewVerificationConfig(map[string]*bundle.KeyConfig{"foo": {Key: "secret", Algorithm: "HS256"}}, "foo", "", nil)
+
+	return New(config, client, "/bundles/test").WithBundleVerificationConfig(vc)
+}
+
+func (*syncTestServer) oneShot(ctx context.Context, t *testing.T, d *Downloader) Update {
+	t.Helper()
+
+	var u Update
+	d.WithCallback(func(_ context.Context, update Update) {
+		u = update
+	})
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal(err)
+	} else if u.Bundle == nil {
+		t.Fatal("expected bundle to be downloaded")
+	}
+
+	return u
+}
@@ -0,0 +1,212 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"slices"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+)
+
+// translatable contains the built-in functions that may be applied to unknown
+// values without producing residuals that cannot be translated.
+var translatable = map[string]struct{}{
+	ast.Equality.Name:      {},
+	ast.Assign.Name:        {},
+	ast.Equal.Name:         {},
+	ast.NotEqual.Name:      {},
+	ast.LessThan.Name:      {},
+	ast.LessThanEq.Name:    {},
+	ast.GreaterThan.Name:   {},
+	ast.GreaterThanEq.Name: {},
+	ast.StartsWith.Name:    {},
+	ast.EndsWith.Name:      {},
+	ast.Contains.Name:      {},
+	ast.Member.Name:        {},
+	ast.InternalPrint.Name: {},
+}
+
+// Check inspects the rules that query depends on and reports calls to
+// built-in functions that receive unknown values and that cannot be
+// translated into data filters. If unknowns is empty, the input document is
+// treated as unknown. The check is conservative: it may reject rules whose
+// residuals would have been discarded by partial evaluation.
+func Check(compiler *ast.Compiler, query ast.Body, unknowns []*ast.Term) error {
+	if len(unknowns) == 0 {
+		unknowns = []*ast.Term{ast.NewTerm(ast.InputRootRef)}
+	}
+
+	c := checker{
+		compiler: compiler,
+		unknowns: unknowns,
+		visited:  map[*ast.Rule]bool{},
+	}
+
+	c.checkBody(query, ast.NewVarSet())
+
+	if len(c.errs) > 0 {
+		slices.SortFunc(c.errs, func(a, b *Error) int {
+			return a.Location.Compare(b.Location)
+		})
+		return c.errs
+	}
+	return nil
+}
+
+type checker struct {
+	compiler *ast.Compiler
+	unknowns []*ast.Term
+	visited  map[*ast.Rule]bool // true if the rule was checked with unknown arguments
+	errs     Errors
+}
+
+func (c *checker) checkBody(body ast.Body, tainted ast.VarSet) {
+	for _, expr := range body {
+		c.checkExpr(expr, tainted)
+	}
+
+	// Follow references to other rules, including function calls.
+	ast.WalkRefs(body, func(ref ast.Ref) bool {
+		if ref.HasPrefix(ast.DefaultRootRef) {
+			for _, rule := range c.compiler.GetRules(ref.ConstantPrefix()) {
+				c.checkRule(rule, false)
+			}
+		}
+		return false
+	})
+}
+
+// checkRule checks the body of rule and its else branches. If unknownArgs is
+// true, the arguments of the function defined by rule are treated as unknown.
+func (c *checker) checkRule(rule *ast.Rule, unknownArgs bool) {
+	for ; rule != nil; rule = rule.Else {
+		if checked, ok := c.visited[rule]; ok && (checked || !unknownArgs) {
+			return
+		}
+		c.visited[rule] = unknownArgs
+		tainted := ast.NewVarSet()
+		if unknownArgs {
+			for _, arg := range rule.Head.Args {
+				taint(tainted, arg)
+			}
+		}
+		c.checkBody(rule.Body, tainted)
+	}
+}
+
+func (c *checker) checkExpr(expr *ast.Expr, tainted ast.VarSet) {
+	terms, ok := expr.Terms.([]*ast.Term)
+	if !ok {
+		ast.WalkTerms(expr, func(t *ast.Term) bool {
+			if call, ok := t.Value.(ast.Call); ok {
+				c.checkCall(expr, call[0], call[1:], tainted)
+			}
+			return false
+		})
+		return
+	}
+
+	operands := terms[1:]
+
+	// Nested calls are checked on their own.
+	for _, t := range operands {
+		ast.WalkTerms(t, func(t *ast.Term) bool {
+			if call, ok := t.Value.(ast.Call); ok {
+				c.checkCall(expr, call[0], call[1:], tainted)
+			}
+			return false
+		})
+	}
+
+	name := expr.Operator().String()
+	if expr.IsEquality() || expr.IsAssignment() {
+		// Values unified with unknowns are unknown, too.
+		if c.unknown(operands[0], tainted) || c.unknown(operands[1], tainted) {
+			for _, t := range operands {
+				taint(tainted, t)
+			}
+		}
+		return
+	}
+
+	if c.checkCall(expr, terms[0], operands, tainted) {
+		// The outputs of calls with unknown operands are unknown, too.
+		if bi, ok := ast.BuiltinMap[name]; ok && len(operands) > bi.Decl.Arity() {
+			taint(tainted, operands[len(operands)-1])
+		}
+	}
+}
+
+// checkCall records an error if the call is not translatable and one of its
+// operands is unknown. It returns true if any operand is unknown.
+func (c *checker) checkCall(expr *ast.Expr, operator *ast.Term, operands []*ast.Term, tainted ast.VarSet) bool {
+	unknown := false
+	for _, t := range operands {
+		if c.unknown(t, tainted) {
+			unknown = true
+			break
+		}
+	}
+	if !unknown {
+		return false
+	}
+
+	ref, ok := operator.Value.(ast.Ref)
+	if !ok {
+		return true
+	}
+
+	if ref.HasPrefix(ast.DefaultRootRef) {
+		for _, rule := range c.compiler.GetRules(ref) {
+			c.checkRule(rule, true)
+		}
+		return true
+	}
+
+	if _, ok := translatable[ref.String()]; !ok {
+		c.errs = append(c.errs, newError(expr.Location, "call to %v cannot be translated", ref))
+	}
+
+	return true
+}
+
+// taint marks the variables in t as unknown. Reference heads are skipped
+// because referencing a value does not make the referenced document unknown.
+func taint(tainted ast.VarSet, t *ast.Term) {
+	vis := ast.NewVarVisitor().WithParams(ast.VarVisitorParams{SkipRefHead: true})
+	vis.Walk(t)
+	for v := range vis.Vars() {
+		tainted.Add(v)
+	}
+}
+
+// unknown returns true if t refers to an unknown value.
+func (c *checker) unknown(t *ast.Term, tainted ast.VarSet) bool {
+	found := false
+	ast.WalkTerms(t, func(x *ast.Term) bool {
+		if found {
+			return true
+		}
+		switch v := x.Value.(type) {
+		case ast.Var:
+			if tainted.Contains(v) {
+				found = true
+			}
+		case ast.Ref:
+			for _, u := range c.unknowns {
+				if prefix, ok := u.Value.(ast.Ref); ok && (v.HasPrefix(prefix) || prefix.HasPrefix(v)) {
+					found = true
+					return true
+				}
+			}
+			if head, ok := v[0].Value.(ast.Var); ok && tainted.Contains(head) {
+				found = true
+				return true
+			}
+		}
+		return false
+	})
+	return found
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		note   string
+		module string
+		rows   []int
+	}{
+		{
+			note: "translatable",
+			module: `package test
+allow if {
+	input.fruits.name == "apple"
+	startswith(input.fruits.colour, "r")
+	input.fruits.price in {1, 2}
+	upper(input.user) == "BOB"
+}`,
+		},
+		{
+			note: "untranslatable builtin",
+			module: `package test
+allow if {
+	input.fruits.name == "apple"
+	lower(input.fruits.colour) == "red"
+}`,
+			rows: []int{4},
+		},
+		{
+			note: "assigned unknown",
+			module: `package test
+allow if {
+	x := input.fruits.tags
+	count(x) > 2
+}`,
+			rows: []int{4},
+		},
+		{
+			note: "dependencies",
+			module: `package test
+allow if expensive
+allow if cheap(input.fruits.price)
+expensive if abs(input.fruits.price) > 100
+cheap(x) if round(x) < 2`,
+			rows: []int{4, 5},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := ast.MustCompileModules(map[string]string{"test.rego": tc.module})
+			unknowns := []*ast.Term{ast.MustParseTerm("input.fruits")}
+			err := Check(c, ast.MustParseBody("data.test.allow == true"), unknowns)
+			if len(tc.rows) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected errors but got %v", err)
+			}
+			if len(errs) != len(tc.rows) {
+				t.Fatalf("expected %d errors but got %v", len(tc.rows), errs)
+			}
+			for i, row := range tc.rows {
+				if errs[i].Location.Row != row {
+					t.Errorf("expected error on row %d but got %v", row, errs[i])
+				}
+			}
+		})
+	}
+}
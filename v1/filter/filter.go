@@ -0,0 +1,416 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package filter translates the results of partial evaluation into data
+// filters that can be pushed down into external data stores.
+//
+// The translation expects the unknowns used for partial evaluation to
+// reference tables and columns as <root>.<table>.<column>, e.g.,
+// input.fruits.name. Residual queries are combined with OR and the
+// expressions of each query are combined with AND.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/rego"
+)
+
+// TranslationErr indicates that a residual expression could not be
+// translated into a data filter.
+const TranslationErr = "data_filter_translation_error"
+
+// Error represents a single translation error. The location refers to the
+// residual expression or term that could not be translated.
+type Error struct {
+	Code     string        `json:"code"`
+	Message  string        `json:"message"`
+	Location *ast.Location `json:"location,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Location != nil {
+		return fmt.Sprintf("%v: %v: %v", e.Location, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+func newError(loc *ast.Location, f string, a ...any) *Error {
+	return &Error{
+		Code:     TranslationErr,
+		Message:  fmt.Sprintf(f, a...),
+		Location: loc,
+	}
+}
+
+// Errors represents a series of errors encountered during translation.
+type Errors []*Error
+
+func (e Errors) Error() string {
+
+	if len(e) == 0 {
+		return "no error(s)"
+	}
+
+	if len(e) == 1 {
+		return fmt.Sprintf("1 error occurred: %v", e[0].Error())
+	}
+
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d errors occurred:\n%s", len(e), strings.Join(s, "\n"))
+}
+
+// Filter is the store-independent representation of a translated partial
+// evaluation result. Use the methods on Filter to render it for a specific
+// data store.
+type Filter struct {
+	root *node
+}
+
+// Translate converts the partial evaluation result pq into a Filter. If any
+// residual expression cannot be represented, an Errors value is returned that
+// contains an error for every offending expression.
+func Translate(pq *rego.PartialQueries) (*Filter, error) {
+	var errs Errors
+
+	for _, m := range pq.Support {
+		for _, r := range m.Rules {
+			errs = append(errs, newError(r.Location, "support rule %v cannot be translated", r.Head.Ref()))
+		}
+	}
+
+	root := &node{kind: kindOr}
+
+	for _, body := range pq.Queries {
+		conj := &node{kind: kindAnd}
+		for _, expr := range body {
+			n, err := translateExpr(expr)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if n.kind != kindTrue {
+				conj.children = append(conj.children, n)
+			}
+		}
+		root.children = append(root.children, conj.simplify())
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &Filter{root: root.simplify()}, nil
+}
+
+// Unconditional returns true if the filter matches all rows, i.e., at least
+// one residual query was empty.
+func (f *Filter) Unconditional() bool {
+	return f.root.kind == kindTrue
+}
+
+// Never returns true if the filter matches no rows, i.e., partial evaluation
+// did not produce any residual queries.
+func (f *Filter) Never() bool {
+	return f.root.kind == kindFalse
+}
+
+type nodeKind int
+
+const (
+	kindTrue nodeKind = iota
+	kindFalse
+	kindAnd
+	kindOr
+	kindNot
+	kindCompare
+)
+
+// Comparison operators understood by the translators.
+const (
+	opEq         = "eq"
+	opNe         = "ne"
+	opLt         = "lt"
+	opLte        = "lte"
+	opGt         = "gt"
+	opGte        = "gte"
+	opIn         = "in"
+	opStartsWith = "startswith"
+	opEndsWith   = "endswith"
+	opContains   = "contains"
+)
+
+var comparisons = map[string]string{
+	ast.Equality.Name:      opEq,
+	ast.Equal.Name:         opEq,
+	ast.NotEqual.Name:      opNe,
+	ast.LessThan.Name:      opLt,
+	ast.LessThanEq.Name:    opLte,
+	ast.GreaterThan.Name:   opGt,
+	ast.GreaterThanEq.Name: opGte,
+}
+
+var flipped = map[string]string{
+	opEq:  opEq,
+	opNe:  opNe,
+	opLt:  opGt,
+	opLte: opGte,
+	opGt:  opLt,
+	opGte: opLte,
+}
+
+var patterns = map[string]string{
+	ast.StartsWith.Name: opStartsWith,
+	ast.EndsWith.Name:   opEndsWith,
+	ast.Contains.Name:   opContains,
+}
+
+// field identifies a column of a table.
+type field struct {
+	Table  string
+	Column string
+}
+
+func (f field) String() string {
+	return f.Table + "." + f.Column
+}
+
+type node struct {
+	kind     nodeKind
+	children []*node
+
+	// The following fields are only set on comparison nodes.
+	op       string
+	field    field
+	other    *field // set when comparing two fields
+	value    any    // JSON-compatible value
+	location *ast.Location
+}
+
+// simplify collapses conjunctions and disjunctions with zero or one children
+// and folds constant children.
+func (n *node) simplify() *node {
+	switch n.kind {
+	case kindAnd:
+		children := n.children[:0]
+		for _, c := range n.children {
+			switch c.kind {
+			case kindTrue:
+				continue
+			case kindFalse:
+				return c
+			}
+			children = append(children, c)
+		}
+		n.children = children
+		switch len(n.children) {
+		case 0:
+			return &node{kind: kindTrue}
+		case 1:
+			return n.children[0]
+		}
+	case kindOr:
+		children := n.children[:0]
+		for _, c := range n.children {
+			switch c.kind {
+			case kindFalse:
+				continue
+			case kindTrue:
+				return c
+			}
+			children = append(children, c)
+		}
+		n.children = children
+		switch len(n.children) {
+		case 0:
+			return &node{kind: kindFalse}
+		case 1:
+			return n.children[0]
+		}
+	}
+	return n
+}
+
+func translateExpr(expr *ast.Expr) (*node, *Error) {
+
+	if len(expr.With) > 0 {
+		return nil, newError(expr.Location, "expressions with 'with' modifiers cannot be translated")
+	}
+
+	var n *node
+	var err *Error
+
+	switch terms := expr.Terms.(type) {
+	case *ast.Term:
+		if b, ok := terms.Value.(ast.Boolean); ok {
+			if bool(b) {
+				n = &node{kind: kindTrue}
+			} else {
+				n = &node{kind: kindFalse}
+			}
+			break
+		}
+		return nil, newError(expr.Location, "expression %v cannot be translated", expr)
+	case []*ast.Term:
+		n, err = translateCall(expr, terms)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newError(expr.Location, "expression %v cannot be translated", expr)
+	}
+
+	if expr.Negated {
+		switch n.kind {
+		case kindTrue:
+			return &node{kind: kindFalse}, nil
+		case kindFalse:
+			return &node{kind: kindTrue}, nil
+		}
+		return &node{kind: kindNot, children: []*node{n}}, nil
+	}
+
+	return n, nil
+}
+
+func translateCall(expr *ast.Expr, terms []*ast.Term) (*node, *Error) {
+	name := expr.Operator().String()
+
+	for _, t := range terms[1:] {
+		if call, ok := t.Value.(ast.Call); ok {
+			return nil, newError(expr.Location, "call to %v cannot be translated", call[0])
+		}
+	}
+
+	if op, ok := comparisons[name]; ok {
+		if len(terms) != 3 {
+			return nil, newError(expr.Location, "call to %v must have two operands", name)
+		}
+		a, b := terms[1], terms[2]
+		if _, ok := toField(a); !ok {
+			a, b = b, a
+			op = flipped[op]
+		}
+		f, ok := toField(a)
+		if !ok {
+			return nil, newError(expr.Location, "expression %v does not reference a column", expr)
+		}
+		if other, ok := toField(b); ok {
+			return &node{kind: kindCompare, op: op, field: f, other: &other, location: expr.Location}, nil
+		}
+		v, err := toValue(b)
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: kindCompare, op: op, field: f, value: v, location: expr.Location}, nil
+	}
+
+	if op, ok := patterns[name]; ok {
+		if len(terms) != 3 {
+			return nil, newError(expr.Location, "call to %v must have two operands", name)
+		}
+		f, ok := toField(terms[1])
+		if !ok {
+			return nil, newError(terms[1].Location, "first operand of %v must reference a column", name)
+		}
+		s, ok := terms[2].Value.(ast.String)
+		if !ok {
+			return nil, newError(terms[2].Location, "second operand of %v must be a string", name)
+		}
+		return &node{kind: kindCompare, op: op, field: f, value: string(s), location: expr.Location}, nil
+	}
+
+	if name == ast.Member.Name {
+		if len(terms) != 3 {
+			return nil, newError(expr.Location, "membership check must have two operands")
+		}
+		f, ok := toField(terms[1])
+		if !ok {
+			return nil, newError(terms[1].Location, "element of membership check must reference a column")
+		}
+		var values []any
+		var err *Error
+		switch coll := terms[2].Value.(type) {
+		case *ast.Array:
+			values = make([]any, 0, coll.Len())
+			coll.Foreach(func(t *ast.Term) {
+				if err != nil {
+					return
+				}
+				var v any
+				v, err = toValue(t)
+				values = append(values, v)
+			})
+		case ast.Set:
+			values = make([]any, 0, coll.Len())
+			coll.Foreach(func(t *ast.Term) {
+				if err != nil {
+					return
+				}
+				var v any
+				v, err = toValue(t)
+				values = append(values, v)
+			})
+		default:
+			return nil, newError(terms[2].Location, "collection of membership check must be a constant array or set")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			// Nothing is a member of the empty collection.
+			return &node{kind: kindFalse}, nil
+		}
+		return &node{kind: kindCompare, op: opIn, field: f, value: values, location: expr.Location}, nil
+	}
+
+	return nil, newError(expr.Location, "call to %v cannot be translated", name)
+}
+
+// toField returns the table and column referenced by t if t is a reference of
+// the form <root>.<table>.<column>.
+func toField(t *ast.Term) (field, bool) {
+	ref, ok := t.Value.(ast.Ref)
+	if !ok || len(ref) != 3 {
+		return field{}, false
+	}
+	if _, ok := ref[0].Value.(ast.Var); !ok {
+		return field{}, false
+	}
+	table, ok1 := ref[1].Value.(ast.String)
+	column, ok2 := ref[2].Value.(ast.String)
+	if !ok1 || !ok2 {
+		return field{}, false
+	}
+	return field{Table: string(table), Column: string(column)}, true
+}
+
+// toValue returns the JSON representation of the ground term t.
+func toValue(t *ast.Term) (any, *Error) {
+	if !t.IsGround() {
+		return nil, newError(t.Location, "term %v is not a constant", t)
+	}
+	v, err := ast.JSON(t.Value)
+	if err != nil {
+		return nil, newError(t.Location, "term %v cannot be converted to JSON: %v", t, err)
+	}
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, newError(t.Location, "number %v is out of range", n)
+		}
+		return f, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"regexp"
+)
+
+// MongoDB is a MongoDB query document for a single collection.
+type MongoDB struct {
+	Collection string         `json:"collection,omitempty"`
+	Filter     map[string]any `json:"filter"`
+}
+
+var mongoOperators = map[string]string{
+	opEq:  "$eq",
+	opNe:  "$ne",
+	opLt:  "$lt",
+	opLte: "$lte",
+	opGt:  "$gt",
+	opGte: "$gte",
+	opIn:  "$in",
+}
+
+// MongoDB renders f as a MongoDB query document. The table referenced by the
+// residual expressions is used as the collection and columns are used as
+// field names, so all expressions must refer to the same table.
+func (f *Filter) MongoDB() (*MongoDB, error) {
+	w := mongoWriter{}
+	doc := w.write(f.root)
+	if len(w.errs) > 0 {
+		return nil, w.errs
+	}
+	return &MongoDB{Collection: w.collection, Filter: doc}, nil
+}
+
+type mongoWriter struct {
+	collection string
+	errs       Errors
+}
+
+func (w *mongoWriter) write(n *node) map[string]any {
+	switch n.kind {
+	case kindTrue:
+		return map[string]any{}
+	case kindFalse:
+		// {} matches every document, so nothing matches its negation.
+		return map[string]any{"$nor": []any{map[string]any{}}}
+	case kindAnd, kindOr, kindNot:
+		op := "$and"
+		switch n.kind {
+		case kindOr:
+			op = "$or"
+		case kindNot:
+			op = "$nor"
+		}
+		children := make([]any, len(n.children))
+		for i, c := range n.children {
+			children[i] = w.write(c)
+		}
+		return map[string]any{op: children}
+	}
+
+	name := w.field(n, n.field)
+
+	if n.other != nil {
+		other := w.field(n, *n.other)
+		return map[string]any{
+			"$expr": map[string]any{mongoOperators[n.op]: []any{"$" + name, "$" + other}},
+		}
+	}
+
+	var cond map[string]any
+
+	switch n.op {
+	case opStartsWith:
+		cond = map[string]any{"$regex": "^" + regexp.QuoteMeta(n.value.(string))}
+	case opEndsWith:
+		cond = map[string]any{"$regex": regexp.QuoteMeta(n.value.(string)) + "$"}
+	case opContains:
+		cond = map[string]any{"$regex": regexp.QuoteMeta(n.value.(string))}
+	default:
+		cond = map[string]any{mongoOperators[n.op]: n.value}
+	}
+
+	return map[string]any{name: cond}
+}
+
+func (w *mongoWriter) field(n *node, f field) string {
+	if w.collection == "" {
+		w.collection = f.Table
+	} else if w.collection != f.Table {
+		w.errs = append(w.errs, newError(n.location, "MongoDB filters cannot reference multiple collections (%v and %v)", w.collection, f.Table))
+	}
+	return f.Column
+}
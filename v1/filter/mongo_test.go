@@ -0,0 +1,90 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+func TestMongoDB(t *testing.T) {
+	tests := []struct {
+		note   string
+		module string
+		exp    string
+	}{
+		{
+			note: "comparisons",
+			module: `package test
+				allow if {
+					input.fruits.price >= 2
+					input.fruits.name in {"apple", "banana"}
+				}`,
+			exp: `{"collection": "fruits", "filter": {"$and": [
+				{"price": {"$gte": 2}},
+				{"name": {"$in": ["apple", "banana"]}}
+			]}}`,
+		},
+		{
+			note: "patterns and negation",
+			module: `package test
+				allow if not startswith(input.fruits.name, "a.b")
+				allow if input.fruits.price < input.fruits.limit`,
+			exp: `{"collection": "fruits", "filter": {"$or": [
+				{"$expr": {"$lt": ["$price", "$limit"]}},
+				{"$nor": [{"name": {"$regex": "^a\\.b"}}]}
+			]}}`,
+		},
+		{
+			note: "never",
+			module: `package test
+				allow if false`,
+			exp: `{"filter": {"$nor": [{}]}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			f, err := Translate(partial(t, tc.module, "data.test.allow == true"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := f.MongoDB()
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs, err := json.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result, exp any
+			if err := util.UnmarshalJSON(bs, &result); err != nil {
+				t.Fatal(err)
+			}
+			if err := util.UnmarshalJSON([]byte(tc.exp), &exp); err != nil {
+				t.Fatal(err)
+			}
+			if util.Compare(result, exp) != 0 {
+				t.Fatalf("expected:\n\n%v\n\ngot:\n\n%s", tc.exp, bs)
+			}
+		})
+	}
+}
+
+func TestMongoDBMultipleCollections(t *testing.T) {
+	f, err := Translate(partial(t, `package test
+allow if input.fruits.owner == input.owners.id`, "data.test.allow == true"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.MongoDB()
+	if err == nil || !strings.Contains(err.Error(), "cannot reference multiple collections") {
+		t.Fatalf("expected multiple collections error but got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dialect identifies the SQL dialect used when rendering a Filter.
+type Dialect string
+
+// Set of supported SQL dialects.
+const (
+	Postgres Dialect = "postgresql"
+	MySQL    Dialect = "mysql"
+	SQLite   Dialect = "sqlite"
+)
+
+// SQL contains a parameterized WHERE clause and the arguments for its
+// placeholders. An empty query means that no filtering is required.
+type SQL struct {
+	Query string `json:"query"`
+	Args  []any  `json:"args,omitempty"`
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var sqlOperators = map[string]string{
+	opEq:  "=",
+	opNe:  "<>",
+	opLt:  "<",
+	opLte: "<=",
+	opGt:  ">",
+	opGte: ">=",
+}
+
+// SQL renders f as a WHERE clause for dialect d. Values are never inlined into
+// the query; they are returned as arguments for the dialect's placeholders.
+func (f *Filter) SQL(d Dialect) (*SQL, error) {
+	switch d {
+	case Postgres, MySQL, SQLite:
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect: %q", d)
+	}
+
+	switch f.root.kind {
+	case kindTrue:
+		return &SQL{}, nil
+	case kindFalse:
+		return &SQL{Query: "WHERE 1 = 0"}, nil
+	}
+
+	w := sqlWriter{dialect: d}
+	w.write(f.root, false)
+	if len(w.errs) > 0 {
+		return nil, w.errs
+	}
+
+	return &SQL{Query: "WHERE " + w.buf.String(), Args: w.args}, nil
+}
+
+type sqlWriter struct {
+	dialect Dialect
+	buf     strings.Builder
+	args    []any
+	errs    Errors
+}
+
+func (w *sqlWriter) write(n *node, nested bool) {
+	switch n.kind {
+	case kindTrue:
+		w.buf.WriteString("1 = 1")
+	case kindFalse:
+		w.buf.WriteString("1 = 0")
+	case kindAnd, kindOr:
+		sep := " AND "
+		if n.kind == kindOr {
+			sep = " OR "
+		}
+		if nested {
+			w.buf.WriteByte('(')
+		}
+		for i, c := range n.children {
+			if i > 0 {
+				w.buf.WriteString(sep)
+			}
+			w.write(c, true)
+		}
+		if nested {
+			w.buf.WriteByte(')')
+		}
+	case kindNot:
+		w.buf.WriteString("NOT (")
+		w.write(n.children[0], false)
+		w.buf.WriteByte(')')
+	case kindCompare:
+		w.writeCompare(n)
+	}
+}
+
+func (w *sqlWriter) writeCompare(n *node) {
+	w.writeField(n, n.field)
+
+	switch n.op {
+	case opEq, opNe, opLt, opLte, opGt, opGte:
+		if n.other != nil {
+			w.buf.WriteString(" " + sqlOperators[n.op] + " ")
+			w.writeField(n, *n.other)
+			return
+		}
+		if n.value == nil {
+			switch n.op {
+			case opEq:
+				w.buf.WriteString(" IS NULL")
+			case opNe:
+				w.buf.WriteString(" IS NOT NULL")
+			default:
+				w.errs = append(w.errs, newError(n.location, "null cannot be compared with %v", sqlOperators[n.op]))
+			}
+			return
+		}
+		w.buf.WriteString(" " + sqlOperators[n.op] + " ")
+		w.writeArg(n, n.value)
+	case opIn:
+		w.buf.WriteString(" IN (")
+		for i, v := range n.value.([]any) {
+			if i > 0 {
+				w.buf.WriteString(", ")
+			}
+			w.writeArg(n, v)
+		}
+		w.buf.WriteByte(')')
+	case opStartsWith, opEndsWith, opContains:
+		s := escapeLike(n.value.(string))
+		switch n.op {
+		case opStartsWith:
+			s += "%"
+		case opEndsWith:
+			s = "%" + s
+		case opContains:
+			s = "%" + s + "%"
+		}
+		w.buf.WriteString(" LIKE ")
+		w.writeArg(n, s)
+		if w.dialect == MySQL {
+			w.buf.WriteString(` ESCAPE '\\'`)
+		} else {
+			w.buf.WriteString(` ESCAPE '\'`)
+		}
+	}
+}
+
+func (w *sqlWriter) writeField(n *node, f field) {
+	if !identifier.MatchString(f.Table) || !identifier.MatchString(f.Column) {
+		w.errs = append(w.errs, newError(n.location, "%q is not a valid SQL identifier", f.String()))
+	}
+	w.buf.WriteString(f.String())
+}
+
+func (w *sqlWriter) writeArg(n *node, v any) {
+	switch v.(type) {
+	case []any, map[string]any:
+		w.errs = append(w.errs, newError(n.location, "collections cannot be compared in SQL"))
+		return
+	}
+	w.args = append(w.args, v)
+	if w.dialect == Postgres {
+		w.buf.WriteString("$" + strconv.Itoa(len(w.args)))
+	} else {
+		w.buf.WriteByte('?')
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/rego"
+)
+
+func partial(t *testing.T, module string, query string) *rego.PartialQueries {
+	t.Helper()
+
+	pq, err := rego.New(
+		rego.Query(query),
+		rego.Module("test.rego", module),
+		rego.Unknowns([]string{"input.fruits", "input.owners"}),
+	).Partial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pq
+}
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		note    string
+		module  string
+		dialect Dialect
+		query   string
+		args    []any
+	}{
+		{
+			note: "equality",
+			module: `package test
+				allow if input.fruits.name == "apple"`,
+			dialect: Postgres,
+			query:   "WHERE fruits.name = $1",
+			args:    []any{"apple"},
+		},
+		{
+			note: "flipped comparison",
+			module: `package test
+				allow if 10 < input.fruits.price`,
+			dialect: MySQL,
+			query:   "WHERE fruits.price > ?",
+			args:    []any{int64(10)},
+		},
+		{
+			note: "conjunction and disjunction",
+			module: `package test
+				allow if {
+					input.fruits.name != "banana"
+					input.fruits.price >= 1.5
+				}
+				allow if input.fruits.owner == input.owners.id`,
+			dialect: Postgres,
+			query:   "WHERE fruits.owner = owners.id OR (fruits.name <> $1 AND fruits.price >= $2)",
+			args:    []any{"banana", 1.5},
+		},
+		{
+			note: "null",
+			module: `package test
+				allow if input.fruits.deleted == null`,
+			dialect: SQLite,
+			query:   "WHERE fruits.deleted IS NULL",
+		},
+		{
+			note: "negation",
+			module: `package test
+				allow if not input.fruits.name == "apple"`,
+			dialect: Postgres,
+			query:   "WHERE NOT (fruits.name = $1)",
+			args:    []any{"apple"},
+		},
+		{
+			note: "membership",
+			module: `package test
+				allow if input.fruits.name in {"apple", "cherry"}`,
+			dialect: SQLite,
+			query:   "WHERE fruits.name IN (?, ?)",
+			args:    []any{"apple", "cherry"},
+		},
+		{
+			note: "patterns",
+			module: `package test
+				allow if {
+					startswith(input.fruits.name, "ap_")
+					contains(input.fruits.colour, "50%")
+				}`,
+			dialect: MySQL,
+			query:   `WHERE fruits.name LIKE ? ESCAPE '\\' AND fruits.colour LIKE ? ESCAPE '\\'`,
+			args:    []any{`ap\_%`, `%50\%%`},
+		},
+		{
+			note: "unconditional",
+			module: `package test
+				allow if input.fruits.name == "apple"
+				allow := true`,
+			dialect: Postgres,
+			query:   "",
+		},
+		{
+			note: "never",
+			module: `package test
+				allow if false`,
+			dialect: Postgres,
+			query:   "WHERE 1 = 0",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			f, err := Translate(partial(t, tc.module, "data.test.allow == true"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, err := f.SQL(tc.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql.Query != tc.query {
+				t.Errorf("expected query:\n\n%v\n\ngot:\n\n%v", tc.query, sql.Query)
+			}
+			if !reflect.DeepEqual(sql.Args, tc.args) {
+				t.Errorf("expected args %v but got %v", tc.args, sql.Args)
+			}
+		})
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		note   string
+		module string
+		errs   []string
+		row    int
+	}{
+		{
+			note: "unsupported builtin",
+			module: `package test
+allow if upper(input.fruits.name) == "APPLE"`,
+			errs: []string{"call to upper cannot be translated"},
+			row:  2,
+		},
+		{
+			note: "nested column",
+			module: `package test
+allow if input.fruits.attrs.colour == "red"`,
+			errs: []string{"does not reference a column"},
+			row:  2,
+		},
+		{
+			note: "multiple errors",
+			module: `package test
+allow if {
+	input.fruits.name == "apple"
+	count(input.fruits.tags) > 1
+	lower(input.fruits.name) == "apple"
+}`,
+			errs: []string{"call to count cannot be translated", "call to lower cannot be translated"},
+			row:  4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := Translate(partial(t, tc.module, "data.test.allow == true"))
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected translation errors but got %v", err)
+			}
+			if len(errs) != len(tc.errs) {
+				t.Fatalf("expected %d errors but got %v", len(tc.errs), errs)
+			}
+			for i := range errs {
+				if errs[i].Code != TranslationErr || !strings.Contains(errs[i].Message, tc.errs[i]) {
+					t.Errorf("expected error %q but got %v", tc.errs[i], errs[i])
+				}
+			}
+			if errs[0].Location == nil || errs[0].Location.Row != tc.row {
+				t.Errorf("expected error on row %d but got location %v", tc.row, errs[0].Location)
+			}
+		})
+	}
+}
+
+func TestSQLErrors(t *testing.T) {
+	f, err := Translate(partial(t, `package test
+allow if input.fruits.tags == ["red"]`, "data.test.allow == true"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.SQL(Postgres)
+	if err == nil || !strings.Contains(err.Error(), "collections cannot be compared in SQL") {
+		t.Fatalf("expected collection error but got %v", err)
+	}
+
+	if _, err := f.SQL("oracle"); err == nil {
+		t.Fatal("expected error for unsupported dialect")
+	}
+}
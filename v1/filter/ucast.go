@@ -0,0 +1,58 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+// UCAST node types.
+const (
+	UCASTCompound = "compound"
+	UCASTField    = "field"
+)
+
+// UCAST is a node of a structured condition tree. Compound nodes combine the
+// nodes in their value with the "and", "or" or "not" operator. Field nodes
+// compare a field with a value; if the value is compared with another field,
+// the value is an object with a single "field" key.
+type UCAST struct {
+	Type     string `json:"type"`
+	Operator string `json:"operator"`
+	Field    string `json:"field,omitempty"`
+	Value    any    `json:"value"`
+}
+
+// UCAST renders f as a condition tree. An unconditional filter is represented
+// as a conjunction without operands and a filter that never matches as a
+// disjunction without operands.
+func (f *Filter) UCAST() *UCAST {
+	return toUCAST(f.root)
+}
+
+func toUCAST(n *node) *UCAST {
+	switch n.kind {
+	case kindTrue:
+		return &UCAST{Type: UCASTCompound, Operator: "and", Value: []*UCAST{}}
+	case kindFalse:
+		return &UCAST{Type: UCASTCompound, Operator: "or", Value: []*UCAST{}}
+	case kindAnd, kindOr, kindNot:
+		op := "and"
+		switch n.kind {
+		case kindOr:
+			op = "or"
+		case kindNot:
+			op = "not"
+		}
+		children := make([]*UCAST, len(n.children))
+		for i, c := range n.children {
+			children[i] = toUCAST(c)
+		}
+		return &UCAST{Type: UCASTCompound, Operator: op, Value: children}
+	}
+
+	value := n.value
+	if n.other != nil {
+		value = map[string]any{"field": n.other.String()}
+	}
+
+	return &UCAST{Type: UCASTField, Operator: n.op, Field: n.field.String(), Value: value}
+}
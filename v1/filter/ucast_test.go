@@ -0,0 +1,86 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+func TestUCAST(t *testing.T) {
+	tests := []struct {
+		note   string
+		module string
+		exp    string
+	}{
+		{
+			note: "field",
+			module: `package test
+				allow if input.fruits.name == "apple"`,
+			exp: `{"type": "field", "operator": "eq", "field": "fruits.name", "value": "apple"}`,
+		},
+		{
+			note: "compound",
+			module: `package test
+				allow if {
+					not input.fruits.name in ["apple", "banana"]
+					input.fruits.owner == input.owners.id
+				}
+				allow if startswith(input.fruits.name, "ch")`,
+			exp: `{"type": "compound", "operator": "or", "value": [
+				{"type": "compound", "operator": "and", "value": [
+					{"type": "compound", "operator": "not", "value": [
+						{"type": "field", "operator": "in", "field": "fruits.name", "value": ["apple", "banana"]}
+					]},
+					{"type": "field", "operator": "eq", "field": "fruits.owner", "value": {"field": "owners.id"}}
+				]},
+				{"type": "field", "operator": "startswith", "field": "fruits.name", "value": "ch"}
+			]}`,
+		},
+		{
+			note: "null",
+			module: `package test
+				allow if input.fruits.deleted != null`,
+			exp: `{"type": "field", "operator": "ne", "field": "fruits.deleted", "value": null}`,
+		},
+		{
+			note: "unconditional",
+			module: `package test
+				allow := true`,
+			exp: `{"type": "compound", "operator": "and", "value": []}`,
+		},
+		{
+			note: "never",
+			module: `package test
+				allow if false`,
+			exp: `{"type": "compound", "operator": "or", "value": []}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			f, err := Translate(partial(t, tc.module, "data.test.allow == true"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs, err := json.Marshal(f.UCAST())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result, exp any
+			if err := util.UnmarshalJSON(bs, &result); err != nil {
+				t.Fatal(err)
+			}
+			if err := util.UnmarshalJSON([]byte(tc.exp), &exp); err != nil {
+				t.Fatal(err)
+			}
+			if util.Compare(result, exp) != 0 {
+				t.Fatalf("expected:\n\n%v\n\ngot:\n\n%s", tc.exp, bs)
+			}
+		})
+	}
+}
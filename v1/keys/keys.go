@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/IUAD1IY7/opa/v1/util"
 )
@@ -29,6 +30,12 @@ type Config struct {
 	PrivateKey string `json:"private_key"`
 	Algorithm  string `json:"algorithm"`
 	Scope      string `json:"scope"`
+
+	// NotBefore and NotAfter optionally bound the time window, in RFC 3339
+	// format, during which the key is accepted for verification. Overlapping
+	// windows allow keys to be rotated without rejecting bundles.
+	NotBefore string `json:"not_before,omitempty"`
+	NotAfter  string `json:"not_after,omitempty"`
 }
 
 // Equal returns true if this key config is equal to the other.
@@ -49,9 +56,52 @@ func (k *Config) validateAndInjectDefaults(id string) error {
 		return fmt.Errorf("unsupported algorithm '%v'", k.Algorithm)
 	}
 
+	notBefore, notAfter, err := k.window()
+	if err != nil {
+		return fmt.Errorf("invalid keys configuration for key ID %v: %w", id, err)
+	}
+
+	if !notBefore.IsZero() && !notAfter.IsZero() && !notBefore.Before(notAfter) {
+		return fmt.Errorf("invalid keys configuration for key ID %v: not_before must be before not_after", id)
+	}
+
+	return nil
+}
+
+// ValidAt returns an error if the key isn't accepted at the given time.
+func (k *Config) ValidAt(t time.Time) error {
+	notBefore, notAfter, err := k.window()
+	if err != nil {
+		return err
+	}
+
+	if !notBefore.IsZero() && t.Before(notBefore) {
+		return fmt.Errorf("key not valid before %v", k.NotBefore)
+	}
+
+	if !notAfter.IsZero() && !t.Before(notAfter) {
+		return fmt.Errorf("key expired at %v", k.NotAfter)
+	}
+
 	return nil
 }
 
+func (k *Config) window() (notBefore, notAfter time.Time, err error) {
+	if k.NotBefore != "" {
+		if notBefore, err = time.Parse(time.RFC3339, k.NotBefore); err != nil {
+			return notBefore, notAfter, fmt.Errorf("invalid not_before: %w", err)
+		}
+	}
+
+	if k.NotAfter != "" {
+		if notAfter, err = time.Parse(time.RFC3339, k.NotAfter); err != nil {
+			return notBefore, notAfter, fmt.Errorf("invalid not_after: %w", err)
+		}
+	}
+
+	return notBefore, notAfter, nil
+}
+
 // NewKeyConfig return a new Config
 func NewKeyConfig(key, alg, scope string) (*Config, error) {
 	var pubKey string
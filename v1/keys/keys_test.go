@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/IUAD1IY7/opa/v1/util/test"
 )
@@ -48,6 +49,21 @@ func TestParseKeysConfig(t *testing.T) {
 			map[string]*Config{"foo": {Key: "FdFYFzERwC2uCBB46pZQi4GG85LujR8obt-KWRBICVQ", Algorithm: "RS256"}},
 			false, nil,
 		},
+		"valid_config_rotation_window": {
+			`{"foo": {"key": "secret", "algorithm": "HS256", "not_before": "2025-01-01T00:00:00Z", "not_after": "2026-01-01T00:00:00Z"}}`,
+			map[string]*Config{"foo": {Key: "secret", Algorithm: "HS256", NotBefore: "2025-01-01T00:00:00Z", NotAfter: "2026-01-01T00:00:00Z"}},
+			false, nil,
+		},
+		"invalid_config_rotation_window": {
+			`{"foo": {"key": "secret", "algorithm": "HS256", "not_before": "2026-01-01T00:00:00Z", "not_after": "2025-01-01T00:00:00Z"}}`,
+			nil,
+			true, errors.New("invalid keys configuration for key ID foo: not_before must be before not_after"),
+		},
+		"invalid_config_rotation_time": {
+			`{"foo": {"key": "secret", "algorithm": "HS256", "not_after": "tomorrow"}}`,
+			nil,
+			true, nil,
+		},
 		"invalid_raw_key_config": {
 			`{"bar": [1,2,3]}`,
 			nil,
@@ -83,6 +99,42 @@ func TestParseKeysConfig(t *testing.T) {
 	}
 }
 
+func TestKeyConfigValidAt(t *testing.T) {
+	kc := &Config{NotBefore: "2025-01-01T00:00:00Z", NotAfter: "2026-01-01T00:00:00Z"}
+
+	tests := []struct {
+		note string
+		at   string
+		err  string
+	}{
+		{note: "before window", at: "2024-12-31T23:59:59Z", err: "key not valid before 2025-01-01T00:00:00Z"},
+		{note: "start of window", at: "2025-01-01T00:00:00Z"},
+		{note: "in window", at: "2025-06-01T00:00:00Z"},
+		{note: "end of window", at: "2026-01-01T00:00:00Z", err: "key expired at 2026-01-01T00:00:00Z"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tc.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = kc.ValidAt(at)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("Unexpected error %v", err)
+			case tc.err != "" && (err == nil || err.Error() != tc.err):
+				t.Fatalf("Expected error %v but got %v", tc.err, err)
+			}
+		})
+	}
+
+	if err := (&Config{}).ValidAt(time.Now()); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+}
+
 func TestNewKeyConfig(t *testing.T) {
 	publicKey := `-----BEGIN PUBLIC KEY-----
 MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA9KaakMv1XKKDaSch3PFR
@@ -5,6 +5,7 @@
 package bundle
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"path"
@@ -19,6 +20,10 @@ import (
 	"github.com/IUAD1IY7/opa/v1/util"
 )
 
+// errShadowLegacy is returned for shadow configuration in the legacy single
+// bundle configuration, which does not support shadow evaluation.
+var errShadowLegacy = errors.New("invalid configuration for bundle: shadow evaluation requires the `bundles` configuration")
+
 // ParseConfig validates the config and injects default values. This is
 // for the legacy single bundle configuration. This will add the bundle
 // to the `Bundles` map to provide compatibility with newer clients.
@@ -34,6 +39,16 @@ func ParseConfig(config []byte, services []string) (*Config, error) {
 		return nil, err
 	}
 
+	var shadow struct {
+		Shadow any `json:"shadow"`
+	}
+	if err := util.Unmarshal(config, &shadow); err != nil {
+		return nil, err
+	}
+	if shadow.Shadow != nil {
+		return nil, errShadowLegacy
+	}
+
 	if err := parsedConfig.validateAndInjectDefaults(services, nil, nil); err != nil {
 		return nil, err
 	}
@@ -147,10 +162,13 @@ type Source struct {
 	download.Config
 
 	Service        string                     `json:"service"`
+	Services       []string                   `json:"services,omitempty"`
 	Resource       string                     `json:"resource"`
 	Signing        *bundle.VerificationConfig `json:"signing"`
 	Persist        bool                       `json:"persist"`
 	SizeLimitBytes int64                      `json:"size_limit_bytes"`
+	Shadow         *ShadowConfig              `json:"shadow,omitempty"`
+	Rollback       *RollbackConfig            `json:"rollback,omitempty"`
 }
 
 // IsMultiBundle returns whether or not the config is the newer multi-bundle
@@ -185,6 +203,19 @@ func (c *Config) validateAndInjectDefaults(services []string, keys map[string]*k
 				return fmt.Errorf("invalid URL for bundle %q: %v", name, err)
 			}
 		} else {
+			if strings.HasPrefix(source.Resource, "s3://") {
+				u, err := url.Parse(source.Resource)
+				if err != nil {
+					return fmt.Errorf("invalid URL for bundle %q: %v", name, err)
+				} else if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+					return fmt.Errorf("invalid URL for bundle %q: want s3://<bucket>/<key>", name)
+				}
+			}
+
+			if err := source.validateServices(services); err != nil {
+				return fmt.Errorf("invalid configuration for bundle %q: %w", name, err)
+			}
+
 			svc, err := c.getServiceFromList(source.Service, services)
 			if err != nil {
 				return fmt.Errorf("invalid configuration for bundle %q: %s", name, err.Error())
@@ -206,8 +237,52 @@ func (c *Config) validateAndInjectDefaults(services []string, keys map[string]*k
 		if source.SizeLimitBytes <= 0 {
 			source.SizeLimitBytes = bundle.DefaultSizeLimitBytes
 		}
+
+		if source.Shadow != nil {
+			if !c.IsMultiBundle() {
+				return errShadowLegacy
+			}
+			if err := source.Shadow.validateAndInjectDefaults(); err != nil {
+				return fmt.Errorf("invalid configuration for bundle %q: %w", name, err)
+			}
+		}
+
+		if source.Rollback != nil {
+			if err := source.Rollback.validateAndInjectDefaults(); err != nil {
+				return fmt.Errorf("invalid configuration for bundle %q: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateServices checks the ordered list of services mirroring the bundle,
+// and makes the first one the service of the source.
+func (s *Source) validateServices(services []string) error {
+	if len(s.Services) == 0 {
+		return nil
+	}
+
+	if s.Service != "" && s.Service != s.Services[0] {
+		return errors.New("specify either service or services")
 	}
 
+	for i, svc := range s.Services {
+		if !slices.Contains(services, svc) {
+			return fmt.Errorf("service name %q not found", svc)
+		}
+		if slices.Contains(s.Services[:i], svc) {
+			return fmt.Errorf("duplicate service name %q in services", svc)
+		}
+	}
+
+	if len(s.Services) > 1 && s.Signing == nil {
+		return errors.New("bundles downloaded from multiple services must be signed")
+	}
+
+	s.Service = s.Services[0]
+
 	return nil
 }
 
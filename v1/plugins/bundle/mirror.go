@@ -0,0 +1,224 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/download"
+)
+
+// mirrorLoader downloads a bundle from an ordered list of services mirroring
+// it. All the services are polled, but only the updates of the first healthy
+// one are activated. The last snapshot downloaded from each of the others is
+// kept as a hot standby, so that a failing service is failed over from without
+// waiting for the next poll.
+type mirrorLoader struct {
+	plugin   *Plugin
+	name     string
+	services []string
+	loaders  []Loader
+	sources  []*mirrorSource // guarded by the plugin mutex
+}
+
+// mirrorSource tracks the health and the downloads of a mirroring service.
+type mirrorSource struct {
+	status     SourceStatus
+	revision   string           // revision of the last bundle downloaded
+	signatures []string         // signatures of the last bundle downloaded
+	standby    *download.Update // last snapshot downloaded while not preferred
+	raw        []byte           // raw standby snapshot, if it's persisted
+}
+
+func (p *Plugin) newMirrorLoader(name string, source *Source, bundles map[string]*Source) *mirrorLoader {
+	m := &mirrorLoader{
+		plugin:   p,
+		name:     name,
+		services: source.Services,
+	}
+
+	for i, service := range source.Services {
+		m.loaders = append(m.loaders, p.newServiceDownloader(name, service, source, bundles, func(ctx context.Context, u download.Update) {
+			m.oneShot(ctx, i, u)
+		}))
+		m.sources = append(m.sources, &mirrorSource{
+			status: SourceStatus{Service: service, Healthy: true},
+		})
+	}
+
+	return m
+}
+
+func (m *mirrorLoader) Start(ctx context.Context) {
+	for _, l := range m.loaders {
+		l.Start(ctx)
+	}
+}
+
+func (m *mirrorLoader) Stop(ctx context.Context) {
+	for _, l := range m.loaders {
+		l.Stop(ctx)
+	}
+}
+
+// Trigger triggers a download from all the services, and only fails if none of
+// them succeeded.
+func (m *mirrorLoader) Trigger(ctx context.Context) error {
+	var errs []error
+	for i, l := range m.loaders {
+		if err := l.Trigger(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("service %q: %w", m.services[i], err))
+		}
+	}
+	if len(errs) < len(m.loaders) {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+func (m *mirrorLoader) SetCache(etag string) {
+	for _, l := range m.loaders {
+		l.SetCache(etag)
+	}
+}
+
+func (m *mirrorLoader) ClearCache() {
+	for _, l := range m.loaders {
+		l.ClearCache()
+	}
+}
+
+func (m *mirrorLoader) oneShot(ctx context.Context, i int, u download.Update) {
+	p := m.plugin
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if _, ok := p.status[m.name]; !ok {
+		return
+	}
+
+	src := m.sources[i]
+	src.status.LastRequest = time.Now().UTC()
+
+	if u.Error == nil && u.Bundle != nil {
+		u.Error = m.checkSignatures(i, u.Bundle)
+	}
+
+	previous := m.preferred()
+
+	if u.Error != nil {
+		src.status.Healthy = false
+		src.status.Message = u.Error.Error()
+	} else {
+		src.status.Healthy = true
+		src.status.Message = ""
+		src.status.LastSuccessfulRequest = src.status.LastRequest
+		if u.Bundle != nil {
+			src.revision = u.Bundle.Manifest.Revision
+			src.signatures = u.Bundle.Signatures.Signatures
+		}
+	}
+
+	preferred := m.preferred()
+
+	switch {
+	case preferred == i || preferred < 0:
+		// Updates of the preferred service are processed as usual, and so are
+		// errors when no service is healthy, to report them.
+		if preferred == i && previous != i {
+			p.log(m.name).Info("Bundle service %v is healthy again, preferring it.", m.services[i])
+		}
+		src.standby, src.raw = nil, nil
+		m.process(ctx, i, u)
+
+	case u.Error != nil:
+		p.log(m.name).Warn("Bundle download from service %v failed: %v", m.services[i], u.Error)
+		if previous == i {
+			p.log(m.name).Warn("Failing over to bundle service %v.", m.services[preferred])
+			if standby := m.sources[preferred]; standby.standby != nil {
+				u := *standby.standby
+				if standby.raw != nil {
+					u.Raw = bytes.NewReader(standby.raw)
+				}
+				standby.standby, standby.raw = nil, nil
+				m.process(ctx, preferred, u)
+			}
+		}
+
+	case u.Bundle != nil && u.Bundle.Type() == bundle.SnapshotBundleType:
+		src.standby, src.raw = &u, nil
+		if u.Raw != nil {
+			raw, err := io.ReadAll(u.Raw)
+			if err != nil {
+				p.log(m.name).Warn("Failed to keep bundle downloaded from service %v: %v", m.services[i], err)
+				src.standby = nil
+				m.loaders[i].SetCache("")
+				break
+			}
+			src.raw = raw
+			u.Raw = nil
+		}
+		p.log(m.name).Debug("Bundle revision %v downloaded from standby service %v.", u.Bundle.Manifest.Revision, m.services[i])
+
+	case u.Bundle != nil:
+		// Delta bundles can't be applied on top of a standby snapshot, have
+		// the service send a snapshot next time instead.
+		src.standby, src.raw = nil, nil
+		m.loaders[i].SetCache("")
+	}
+
+	status := p.status[m.name]
+	status.Sources = make([]SourceStatus, len(m.sources))
+	for j, s := range m.sources {
+		status.Sources[j] = s.status
+	}
+
+	p.notifyListeners(m.name)
+}
+
+// process processes the update of the i-th service, and records it as the
+// source of the active revision if it was activated.
+func (m *mirrorLoader) process(ctx context.Context, i int, u download.Update) {
+	status := m.plugin.status[m.name]
+	activation := status.LastSuccessfulActivation
+
+	m.plugin.process(ctx, m.name, u)
+
+	if !status.LastSuccessfulActivation.Equal(activation) {
+		status.Source = m.services[i]
+	}
+}
+
+// preferred returns the index of the first healthy service, or -1 if none of
+// them is.
+func (m *mirrorLoader) preferred() int {
+	return slices.IndexFunc(m.sources, func(s *mirrorSource) bool {
+		return s.status.Healthy
+	})
+}
+
+// checkSignatures returns an error if another service served the same revision
+// of the bundle with different signatures.
+func (m *mirrorLoader) checkSignatures(i int, b *bundle.Bundle) error {
+	revision := b.Manifest.Revision
+	if revision == "" {
+		return nil
+	}
+
+	for j, s := range m.sources {
+		if j != i && s.revision == revision && !slices.Equal(s.signatures, b.Signatures.Signatures) {
+			return fmt.Errorf("bundle revision %q from service %q has different signatures than from service %q", revision, m.services[i], m.services[j])
+		}
+	}
+
+	return nil
+}
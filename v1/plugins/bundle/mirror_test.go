@@ -0,0 +1,217 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/download"
+	"github.com/IUAD1IY7/opa/v1/keys"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+)
+
+func TestMirrorConfig(t *testing.T) {
+	t.Parallel()
+
+	keyConfigs := map[string]*keys.Config{"foo": {Key: "secret", Algorithm: "HS256"}}
+
+	tests := []struct {
+		note    string
+		config  string
+		keys    map[string]*keys.Config
+		exp     string
+		wantErr string
+	}{
+		{
+			note:   "single service",
+			config: `{"services": ["s2"]}`,
+			exp:    "s2",
+		},
+		{
+			note:   "mirrored services",
+			config: `{"services": ["s2", "s1"]}`,
+			keys:   keyConfigs,
+			exp:    "s2",
+		},
+		{
+			note:   "service and services",
+			config: `{"service": "s1", "services": ["s1", "s2"]}`,
+			keys:   keyConfigs,
+			exp:    "s1",
+		},
+		{
+			note:    "conflicting service",
+			config:  `{"service": "s2", "services": ["s1", "s2"]}`,
+			keys:    keyConfigs,
+			wantErr: "specify either service or services",
+		},
+		{
+			note:    "unknown service",
+			config:  `{"services": ["s1", "s3"]}`,
+			keys:    keyConfigs,
+			wantErr: `service name "s3" not found`,
+		},
+		{
+			note:    "duplicate service",
+			config:  `{"services": ["s1", "s1"]}`,
+			keys:    keyConfigs,
+			wantErr: `duplicate service name "s1"`,
+		},
+		{
+			note:    "unsigned",
+			config:  `{"services": ["s1", "s2"]}`,
+			wantErr: "must be signed",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			config, err := NewConfigBuilder().
+				WithBytes([]byte(`{"authz": ` + tc.config + `}`)).
+				WithServices([]string{"s1", "s2"}).
+				WithKeyConfigs(tc.keys).
+				Parse()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "":
+				if svc := config.Bundles["authz"].Service; svc != tc.exp {
+					t.Fatalf("expected service %q but got %q", tc.exp, svc)
+				}
+			}
+		})
+	}
+}
+
+func TestPluginMirrorFailover(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	plugin, m := newMirrorTestPlugin(t)
+
+	// The standby's snapshot is kept while the primary service is healthy.
+	m.oneShot(ctx, 0, download.Update{Bundle: mirrorTestBundle("r1", "1", "sig1"), ETag: "a1"})
+	m.oneShot(ctx, 1, download.Update{Bundle: mirrorTestBundle("r2", "2", "sig2"), ETag: "b2"})
+
+	status := mirrorTestStatus(plugin)
+	if status.ActiveRevision != "r1" || status.Source != "s1" || !status.Sources[0].Healthy || !status.Sources[1].Healthy {
+		t.Fatalf("expected r1 from s1 to be active but got: %+v", status)
+	}
+	if v := evalActive(t, plugin); v != json.Number("1") {
+		t.Fatalf("expected r1 to be active but got %v", v)
+	}
+
+	// The standby's snapshot is activated when the primary service fails.
+	m.oneShot(ctx, 0, download.Update{Error: errors.New("connection refused")})
+
+	status = mirrorTestStatus(plugin)
+	if status.ActiveRevision != "r2" || status.Source != "s2" || status.Message != "" {
+		t.Fatalf("expected r2 from s2 to be active but got: %+v", status)
+	}
+	if status.Sources[0].Healthy || status.Sources[0].Message != "connection refused" || !status.Sources[1].Healthy {
+		t.Fatalf("expected s1 to be unhealthy but got: %+v", status.Sources)
+	}
+	if v := evalActive(t, plugin); v != json.Number("2") {
+		t.Fatalf("expected r2 to be active but got %v", v)
+	}
+
+	// The primary service is preferred again once it recovers.
+	m.oneShot(ctx, 0, download.Update{Bundle: mirrorTestBundle("r3", "3", "sig3"), ETag: "a3"})
+
+	status = mirrorTestStatus(plugin)
+	if status.ActiveRevision != "r3" || status.Source != "s1" || !status.Sources[0].Healthy {
+		t.Fatalf("expected r3 from s1 to be active but got: %+v", status)
+	}
+
+	// A revision served with different signatures makes the service unhealthy.
+	m.oneShot(ctx, 1, download.Update{Bundle: mirrorTestBundle("r3", "4", "forged"), ETag: "b3"})
+
+	status = mirrorTestStatus(plugin)
+	if status.ActiveRevision != "r3" || status.Sources[1].Healthy || !strings.Contains(status.Sources[1].Message, "different signatures") {
+		t.Fatalf("expected s2 to be unhealthy but got: %+v", status)
+	}
+
+	// Errors are reported once no service is healthy.
+	m.oneShot(ctx, 0, download.Update{Error: errors.New("connection refused")})
+
+	status = mirrorTestStatus(plugin)
+	if status.ActiveRevision != "r3" || status.Message != "connection refused" {
+		t.Fatalf("expected error to be reported but got: %+v", status)
+	}
+	if v := evalActive(t, plugin); v != json.Number("3") {
+		t.Fatalf("expected r3 to remain active but got %v", v)
+	}
+}
+
+func TestPluginMirrorDeltaFromStandby(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	plugin, m := newMirrorTestPlugin(t)
+
+	m.oneShot(ctx, 0, download.Update{Bundle: mirrorTestBundle("r1", "1", "sig1"), ETag: "a1"})
+	m.oneShot(ctx, 1, download.Update{Bundle: mirrorTestBundle("r2", "2", "sig2"), ETag: "b2"})
+
+	delta := &bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "r3"},
+		Patch:    bundle.Patch{Data: []bundle.PatchOperation{{Op: "upsert", Path: "/x", Value: 1}}},
+	}
+	m.oneShot(ctx, 1, download.Update{Bundle: delta, ETag: "b3"})
+
+	// Delta bundles aren't kept, so there's nothing to fail over to until the
+	// standby service sends a snapshot.
+	m.oneShot(ctx, 0, download.Update{Error: errors.New("connection refused")})
+
+	status := mirrorTestStatus(plugin)
+	if status.ActiveRevision != "r1" || status.Source != "s1" {
+		t.Fatalf("expected r1 from s1 to remain active but got: %+v", status)
+	}
+}
+
+func newMirrorTestPlugin(t *testing.T) (*Plugin, *mirrorLoader) {
+	t.Helper()
+
+	ctx := context.Background()
+	manager := getTestManagerWithOpts([]byte(`{"services": {"s1": {"url": "http://localhost:1"}, "s2": {"url": "http://localhost:2"}}}`))
+	if err := manager.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := NewConfigBuilder().
+		WithBytes([]byte(`{"test-bundle": {"services": ["s1", "s2"]}}`)).
+		WithServices([]string{"s1", "s2"}).
+		WithKeyConfigs(map[string]*keys.Config{"foo": {Key: "secret", Algorithm: "HS256"}}).
+		Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := New(config, manager)
+	plugin.status["test-bundle"] = &Status{Name: "test-bundle", Metrics: metrics.New()}
+
+	m := plugin.newMirrorLoader("test-bundle", config.Bundles["test-bundle"], config.Bundles)
+	plugin.downloaders["test-bundle"] = m
+
+	return plugin, m
+}
+
+func mirrorTestStatus(plugin *Plugin) Status {
+	plugin.mtx.Lock()
+	defer plugin.mtx.Unlock()
+	return *plugin.status["test-bundle"]
+}
+
+func mirrorTestBundle(revision, value, signature string) *bundle.Bundle {
+	b := shadowTestBundle(revision, value)
+	b.Signatures.Signatures = []string{signature}
+	return b
+}
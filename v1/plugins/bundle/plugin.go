@@ -6,10 +6,14 @@
 package bundle
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"maps"
 	"net/url"
 	"os"
@@ -18,9 +22,13 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+
 	bundleUtils "github.com/IUAD1IY7/opa/internal/bundle"
+	"github.com/IUAD1IY7/opa/internal/pathwatcher"
 	"github.com/IUAD1IY7/opa/internal/ref"
 	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/bundle"
@@ -70,6 +78,17 @@ type Plugin struct {
 	ready             bool
 	bundlePersistPath string
 	stopped           bool
+	shadowMtx         sync.RWMutex
+	shadows           map[string]*shadowCandidate // bundles in shadow evaluation
+	shadowQueue       chan shadowJob              // decisions waiting for shadow evaluation
+	shadowStop        chan struct{}               // closed to stop the shadow workers
+	shadowTrigger     storage.TriggerHandle       // counts the commits to the store
+	shadowCommits     atomic.Uint64               // commits to the store since the shadow workers started
+	shadowRefresh     time.Duration               // minimum time between copies of the store for shadow evaluation
+	activations       map[string]*activation      // last activated revisions of bundles configured for rollbacks
+	lastGood          map[string]*activation      // last known-good revisions of bundles configured for rollbacks
+	probationMtx      sync.RWMutex
+	probations        map[string]*probation // revisions on probation
 }
 
 // New returns a new Plugin with the given config.
@@ -89,6 +108,8 @@ func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 		etags:       make(map[string]string),
 		ready:       false,
 		logger:      manager.Logger(),
+
+		shadowRefresh: defaultShadowRefreshInterval,
 	}
 
 	manager.UpdatePluginStatus(Name, &plugins.Status{State: plugins.StateNotReady})
@@ -137,6 +158,13 @@ func (p *Plugin) Stop(ctx context.Context) {
 	maps.Copy(stopDownloaders, p.downloaders)
 	p.downloaders = nil
 	p.stopped = true
+	for name := range p.shadows {
+		p.dropShadow(name)
+	}
+	p.stopShadowWorkers(ctx)
+	for name := range p.activations {
+		p.forgetActivations(name)
+	}
 	p.mtx.Unlock()
 
 	for name, dl := range stopDownloaders {
@@ -185,6 +213,8 @@ func (p *Plugin) Reconfigure(ctx context.Context, config any) {
 	for name := range p.downloaders {
 		if _, deleted := deletedBundles[name]; deleted {
 			p.log(name).Info("Bundle loader configuration removed. Stopping bundle loader.")
+			p.dropShadow(name)
+			p.forgetActivations(name)
 			delete(p.downloaders, name)
 			delete(p.status, name)
 			delete(p.etags, name)
@@ -228,6 +258,8 @@ func (p *Plugin) Reconfigure(ctx context.Context, config any) {
 				p.log(name).Info("New bundle loader configuration added. Starting bundle loader.")
 			} else {
 				p.log(name).Info("Bundle loader configuration changed. Restarting bundle loader.")
+				p.dropShadow(name)
+				p.forgetActivations(name)
 			}
 
 			downloader := p.newDownloader(name, source, bundles)
@@ -439,15 +471,30 @@ func (p *Plugin) newDownloader(name string, source *Source, bundles map[string]*
 			sizeLimitBytes:   source.SizeLimitBytes,
 			f:                p.oneShot,
 			bundleParserOpts: p.manager.ParserOptions(),
+			trigger:          source.Config.Trigger,
+			logger:           p.log(name),
 		}
 	}
 
-	conf := source.Config
-	client := p.manager.Client(source.Service)
-	path := source.Resource
-	callback := func(ctx context.Context, u download.Update) {
+	if len(source.Services) > 1 {
+		return p.newMirrorLoader(name, source, bundles)
+	}
+
+	return p.newServiceDownloader(name, source.Service, source, bundles, func(ctx context.Context, u download.Update) {
 		// wrap the callback to include the name of the bundle that was updated
 		p.oneShot(ctx, name, u)
+	})
+}
+
+// newServiceDownloader returns a downloader for the bundle from the service.
+func (p *Plugin) newServiceDownloader(name, service string, source *Source, bundles map[string]*Source, callback func(context.Context, download.Update)) Loader {
+	conf := source.Config
+	client := p.manager.Client(service)
+	path := source.Resource
+	if u, err := url.Parse(source.Resource); err == nil && u.Scheme == "s3" {
+		// S3 objects are requested path-style from the service, which makes
+		// them work with S3-compatible stores too.
+		path = s3ObjectPath(u)
 	}
 	if strings.ToLower(client.Config().Type) == "oci" {
 		ociStorePath := filepath.Join(os.TempDir(), "opa", "oci") // use temporary folder /tmp/opa/oci
@@ -476,6 +523,11 @@ func (p *Plugin) oneShot(ctx context.Context, name string, u download.Update) {
 	defer p.mtx.Unlock()
 
 	p.process(ctx, name, u)
+	p.notifyListeners(name)
+}
+
+func (p *Plugin) notifyListeners(name string) {
+	p.updateShadowStatus()
 
 	for _, listener := range p.listeners {
 		listener(*p.status[name])
@@ -526,8 +578,23 @@ func (p *Plugin) process(ctx context.Context, name string, u download.Update) {
 
 		p.cfgMtx.RLock()
 		isMultiBundle := p.config.IsMultiBundle()
+		shadow := p.shadowConfig(name, u.Bundle)
 		p.cfgMtx.RUnlock()
 
+		if shadow != nil {
+			if err := p.startShadow(ctx, name, u, shadow); err != nil {
+				p.log(name).Error("Bundle shadow activation failed: %v", err)
+				p.status[name].SetError(err)
+				if !p.stopped {
+					etag := p.etags[name]
+					p.downloaders[name].SetCache(etag)
+				}
+			}
+			return
+		}
+
+		kept := p.rollbackCopy(name, u.Bundle)
+
 		if err := p.activate(ctx, name, u.Bundle, isMultiBundle); err != nil {
 			p.log(name).Error("Bundle activation failed: %v", err)
 			p.status[name].SetError(err)
@@ -538,35 +605,14 @@ func (p *Plugin) process(ctx context.Context, name string, u download.Update) {
 			return
 		}
 
-		if u.Bundle.Type() == bundle.SnapshotBundleType && p.persistBundle(name, p.getBundlesCpy()) {
-			p.log(name).Debug("Persisting bundle to disk in progress.")
-
-			err := p.saveBundleToDisk(name, u.Raw)
-			if err != nil {
-				p.log(name).Error("Persisting bundle to disk failed: %v", err)
-				p.status[name].SetError(err)
-				if !p.stopped {
-					etag := p.etags[name]
-					p.downloaders[name].SetCache(etag)
-				}
-				return
+		if err := p.completeActivation(name, kept, u.Raw, u.Size, u.ETag); err != nil {
+			p.log(name).Error("Persisting bundle to disk failed: %v", err)
+			p.status[name].SetError(err)
+			if !p.stopped {
+				etag := p.etags[name]
+				p.downloaders[name].SetCache(etag)
 			}
-			p.log(name).Debug("Bundle persisted to disk successfully at path %v.", filepath.Join(p.bundlePersistPath, name))
 		}
-
-		p.status[name].SetError(nil)
-		p.status[name].SetActivateSuccess(u.Bundle.Manifest.Revision)
-		p.status[name].SetBundleSize(u.Size)
-
-		if u.ETag != "" {
-			p.log(name).Info("Bundle loaded and activated successfully. Etag updated to %v.", u.ETag)
-		} else {
-			p.log(name).Info("Bundle loaded and activated successfully.")
-		}
-		p.etags[name] = u.ETag
-
-		// If the plugin wasn't ready yet then check if we are now after activating this bundle.
-		p.checkPluginReadiness()
 		return
 	}
 
@@ -580,6 +626,48 @@ func (p *Plugin) process(ctx context.Context, name string, u download.Update) {
 	}
 }
 
+// completeActivation persists an activated bundle, if configured, and records
+// the activation in the status.
+func (p *Plugin) completeActivation(name string, b *bundle.Bundle, raw io.Reader, size int, etag string) error {
+	var rawBytes []byte
+
+	if b.Type() == bundle.SnapshotBundleType && p.persistBundle(name, p.getBundlesCpy()) {
+		p.log(name).Debug("Persisting bundle to disk in progress.")
+
+		// Keep the raw bundle to persist it again if it's rolled back to.
+		if p.rollbackConfig(name) != nil && raw != nil {
+			var err error
+			if rawBytes, err = io.ReadAll(raw); err != nil {
+				return err
+			}
+			raw = bytes.NewReader(rawBytes)
+		}
+
+		if err := p.saveBundleToDisk(name, raw); err != nil {
+			return err
+		}
+		p.log(name).Debug("Bundle persisted to disk successfully at path %v.", filepath.Join(p.bundlePersistPath, name))
+	}
+
+	p.status[name].SetError(nil)
+	p.status[name].SetActivateSuccess(b.Manifest.Revision)
+	p.status[name].SetBundleSize(size)
+
+	if etag != "" {
+		p.log(name).Info("Bundle loaded and activated successfully. Etag updated to %v.", etag)
+	} else {
+		p.log(name).Info("Bundle loaded and activated successfully.")
+	}
+	p.etags[name] = etag
+
+	p.trackActivation(name, b, rawBytes, size, etag)
+
+	// If the plugin wasn't ready yet then check if we are now after activating this bundle.
+	p.checkPluginReadiness()
+
+	return nil
+}
+
 func (p *Plugin) checkPluginReadiness() {
 	if !p.ready {
 		readyNow := true // optimistically
@@ -598,12 +686,21 @@ func (p *Plugin) checkPluginReadiness() {
 }
 
 func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, isMultiBundle bool) error {
+	_, err := p.activateOn(ctx, p.manager.Store, name, b, isMultiBundle, p.status[name].Metrics)
+	return err
+}
+
+// activateOn activates the bundle in the given store and returns the compiler
+// holding the resulting policies.
+func (p *Plugin) activateOn(ctx context.Context, store storage.Store, name string, b *bundle.Bundle, isMultiBundle bool, m metrics.Metrics) (*ast.Compiler, error) {
 	p.log(name).Debug("Bundle activation in progress (%v). Opening storage transaction.", b.Manifest.Revision)
 
 	params := storage.WriteParams
-	params.Context = storage.NewContext().WithMetrics(p.status[name].Metrics)
+	params.Context = storage.NewContext().WithMetrics(m)
 
-	err := storage.Txn(ctx, p.manager.Store, params, func(txn storage.Transaction) error {
+	var compiler *ast.Compiler
+
+	err := storage.Txn(ctx, store, params, func(txn storage.Transaction) error {
 		p.log(name).Debug("Opened storage transaction (%v).", txn.ID())
 		defer p.log(name).Debug("Closing storage transaction (%v).", txn.ID())
 
@@ -611,7 +708,6 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 		// transaction params for use by onCommit hooks.
 		// If activating a delta bundle, use the manager's compiler which should have
 		// the polices compiled on it.
-		var compiler *ast.Compiler
 		if b.Type() == bundle.DeltaBundleType {
 			compiler = p.manager.GetCompiler()
 		}
@@ -620,7 +716,7 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 			compiler = ast.NewCompiler()
 		}
 
-		compiler = compiler.WithPathConflictsCheck(storage.NonEmpty(ctx, p.manager.Store, txn)).
+		compiler = compiler.WithPathConflictsCheck(storage.NonEmpty(ctx, store, txn)).
 			WithEnablePrintStatements(p.manager.EnablePrintStatements())
 
 		if b.Manifest.Roots != nil {
@@ -631,11 +727,11 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 
 		opts := &bundle.ActivateOpts{
 			Ctx:           ctx,
-			Store:         p.manager.Store,
+			Store:         store,
 			Txn:           txn,
 			TxnCtx:        params.Context,
 			Compiler:      compiler,
-			Metrics:       p.status[name].Metrics,
+			Metrics:       m,
 			Bundles:       map[string]*bundle.Bundle{name: b},
 			ParserOptions: p.manager.ParserOptions(),
 		}
@@ -662,7 +758,7 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 
 		plugins.SetCompilerOnContext(params.Context, compiler)
 
-		resolvers, err := bundleUtils.LoadWasmResolversFromStore(ctx, p.manager.Store, txn, nil)
+		resolvers, err := bundleUtils.LoadWasmResolversFromStore(ctx, store, txn, nil)
 		if err != nil {
 			return err
 		}
@@ -672,7 +768,7 @@ func (p *Plugin) activate(ctx context.Context, name string, b *bundle.Bundle, is
 		return activateErr
 	})
 
-	return err
+	return compiler, err
 }
 
 func (*Plugin) persistBundle(name string, bundles map[string]*Source) bool {
@@ -802,6 +898,17 @@ func isReservedCharacter(r rune) bool {
 	return r == '<' || r == '>' || r == ':' || r == '"' || r == '/' || r == '\\' || r == '|' || r == '?' || r == '*'
 }
 
+// s3ObjectPath returns the path-style request path of the object referenced by
+// an s3://bucket/key URL.
+func s3ObjectPath(u *url.URL) string {
+	return u.Host + "/" + strings.TrimPrefix(u.Path, "/")
+}
+
+// fileBundleWatchDebounce is the time the file loader waits for further
+// changes to the watched path before reloading the bundle, so that a bundle
+// being written isn't read half way through.
+const fileBundleWatchDebounce = 200 * time.Millisecond
+
 type fileLoader struct {
 	name             string
 	path             string
@@ -809,24 +916,74 @@ type fileLoader struct {
 	sizeLimitBytes   int64
 	f                func(context.Context, string, download.Update)
 	bundleParserOpts ast.ParserOptions
+	trigger          *plugins.TriggerMode
+	logger           logging.Logger
+
+	loadMtx sync.Mutex // serializes loads
+	mtx     sync.Mutex // guards the fields below
+	etag    string
+	watcher *fsnotify.Watcher
+	stop    chan struct{} // closed when the loader is stopped
+	stopped bool
 }
 
 func (fl *fileLoader) Start(ctx context.Context) {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
+
+	if fl.stopped {
+		return
+	}
+	if fl.stop == nil {
+		fl.stop = make(chan struct{})
+	}
+	stop := fl.stop
+
 	go func() {
 		fl.oneShot(ctx)
+
+		if fl.trigger != nil && *fl.trigger == plugins.TriggerManual {
+			return
+		}
+
+		if err := fl.watch(ctx, stop); err != nil {
+			fl.logger.Error("Failed to watch bundle path %v: %v", fl.path, err)
+		}
 	}()
 }
 
-func (*fileLoader) Stop(context.Context) {
+// Stop stops watching the bundle path. A loader that's stopped while the
+// bundle is first loaded doesn't start watching the path afterwards.
+func (fl *fileLoader) Stop(context.Context) {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
 
+	if fl.stopped {
+		return
+	}
+	fl.stopped = true
+
+	if fl.stop != nil {
+		close(fl.stop)
+	}
+	if fl.watcher != nil {
+		fl.watcher.Close()
+		fl.watcher = nil
+	}
 }
 
-func (*fileLoader) ClearCache() {
+func (fl *fileLoader) ClearCache() {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
 
+	fl.etag = ""
 }
 
-func (*fileLoader) SetCache(string) {
+func (fl *fileLoader) SetCache(etag string) {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
 
+	fl.etag = etag
 }
 
 func (fl *fileLoader) Trigger(ctx context.Context) error {
@@ -834,7 +991,97 @@ func (fl *fileLoader) Trigger(ctx context.Context) error {
 	return nil
 }
 
+// watch reloads the bundle whenever the watched path changes, until the
+// loader is stopped or the context is done.
+func (fl *fileLoader) watch(ctx context.Context, stop <-chan struct{}) error {
+	watcher, err := pathwatcher.CreatePathWatcher([]string{fl.path})
+	if err != nil {
+		return err
+	}
+
+	fl.mtx.Lock()
+	if fl.stopped || fl.watcher != nil {
+		fl.mtx.Unlock()
+		watcher.Close()
+		return nil
+	}
+	fl.watcher = watcher
+	fl.mtx.Unlock()
+
+	fl.logger.Debug("Watching bundle path %v for changes.", fl.path)
+
+	go func() {
+		timer := time.NewTimer(fileBundleWatchDebounce)
+		timer.Stop()
+
+		for {
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-ctx.Done():
+				timer.Stop()
+				fl.closeWatcher(watcher)
+				return
+			case evt, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !evt.Has(fsnotify.Create) && !evt.Has(fsnotify.Write) && !evt.Has(fsnotify.Remove) && !evt.Has(fsnotify.Rename) {
+					continue
+				}
+				if evt.Has(fsnotify.Create) {
+					// Directories created below a watched directory are
+					// watched too.
+					if info, err := os.Stat(evt.Name); err == nil && info.IsDir() {
+						if err := watcher.Add(evt.Name); err != nil {
+							fl.logger.Warn("Failed to watch %v: %v", evt.Name, err)
+						}
+					}
+				}
+				timer.Reset(fileBundleWatchDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fl.logger.Warn("Bundle path watcher error: %v", err)
+			case <-timer.C:
+				select {
+				case <-stop:
+					return
+				default:
+					fl.load(ctx, false)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// closeWatcher closes the watcher, unless the loader was stopped and closed
+// it already.
+func (fl *fileLoader) closeWatcher(watcher *fsnotify.Watcher) {
+	fl.mtx.Lock()
+	defer fl.mtx.Unlock()
+
+	if fl.watcher == watcher {
+		fl.watcher.Close()
+		fl.watcher = nil
+	}
+}
+
 func (fl *fileLoader) oneShot(ctx context.Context) {
+	fl.load(ctx, true)
+}
+
+// load reads the bundle from disk and passes it to the callback. The bundle
+// isn't read again if its content hash matches the cached etag; the callback
+// is then only invoked, without a bundle, if reportUnchanged is set.
+func (fl *fileLoader) load(ctx context.Context, reportUnchanged bool) {
+	fl.loadMtx.Lock()
+	defer fl.loadMtx.Unlock()
+
 	var u download.Update
 	u.Metrics = metrics.New()
 
@@ -845,6 +1092,25 @@ func (fl *fileLoader) oneShot(ctx context.Context) {
 		return
 	}
 
+	etag, err := hashBundlePath(fl.path, info.IsDir())
+	u.Error = err
+	if err != nil {
+		fl.f(ctx, fl.name, u)
+		return
+	}
+
+	fl.mtx.Lock()
+	cached := fl.etag
+	fl.mtx.Unlock()
+
+	if etag == cached {
+		if reportUnchanged {
+			u.ETag = etag
+			fl.f(ctx, fl.name, u)
+		}
+		return
+	}
+
 	var reader *bundle.Reader
 
 	if info.IsDir() {
@@ -858,6 +1124,14 @@ func (fl *fileLoader) oneShot(ctx context.Context) {
 		}
 		defer f.Close()
 		reader = bundle.NewReader(f)
+
+		// The tarball is opened again to be persisted as is.
+		raw, err := os.Open(fl.path)
+		if err == nil {
+			defer raw.Close()
+			u.Raw = raw
+			u.Size = int(info.Size())
+		}
 	}
 
 	b, err := reader.
@@ -865,10 +1139,62 @@ func (fl *fileLoader) oneShot(ctx context.Context) {
 		WithBundleVerificationConfig(fl.bvc).
 		WithSizeLimitBytes(fl.sizeLimitBytes).
 		WithRegoVersion(fl.bundleParserOpts.RegoVersion).
+		WithBundleEtag(etag).
 		Read()
 	u.Error = err
 	if err == nil {
 		u.Bundle = &b
+		u.ETag = etag
+		fl.SetCache(etag)
+	} else {
+		u.Raw = nil
 	}
 	fl.f(ctx, fl.name, u)
 }
+
+// hashBundlePath returns the hex encoded SHA-256 hash of the bundle at path,
+// used as the etag of file bundles. For directories, the hash covers the
+// relative paths and contents of all files below it.
+func hashBundlePath(path string, isDir bool) (string, error) {
+	h := sha256.New()
+
+	if !isDir {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	// WalkDir visits files in lexical order, so the hash is stable.
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(path, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -8,6 +8,7 @@ package bundle
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -20,6 +21,7 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -5776,6 +5778,310 @@ func TestPluginUsingDirectoryLoader(t *testing.T) {
 	})
 }
 
+func TestPluginFileLoaderWatch(t *testing.T) {
+	t.Parallel()
+
+	writeTarball := func(t *testing.T, name string, b *bundle.Bundle) {
+		t.Helper()
+
+		// Write the bundle next to the watched one and move it in place, as
+		// tools publishing bundles to shared volumes do.
+		tmp := name + ".tmp"
+		f, err := os.Create(tmp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bundle.NewWriter(f).Write(*b); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+
+		if err := os.Rename(tmp, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeDir := func(t *testing.T, dir string, b *bundle.Bundle) {
+		t.Helper()
+
+		manifest := fmt.Sprintf(`{"revision": %q}`, b.Manifest.Revision)
+		if err := os.WriteFile(filepath.Join(dir, ".manifest"), []byte(manifest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "example.rego"), b.Modules[0].Raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		note  string
+		path  func(dir string) string
+		write func(t *testing.T, path string, b *bundle.Bundle)
+	}{
+		{
+			note:  "tarball",
+			path:  func(dir string) string { return filepath.Join(dir, "bundle.tar.gz") },
+			write: writeTarball,
+		},
+		{
+			note: "directory",
+			path: func(dir string) string {
+				p := filepath.Join(dir, "bundle")
+				if err := os.Mkdir(p, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				return p
+			},
+			write: writeDir,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			ctx := context.Background()
+			bundlePath := tc.path(t.TempDir())
+			tc.write(t, bundlePath, shadowTestBundle("r1", "1"))
+
+			manager := getTestManager()
+			if err := manager.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			p := New(&Config{Bundles: map[string]*Source{
+				"test": {
+					SizeLimitBytes: 1e5,
+					Resource:       "file://" + bundlePath,
+				},
+			}}, manager)
+
+			ch := make(chan Status, 10)
+			p.Register("test", func(s Status) {
+				ch <- s
+			})
+
+			if err := p.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+			defer p.Stop(ctx)
+
+			waitForRevision := func(revision string) Status {
+				t.Helper()
+				for {
+					select {
+					case s := <-ch:
+						if s.ActiveRevision == revision {
+							return s
+						}
+					case <-time.After(10 * time.Second):
+						t.Fatalf("timed out waiting for revision %v to be activated", revision)
+					}
+				}
+			}
+
+			s := waitForRevision("r1")
+			if s.Code != "" || s.LastSuccessfulActivation.IsZero() {
+				t.Fatalf("unexpected status: %+v", s)
+			}
+
+			tc.write(t, bundlePath, shadowTestBundle("r2", "2"))
+			waitForRevision("r2")
+
+			if v := evalActive(t, p); v != json.Number("2") {
+				t.Fatalf("expected r2 to be active but got %v", v)
+			}
+
+			// Triggering the loader without changes to the bundle doesn't
+			// activate it again.
+			if err := p.Trigger(ctx); err != nil {
+				t.Fatal(err)
+			}
+			s = <-ch
+			if s.ActiveRevision != "r2" || s.LastSuccessfulActivation.After(s.LastSuccessfulRequest) {
+				t.Fatalf("unexpected status: %+v", s)
+			}
+		})
+	}
+}
+
+func TestPluginFileLoaderStopWhileLoading(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+
+	write := func(b *bundle.Bundle) {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := bundle.NewWriter(&buf).Write(*b); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(bundlePath, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(shadowTestBundle("r1", "1"))
+
+	var mtx sync.Mutex
+	var loads int
+	loading := make(chan struct{})
+	release := make(chan struct{})
+
+	fl := &fileLoader{
+		name:           "test",
+		path:           bundlePath,
+		sizeLimitBytes: 1e5,
+		logger:         logging.NewNoOpLogger(),
+		f: func(context.Context, string, download.Update) {
+			mtx.Lock()
+			loads++
+			first := loads == 1
+			mtx.Unlock()
+			if first {
+				close(loading)
+				<-release
+			}
+		},
+	}
+
+	fl.Start(ctx)
+	<-loading
+
+	// The loader is stopped while the bundle is first loaded.
+	fl.Stop(ctx)
+	close(release)
+
+	time.Sleep(fileBundleWatchDebounce)
+	write(shadowTestBundle("r2", "2"))
+	time.Sleep(4 * fileBundleWatchDebounce)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if loads != 1 {
+		t.Fatalf("expected no reload after the loader was stopped but got %d loads", loads)
+	}
+}
+
+func TestPluginS3Source(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	if err := bundle.NewWriter(&buf).Write(*shadowTestBundle("r1", "1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stub serves a single object, like an S3-compatible store, and
+	// checks that requests are signed.
+	var requests, notModified int
+	var mtx sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests++
+
+		if r.URL.Path != "/policies/authz/bundle.tar.gz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=minio/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"r1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"r1"`)
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	credentials := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(credentials, []byte("[default]\naws_access_key_id=minio\naws_secret_access_key=minio123\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := getTestManagerWithOpts(fmt.Appendf(nil, `{
+		"services": {
+			"minio": {
+				"url": %q,
+				"credentials": {
+					"s3_signing": {
+						"profile_credentials": {"path": %q, "aws_region": "us-east-1"}
+					}
+				}
+			}
+		}
+	}`, ts.URL, credentials))
+	if err := manager.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	trigger := plugins.TriggerManual
+	config, err := NewConfigBuilder().
+		WithBytes([]byte(`{"authz": {"service": "minio", "resource": "s3://policies/authz/bundle.tar.gz"}}`)).
+		WithServices(manager.Services()).
+		WithTriggerMode(&trigger).
+		Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New(config, manager)
+	if err := p.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop(ctx)
+
+	for range 2 {
+		if err := p.Trigger(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.mtx.Lock()
+	status := *p.status["authz"]
+	p.mtx.Unlock()
+
+	if status.ActiveRevision != "r1" || status.Code != "" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if v := evalActive(t, p); v != json.Number("1") {
+		t.Fatalf("expected r1 to be active but got %v", v)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if requests != 2 || notModified != 1 {
+		t.Fatalf("expected one download and one not modified response but got %d requests", requests)
+	}
+}
+
+func TestS3SourceConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note     string
+		resource string
+		wantErr  bool
+	}{
+		{note: "object", resource: "s3://policies/authz/bundle.tar.gz"},
+		{note: "missing key", resource: "s3://policies", wantErr: true},
+		{note: "missing bucket", resource: "s3:///bundle.tar.gz", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := ParseBundlesConfig([]byte(`{"authz": {"service": "s1", "resource": "`+tc.resource+`"}}`), []string{"s1"})
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error %v but got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestPluginUsingDirectoryLoaderV1Compatible(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,362 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/IUAD1IY7/opa/internal/ref"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/rego"
+)
+
+const (
+	defaultRollbackMaxErrorRate        = 0.5
+	defaultRollbackMinDecisions        = int64(100)
+	defaultRollbackProbationSeconds    = int64(300)
+	defaultRollbackHealthCheckInterval = int64(30)
+)
+
+// RollbackConfig represents the configuration of automatic rollbacks for a
+// bundle. After a new revision is activated it's on probation: if its health
+// signals trip before the probation period ends, the previous revision is
+// activated again.
+type RollbackConfig struct {
+	MaxErrorRate               *float64 `json:"max_error_rate,omitempty"`                // share of erroring decisions that trips the rollback
+	MinDecisions               *int64   `json:"min_decisions,omitempty"`                 // decisions to observe before the error rate is considered
+	HealthRule                 string   `json:"health_rule,omitempty"`                   // path of a rule that must be true while on probation
+	ProbationSeconds           *int64   `json:"probation_seconds,omitempty"`             // time after which a revision is considered good
+	HealthCheckIntervalSeconds *int64   `json:"health_check_interval_seconds,omitempty"` // interval between health rule evaluations
+
+	healthRuleRef ast.Ref
+}
+
+func (c *RollbackConfig) validateAndInjectDefaults() error {
+	if c.MaxErrorRate == nil {
+		rate := defaultRollbackMaxErrorRate
+		c.MaxErrorRate = &rate
+	} else if *c.MaxErrorRate < 0 || *c.MaxErrorRate >= 1 {
+		return errors.New("invalid rollback.max_error_rate, must be at least 0 and lower than 1")
+	}
+
+	if c.MinDecisions == nil {
+		n := defaultRollbackMinDecisions
+		c.MinDecisions = &n
+	} else if *c.MinDecisions <= 0 {
+		return errors.New("invalid rollback.min_decisions, must be higher than 0")
+	}
+
+	if c.ProbationSeconds == nil {
+		n := defaultRollbackProbationSeconds
+		c.ProbationSeconds = &n
+	} else if *c.ProbationSeconds <= 0 {
+		return errors.New("invalid rollback.probation_seconds, must be higher than 0")
+	}
+
+	if c.HealthCheckIntervalSeconds == nil {
+		n := defaultRollbackHealthCheckInterval
+		c.HealthCheckIntervalSeconds = &n
+	} else if *c.HealthCheckIntervalSeconds <= 0 {
+		return errors.New("invalid rollback.health_check_interval_seconds, must be higher than 0")
+	}
+
+	if c.HealthRule != "" {
+		r, err := ref.ParseDataPath(c.HealthRule)
+		if err != nil {
+			return fmt.Errorf("invalid rollback.health_rule %q: %w", c.HealthRule, err)
+		}
+		c.healthRuleRef = r
+	}
+
+	return nil
+}
+
+// activation is an activated bundle revision that can be activated again.
+type activation struct {
+	bundle *bundle.Bundle // copy made before the revision was activated
+	raw    []byte         // set if the bundle is persisted
+	size   int
+	etag   string
+}
+
+// probation monitors the health of a newly activated revision.
+type probation struct {
+	config    RollbackConfig
+	revision  string
+	roots     []string
+	decisions atomic.Int64
+	errors    atomic.Int64
+	tripped   atomic.Bool
+	done      chan struct{}
+}
+
+// rollbackCopy returns a copy of the bundle to keep for rollbacks, if the
+// bundle is configured for them, and the bundle itself otherwise. The copy is
+// made before the bundle is activated, as the store may keep and modify the
+// data of activated bundles.
+func (p *Plugin) rollbackCopy(name string, b *bundle.Bundle) *bundle.Bundle {
+	if p.rollbackConfig(name) == nil || b.Type() != bundle.SnapshotBundleType {
+		return b
+	}
+	cpy := b.Copy()
+	return &cpy
+}
+
+func (p *Plugin) rollbackConfig(name string) *RollbackConfig {
+	p.cfgMtx.RLock()
+	defer p.cfgMtx.RUnlock()
+
+	if src, ok := p.config.Bundles[name]; ok && p.config.IsMultiBundle() {
+		return src.Rollback
+	}
+	return nil
+}
+
+// trackActivation remembers the activated revision and puts it on probation,
+// if the bundle is configured for rollbacks and a known-good revision exists.
+// The revision it replaces becomes the last known-good one, unless it was
+// still on probation itself.
+func (p *Plugin) trackActivation(name string, b *bundle.Bundle, raw []byte, size int, etag string) {
+	config := p.rollbackConfig(name)
+	if config == nil || b.Type() != bundle.SnapshotBundleType {
+		p.forgetActivations(name)
+		return
+	}
+
+	if p.activations == nil {
+		p.activations = map[string]*activation{}
+		p.lastGood = map[string]*activation{}
+	}
+
+	prev := p.activations[name]
+	p.activations[name] = &activation{bundle: b, raw: raw, size: size, etag: etag}
+
+	if !p.endProbation(name) && prev != nil {
+		p.lastGood[name] = prev
+	}
+
+	if p.lastGood[name] == nil {
+		return
+	}
+
+	pr := &probation{
+		config:   *config,
+		revision: b.Manifest.Revision,
+		roots:    []string{""},
+		done:     make(chan struct{}),
+	}
+	if b.Manifest.Roots != nil {
+		pr.roots = *b.Manifest.Roots
+	}
+
+	p.probationMtx.Lock()
+	if p.probations == nil {
+		p.probations = map[string]*probation{}
+	}
+	p.probations[name] = pr
+	p.probationMtx.Unlock()
+
+	p.log(name).Debug("Bundle revision %v on probation for %ds.", pr.revision, *config.ProbationSeconds)
+
+	go p.watchProbation(name, pr)
+}
+
+// endProbation ends the probation of the bundle, if any, and returns true if
+// there was one.
+func (p *Plugin) endProbation(name string) bool {
+	p.probationMtx.Lock()
+	defer p.probationMtx.Unlock()
+
+	pr, ok := p.probations[name]
+	if ok {
+		close(pr.done)
+		delete(p.probations, name)
+	}
+	return ok
+}
+
+// forgetActivations discards the revisions kept for the bundle.
+func (p *Plugin) forgetActivations(name string) {
+	p.endProbation(name)
+	delete(p.activations, name)
+	delete(p.lastGood, name)
+}
+
+func (p *Plugin) watchProbation(name string, pr *probation) {
+	timer := time.NewTimer(time.Duration(*pr.config.ProbationSeconds) * time.Second)
+	defer timer.Stop()
+
+	var ticks <-chan time.Time
+	if pr.config.healthRuleRef != nil {
+		ticker := time.NewTicker(time.Duration(*pr.config.HealthCheckIntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		ticks = ticker.C
+
+		if !p.checkHealthRule(name, pr) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-pr.done:
+			return
+		case <-ticks:
+			if !p.checkHealthRule(name, pr) {
+				return
+			}
+		case <-timer.C:
+			p.passProbation(name, pr)
+			return
+		}
+	}
+}
+
+// checkHealthRule evaluates the health rule and rolls the revision back if it
+// isn't true. It returns false if the revision was rolled back.
+func (p *Plugin) checkHealthRule(name string, pr *probation) bool {
+	input := map[string]any{
+		"bundle":    name,
+		"revision":  pr.revision,
+		"decisions": pr.decisions.Load(),
+		"errors":    pr.errors.Load(),
+	}
+
+	rs, err := rego.New(
+		rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(pr.config.healthRuleRef)))),
+		rego.Compiler(p.manager.GetCompiler()),
+		rego.Store(p.manager.Store),
+		rego.Input(input),
+		rego.Runtime(p.manager.Info),
+		rego.EnablePrintStatements(p.manager.EnablePrintStatements()),
+		rego.PrintHook(p.manager.PrintHook()),
+	).Eval(context.Background())
+
+	var reason string
+	switch {
+	case err != nil:
+		reason = fmt.Sprintf("health rule %v failed: %v", pr.config.HealthRule, err)
+	case len(rs) == 0:
+		reason = fmt.Sprintf("health rule %v is undefined", pr.config.HealthRule)
+	case rs[0].Expressions[0].Value != true:
+		reason = fmt.Sprintf("health rule %v is not true", pr.config.HealthRule)
+	default:
+		return true
+	}
+
+	if pr.tripped.CompareAndSwap(false, true) {
+		p.rollback(context.Background(), name, pr, reason)
+	}
+	return false
+}
+
+// RecordDecision records the outcome of a decision made for the given path,
+// for the error rates of the revisions on probation whose roots contain it.
+func (p *Plugin) RecordDecision(path string, err error) {
+	p.probationMtx.RLock()
+	defer p.probationMtx.RUnlock()
+
+	for name, pr := range p.probations {
+		if pr.tripped.Load() || !bundle.RootPathsContain(pr.roots, path) {
+			continue
+		}
+
+		decisions := pr.decisions.Add(1)
+		errs := pr.errors.Load()
+		if err != nil {
+			errs = pr.errors.Add(1)
+		}
+
+		if decisions < *pr.config.MinDecisions {
+			continue
+		}
+
+		if rate := float64(errs) / float64(decisions); rate > *pr.config.MaxErrorRate && pr.tripped.CompareAndSwap(false, true) {
+			reason := fmt.Sprintf("%d of %d decisions failed, above the maximum error rate of %v", errs, decisions, *pr.config.MaxErrorRate)
+			go p.rollback(context.Background(), name, pr, reason)
+		}
+	}
+}
+
+func (p *Plugin) passProbation(name string, pr *probation) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.probationMtx.Lock()
+	defer p.probationMtx.Unlock()
+
+	if p.probations[name] != pr || pr.tripped.Load() {
+		return
+	}
+	close(pr.done)
+	delete(p.probations, name)
+
+	p.lastGood[name] = p.activations[name]
+
+	p.log(name).Info("Bundle revision %v passed probation.", pr.revision)
+}
+
+// rollback activates the last known-good revision of the bundle again.
+func (p *Plugin) rollback(ctx context.Context, name string, pr *probation, reason string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.probationMtx.Lock()
+	current := p.probations[name]
+	if current == pr {
+		close(pr.done)
+		delete(p.probations, name)
+	}
+	p.probationMtx.Unlock()
+
+	status, ok := p.status[name]
+	good := p.lastGood[name]
+	if current != pr || p.stopped || !ok || good == nil {
+		return
+	}
+
+	p.log(name).Warn("Rolling back bundle revision %v to %v: %v.", pr.revision, good.bundle.Manifest.Revision, reason)
+
+	p.cfgMtx.RLock()
+	isMultiBundle := p.config.IsMultiBundle()
+	p.cfgMtx.RUnlock()
+
+	// The kept revision is activated from a copy, to keep it unmodified.
+	b := good.bundle.Copy()
+	if err := p.activate(ctx, name, &b, isMultiBundle); err != nil {
+		p.log(name).Error("Bundle rollback failed: %v", err)
+		status.SetError(fmt.Errorf("bundle rollback failed: %w", err))
+		p.notifyListeners(name)
+		return
+	}
+
+	if good.raw != nil && p.persistBundle(name, p.getBundlesCpy()) {
+		if err := p.saveBundleToDisk(name, bytes.NewReader(good.raw)); err != nil {
+			p.log(name).Error("Persisting bundle to disk failed: %v", err)
+		}
+	}
+
+	p.activations[name] = good
+
+	// The etag of the rolled back revision is kept, so that the downloader
+	// doesn't fetch it again.
+	status.SetActivateSuccess(good.bundle.Manifest.Revision)
+	status.SetBundleSize(good.size)
+	status.SetError(fmt.Errorf("bundle revision %q rolled back to %q: %s", pr.revision, good.bundle.Manifest.Revision, reason))
+	status.Rollback = &RollbackStatus{
+		Revision:         pr.revision,
+		RestoredRevision: good.bundle.Manifest.Revision,
+		Reason:           reason,
+		Time:             time.Now().UTC(),
+	}
+
+	p.notifyListeners(name)
+}
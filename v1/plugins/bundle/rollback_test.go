@@ -0,0 +1,269 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/download"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+)
+
+func TestRollbackConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		config  string
+		wantErr string
+	}{
+		{
+			note:   "defaults",
+			config: `{}`,
+		},
+		{
+			note:   "health rule",
+			config: `{"health_rule": "/system/health/bundle", "probation_seconds": 60, "health_check_interval_seconds": 5}`,
+		},
+		{
+			note:    "bad error rate",
+			config:  `{"max_error_rate": 1}`,
+			wantErr: "invalid rollback.max_error_rate",
+		},
+		{
+			note:    "bad min decisions",
+			config:  `{"min_decisions": 0}`,
+			wantErr: "invalid rollback.min_decisions",
+		},
+		{
+			note:    "bad probation",
+			config:  `{"probation_seconds": -1}`,
+			wantErr: "invalid rollback.probation_seconds",
+		},
+		{
+			note:    "bad health check interval",
+			config:  `{"health_check_interval_seconds": 0}`,
+			wantErr: "invalid rollback.health_check_interval_seconds",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			config, err := ParseBundlesConfig([]byte(`{"authz": {"service": "s1", "rollback": `+tc.config+`}}`), []string{"s1"})
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "":
+				rollback := config.Bundles["authz"].Rollback
+				if rollback.MaxErrorRate == nil || rollback.MinDecisions == nil || rollback.ProbationSeconds == nil || rollback.HealthCheckIntervalSeconds == nil {
+					t.Fatalf("expected defaults to be injected but got: %+v", rollback)
+				}
+				if (rollback.HealthRule != "") != (rollback.healthRuleRef != nil) {
+					t.Fatalf("expected health rule to be parsed but got: %v", rollback.healthRuleRef)
+				}
+			}
+		})
+	}
+}
+
+func TestPluginRollbackErrorRate(t *testing.T) {
+	t.Parallel()
+
+	plugin, rolledBack := newRollbackTestPlugin(t, `{"max_error_rate": 0.5, "min_decisions": 4}`)
+	activateRollbackTestBundle(t, plugin, shadowTestBundle("r1", "1"))
+
+	r2 := shadowTestBundle("r2", "2")
+	r2.Manifest.Roots = &[]string{"example"}
+	activateRollbackTestBundle(t, plugin, r2)
+
+	if v := evalActive(t, plugin); v != json.Number("2") {
+		t.Fatalf("expected r2 to be active but got %v", v)
+	}
+
+	// Decisions outside of the bundle roots aren't counted.
+	for range 10 {
+		plugin.RecordDecision("other/p", errors.New("boom"))
+	}
+
+	plugin.RecordDecision("example/p", nil)
+	plugin.RecordDecision("example/p", errors.New("boom"))
+	plugin.RecordDecision("example/p", errors.New("boom"))
+
+	select {
+	case s := <-rolledBack:
+		t.Fatalf("unexpected rollback: %+v", s.Rollback)
+	default:
+	}
+
+	plugin.RecordDecision("example/p", errors.New("boom"))
+
+	status := waitForRollback(t, rolledBack)
+	if status.Rollback.Revision != "r2" || status.Rollback.RestoredRevision != "r1" || !strings.Contains(status.Rollback.Reason, "3 of 4 decisions failed") {
+		t.Fatalf("unexpected rollback status: %+v", status.Rollback)
+	}
+	if status.ActiveRevision != "r1" || status.Code != errCode || !strings.Contains(status.Message, `bundle revision "r2" rolled back to "r1"`) {
+		t.Fatalf("unexpected bundle status: %+v", status)
+	}
+	if v := evalActive(t, plugin); v != json.Number("1") {
+		t.Fatalf("expected r1 to be active again but got %v", v)
+	}
+}
+
+func TestPluginRollbackHealthRule(t *testing.T) {
+	t.Parallel()
+
+	plugin, rolledBack := newRollbackTestPlugin(t, `{"health_rule": "/system/health/ok"}`)
+
+	health := "package system.health\n\nok if input.revision == \"r1\""
+	r1 := shadowTestBundle("r1", "1")
+	r1.Modules = append(r1.Modules, bundle.ModuleFile{
+		Path:   "/health.rego",
+		Raw:    []byte(health),
+		Parsed: ast.MustParseModule(health),
+	})
+	activateRollbackTestBundle(t, plugin, r1)
+
+	// The health rule is evaluated as soon as r2 is activated.
+	r2 := shadowTestBundle("r2", "2")
+	r2.Modules = append(r2.Modules, r1.Modules[1])
+	plugin.oneShot(context.Background(), "test-bundle", download.Update{Bundle: r2, ETag: "r2"})
+
+	status := waitForRollback(t, rolledBack)
+	if status.Rollback.Revision != "r2" || status.Rollback.Reason != "health rule /system/health/ok is undefined" {
+		t.Fatalf("unexpected rollback status: %+v", status.Rollback)
+	}
+	if v := evalActive(t, plugin); v != json.Number("1") {
+		t.Fatalf("expected r1 to be active again but got %v", v)
+	}
+}
+
+func TestPluginRollbackProbationPassed(t *testing.T) {
+	t.Parallel()
+
+	plugin, rolledBack := newRollbackTestPlugin(t, `{"min_decisions": 1, "probation_seconds": 1}`)
+	activateRollbackTestBundle(t, plugin, shadowTestBundle("r1", "1"))
+	activateRollbackTestBundle(t, plugin, shadowTestBundle("r2", "2"))
+
+	time.Sleep(1500 * time.Millisecond)
+
+	// Once out of probation, errors don't trip a rollback.
+	plugin.RecordDecision("example/p", errors.New("boom"))
+
+	activateRollbackTestBundle(t, plugin, shadowTestBundle("r3", "3"))
+	plugin.RecordDecision("example/p", errors.New("boom"))
+
+	status := waitForRollback(t, rolledBack)
+	if status.Rollback.Revision != "r3" || status.Rollback.RestoredRevision != "r2" {
+		t.Fatalf("unexpected rollback status: %+v", status.Rollback)
+	}
+	if v := evalActive(t, plugin); v != json.Number("2") {
+		t.Fatalf("expected r2 to be active again but got %v", v)
+	}
+}
+
+func TestPluginRollbackStoreWrites(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// The store keeps the data of activated bundles without copying it.
+	store := inmem.NewWithOpts(inmem.OptRoundTripOnWrite(false))
+	plugin, rolledBack := newRollbackTestPlugin(t, `{"min_decisions": 1}`, store)
+
+	r1 := shadowTestBundle("r1", "1")
+	r1.Data = map[string]any{"example": map[string]any{"d": "r1"}}
+	activateRollbackTestBundle(t, plugin, r1)
+
+	err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.Write(ctx, txn, storage.ReplaceOp, storage.MustParsePath("/example/d"), "modified")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activateRollbackTestBundle(t, plugin, shadowTestBundle("r2", "2"))
+	plugin.RecordDecision("example/p", errors.New("boom"))
+	waitForRollback(t, rolledBack)
+
+	// r1 is activated again as it was downloaded.
+	v, err := storage.ReadOne(ctx, store, storage.MustParsePath("/example/d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "r1" {
+		t.Fatalf("expected data of r1 but got %v", v)
+	}
+}
+
+// newRollbackTestPlugin returns a plugin configured for rollbacks and a channel
+// receiving the status of the bundle when it's rolled back.
+func newRollbackTestPlugin(t *testing.T, rollback string, stores ...storage.Store) (*Plugin, chan Status) {
+	t.Helper()
+
+	manager := getTestManagerWithOpts(nil, stores...)
+	if err := manager.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseBundlesConfig([]byte(`{"test-bundle": {"service": "s1", "rollback": `+rollback+`}}`), []string{"s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := New(config, manager)
+	plugin.status["test-bundle"] = &Status{Name: "test-bundle", Metrics: metrics.New()}
+	plugin.downloaders["test-bundle"] = download.New(download.Config{}, plugin.manager.Client(""), "test-bundle")
+	t.Cleanup(func() {
+		plugin.mtx.Lock()
+		plugin.forgetActivations("test-bundle")
+		plugin.mtx.Unlock()
+	})
+
+	rolledBack := make(chan Status, 1)
+	plugin.Register("test", func(s Status) {
+		if s.Rollback != nil {
+			rolledBack <- s
+		}
+	})
+
+	return plugin, rolledBack
+}
+
+func activateRollbackTestBundle(t *testing.T, plugin *Plugin, b *bundle.Bundle) {
+	t.Helper()
+
+	plugin.oneShot(context.Background(), "test-bundle", download.Update{Bundle: b, ETag: b.Manifest.Revision})
+
+	plugin.mtx.Lock()
+	status := *plugin.status["test-bundle"]
+	plugin.mtx.Unlock()
+
+	if status.ActiveRevision != b.Manifest.Revision {
+		t.Fatalf("expected %v to be active but got: %+v", b.Manifest.Revision, status)
+	}
+}
+
+func waitForRollback(t *testing.T, rolledBack chan Status) Status {
+	t.Helper()
+
+	select {
+	case s := <-rolledBack:
+		return s
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the bundle to be rolled back")
+	}
+	return Status{}
+}
@@ -0,0 +1,631 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IUAD1IY7/opa/internal/ref"
+	storageUtils "github.com/IUAD1IY7/opa/internal/storage"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/download"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/rego"
+	"github.com/IUAD1IY7/opa/v1/storage"
+)
+
+const (
+	shadowPromotionAuto          = "auto"
+	shadowPromotionManual        = "manual"
+	defaultShadowSampleRate      = 0.1
+	defaultShadowMinDecisions    = int64(100)
+	defaultShadowMaxDivergeRatio = 0.0
+
+	// shadowQueueSize is the number of decisions waiting for shadow
+	// evaluation. Decisions sampled while the queue is full are dropped.
+	shadowQueueSize = 1024
+	// shadowWorkers is the number of workers evaluating queued decisions.
+	shadowWorkers = 2
+	// defaultShadowRefreshInterval is the minimum time between two copies of
+	// the store made for a revision in shadow evaluation.
+	defaultShadowRefreshInterval = 5 * time.Second
+
+	// ShadowStateEvaluating means the revision is being evaluated in shadow.
+	ShadowStateEvaluating = "evaluating"
+	// ShadowStatePromoted means the revision was promoted and activated.
+	ShadowStatePromoted = "promoted"
+	// ShadowStateRejected means the revision was rejected and discarded.
+	ShadowStateRejected = "rejected"
+)
+
+// ErrNoShadow is returned when promoting or rejecting a bundle that has no
+// revision in shadow evaluation.
+var ErrNoShadow = errors.New("no bundle revision in shadow evaluation")
+
+// ShadowConfig represents the configuration of the shadow phase of a bundle.
+// New revisions are activated in an isolated copy of the store and a share of
+// live decisions is re-evaluated against them before they're promoted.
+type ShadowConfig struct {
+	SampleRate         *float64 `json:"sample_rate,omitempty"`          // fraction of decisions re-evaluated against the new revision
+	Promotion          string   `json:"promotion,omitempty"`            // auto or manual
+	MinDecisions       *int64   `json:"min_decisions,omitempty"`        // decisions to evaluate before automatic promotion
+	MaxDivergenceRatio *float64 `json:"max_divergence_ratio,omitempty"` // share of diverging decisions tolerated by automatic promotion
+}
+
+func (c *ShadowConfig) validateAndInjectDefaults() error {
+	if c.SampleRate == nil {
+		rate := defaultShadowSampleRate
+		c.SampleRate = &rate
+	} else if *c.SampleRate <= 0 || *c.SampleRate > 1 {
+		return errors.New("invalid shadow.sample_rate, must be higher than 0 and at most 1")
+	}
+
+	switch c.Promotion {
+	case "":
+		c.Promotion = shadowPromotionAuto
+	case shadowPromotionAuto, shadowPromotionManual:
+	default:
+		return fmt.Errorf("invalid shadow.promotion %q, expected %q or %q", c.Promotion, shadowPromotionAuto, shadowPromotionManual)
+	}
+
+	if c.MinDecisions == nil {
+		n := defaultShadowMinDecisions
+		c.MinDecisions = &n
+	} else if *c.MinDecisions <= 0 {
+		return errors.New("invalid shadow.min_decisions, must be higher than 0")
+	}
+
+	if c.MaxDivergenceRatio == nil {
+		ratio := defaultShadowMaxDivergeRatio
+		c.MaxDivergenceRatio = &ratio
+	} else if *c.MaxDivergenceRatio < 0 || *c.MaxDivergenceRatio > 1 {
+		return errors.New("invalid shadow.max_divergence_ratio, must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// ShadowDecision describes a live decision to re-evaluate against the bundle
+// revisions in shadow evaluation.
+type ShadowDecision struct {
+	Path     string
+	Input    *any
+	InputAST ast.Value
+	Result   *any
+}
+
+// ShadowResult describes the result of a decision evaluated against a bundle
+// revision in shadow evaluation.
+type ShadowResult struct {
+	Revision string
+	Result   *any
+	Error    error
+	Diverged bool
+}
+
+// shadowCandidate is a bundle revision activated in a copy of the store. The
+// copy is made again in the background when the store was written to since,
+// so that decisions are evaluated against recent data.
+type shadowCandidate struct {
+	config     ShadowConfig
+	bundle     *bundle.Bundle
+	raw        []byte
+	size       int
+	etag       string
+	started    time.Time
+	evaluated  atomic.Int64
+	diverged   atomic.Int64
+	dropped    atomic.Int64
+	settled    atomic.Bool
+	commit     atomic.Uint64 // store commits at the time the copy was made
+	refreshed  atomic.Int64  // time the last copy was started, in UNIX nanoseconds
+	refreshing atomic.Bool
+
+	mtx      sync.Mutex
+	store    storage.Store
+	compiler *ast.Compiler
+	queries  map[string]rego.PreparedEvalQuery // prepared queries by decision path
+}
+
+// shadowJob is a decision queued for evaluation against the sampled
+// revisions in shadow evaluation.
+type shadowJob struct {
+	decision   ShadowDecision
+	candidates map[string]*shadowCandidate
+	done       func(map[string]ShadowResult)
+}
+
+// shadowConfig returns the shadow configuration to apply to the bundle, or nil
+// if it should be activated immediately. Only snapshot bundles replacing an
+// active revision are evaluated in shadow.
+func (p *Plugin) shadowConfig(name string, b *bundle.Bundle) *ShadowConfig {
+	src, ok := p.config.Bundles[name]
+	if !ok || src.Shadow == nil || !p.config.IsMultiBundle() {
+		return nil
+	}
+	if b.Type() != bundle.SnapshotBundleType || p.status[name].LastSuccessfulActivation.IsZero() {
+		return nil
+	}
+	return src.Shadow
+}
+
+// startShadow activates the downloaded bundle in a copy of the store and keeps
+// it alongside the active revision. A revision already in shadow evaluation is
+// replaced.
+func (p *Plugin) startShadow(ctx context.Context, name string, u download.Update, config *ShadowConfig) error {
+	if err := p.startShadowWorkers(ctx); err != nil {
+		return err
+	}
+
+	commit := p.shadowCommits.Load()
+
+	store, compiler, err := p.shadowStore(ctx, name, u.Bundle, p.status[name].Metrics)
+	if err != nil {
+		return err
+	}
+
+	c := &shadowCandidate{
+		config:   *config,
+		bundle:   u.Bundle,
+		size:     u.Size,
+		etag:     u.ETag,
+		store:    store,
+		compiler: compiler,
+		queries:  map[string]rego.PreparedEvalQuery{},
+		started:  time.Now().UTC(),
+	}
+	c.commit.Store(commit)
+
+	if p.persistBundle(name, p.getBundlesCpy()) && u.Raw != nil {
+		if c.raw, err = io.ReadAll(u.Raw); err != nil {
+			return err
+		}
+	}
+
+	p.shadowMtx.Lock()
+	if p.shadows == nil {
+		p.shadows = map[string]*shadowCandidate{}
+	}
+	if old, ok := p.shadows[name]; ok {
+		old.settled.Store(true)
+	}
+	p.shadows[name] = c
+	p.shadowMtx.Unlock()
+
+	p.status[name].SetError(nil)
+	p.status[name].Shadow = &ShadowStatus{
+		Revision: u.Bundle.Manifest.Revision,
+		State:    ShadowStateEvaluating,
+		Started:  c.started,
+	}
+
+	p.log(name).Info("Bundle loaded and activated in shadow (%v).", u.Bundle.Manifest.Revision)
+
+	return nil
+}
+
+// shadowStore activates the bundle in a copy of the store.
+func (p *Plugin) shadowStore(ctx context.Context, name string, b *bundle.Bundle, m metrics.Metrics) (storage.Store, *ast.Compiler, error) {
+	store, err := p.snapshotStore(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	compiler, err := p.activateOn(ctx, store, name, b, true, m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return store, compiler, nil
+}
+
+// startShadowWorkers starts the workers evaluating queued decisions, unless
+// they're running already, and counts the commits to the store from then on.
+// The caller must hold p.mtx.
+func (p *Plugin) startShadowWorkers(ctx context.Context) error {
+	if p.shadowQueue != nil {
+		return nil
+	}
+
+	err := storage.Txn(ctx, p.manager.Store, storage.WriteParams, func(txn storage.Transaction) error {
+		var err error
+		p.shadowTrigger, err = p.manager.Store.Register(ctx, txn, storage.TriggerConfig{
+			OnCommit: func(context.Context, storage.Transaction, storage.TriggerEvent) {
+				p.shadowCommits.Add(1)
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	queue := make(chan shadowJob, shadowQueueSize)
+	p.shadowStop = make(chan struct{})
+	for range shadowWorkers {
+		go p.shadowWorker(queue, p.shadowStop)
+	}
+
+	p.shadowMtx.Lock()
+	p.shadowQueue = queue
+	p.shadowMtx.Unlock()
+
+	return nil
+}
+
+// stopShadowWorkers stops the workers evaluating queued decisions. Queued
+// decisions are discarded. The caller must hold p.mtx.
+func (p *Plugin) stopShadowWorkers(ctx context.Context) {
+	if p.shadowQueue == nil {
+		return
+	}
+
+	p.shadowMtx.Lock()
+	p.shadowQueue = nil
+	p.shadowMtx.Unlock()
+
+	close(p.shadowStop)
+
+	err := storage.Txn(ctx, p.manager.Store, storage.WriteParams, func(txn storage.Transaction) error {
+		p.shadowTrigger.Unregister(ctx, txn)
+		return nil
+	})
+	if err != nil {
+		p.logger.Error("Failed to unregister bundle shadow trigger: %v", err)
+	}
+}
+
+func (p *Plugin) shadowWorker(queue <-chan shadowJob, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case job := <-queue:
+			p.evalShadow(context.Background(), job)
+		}
+	}
+}
+
+// snapshotStore copies the data and policies of the store into a new
+// in-memory store.
+func (p *Plugin) snapshotStore(ctx context.Context) (storage.Store, error) {
+	var store storage.Store
+	err := storage.Txn(ctx, p.manager.Store, storage.TransactionParams{}, func(txn storage.Transaction) error {
+		var err error
+		store, err = storageUtils.Snapshot(ctx, p.manager.Store, txn)
+		return err
+	})
+	return store, err
+}
+
+// Shadow queues the decision for re-evaluation against the bundle revisions in
+// shadow evaluation, according to their sample rate. Queued decisions are
+// evaluated in the background and the results are passed to done by bundle
+// name. Decisions sampled while the queue is full are dropped and counted in
+// the shadow status. Revisions configured for automatic promotion are promoted
+// or rejected once enough decisions have been evaluated.
+func (p *Plugin) Shadow(d ShadowDecision, done func(map[string]ShadowResult)) {
+	p.shadowMtx.RLock()
+	queue := p.shadowQueue
+	var sampled map[string]*shadowCandidate
+	for name, c := range p.shadows {
+		if c.settled.Load() || rand.Float64() >= *c.config.SampleRate {
+			continue
+		}
+		if sampled == nil {
+			sampled = map[string]*shadowCandidate{}
+		}
+		sampled[name] = c
+	}
+	p.shadowMtx.RUnlock()
+
+	if queue == nil || len(sampled) == 0 {
+		return
+	}
+
+	// The input may be modified once the decision is logged, e.g. masked, so
+	// it's converted before the decision is queued.
+	if d.InputAST == nil && d.Input != nil {
+		input, err := ast.InterfaceToValue(*d.Input)
+		if err != nil {
+			p.logger.Debug("Decision to path %v not evaluated in shadow: %v", d.Path, err)
+			return
+		}
+		d.InputAST = input
+	}
+	d.Input = nil
+
+	select {
+	case queue <- shadowJob{decision: d, candidates: sampled, done: done}:
+	default:
+		for _, c := range sampled {
+			c.dropped.Add(1)
+		}
+	}
+}
+
+func (p *Plugin) evalShadow(ctx context.Context, job shadowJob) {
+	var results map[string]ShadowResult
+
+	for name, c := range job.candidates {
+		if c.settled.Load() {
+			continue
+		}
+
+		p.refreshShadow(name, c)
+
+		r := c.eval(ctx, job.decision, p.manager.Info)
+		if results == nil {
+			results = map[string]ShadowResult{}
+		}
+		results[name] = r
+
+		evaluated := c.evaluated.Add(1)
+		diverged := c.diverged.Load()
+		if r.Diverged {
+			diverged = c.diverged.Add(1)
+			p.log(name).Debug("Decision to path %v diverged in shadow revision %v.", job.decision.Path, r.Revision)
+		}
+
+		if c.config.Promotion == shadowPromotionAuto && evaluated >= *c.config.MinDecisions && c.settled.CompareAndSwap(false, true) {
+			promote := float64(diverged)/float64(evaluated) <= *c.config.MaxDivergenceRatio
+			go func() {
+				_, _ = p.settleShadow(context.Background(), name, c, promote)
+			}()
+		}
+	}
+
+	if len(results) > 0 && job.done != nil {
+		job.done(results)
+	}
+}
+
+// refreshShadow activates the revision in a new copy of the store in the
+// background, if the store was written to since the last copy was made. Copies
+// are made at most once per refresh interval, and decisions are evaluated
+// against the last copy in the meantime.
+func (p *Plugin) refreshShadow(name string, c *shadowCandidate) {
+	commit := p.shadowCommits.Load()
+	if c.commit.Load() == commit || time.Since(time.Unix(0, c.refreshed.Load())) < p.shadowRefresh {
+		return
+	}
+	if !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	c.refreshed.Store(time.Now().UnixNano())
+
+	go func() {
+		defer c.refreshing.Store(false)
+
+		// The revision is activated from a copy, as it may be promoted at
+		// the same time.
+		b := c.bundle.Copy()
+		store, compiler, err := p.shadowStore(context.Background(), name, &b, metrics.New())
+		if err != nil {
+			p.log(name).Error("Bundle shadow store refresh failed: %v", err)
+			return
+		}
+		if c.settled.Load() {
+			return
+		}
+
+		c.mtx.Lock()
+		c.store, c.compiler, c.queries = store, compiler, map[string]rego.PreparedEvalQuery{}
+		c.mtx.Unlock()
+		c.commit.Store(commit)
+	}()
+}
+
+func (c *shadowCandidate) eval(ctx context.Context, d ShadowDecision, runtime *ast.Term) ShadowResult {
+	result := ShadowResult{Revision: c.bundle.Manifest.Revision}
+
+	pq, err := c.prepare(ctx, d.Path, runtime)
+	if err != nil {
+		result.Error = err
+		result.Diverged = true
+		return result
+	}
+
+	var opts []rego.EvalOption
+	if d.InputAST != nil {
+		opts = append(opts, rego.EvalParsedInput(d.InputAST))
+	}
+
+	rs, err := pq.Eval(ctx, opts...)
+	if err != nil {
+		result.Error = err
+		result.Diverged = true
+		return result
+	}
+
+	if len(rs) > 0 {
+		x := rs[0].Expressions[0].Value
+		result.Result = &x
+	}
+	result.Diverged = !equalResults(d.Result, result.Result)
+
+	return result
+}
+
+// prepare returns the query of the decision path, prepared against the current
+// copy of the store.
+func (c *shadowCandidate) prepare(ctx context.Context, path string, runtime *ast.Term) (rego.PreparedEvalQuery, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if pq, ok := c.queries[path]; ok {
+		return pq, nil
+	}
+
+	dataRef, err := ref.ParseDataPath(path)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, err
+	}
+
+	pq, err := rego.New(
+		rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(dataRef)))),
+		rego.Compiler(c.compiler),
+		rego.Store(c.store),
+		rego.Runtime(runtime),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, err
+	}
+
+	c.queries[path] = pq
+	return pq, nil
+}
+
+func equalResults(a, b *any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	x, err := ast.InterfaceToValue(*a)
+	if err != nil {
+		return false
+	}
+	y, err := ast.InterfaceToValue(*b)
+	if err != nil {
+		return false
+	}
+
+	return x.Compare(y) == 0
+}
+
+// Promote activates the bundle revision in shadow evaluation and returns its
+// final shadow status.
+func (p *Plugin) Promote(ctx context.Context, name string) (*ShadowStatus, error) {
+	return p.settle(ctx, name, true)
+}
+
+// Reject discards the bundle revision in shadow evaluation and returns its
+// final shadow status. The active revision is left untouched and the rejection
+// is reported in the bundle status.
+func (p *Plugin) Reject(ctx context.Context, name string) (*ShadowStatus, error) {
+	return p.settle(ctx, name, false)
+}
+
+func (p *Plugin) settle(ctx context.Context, name string, promote bool) (*ShadowStatus, error) {
+	p.shadowMtx.RLock()
+	c, ok := p.shadows[name]
+	p.shadowMtx.RUnlock()
+
+	if !ok || !c.settled.CompareAndSwap(false, true) {
+		return nil, ErrNoShadow
+	}
+
+	return p.settleShadow(ctx, name, c, promote)
+}
+
+func (p *Plugin) settleShadow(ctx context.Context, name string, c *shadowCandidate, promote bool) (*ShadowStatus, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.shadowMtx.Lock()
+	current, ok := p.shadows[name]
+	if ok && current == c {
+		delete(p.shadows, name)
+	}
+	p.shadowMtx.Unlock()
+
+	// The revision was replaced or its bundle removed in the meantime.
+	if !ok || current != c {
+		return nil, ErrNoShadow
+	}
+
+	status, ok := p.status[name]
+	if !ok {
+		return nil, ErrNoShadow
+	}
+
+	shadow := c.status(ShadowStateRejected)
+	revision := c.bundle.Manifest.Revision
+
+	var err error
+	if promote {
+		err = p.promote(ctx, name, c)
+		if err == nil {
+			shadow.State = ShadowStatePromoted
+		}
+	} else {
+		p.log(name).Warn("Bundle revision %v rejected after shadow evaluation: %d of %d decisions diverged.", revision, shadow.Diverged, shadow.Evaluated)
+		status.SetError(fmt.Errorf("bundle revision %q rejected after shadow evaluation: %d of %d decisions diverged", revision, shadow.Diverged, shadow.Evaluated))
+	}
+
+	status.Shadow = shadow
+	p.notifyListeners(name)
+
+	return shadow, err
+}
+
+func (p *Plugin) promote(ctx context.Context, name string, c *shadowCandidate) error {
+	p.cfgMtx.RLock()
+	isMultiBundle := p.config.IsMultiBundle()
+	p.cfgMtx.RUnlock()
+
+	kept := p.rollbackCopy(name, c.bundle)
+
+	err := p.activate(ctx, name, c.bundle, isMultiBundle)
+	if err == nil {
+		err = p.completeActivation(name, kept, bytes.NewReader(c.raw), c.size, c.etag)
+	}
+
+	if err != nil {
+		p.log(name).Error("Bundle promotion failed: %v", err)
+		p.status[name].SetError(err)
+		if dl, ok := p.downloaders[name]; ok && !p.stopped {
+			dl.SetCache(p.etags[name])
+		}
+		return err
+	}
+
+	p.log(name).Info("Bundle revision %v promoted after shadow evaluation.", c.bundle.Manifest.Revision)
+
+	return nil
+}
+
+// dropShadow discards the revision in shadow evaluation without reporting it.
+func (p *Plugin) dropShadow(name string) {
+	p.shadowMtx.Lock()
+	defer p.shadowMtx.Unlock()
+
+	if c, ok := p.shadows[name]; ok {
+		c.settled.Store(true)
+		delete(p.shadows, name)
+	}
+}
+
+// updateShadowStatus copies the shadow evaluation counters into the status.
+func (p *Plugin) updateShadowStatus() {
+	p.shadowMtx.RLock()
+	defer p.shadowMtx.RUnlock()
+
+	for name, c := range p.shadows {
+		if s, ok := p.status[name]; ok {
+			s.Shadow = c.status(ShadowStateEvaluating)
+		}
+	}
+}
+
+func (c *shadowCandidate) status(state string) *ShadowStatus {
+	return &ShadowStatus{
+		Revision:  c.bundle.Manifest.Revision,
+		State:     state,
+		Started:   c.started,
+		Evaluated: c.evaluated.Load(),
+		Diverged:  c.diverged.Load(),
+		Dropped:   c.dropped.Load(),
+	}
+}
@@ -0,0 +1,403 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/download"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/plugins"
+	"github.com/IUAD1IY7/opa/v1/rego"
+	"github.com/IUAD1IY7/opa/v1/storage"
+)
+
+func TestShadowConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		config  string
+		exp     ShadowConfig
+		wantErr string
+	}{
+		{
+			note:   "defaults",
+			config: `{}`,
+			exp:    ShadowConfig{Promotion: shadowPromotionAuto},
+		},
+		{
+			note:   "manual",
+			config: `{"sample_rate": 1, "promotion": "manual", "min_decisions": 10, "max_divergence_ratio": 0.01}`,
+			exp:    ShadowConfig{Promotion: shadowPromotionManual},
+		},
+		{
+			note:    "bad sample rate",
+			config:  `{"sample_rate": 0}`,
+			wantErr: "invalid shadow.sample_rate",
+		},
+		{
+			note:    "bad promotion",
+			config:  `{"promotion": "never"}`,
+			wantErr: `invalid shadow.promotion "never"`,
+		},
+		{
+			note:    "bad min decisions",
+			config:  `{"min_decisions": -1}`,
+			wantErr: "invalid shadow.min_decisions",
+		},
+		{
+			note:    "bad max divergence ratio",
+			config:  `{"max_divergence_ratio": 2}`,
+			wantErr: "invalid shadow.max_divergence_ratio",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			config, err := ParseBundlesConfig([]byte(`{"authz": {"service": "s1", "shadow": `+tc.config+`}}`), []string{"s1"})
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "":
+				shadow := config.Bundles["authz"].Shadow
+				if shadow.Promotion != tc.exp.Promotion || shadow.SampleRate == nil || shadow.MinDecisions == nil || shadow.MaxDivergenceRatio == nil {
+					t.Fatalf("unexpected shadow config: %+v", shadow)
+				}
+			}
+		})
+	}
+}
+
+func TestShadowConfigLegacy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note  string
+		parse func() (*Config, error)
+	}{
+		{
+			note: "legacy config",
+			parse: func() (*Config, error) {
+				return ParseConfig([]byte(`{"name": "authz", "service": "s1", "shadow": {}}`), []string{"s1"})
+			},
+		},
+		{
+			note: "legacy bundle source",
+			parse: func() (*Config, error) {
+				c := Config{Name: "authz", Bundles: map[string]*Source{"authz": {Service: "s1", Shadow: &ShadowConfig{}}}}
+				return &c, c.validateAndInjectDefaults([]string{"s1"}, nil, nil)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			if _, err := tc.parse(); !errors.Is(err, errShadowLegacy) {
+				t.Fatalf("expected errShadowLegacy but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestPluginShadowAutoPromotion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note     string
+		results  []any // active results of the decisions re-evaluated in shadow
+		expState string
+		expValue any
+	}{
+		{
+			note:     "promoted",
+			results:  []any{json.Number("2"), json.Number("2"), json.Number("1")},
+			expState: ShadowStatePromoted,
+			expValue: json.Number("2"),
+		},
+		{
+			note:     "rejected",
+			results:  []any{json.Number("1"), json.Number("1"), json.Number("2")},
+			expState: ShadowStateRejected,
+			expValue: json.Number("1"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			plugin, settled := newShadowTestPlugin(t, `{"sample_rate": 1, "min_decisions": 3, "max_divergence_ratio": 0.5}`)
+
+			for i, result := range tc.results {
+				r, ok := shadowEval(t, plugin, ShadowDecision{Path: "example/p", Result: &result})["test-bundle"]
+				if !ok {
+					t.Fatalf("expected decision %d to be evaluated in shadow", i)
+				}
+				if r.Revision != "r2" || r.Error != nil || *r.Result != json.Number("2") || r.Diverged != (result != json.Number("2")) {
+					t.Fatalf("unexpected shadow result for decision %d: %+v", i, r)
+				}
+			}
+
+			var status Status
+			select {
+			case status = <-settled:
+			case <-time.After(10 * time.Second):
+				t.Fatal("timed out waiting for the shadow revision to be settled")
+			}
+
+			if status.Shadow.State != tc.expState || status.Shadow.Evaluated != 3 {
+				t.Fatalf("unexpected shadow status: %+v", status.Shadow)
+			}
+			if tc.expState == ShadowStateRejected && (status.Code != errCode || !strings.Contains(status.Message, `bundle revision "r2" rejected`)) {
+				t.Fatalf("expected rejection to be reported in the status but got: %v %v", status.Code, status.Message)
+			}
+			if tc.expState == ShadowStatePromoted && status.ActiveRevision != "r2" {
+				t.Fatalf("expected r2 to be active but got %v", status.ActiveRevision)
+			}
+
+			if v := evalActive(t, plugin); v != tc.expValue {
+				t.Fatalf("expected %v but got %v", tc.expValue, v)
+			}
+
+			plugin.shadowMtx.RLock()
+			defer plugin.shadowMtx.RUnlock()
+			if len(plugin.shadows) != 0 {
+				t.Fatalf("expected no revision in shadow evaluation but got: %v", plugin.shadows)
+			}
+		})
+	}
+}
+
+func TestPluginShadowManualPromotion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	plugin, _ := newShadowTestPlugin(t, `{"sample_rate": 1, "promotion": "manual", "min_decisions": 1}`)
+
+	result := any(json.Number("1"))
+	for range 3 {
+		if r := shadowEval(t, plugin, ShadowDecision{Path: "example/p", Result: &result}); !r["test-bundle"].Diverged {
+			t.Fatalf("expected decision to diverge but got: %+v", r)
+		}
+	}
+
+	// Manual promotion is never settled automatically.
+	if v := evalActive(t, plugin); v != json.Number("1") {
+		t.Fatalf("expected r1 to remain active but got %v", v)
+	}
+
+	status, err := plugin.Promote(ctx, "test-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != ShadowStatePromoted || status.Evaluated != 3 || status.Diverged != 3 {
+		t.Fatalf("unexpected shadow status: %+v", status)
+	}
+	if v := evalActive(t, plugin); v != json.Number("2") {
+		t.Fatalf("expected r2 to be active but got %v", v)
+	}
+
+	if _, err := plugin.Reject(ctx, "test-bundle"); !errors.Is(err, ErrNoShadow) {
+		t.Fatalf("expected ErrNoShadow but got: %v", err)
+	}
+}
+
+func TestPluginShadowDropped(t *testing.T) {
+	t.Parallel()
+
+	plugin, _ := newShadowTestPlugin(t, `{"sample_rate": 1, "promotion": "manual"}`)
+
+	// Nothing receives from the queue, as if the workers were busy.
+	plugin.shadowMtx.Lock()
+	plugin.shadowQueue = make(chan shadowJob)
+	plugin.shadowMtx.Unlock()
+
+	for range 3 {
+		plugin.Shadow(ShadowDecision{Path: "example/p"}, func(map[string]ShadowResult) {
+			t.Error("expected decision to be dropped")
+		})
+	}
+
+	plugin.mtx.Lock()
+	plugin.updateShadowStatus()
+	status := *plugin.status["test-bundle"].Shadow
+	plugin.mtx.Unlock()
+
+	if status.Evaluated != 0 || status.Dropped != 3 {
+		t.Fatalf("unexpected shadow status: %+v", status)
+	}
+}
+
+func TestPluginShadowStoreRefresh(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	plugin, _ := newShadowTestPlugin(t, `{"sample_rate": 1, "promotion": "manual"}`)
+	plugin.shadowRefresh = 0
+
+	for _, value := range []string{"a", "b"} {
+		err := storage.Txn(ctx, plugin.manager.Store, storage.WriteParams, func(txn storage.Transaction) error {
+			return plugin.manager.Store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/ext"), value)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The copy of the store is made again in the background, decisions
+		// are evaluated against the previous one until it's done.
+		result := any(value)
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			r := shadowEval(t, plugin, ShadowDecision{Path: "example/ext", Result: &result})["test-bundle"]
+			if r.Error == nil && !r.Diverged {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected shadow result %v but got: %+v", value, r)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestPluginShadowReject(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	plugin, _ := newShadowTestPlugin(t, `{"promotion": "manual"}`)
+
+	status, err := plugin.Reject(ctx, "test-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != ShadowStateRejected || status.Revision != "r2" {
+		t.Fatalf("unexpected shadow status: %+v", status)
+	}
+
+	plugin.mtx.Lock()
+	s := *plugin.status["test-bundle"]
+	plugin.mtx.Unlock()
+
+	if s.ActiveRevision != "r1" || s.Code != errCode || s.Shadow.State != ShadowStateRejected {
+		t.Fatalf("unexpected bundle status: %+v", s)
+	}
+	if v := evalActive(t, plugin); v != json.Number("1") {
+		t.Fatalf("expected r1 to remain active but got %v", v)
+	}
+
+	if _, err := plugin.Promote(ctx, "test-bundle"); !errors.Is(err, ErrNoShadow) {
+		t.Fatalf("expected ErrNoShadow but got: %v", err)
+	}
+}
+
+// newShadowTestPlugin returns a plugin with revision r1 active and r2 in
+// shadow evaluation, and a channel receiving the status once r2 is settled.
+func newShadowTestPlugin(t *testing.T, shadow string) (*Plugin, chan Status) {
+	t.Helper()
+
+	ctx := context.Background()
+	manager := getTestManager()
+	if err := manager.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseBundlesConfig([]byte(`{"test-bundle": {"service": "s1", "shadow": `+shadow+`}}`), []string{"s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := New(config, manager)
+	plugin.status["test-bundle"] = &Status{Name: "test-bundle", Metrics: metrics.New()}
+	plugin.downloaders["test-bundle"] = download.New(download.Config{}, plugin.manager.Client(""), "test-bundle")
+
+	settled := make(chan Status, 1)
+	plugin.Register("test", func(s Status) {
+		if s.Shadow != nil && s.Shadow.State != ShadowStateEvaluating {
+			settled <- s
+		}
+	})
+
+	plugin.oneShot(ctx, "test-bundle", download.Update{Bundle: shadowTestBundle("r1", "1"), ETag: "r1"})
+	ensurePluginState(t, plugin, plugins.StateOK)
+
+	plugin.oneShot(ctx, "test-bundle", download.Update{Bundle: shadowTestBundle("r2", "2"), ETag: "r2"})
+
+	plugin.mtx.Lock()
+	status := *plugin.status["test-bundle"]
+	plugin.mtx.Unlock()
+
+	if status.ActiveRevision != "r1" || status.Shadow == nil || status.Shadow.State != ShadowStateEvaluating || status.Shadow.Revision != "r2" {
+		t.Fatalf("expected r2 to be in shadow evaluation but got: %+v", status)
+	}
+	if v := evalActive(t, plugin); v != json.Number("1") {
+		t.Fatalf("expected r1 to remain active but got %v", v)
+	}
+
+	return plugin, settled
+}
+
+// shadowEval queues the decision for shadow evaluation and returns the results.
+func shadowEval(t *testing.T, plugin *Plugin, d ShadowDecision) map[string]ShadowResult {
+	t.Helper()
+
+	ch := make(chan map[string]ShadowResult, 1)
+	plugin.Shadow(d, func(results map[string]ShadowResult) {
+		ch <- results
+	})
+
+	select {
+	case results := <-ch:
+		return results
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the shadow results")
+	}
+
+	return nil
+}
+
+func shadowTestBundle(revision string, value string) *bundle.Bundle {
+	module := "package example\n\np := " + value + "\n\next := data.ext"
+
+	b := &bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: revision, Roots: &[]string{"example"}},
+		Data:     map[string]any{},
+		Modules: []bundle.ModuleFile{
+			{
+				URL:    "/example.rego",
+				Path:   "/example.rego",
+				Raw:    []byte(module),
+				Parsed: ast.MustParseModule(module),
+			},
+		},
+	}
+	b.Manifest.Init()
+
+	return b
+}
+
+func evalActive(t *testing.T, plugin *Plugin) any {
+	t.Helper()
+
+	rs, err := rego.New(
+		rego.Query("data.example.p"),
+		rego.Compiler(plugin.manager.GetCompiler()),
+		rego.Store(plugin.manager.Store),
+	).Eval(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 1 {
+		t.Fatalf("expected one result but got: %v", rs)
+	}
+
+	return rs[0].Expressions[0].Value
+}
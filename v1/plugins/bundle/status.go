@@ -36,6 +36,39 @@ type Status struct {
 	Errors                   []error         `json:"errors,omitempty"`
 	Metrics                  metrics.Metrics `json:"metrics,omitempty"`
 	HTTPCode                 json.Number     `json:"http_code,omitempty"`
+	Shadow                   *ShadowStatus   `json:"shadow,omitempty"`
+	Rollback                 *RollbackStatus `json:"rollback,omitempty"`
+	Source                   string          `json:"source,omitempty"`
+	Sources                  []SourceStatus  `json:"sources,omitempty"`
+}
+
+// SourceStatus represents the health of one of the services mirroring a
+// bundle.
+type SourceStatus struct {
+	Service               string    `json:"service"`
+	Healthy               bool      `json:"healthy"`
+	Message               string    `json:"message,omitempty"`
+	LastRequest           time.Time `json:"last_request,omitempty"`
+	LastSuccessfulRequest time.Time `json:"last_successful_request,omitempty"`
+}
+
+// ShadowStatus represents the status of a bundle revision in shadow
+// evaluation, or of the last one that was promoted or rejected.
+type ShadowStatus struct {
+	Revision  string    `json:"revision"`
+	State     string    `json:"state"`
+	Started   time.Time `json:"started"`
+	Evaluated int64     `json:"evaluated"`
+	Diverged  int64     `json:"diverged"`
+	Dropped   int64     `json:"dropped"`
+}
+
+// RollbackStatus represents the last automatic rollback of a bundle.
+type RollbackStatus struct {
+	Revision         string    `json:"revision"`
+	RestoredRevision string    `json:"restored_revision"`
+	Reason           string    `json:"reason"`
+	Time             time.Time `json:"time"`
 }
 
 // SetActivateSuccess updates the status object to reflect a successful
@@ -112,7 +145,11 @@ func (s *Status) Equal(other *Status) bool {
 		s.LastSuccessfulActivation.Equal(other.LastSuccessfulActivation) &&
 		s.LastSuccessfulDownload.Equal(other.LastSuccessfulDownload) &&
 		s.LastSuccessfulRequest.Equal(other.LastSuccessfulRequest) &&
-		s.LastRequest.Equal(other.LastRequest)
+		s.LastRequest.Equal(other.LastRequest) &&
+		reflect.DeepEqual(s.Shadow, other.Shadow) &&
+		reflect.DeepEqual(s.Rollback, other.Rollback) &&
+		s.Source == other.Source &&
+		reflect.DeepEqual(s.Sources, other.Sources)
 
 	if !equal {
 		return false
@@ -0,0 +1,575 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/plugins/rest"
+)
+
+const (
+	diskSegmentExt = ".ndjson"
+
+	// diskOffsetExt is appended to the path of a segment to name the file
+	// holding the offset up to which the segment was uploaded.
+	diskOffsetExt = ".offset"
+
+	// diskSegmentMaxBytes caps the size of a single segment file. Segments are
+	// the unit of upload, deletion and dropping, so they are kept small
+	// relative to the buffer size limit.
+	diskSegmentMaxBytes = int64(1024 * 1024) // 1MB
+)
+
+// diskSegment is a file holding newline delimited JSON encoded EventV1
+// entries. Only the last segment is appended to, all others are sealed.
+type diskSegment struct {
+	seq      uint64
+	path     string
+	size     int64
+	events   int64 // number of events not uploaded yet
+	uploaded int64 // offset up to which the events were uploaded
+}
+
+// diskBuffer stores EventV1 entries in segment files in a local directory so
+// that they survive restarts. Segments found in the directory when the buffer
+// is opened are uploaded first. If the total size exceeds the limit, the
+// oldest segments are dropped.
+type diskBuffer struct {
+	mtx                  sync.Mutex
+	upload               sync.Mutex // upload controls that uploads are done sequentially
+	dir                  string
+	limit                int64 // max total size of all segments, 0 means unlimited
+	client               rest.Client
+	uploadPath           string
+	uploadSizeLimitBytes int64
+	format               string
+	fsync                string
+	fsyncInterval        time.Duration
+	syncTimer            *time.Timer    // pending sync of the active segment
+	segments             []*diskSegment // oldest first, the last one is active
+	active               *os.File
+	nextSeq              uint64
+	opened               bool
+	metrics              metrics.Metrics
+	logger               logging.Logger
+}
+
+func newDiskBuffer(dir string, bufferSizeLimitBytes int64, client rest.Client, uploadPath string, uploadSizeLimitBytes int64) *diskBuffer {
+	return &diskBuffer{
+		dir:                  dir,
+		limit:                bufferSizeLimitBytes,
+		client:               client,
+		uploadPath:           uploadPath,
+		uploadSizeLimitBytes: uploadSizeLimitBytes,
+		fsync:                diskFsyncInterval,
+		fsyncInterval:        defaultDiskFsyncIntervalSeconds * time.Second,
+		nextSeq:              1,
+	}
+}
+
+// WithFsync sets when writes to the active segment are synced to disk: after
+// every event, or at most the interval after an event was written.
+func (b *diskBuffer) WithFsync(policy string, interval time.Duration) *diskBuffer {
+	b.fsync = policy
+	b.fsyncInterval = interval
+	return b
+}
+
+func (b *diskBuffer) WithMetrics(m metrics.Metrics) *diskBuffer {
+	b.metrics = m
+	return b
+}
+
+func (b *diskBuffer) WithFormat(format string) *diskBuffer {
+	b.format = format
+	return b
+}
+
+func (b *diskBuffer) WithLogger(l logging.Logger) *diskBuffer {
+	b.logger = l
+	return b
+}
+
+func (b *diskBuffer) incrMetric(name string) {
+	if b.metrics != nil {
+		b.metrics.Counter(name).Incr()
+	}
+}
+
+func (b *diskBuffer) logError(fmt string, a ...any) {
+	if b.logger != nil {
+		b.logger.Error(fmt, a...)
+	}
+}
+
+// Open creates the buffer directory and loads the segments left behind by a
+// previous process. It is safe to call Open more than once.
+func (b *diskBuffer) Open() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.open()
+}
+
+func (b *diskBuffer) open() error {
+	if b.opened {
+		return nil
+	}
+
+	if err := os.MkdirAll(b.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create decision log buffer directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read decision log buffer directory: %w", err)
+	}
+
+	var segments []*diskSegment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, diskSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, diskSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s := &diskSegment{seq: seq, path: filepath.Join(b.dir, name)}
+		if err := s.readOffset(); err != nil {
+			return err
+		}
+		if err := s.count(); err != nil {
+			return err
+		}
+		segments = append(segments, s)
+	}
+
+	slices.SortFunc(segments, func(a, b *diskSegment) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	b.segments = segments
+	if len(segments) > 0 {
+		b.nextSeq = segments[len(segments)-1].seq + 1
+	}
+	b.opened = true
+
+	return nil
+}
+
+// count determines the size of a segment and the number of events that were
+// not uploaded yet.
+func (s *diskSegment) count() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		s.size += int64(len(line))
+		if len(line) > 0 && line[len(line)-1] == '\n' && s.size > s.uploaded {
+			s.events++
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// readOffset reads the offset up to which the segment was uploaded by a
+// previous process.
+func (s *diskSegment) readOffset() error {
+	bs, err := os.ReadFile(s.path + diskOffsetExt)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(bs)), 10, 64)
+	if err != nil {
+		// The offset only saves uploading events again, so a corrupt file
+		// is not fatal.
+		return nil
+	}
+	s.uploaded = max(offset, 0)
+
+	return nil
+}
+
+func (s *diskSegment) writeOffset() error {
+	return os.WriteFile(s.path+diskOffsetExt, []byte(strconv.FormatInt(s.uploaded, 10)), 0o600)
+}
+
+// remove deletes the segment and its offset file.
+func (s *diskSegment) remove() error {
+	err := os.Remove(s.path)
+	if oerr := os.Remove(s.path + diskOffsetExt); oerr != nil && !errors.Is(oerr, os.ErrNotExist) && err == nil {
+		err = oerr
+	}
+	return err
+}
+
+// Reconfigure updates the user configurable values.
+func (b *diskBuffer) Reconfigure(bufferSizeLimitBytes int64, client rest.Client, uploadPath string, uploadSizeLimitBytes int64, format string, fsync string, fsyncInterval time.Duration) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.limit = bufferSizeLimitBytes
+	b.client = client
+	b.uploadPath = uploadPath
+	b.uploadSizeLimitBytes = uploadSizeLimitBytes
+	b.format = format
+	b.fsync = fsync
+	b.fsyncInterval = fsyncInterval
+}
+
+// Push appends the event to the active segment. This can be called
+// concurrently.
+func (b *diskBuffer) Push(event *EventV1) {
+	serialized, err := b.processEvent(event)
+	if err != nil {
+		b.logError("%v", err)
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if err := b.open(); err != nil {
+		b.logError("Dropping event with decision ID %v: %v.", event.DecisionID, err)
+		return
+	}
+
+	size := int64(len(serialized))
+
+	if b.limit > 0 {
+		if size > b.limit {
+			b.incrMetric(logBufferEventDropCounterName)
+			b.incrMetric(logBufferSizeLimitExDropCounterName)
+			b.logError("Dropped event with decision ID %v larger than buffer size limit.", event.DecisionID)
+			return
+		}
+
+		var dropped int64
+		for b.usage()+size > b.limit {
+			if len(b.segments) == 1 && b.active != nil {
+				// Only the active segment is left, seal it so that it can be
+				// dropped as well.
+				if err := b.seal(); err != nil {
+					b.logError("Failed to seal decision log buffer segment: %v.", err)
+					return
+				}
+			}
+			oldest := b.segments[0]
+			if err := oldest.remove(); err != nil && !errors.Is(err, os.ErrNotExist) {
+				b.logError("Failed to drop decision log buffer segment: %v.", err)
+				return
+			}
+			b.segments = b.segments[1:]
+			dropped += oldest.events
+		}
+
+		if dropped > 0 {
+			b.incrMetric(logBufferEventDropCounterName)
+			b.incrMetric(logBufferSizeLimitExDropCounterName)
+			b.logError("Dropped %v events from buffer. Reduce reporting interval or increase buffer size.", dropped)
+		}
+	}
+
+	if b.active != nil && b.segments[len(b.segments)-1].size+size > diskSegmentMaxBytes {
+		if err := b.seal(); err != nil {
+			b.logError("Failed to seal decision log buffer segment: %v.", err)
+			return
+		}
+	}
+
+	if b.active == nil {
+		if err := b.newSegment(); err != nil {
+			b.logError("Dropping event with decision ID %v: %v.", event.DecisionID, err)
+			return
+		}
+	}
+
+	s := b.segments[len(b.segments)-1]
+	n, err := b.active.Write(serialized)
+	s.size += int64(n)
+	if err != nil {
+		b.logError("Failed to write event with decision ID %v to buffer: %v.", event.DecisionID, err)
+		return
+	}
+	s.events++
+
+	if b.fsync == diskFsyncEvent {
+		if err := b.active.Sync(); err != nil {
+			b.logError("Failed to sync decision log buffer segment: %v.", err)
+		}
+	} else if b.syncTimer == nil {
+		b.syncTimer = time.AfterFunc(b.fsyncInterval, b.sync)
+	}
+}
+
+// sync syncs the writes to the active segment to disk. Sealed segments were
+// synced when they were sealed.
+func (b *diskBuffer) sync() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.syncTimer = nil
+	if b.active == nil {
+		return
+	}
+	if err := b.active.Sync(); err != nil {
+		b.logError("Failed to sync decision log buffer segment: %v.", err)
+	}
+}
+
+func (b *diskBuffer) usage() int64 {
+	var total int64
+	for _, s := range b.segments {
+		total += s.size
+	}
+	return total
+}
+
+func (b *diskBuffer) newSegment() error {
+	s := &diskSegment{seq: b.nextSeq, path: filepath.Join(b.dir, fmt.Sprintf("%020d%s", b.nextSeq, diskSegmentExt))}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	b.nextSeq++
+	b.active = f
+	b.segments = append(b.segments, s)
+
+	return nil
+}
+
+// seal closes the active segment. Sealed segments are never written again.
+func (b *diskBuffer) seal() error {
+	if b.active == nil {
+		return nil
+	}
+
+	err := b.active.Sync()
+	if cerr := b.active.Close(); err == nil {
+		err = cerr
+	}
+	b.active = nil
+
+	return err
+}
+
+// Close seals the active segment. Buffered events stay on disk until the
+// buffer is opened again.
+func (b *diskBuffer) Close() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.syncTimer != nil {
+		b.syncTimer.Stop()
+		b.syncTimer = nil
+	}
+
+	return b.seal()
+}
+
+// Depth returns the number of events and bytes currently buffered.
+func (b *diskBuffer) Depth() (events int64, size int64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for _, s := range b.segments {
+		events += s.events
+		size += s.size
+	}
+	return events, size
+}
+
+// Upload uploads the buffered segments to the configured client, oldest
+// first. A segment is removed once all of its events were uploaded. The offset
+// up to which a segment was uploaded is recorded after every chunk, so if an
+// upload fails, the next upload resumes after the last uploaded chunk. Events
+// of the failed chunk may be uploaded more than once.
+func (b *diskBuffer) Upload(ctx context.Context) error {
+	b.upload.Lock()
+	defer b.upload.Unlock()
+
+	b.mtx.Lock()
+	if err := b.open(); err != nil {
+		b.mtx.Unlock()
+		return err
+	}
+	if err := b.seal(); err != nil {
+		b.mtx.Unlock()
+		return err
+	}
+	segments := slices.Clone(b.segments)
+	client, uploadPath, uploadSizeLimitBytes, format := b.client, b.uploadPath, b.uploadSizeLimitBytes, b.format
+	b.mtx.Unlock()
+
+	if len(segments) == 0 {
+		return &bufferEmpty{}
+	}
+
+	for _, s := range segments {
+		if err := b.uploadSegment(ctx, s, client, uploadPath, uploadSizeLimitBytes, format); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// dropped while uploading
+				continue
+			}
+			return err
+		}
+
+		b.mtx.Lock()
+		if i := slices.Index(b.segments, s); i >= 0 {
+			b.segments = slices.Delete(b.segments, i, i+1)
+		}
+		err := s.remove()
+		b.mtx.Unlock()
+
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uploadSegment uploads the events of the segment after its uploaded offset.
+func (b *diskBuffer) uploadSegment(ctx context.Context, s *diskSegment, client rest.Client, uploadPath string, uploadSizeLimitBytes int64, format string) error {
+	bs, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	b.mtx.Lock()
+	offset := min(s.uploaded, int64(len(bs)))
+	b.mtx.Unlock()
+
+	enc := newChunkEncoder(uploadSizeLimitBytes).WithMetrics(b.metrics).WithFormat(format)
+
+	// ends holds the offsets at which the events written to the encoder, but
+	// not uploaded yet, end.
+	var ends []int64
+
+	upload := func(chunks [][]byte) error {
+		for _, chunk := range chunks {
+			if err := uploadChunk(ctx, client, uploadPath, format, chunk); err != nil {
+				return err
+			}
+		}
+
+		// The events still held by the encoder are the last ones written,
+		// all others were part of the uploaded chunks.
+		if n := len(ends) - enc.eventsWritten; n > 0 {
+			b.setUploaded(s, ends[n-1], int64(n))
+			ends = ends[n:]
+		}
+		return nil
+	}
+
+	for line := range bytes.Lines(bs[offset:]) {
+		offset += int64(len(line))
+		if !bytes.HasSuffix(line, []byte("\n")) || !json.Valid(line) {
+			// incomplete write, e.g. if the process crashed
+			b.logError("Skipping corrupt event in decision log buffer segment %v.", s.path)
+			continue
+		}
+		result, err := enc.WriteBytes(line)
+		if err != nil {
+			b.incrMetric(logEncodingFailureCounterName)
+			b.logError("encoding failure: %v, dropping event", err)
+			continue
+		}
+		ends = append(ends, offset)
+		if err := upload(result); err != nil {
+			return err
+		}
+	}
+
+	result, err := enc.Flush()
+	if err != nil {
+		b.incrMetric(logEncodingFailureCounterName)
+		b.logError("encoding failure: %v", err)
+	}
+
+	return upload(result)
+}
+
+// setUploaded records that the events of the segment up to the offset were
+// uploaded.
+func (b *diskBuffer) setUploaded(s *diskSegment, offset int64, events int64) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !slices.Contains(b.segments, s) {
+		// dropped while uploading
+		return
+	}
+
+	s.uploaded = offset
+	s.events -= events
+	if err := s.writeOffset(); err != nil {
+		b.logError("Failed to record upload offset of decision log buffer segment: %v.", err)
+	}
+}
+
+// processEvent serializes the event and drops the ND builtins cache if the
+// event would not fit into an upload otherwise.
+func (b *diskBuffer) processEvent(event *EventV1) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(event); err != nil {
+		b.incrMetric(logEncodingFailureCounterName)
+		return nil, fmt.Errorf("encoding failure: %v, dropping event with decision ID: %v", err, event.DecisionID)
+	}
+
+	b.mtx.Lock()
+	limit := b.uploadSizeLimitBytes
+	b.mtx.Unlock()
+
+	if int64(buf.Len()) < limit || event.NDBuiltinCache == nil {
+		return buf.Bytes(), nil
+	}
+
+	newEvent := *event
+	newEvent.NDBuiltinCache = nil
+
+	buf.Reset()
+	if err := json.NewEncoder(&buf).Encode(newEvent); err != nil {
+		b.incrMetric(logEncodingFailureCounterName)
+		return nil, fmt.Errorf("encoding failure: %v, dropping event with decision ID: %v", err, event.DecisionID)
+	}
+
+	b.incrMetric(logNDBDropCounterName)
+	b.logError("ND builtins cache dropped from this event to fit under maximum upload size limits. Increase upload size limit or change usage of non-deterministic builtins.")
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,371 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/plugins/rest"
+)
+
+func checkDiskBufferDepth(t *testing.T, b *diskBuffer, expected int64) {
+	t.Helper()
+
+	if events, _ := b.Depth(); events != expected {
+		t.Fatalf("expected %d buffered events, got %d", expected, events)
+	}
+}
+
+func TestDiskBuffer_PersistAndReplay(t *testing.T) {
+	t.Parallel()
+
+	uploadPath := "/v1/test"
+	dir := t.TempDir()
+
+	var mtx sync.Mutex
+	var received []string
+	status := http.StatusInternalServerError
+
+	client, ts := setupTestServer(t, uploadPath, func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if status == http.StatusOK {
+			for _, e := range decodeLogEvent(t, r.Body) {
+				received = append(received, e.DecisionID)
+			}
+		}
+		w.WriteHeader(status)
+	})
+	defer ts.Close()
+
+	b := newDiskBuffer(dir, 0, client, uploadPath, defaultUploadSizeLimitBytes).WithMetrics(metrics.New()).WithLogger(logging.NewNoOpLogger())
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		b.Push(newTestEvent(t, strconv.Itoa(i), false))
+	}
+
+	if err := b.Upload(context.Background()); err == nil {
+		t.Fatal("expected upload to fail")
+	}
+	checkDiskBufferDepth(t, b, 3)
+
+	b.Push(newTestEvent(t, "3", false))
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a restart.
+	b = newDiskBuffer(dir, 0, client, uploadPath, defaultUploadSizeLimitBytes).WithMetrics(metrics.New()).WithLogger(logging.NewNoOpLogger())
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+	checkDiskBufferDepth(t, b, 4)
+
+	mtx.Lock()
+	status = http.StatusOK
+	mtx.Unlock()
+
+	if err := b.Upload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkDiskBufferDepth(t, b, 0)
+
+	if exp := []string{"0", "1", "2", "3"}; !slices.Equal(received, exp) {
+		t.Fatalf("expected events %v, got %v", exp, received)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected buffer directory to be empty, got %v", entries)
+	}
+
+	if err := b.Upload(context.Background()); err == nil || err.Error() != (&bufferEmpty{}).Error() {
+		t.Fatalf("expected empty buffer, got %v", err)
+	}
+}
+
+func TestDiskBuffer_ResumeUpload(t *testing.T) {
+	t.Parallel()
+
+	uploadPath := "/v1/test"
+	dir := t.TempDir()
+
+	bs, err := json.Marshal(newTestEvent(t, "0", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mtx sync.Mutex
+	var received []string
+	var requests int
+	failAfter := 1
+
+	client, ts := setupTestServer(t, uploadPath, func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests++
+		if failAfter > 0 && requests > failAfter {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for _, e := range decodeLogEvent(t, r.Body) {
+			received = append(received, e.DecisionID)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer ts.Close()
+
+	// Chunks hold a few events at most.
+	limit := int64(2 * len(bs))
+
+	b := newDiskBuffer(dir, 0, client, uploadPath, limit).WithLogger(logging.NewNoOpLogger())
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	var exp []string
+	for i := range 10 {
+		exp = append(exp, strconv.Itoa(i))
+		b.Push(newTestEvent(t, strconv.Itoa(i), false))
+	}
+
+	if err := b.Upload(context.Background()); err == nil {
+		t.Fatal("expected upload to fail")
+	}
+
+	mtx.Lock()
+	uploaded := int64(len(received))
+	mtx.Unlock()
+	if uploaded == 0 || uploaded == 10 {
+		t.Fatalf("expected some events to be uploaded, got %d", uploaded)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a restart, the upload resumes after the uploaded events.
+	b = newDiskBuffer(dir, 0, client, uploadPath, limit).WithLogger(logging.NewNoOpLogger())
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+	checkDiskBufferDepth(t, b, 10-uploaded)
+
+	mtx.Lock()
+	failAfter = 0
+	mtx.Unlock()
+
+	if err := b.Upload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	checkDiskBufferDepth(t, b, 0)
+
+	if !slices.Equal(received, exp) {
+		t.Fatalf("expected events %v, got %v", exp, received)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected buffer directory to be empty, got %v", entries)
+	}
+}
+
+func TestDiskBuffer_Fsync(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []string{diskFsyncEvent, diskFsyncInterval} {
+		t.Run(policy, func(t *testing.T) {
+			t.Parallel()
+
+			b := newDiskBuffer(t.TempDir(), 0, rest.Client{}, "", defaultUploadSizeLimitBytes).
+				WithLogger(logging.NewNoOpLogger()).
+				WithFsync(policy, time.Millisecond)
+
+			b.Push(newTestEvent(t, "0", false))
+			b.Push(newTestEvent(t, "1", false))
+
+			b.mtx.Lock()
+			pending := b.syncTimer != nil
+			b.mtx.Unlock()
+			if policy == diskFsyncEvent && pending {
+				t.Fatal("expected no pending sync")
+			}
+
+			if err := b.Close(); err != nil {
+				t.Fatal(err)
+			}
+			b.mtx.Lock()
+			pending = b.syncTimer != nil
+			b.mtx.Unlock()
+			if pending {
+				t.Fatal("expected pending sync to be stopped")
+			}
+			checkDiskBufferDepth(t, b, 2)
+		})
+	}
+}
+
+func TestDiskBuffer_SizeLimit(t *testing.T) {
+	t.Parallel()
+
+	event := newTestEvent(t, "0", false)
+	bs, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(bs) + 1)
+
+	b := newDiskBuffer(t.TempDir(), 2*size, rest.Client{}, "", defaultUploadSizeLimitBytes).WithMetrics(metrics.New())
+
+	b.Push(newTestEvent(t, "0", false))
+	b.Push(newTestEvent(t, "1", false))
+	checkDiskBufferDepth(t, b, 2)
+
+	// The oldest segment is dropped to make room.
+	b.Push(newTestEvent(t, "2", false))
+	checkDiskBufferDepth(t, b, 1)
+
+	if _, bytes := b.Depth(); bytes != size {
+		t.Fatalf("expected %d buffered bytes, got %d", size, bytes)
+	}
+
+	if dropped := b.metrics.Counter(logBufferEventDropCounterName).Value().(uint64); dropped != 1 {
+		t.Fatalf("expected drop counter to be 1, got %d", dropped)
+	}
+}
+
+func TestDiskBuffer_CorruptSegment(t *testing.T) {
+	t.Parallel()
+
+	uploadPath := "/v1/test"
+	dir := t.TempDir()
+
+	bs, err := json.Marshal(newTestEvent(t, "0", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The process crashed while writing the second event.
+	content := string(bs) + "\n" + string(bs[:len(bs)/2])
+	if err := os.WriteFile(filepath.Join(dir, "00000000000000000001"+diskSegmentExt), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var received int
+	client, ts := setupTestServer(t, uploadPath, func(w http.ResponseWriter, r *http.Request) {
+		received += len(decodeLogEvent(t, r.Body))
+		w.WriteHeader(http.StatusOK)
+	})
+	defer ts.Close()
+
+	b := newDiskBuffer(dir, 0, client, uploadPath, defaultUploadSizeLimitBytes).WithLogger(logging.NewNoOpLogger())
+	if err := b.Open(); err != nil {
+		t.Fatal(err)
+	}
+	checkDiskBufferDepth(t, b, 1)
+
+	// New events go to a new segment.
+	b.Push(newTestEvent(t, "1", false))
+	checkDiskBufferDepth(t, b, 2)
+
+	if err := b.Upload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if received != 2 {
+		t.Fatalf("expected 2 events to be uploaded, got %d", received)
+	}
+}
+
+func TestDiskBufferConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		config  string
+		wantErr string
+	}{
+		{
+			note:   "disk",
+			config: `{"service": "example", "reporting": {"buffer_type": "disk", "buffer_path": "/tmp/logs", "buffer_size_limit_bytes": 1024}}`,
+		},
+		{
+			note:    "missing path",
+			config:  `{"service": "example", "reporting": {"buffer_type": "disk"}}`,
+			wantErr: "'buffer_path' is required for the disk buffer type",
+		},
+		{
+			note:    "event limit",
+			config:  `{"service": "example", "reporting": {"buffer_type": "disk", "buffer_path": "/tmp/logs", "buffer_size_limit_events": 10}}`,
+			wantErr: "'buffer_size_limit_events' isn't supported for the disk buffer type",
+		},
+		{
+			note:   "fsync event",
+			config: `{"service": "example", "reporting": {"buffer_type": "disk", "buffer_path": "/tmp/logs", "buffer_fsync": "event"}}`,
+		},
+		{
+			note:   "fsync interval",
+			config: `{"service": "example", "reporting": {"buffer_type": "disk", "buffer_path": "/tmp/logs", "buffer_fsync": "interval", "buffer_fsync_interval_seconds": 5}}`,
+		},
+		{
+			note:    "invalid fsync policy",
+			config:  `{"service": "example", "reporting": {"buffer_type": "disk", "buffer_path": "/tmp/logs", "buffer_fsync": "never"}}`,
+			wantErr: `invalid 'buffer_fsync' policy "never"`,
+		},
+		{
+			note:    "fsync interval with event policy",
+			config:  `{"service": "example", "reporting": {"buffer_type": "disk", "buffer_path": "/tmp/logs", "buffer_fsync": "event", "buffer_fsync_interval_seconds": 5}}`,
+			wantErr: "'buffer_fsync_interval_seconds' is only supported for the \"interval\" 'buffer_fsync' policy",
+		},
+		{
+			note:    "invalid fsync interval",
+			config:  `{"service": "example", "reporting": {"buffer_type": "disk", "buffer_path": "/tmp/logs", "buffer_fsync_interval_seconds": 0}}`,
+			wantErr: "'buffer_fsync_interval_seconds' must be higher than 0",
+		},
+		{
+			note:    "fsync without disk",
+			config:  `{"service": "example", "reporting": {"buffer_fsync": "event"}}`,
+			wantErr: "'buffer_fsync' and 'buffer_fsync_interval_seconds' are only supported for the disk buffer type",
+		},
+		{
+			note:    "path without disk",
+			config:  `{"service": "example", "reporting": {"buffer_path": "/tmp/logs"}}`,
+			wantErr: "'buffer_path' is only supported for the disk buffer type",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.config), []string{"example"}, nil)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
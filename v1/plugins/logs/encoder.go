@@ -26,15 +26,19 @@ const (
 
 // chunkEncoder implements log buffer chunking and compression. Log events are
 // written to the encoder and the encoder outputs chunks that are fit to the
-// configured limit.
+// configured limit. Chunks are gzip compressed JSON arrays of events, or OTLP
+// requests if the encoder is configured with the OTLP format.
 type chunkEncoder struct {
 	limit                      int64
 	softLimit                  int64
 	softLimitScaleUpExponent   float64
 	softLimitScaleDownExponent float64
 	bytesWritten               int
+	eventsWritten              int // number of events in the current chunk
 	buf                        *bytes.Buffer
 	w                          *gzip.Writer
+	format                     string
+	otlp                       *otlpChunk
 	metrics                    metrics.Metrics
 }
 
@@ -71,6 +75,11 @@ func (enc *chunkEncoder) WithMetrics(m metrics.Metrics) *chunkEncoder {
 	return enc
 }
 
+func (enc *chunkEncoder) WithFormat(format string) *chunkEncoder {
+	enc.format = format
+	return enc
+}
+
 func (enc *chunkEncoder) Write(event EventV1) (result [][]byte, err error) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(event); err != nil {
@@ -86,6 +95,8 @@ func (enc *chunkEncoder) Write(event EventV1) (result [][]byte, err error) {
 func (enc *chunkEncoder) WriteBytes(bs []byte) (result [][]byte, err error) {
 	if len(bs) == 0 {
 		return nil, nil
+	} else if enc.format == otlpFormat {
+		return enc.writeOTLP(bs)
 	} else if int64(len(bs)+2) > enc.limit {
 		if enc.metrics != nil {
 			enc.metrics.Counter(encLogExUploadSizeLimitCounterName).Incr()
@@ -125,6 +136,7 @@ func (enc *chunkEncoder) WriteBytes(bs []byte) (result [][]byte, err error) {
 	}
 
 	enc.bytesWritten += n
+	enc.eventsWritten++
 	return
 }
 
@@ -136,6 +148,9 @@ func (enc *chunkEncoder) writeClose() error {
 }
 
 func (enc *chunkEncoder) Flush() ([][]byte, error) {
+	if enc.format == otlpFormat {
+		return enc.flushOTLP()
+	}
 	if enc.bytesWritten == 0 {
 		return nil, nil
 	}
@@ -227,6 +242,7 @@ func (enc *chunkEncoder) update() [][]byte {
 func (enc *chunkEncoder) initialize() {
 	enc.buf = new(bytes.Buffer)
 	enc.bytesWritten = 0
+	enc.eventsWritten = 0
 	enc.w = gzip.NewWriter(enc.buf)
 }
 
@@ -28,7 +28,8 @@ type eventBuffer struct {
 	client               rest.Client      // client is used to upload the data to the configured service
 	uploadPath           string           // uploadPath is the configured HTTP resource path for upload
 	uploadSizeLimitBytes int64            // uploadSizeLimitBytes will enforce a maximum payload size to be uploaded
-	enc                  *chunkEncoder    // encoder appends events into the gzip compressed JSON array
+	format               string           // format is the payload format sent to the configured service
+	enc                  *chunkEncoder    // encoder appends events into the gzip compressed JSON array or OTLP request
 	metrics              metrics.Metrics
 	logger               logging.Logger
 }
@@ -49,6 +50,12 @@ func (b *eventBuffer) WithMetrics(m metrics.Metrics) *eventBuffer {
 	return b
 }
 
+func (b *eventBuffer) WithFormat(format string) *eventBuffer {
+	b.format = format
+	b.enc.WithFormat(format)
+	return b
+}
+
 func (b *eventBuffer) WithLogger(l logging.Logger) *eventBuffer {
 	b.logger = l
 	return b
@@ -69,11 +76,13 @@ func (b *eventBuffer) logError(fmt string, a ...any) {
 // Reconfigure updates the user configurable values
 // This cannot be called concurrently, this could change the underlying channel.
 // Plugin manages a lock to control this so that changes to both buffer types can be managed sequentially.
-func (b *eventBuffer) Reconfigure(bufferSizeLimitEvents int64, client rest.Client, uploadPath string, uploadSizeLimitBytes int64) {
+func (b *eventBuffer) Reconfigure(bufferSizeLimitEvents int64, client rest.Client, uploadPath string, uploadSizeLimitBytes int64, format string) {
 	b.client = client
 	b.uploadPath = uploadPath
 	b.uploadSizeLimitBytes = uploadSizeLimitBytes
+	b.format = format
 	b.enc.Reconfigure(uploadSizeLimitBytes)
+	b.enc.WithFormat(format)
 
 	if int64(cap(b.buffer)) == bufferSizeLimitEvents {
 		return
@@ -163,7 +172,7 @@ func (b *eventBuffer) Upload(ctx context.Context) error {
 func (b *eventBuffer) uploadChunks(ctx context.Context, result [][]byte) error {
 	var finalErr error
 	for _, chunk := range result {
-		err := uploadChunk(ctx, b.client, b.uploadPath, chunk)
+		err := uploadChunk(ctx, b.client, b.uploadPath, b.format, chunk)
 
 		// if an upload failed, requeue the chunk
 		if err != nil {
@@ -0,0 +1,334 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/logging"
+)
+
+const (
+	defaultFileMaxSizeBytes = int64(100 * 1024 * 1024) // 100MB
+	fileSyncNone            = "none"
+	fileSyncRotate          = "rotate"
+	fileSyncAlways          = "always"
+	fileRotatedTimeFormat   = "20060102T150405.000000000"
+	fileCompressedExt       = ".gz"
+)
+
+// FileConfig represents the configuration of the decision log file sink.
+type FileConfig struct {
+	Path                  string `json:"path"`                              // path of the active log file
+	MaxSizeBytes          *int64 `json:"max_size_bytes,omitempty"`          // rotate once the active file would exceed this size
+	RotateIntervalSeconds *int64 `json:"rotate_interval_seconds,omitempty"` // rotate once the active file is older than this, disabled by default
+	Compress              bool   `json:"compress,omitempty"`                // gzip rotated files
+	MaxFiles              *int   `json:"max_files,omitempty"`               // max number of rotated files to keep, unlimited by default
+	MaxAgeSeconds         *int64 `json:"max_age_seconds,omitempty"`         // max age of rotated files to keep, unlimited by default
+	Sync                  string `json:"sync,omitempty"`                    // fsync policy: none, rotate or always
+}
+
+func (c *FileConfig) validateAndInjectDefaults() error {
+	if c.Path == "" {
+		return errors.New("missing required decision_logs.file.path")
+	}
+
+	if c.MaxSizeBytes == nil {
+		maxSize := defaultFileMaxSizeBytes
+		c.MaxSizeBytes = &maxSize
+	} else if *c.MaxSizeBytes <= 0 {
+		return errors.New("invalid decision_logs.file.max_size_bytes, must be higher than 0")
+	}
+
+	if c.RotateIntervalSeconds != nil && *c.RotateIntervalSeconds <= 0 {
+		return errors.New("invalid decision_logs.file.rotate_interval_seconds, must be higher than 0")
+	}
+
+	if c.MaxFiles != nil && *c.MaxFiles <= 0 {
+		return errors.New("invalid decision_logs.file.max_files, must be higher than 0")
+	}
+
+	if c.MaxAgeSeconds != nil && *c.MaxAgeSeconds <= 0 {
+		return errors.New("invalid decision_logs.file.max_age_seconds, must be higher than 0")
+	}
+
+	switch c.Sync {
+	case "":
+		c.Sync = fileSyncRotate
+	case fileSyncNone, fileSyncRotate, fileSyncAlways:
+	default:
+		return fmt.Errorf("invalid decision_logs.file.sync %q, expected %q, %q or %q", c.Sync, fileSyncNone, fileSyncRotate, fileSyncAlways)
+	}
+
+	return nil
+}
+
+// fileSink appends encoded decision log events to a local file. The file is
+// rotated when it reaches the configured size or age. Rotated files are
+// renamed to <path>.<timestamp>, optionally compressed, and removed according
+// to the retention limits.
+type fileSink struct {
+	mtx     sync.Mutex
+	config  FileConfig
+	logger  logging.Logger
+	now     func() time.Time
+	file    *os.File
+	size    int64
+	opened  time.Time
+	rotated time.Time      // timestamp of the last rotated file, keeps names unique
+	pending sync.WaitGroup // compression and retention running in the background
+	bgMtx   sync.Mutex     // serializes background work on rotated files
+}
+
+func newFileSink(config FileConfig, logger logging.Logger) *fileSink {
+	return &fileSink{
+		config: config,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Write appends bs to the active file, rotating it first if required.
+func (s *fileSink) Write(bs []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.file != nil && s.shouldRotate(int64(len(bs))) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(bs)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if s.config.Sync == fileSyncAlways {
+		return s.file.Sync()
+	}
+
+	return nil
+}
+
+// Close closes the active file and waits for background work on rotated
+// files to finish.
+func (s *fileSink) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var err error
+	if s.file != nil {
+		err = s.closeFile()
+	}
+
+	s.pending.Wait()
+
+	return err
+}
+
+func (s *fileSink) shouldRotate(n int64) bool {
+	if s.size > 0 && s.size+n > *s.config.MaxSizeBytes {
+		return true
+	}
+	if s.config.RotateIntervalSeconds != nil {
+		return s.now().Sub(s.opened) >= time.Duration(*s.config.RotateIntervalSeconds)*time.Second
+	}
+	return false
+}
+
+func (s *fileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.config.Path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = fi.Size()
+	s.opened = s.now()
+
+	return nil
+}
+
+func (s *fileSink) closeFile() error {
+	var err error
+	if s.config.Sync != fileSyncNone {
+		err = s.file.Sync()
+	}
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	s.file = nil
+	s.size = 0
+	return err
+}
+
+func (s *fileSink) rotate() error {
+	if err := s.closeFile(); err != nil {
+		return err
+	}
+
+	ts := s.now().UTC()
+	if !ts.After(s.rotated) {
+		ts = s.rotated.Add(time.Nanosecond)
+	}
+	s.rotated = ts
+
+	rotated := s.config.Path + "." + ts.Format(fileRotatedTimeFormat)
+	if err := os.Rename(s.config.Path, rotated); err != nil {
+		return err
+	}
+
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+
+		s.bgMtx.Lock()
+		defer s.bgMtx.Unlock()
+
+		if s.config.Compress {
+			if err := s.compress(rotated); err != nil {
+				s.logger.Error("Failed to compress rotated decision log file %v: %v.", rotated, err)
+			}
+		}
+
+		if err := s.removeExpired(); err != nil {
+			s.logger.Error("Failed to remove expired decision log files: %v.", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *fileSink) compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+fileCompressedExt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	gw := gzip.NewWriter(dst)
+	if _, err := io.Copy(gw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if s.config.Sync != fileSyncNone {
+		if err := dst.Sync(); err != nil {
+			dst.Close()
+			return err
+		}
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+// rotatedFiles returns the paths of rotated files, oldest first.
+func (s *fileSink) rotatedFiles() ([]string, error) {
+	dir, base := filepath.Split(s.config.Path)
+	if dir == "" {
+		dir = "."
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, base+".") {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, base+"."), fileCompressedExt)
+		if _, err := time.Parse(fileRotatedTimeFormat, ts); err != nil {
+			continue
+		}
+		result = append(result, filepath.Join(dir, name))
+	}
+
+	// The timestamp format sorts lexicographically.
+	slices.Sort(result)
+
+	return result, nil
+}
+
+func (s *fileSink) removeExpired() error {
+	if s.config.MaxFiles == nil && s.config.MaxAgeSeconds == nil {
+		return nil
+	}
+
+	files, err := s.rotatedFiles()
+	if err != nil {
+		return err
+	}
+
+	var expired []string
+
+	if s.config.MaxFiles != nil && len(files) > *s.config.MaxFiles {
+		n := len(files) - *s.config.MaxFiles
+		expired, files = files[:n], files[n:]
+	}
+
+	if s.config.MaxAgeSeconds != nil {
+		cutoff := s.now().Add(-time.Duration(*s.config.MaxAgeSeconds) * time.Second)
+		for _, name := range files {
+			fi, err := os.Stat(name)
+			if err != nil {
+				continue
+			}
+			if fi.ModTime().Before(cutoff) {
+				expired = append(expired, name)
+			}
+		}
+	}
+
+	var errs []error
+	for _, name := range expired {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,298 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/plugins"
+	"github.com/IUAD1IY7/opa/v1/server"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+func newTestFileSink(t *testing.T, config string) (*fileSink, *time.Time) {
+	t.Helper()
+
+	var c FileConfig
+	if err := util.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatal(err)
+	}
+	c.Path = filepath.Join(t.TempDir(), "decisions.log")
+	if err := c.validateAndInjectDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newFileSink(c, logging.NewNoOpLogger())
+	s.now = func() time.Time { return now }
+
+	return s, &now
+}
+
+func writeLines(t *testing.T, s *fileSink, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if err := s.Write([]byte(line + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readLines(t *testing.T, name string) []string {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r = bufio.NewScanner(f)
+	if strings.HasSuffix(name, fileCompressedExt) {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = bufio.NewScanner(gr)
+	}
+
+	var lines []string
+	for r.Scan() {
+		lines = append(lines, r.Text())
+	}
+	return lines
+}
+
+func expectRotatedFiles(t *testing.T, s *fileSink, exp ...[]string) {
+	t.Helper()
+
+	files, err := s.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(exp) {
+		t.Fatalf("expected %d rotated files but got: %v", len(exp), files)
+	}
+	for i := range exp {
+		if act := readLines(t, files[i]); strings.Join(act, ",") != strings.Join(exp[i], ",") {
+			t.Fatalf("expected %v in %v but got %v", exp[i], files[i], act)
+		}
+	}
+}
+
+func TestFileSinkRotateSize(t *testing.T) {
+	t.Parallel()
+
+	s, now := newTestFileSink(t, `{"max_size_bytes": 6}`)
+
+	writeLines(t, s, "a", "b", "c")
+	*now = now.Add(time.Second)
+	writeLines(t, s, "dddddd", "e")
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A single write larger than the limit is never split or dropped.
+	expectRotatedFiles(t, s, []string{"a", "b", "c"}, []string{"dddddd"})
+
+	if act := readLines(t, s.config.Path); len(act) != 1 || act[0] != "e" {
+		t.Fatalf("unexpected active file contents: %v", act)
+	}
+}
+
+func TestFileSinkRotateInterval(t *testing.T) {
+	t.Parallel()
+
+	s, now := newTestFileSink(t, `{"rotate_interval_seconds": 60}`)
+
+	writeLines(t, s, "a")
+	*now = now.Add(30 * time.Second)
+	writeLines(t, s, "b")
+	*now = now.Add(30 * time.Second)
+	writeLines(t, s, "c")
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expectRotatedFiles(t, s, []string{"a", "b"})
+}
+
+func TestFileSinkCompressAndRetention(t *testing.T) {
+	t.Parallel()
+
+	s, now := newTestFileSink(t, `{"max_size_bytes": 1, "compress": true, "max_files": 2}`)
+
+	for _, line := range []string{"a", "b", "c", "d"} {
+		writeLines(t, s, line)
+		*now = now.Add(time.Second)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := s.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		if !strings.HasSuffix(name, fileCompressedExt) {
+			t.Fatalf("expected rotated file to be compressed: %v", name)
+		}
+	}
+
+	expectRotatedFiles(t, s, []string{"b"}, []string{"c"})
+}
+
+func TestFileSinkMaxAge(t *testing.T) {
+	t.Parallel()
+
+	s, now := newTestFileSink(t, `{"max_size_bytes": 1, "max_age_seconds": 3600}`)
+
+	writeLines(t, s, "a", "b")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := s.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := now.Add(-2 * time.Hour)
+	if err := os.Chtimes(files[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	*now = time.Now()
+	writeLines(t, s, "c", "d")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The active file is appended to when the sink is reopened.
+	expectRotatedFiles(t, s, []string{"b", "c"})
+}
+
+func TestFileConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		config  string
+		wantErr string
+	}{
+		{
+			note:   "defaults",
+			config: `{"path": "decisions.log"}`,
+		},
+		{
+			note:    "missing path",
+			config:  `{}`,
+			wantErr: "missing required decision_logs.file.path",
+		},
+		{
+			note:    "bad size",
+			config:  `{"path": "decisions.log", "max_size_bytes": 0}`,
+			wantErr: "invalid decision_logs.file.max_size_bytes",
+		},
+		{
+			note:    "bad interval",
+			config:  `{"path": "decisions.log", "rotate_interval_seconds": -1}`,
+			wantErr: "invalid decision_logs.file.rotate_interval_seconds",
+		},
+		{
+			note:    "bad max files",
+			config:  `{"path": "decisions.log", "max_files": 0}`,
+			wantErr: "invalid decision_logs.file.max_files",
+		},
+		{
+			note:    "bad max age",
+			config:  `{"path": "decisions.log", "max_age_seconds": 0}`,
+			wantErr: "invalid decision_logs.file.max_age_seconds",
+		},
+		{
+			note:    "bad sync",
+			config:  `{"path": "decisions.log", "sync": "sometimes"}`,
+			wantErr: "invalid decision_logs.file.sync",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var c FileConfig
+			if err := util.Unmarshal([]byte(tc.config), &c); err != nil {
+				t.Fatal(err)
+			}
+			err := c.validateAndInjectDefaults()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "" && (c.Sync != fileSyncRotate || *c.MaxSizeBytes != defaultFileMaxSizeBytes):
+				t.Fatalf("expected defaults to be injected but got: %+v", c)
+			}
+		})
+	}
+}
+
+func TestPluginFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "logs", "decisions.log")
+
+	manager, err := plugins.New(nil, "test-instance-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ParseConfig([]byte(`{"file": {"path": "`+filepath.ToSlash(path)+`"}}`), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if config == nil {
+		t.Fatal("expected file only config to enable the plugin")
+	}
+
+	plugin := New(config, manager)
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if err := plugin.Log(ctx, &server.Info{DecisionID: id, Path: "test/allow"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plugin.Stop(ctx)
+
+	lines := readLines(t, path)
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 events but got: %v", lines)
+	}
+	for i, line := range lines {
+		var event EventV1
+		if err := util.UnmarshalJSON([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.DecisionID != strconv.Itoa(i+1) || event.Path != "test/allow" {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+	}
+}
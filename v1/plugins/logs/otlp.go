@@ -0,0 +1,283 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+const (
+	otlpScopeName     = "github.com/IUAD1IY7/opa/v1/plugins/logs"
+	otlpEventName     = "opa.decision"
+	otlpServiceName   = "opa"
+	otlpAttrPrefix    = "opa."
+	otlpLabelPrefix   = otlpAttrPrefix + "labels."
+	otlpMetricPrefix  = otlpAttrPrefix + "metrics."
+	otlpBundlePrefix  = otlpAttrPrefix + "bundles."
+	otlpTraceIDLength = 16
+	otlpSpanIDLength  = 8
+)
+
+// otlpMaxLenSize is the maximum size of the length prefix of a message in a
+// request, whose size is limited by maxUploadSizeLimitBytes.
+const otlpMaxLenSize = 5
+
+// otlpChunk holds the log records of an OTLP chunk. Records are grouped into
+// one resource per distinct set of labels.
+type otlpChunk struct {
+	req       *collogspb.ExportLogsServiceRequest
+	resources map[string]*logspb.ScopeLogs
+	size      int64 // upper bound of the encoded size of req
+}
+
+func newOTLPChunk() *otlpChunk {
+	return &otlpChunk{
+		req:       &collogspb.ExportLogsServiceRequest{},
+		resources: map[string]*logspb.ScopeLogs{},
+	}
+}
+
+// writeOTLP maps a serialized event to an OTel log record and adds it to the
+// current chunk. If the encoded request would exceed the upload limit, the
+// chunk is closed and returned, and the record is added to the next chunk.
+func (enc *chunkEncoder) writeOTLP(bs []byte) (result [][]byte, err error) {
+	var event map[string]any
+	if err := util.NewJSONDecoder(bytes.NewReader(bs)).Decode(&event); err != nil {
+		return nil, err
+	}
+
+	labels, _ := event["labels"].(map[string]any)
+	key := otlpLabelsKey(labels)
+	record := newOTLPLogRecord(event, time.Now())
+
+	recordSize := otlpFieldSize(record)
+	resourceSize := otlpResourceSize(labels)
+
+	if recordSize+resourceSize > enc.limit {
+		if enc.metrics != nil {
+			enc.metrics.Counter(encLogExUploadSizeLimitCounterName).Incr()
+		}
+		return nil, fmt.Errorf("upload chunk size (%d) exceeds upload_size_limit_bytes (%d)",
+			recordSize+resourceSize, enc.limit)
+	}
+
+	if enc.otlp == nil {
+		enc.otlp = newOTLPChunk()
+	}
+
+	size := recordSize
+	if _, ok := enc.otlp.resources[key]; !ok {
+		size += resourceSize
+	}
+
+	if enc.eventsWritten > 0 && enc.otlp.size+size > enc.limit {
+		result, err = enc.flushOTLP()
+		if err != nil {
+			return nil, err
+		}
+		size = recordSize + resourceSize
+	}
+
+	enc.otlp.add(key, labels, record)
+	enc.otlp.size += size
+	enc.eventsWritten++
+
+	return result, nil
+}
+
+// flushOTLP closes the current chunk and returns it as a gzip compressed
+// ExportLogsServiceRequest in protobuf encoding.
+func (enc *chunkEncoder) flushOTLP() ([][]byte, error) {
+	if enc.eventsWritten == 0 {
+		return nil, nil
+	}
+
+	bs, err := proto.Marshal(enc.otlp.req)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(bs); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	enc.otlp = newOTLPChunk()
+	enc.eventsWritten = 0
+
+	return [][]byte{buf.Bytes()}, nil
+}
+
+func (c *otlpChunk) add(key string, labels map[string]any, record *logspb.LogRecord) {
+	scope, ok := c.resources[key]
+	if !ok {
+		scope = newOTLPScopeLogs()
+		c.resources[key] = scope
+		c.req.ResourceLogs = append(c.req.ResourceLogs, &logspb.ResourceLogs{
+			Resource:  &resourcepb.Resource{Attributes: otlpResourceAttributes(labels)},
+			ScopeLogs: []*logspb.ScopeLogs{scope},
+		})
+	}
+
+	scope.LogRecords = append(scope.LogRecords, record)
+}
+
+func newOTLPScopeLogs() *logspb.ScopeLogs {
+	return &logspb.ScopeLogs{
+		Scope: &commonpb.InstrumentationScope{Name: otlpScopeName},
+	}
+}
+
+// otlpFieldSize returns the encoded size of the message as a field of
+// another message.
+func otlpFieldSize(m proto.Message) int64 {
+	return int64(1 + protowire.SizeBytes(proto.Size(m)))
+}
+
+// otlpResourceSize returns an upper bound of the encoded size of a resource
+// without log records. The length prefixes of the resource and scope grow as
+// records are added, so their maximum size is assumed.
+func otlpResourceSize(labels map[string]any) int64 {
+	resource := &resourcepb.Resource{Attributes: otlpResourceAttributes(labels)}
+	return 2*(1+otlpMaxLenSize) + otlpFieldSize(resource) + otlpFieldSize(newOTLPScopeLogs().Scope)
+}
+
+func otlpLabelsKey(labels map[string]any) string {
+	var sb strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		fmt.Fprintf(&sb, "%q=%q,", k, fmt.Sprint(labels[k]))
+	}
+	return sb.String()
+}
+
+func otlpResourceAttributes(labels map[string]any) []*commonpb.KeyValue {
+	attrs := []*commonpb.KeyValue{otlpKeyValue("service.name", otlpServiceName)}
+
+	if id, ok := labels["id"]; ok {
+		attrs = append(attrs, otlpKeyValue("service.instance.id", id))
+	}
+	if version, ok := labels["version"]; ok {
+		attrs = append(attrs, otlpKeyValue("service.version", version))
+	}
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, otlpKeyValue(otlpLabelPrefix+k, labels[k]))
+	}
+
+	return attrs
+}
+
+func newOTLPLogRecord(event map[string]any, observed time.Time) *logspb.LogRecord {
+	record := &logspb.LogRecord{
+		EventName:            otlpEventName,
+		ObservedTimeUnixNano: uint64(observed.UnixNano()),
+		SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:         "INFO",
+		Body:                 otlpAnyValue(event),
+	}
+
+	if ts, ok := event["timestamp"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			record.TimeUnixNano = uint64(t.UnixNano())
+		}
+	}
+
+	if _, ok := event["error"]; ok {
+		record.SeverityNumber = logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+		record.SeverityText = "ERROR"
+	}
+
+	// Malformed IDs are left out of the record but kept in the body.
+	if id, ok := event["trace_id"].(string); ok {
+		if bs, err := hex.DecodeString(id); err == nil && len(bs) == otlpTraceIDLength {
+			record.TraceId = bs
+		}
+	}
+	if id, ok := event["span_id"].(string); ok {
+		if bs, err := hex.DecodeString(id); err == nil && len(bs) == otlpSpanIDLength {
+			record.SpanId = bs
+		}
+	}
+
+	for _, k := range []string{"decision_id", "path", "query", "revision", "requested_by", "req_id"} {
+		if v, ok := event[k]; ok {
+			record.Attributes = append(record.Attributes, otlpKeyValue(otlpAttrPrefix+k, v))
+		}
+	}
+
+	bundles, _ := event["bundles"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(bundles)) {
+		if info, ok := bundles[name].(map[string]any); ok {
+			if revision, ok := info["revision"]; ok {
+				record.Attributes = append(record.Attributes, otlpKeyValue(otlpBundlePrefix+name+".revision", revision))
+			}
+		}
+	}
+
+	metrics, _ := event["metrics"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(metrics)) {
+		record.Attributes = append(record.Attributes, otlpKeyValue(otlpMetricPrefix+name, metrics[name]))
+	}
+
+	return record
+}
+
+func otlpKeyValue(key string, value any) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: otlpAnyValue(value)}
+}
+
+// otlpAnyValue converts a decoded JSON value into an OTLP AnyValue.
+func otlpAnyValue(value any) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+		if f, err := v.Float64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.String()}}
+	case []any:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i := range v {
+			values[i] = otlpAnyValue(v[i])
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]any:
+		kvs := make([]*commonpb.KeyValue, 0, len(v))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			kvs = append(kvs, otlpKeyValue(k, v[k]))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: kvs}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
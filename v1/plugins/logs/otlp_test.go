@@ -0,0 +1,414 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/plugins"
+	lstat "github.com/IUAD1IY7/opa/v1/plugins/logs/status"
+	"github.com/IUAD1IY7/opa/v1/server"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+)
+
+// testCollector is a stand-in for an OTLP/HTTP collector.
+type testCollector struct {
+	t      *testing.T
+	mtx    sync.Mutex
+	status []int // status codes to respond with, then 200
+	reqs   []*collogspb.ExportLogsServiceRequest
+}
+
+func (c *testCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if ct := r.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+		c.t.Errorf("unexpected content type: %v", ct)
+	}
+
+	if len(c.status) > 0 {
+		status := c.status[0]
+		c.status = c.status[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	gr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		c.t.Error(err)
+		return
+	}
+	bs, err := io.ReadAll(gr)
+	if err != nil {
+		c.t.Error(err)
+		return
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(bs, &req); err != nil {
+		c.t.Error(err)
+		return
+	}
+	c.reqs = append(c.reqs, &req)
+
+	resp, err := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+	if err != nil {
+		c.t.Error(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(resp)
+}
+
+func (c *testCollector) records() []*logspb.LogRecord {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var result []*logspb.LogRecord
+	for _, req := range c.reqs {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				result = append(result, sl.LogRecords...)
+			}
+		}
+	}
+	return result
+}
+
+func otlpAttributes(kvs []*commonpb.KeyValue) map[string]string {
+	result := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		switch v := kv.Value.Value.(type) {
+		case *commonpb.AnyValue_StringValue:
+			result[kv.Key] = v.StringValue
+		case *commonpb.AnyValue_IntValue:
+			result[kv.Key] = fmt.Sprint(v.IntValue)
+		case *commonpb.AnyValue_DoubleValue:
+			result[kv.Key] = fmt.Sprint(v.DoubleValue)
+		case *commonpb.AnyValue_BoolValue:
+			result[kv.Key] = fmt.Sprint(v.BoolValue)
+		default:
+			result[kv.Key] = kv.Value.String()
+		}
+	}
+	return result
+}
+
+func TestOTLPRequest(t *testing.T) {
+	t.Parallel()
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID := "00f067aa0ba902b7"
+
+	event := newTestEvent(t, "1", false)
+	event.TraceID = traceID
+	event.SpanID = spanID
+	event.Bundles = map[string]BundleInfoV1{"authz": {Revision: "r1"}}
+	event.Metrics = map[string]any{"timer_rego_query_eval_ns": 1234}
+	event.RequestID = 7
+
+	other := newTestEvent(t, "2", false)
+	other.Labels = map[string]string{"id": "other-instance-id", "version": "1.2.3"}
+	other.TraceID = "not-a-trace-id"
+	other.Error = errors.New("boom")
+
+	enc := newChunkEncoder(defaultUploadSizeLimitBytes).WithFormat(otlpFormat)
+	for _, e := range []*EventV1{event, other} {
+		serialized, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := enc.WriteBytes(serialized); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunks, err := enc.Flush()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk but got %d", len(chunks))
+	}
+
+	req, _ := decodeOTLPRequest(t, chunks[0])
+	if len(req.ResourceLogs) != 2 {
+		t.Fatalf("expected a resource per label set but got: %v", req.ResourceLogs)
+	}
+
+	resource := otlpAttributes(req.ResourceLogs[0].Resource.Attributes)
+	expResource := map[string]string{
+		"service.name":        "opa",
+		"service.instance.id": "test-instance-id",
+		"opa.labels.id":       "test-instance-id",
+		"opa.labels.app":      "example-app",
+	}
+	for k, v := range expResource {
+		if resource[k] != v {
+			t.Fatalf("expected resource attribute %v=%v but got: %v", k, v, resource)
+		}
+	}
+	if v := otlpAttributes(req.ResourceLogs[1].Resource.Attributes)["service.version"]; v != "1.2.3" {
+		t.Fatalf("expected service version but got: %v", v)
+	}
+
+	record := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if hex.EncodeToString(record.TraceId) != traceID || hex.EncodeToString(record.SpanId) != spanID {
+		t.Fatalf("unexpected trace context: %x %x", record.TraceId, record.SpanId)
+	}
+	if record.TimeUnixNano != uint64(event.Timestamp.UnixNano()) {
+		t.Fatalf("unexpected timestamp: %v", record.TimeUnixNano)
+	}
+	if record.EventName != otlpEventName || record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Fatalf("unexpected record: %v", record)
+	}
+
+	attrs := otlpAttributes(record.Attributes)
+	expAttrs := map[string]string{
+		"opa.decision_id":                      "1",
+		"opa.path":                             "foo/bar",
+		"opa.requested_by":                     "test",
+		"opa.req_id":                           "7",
+		"opa.bundles.authz.revision":           "r1",
+		"opa.metrics.timer_rego_query_eval_ns": "1234",
+	}
+	for k, v := range expAttrs {
+		if attrs[k] != v {
+			t.Fatalf("expected attribute %v=%v but got: %v", k, v, attrs)
+		}
+	}
+
+	body := otlpAttributes(record.Body.GetKvlistValue().GetValues())
+	if !strings.Contains(body["input"], "GET") || body["result"] != "false" {
+		t.Fatalf("expected the event in the body but got: %v", body)
+	}
+
+	record = req.ResourceLogs[1].ScopeLogs[0].LogRecords[0]
+	if record.TraceId != nil || record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		t.Fatalf("unexpected record: %v", record)
+	}
+}
+
+func decodeOTLPRequest(t *testing.T, chunk []byte) (*collogspb.ExportLogsServiceRequest, int) {
+	t.Helper()
+
+	gr, err := gzip.NewReader(bytes.NewReader(chunk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	if err := proto.Unmarshal(bs, &req); err != nil {
+		t.Fatal(err)
+	}
+	return &req, len(bs)
+}
+
+func TestOTLPChunkSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	limit := int64(2048)
+	enc := newChunkEncoder(limit).WithFormat(otlpFormat).WithMetrics(metrics.New())
+
+	var chunks [][]byte
+	for i := range 20 {
+		event := newTestEvent(t, strconv.Itoa(i), false)
+		if i%2 == 1 {
+			event.Labels = map[string]string{"id": "other-instance-id"}
+		}
+		result, err := enc.Write(*event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, result...)
+	}
+	result, err := enc.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks = append(chunks, result...)
+
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks but got %d", len(chunks))
+	}
+
+	var records int
+	for _, chunk := range chunks {
+		req, size := decodeOTLPRequest(t, chunk)
+		if int64(size) > limit {
+			t.Fatalf("expected chunk of at most %d bytes but got %d", limit, size)
+		}
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records += len(sl.LogRecords)
+			}
+		}
+	}
+	if records != 20 {
+		t.Fatalf("expected 20 records but got %d", records)
+	}
+
+	large := newTestEvent(t, "large", false)
+	var input any = strings.Repeat("x", int(limit))
+	large.Input = &input
+	if _, err := enc.Write(*large); err == nil || !strings.Contains(err.Error(), "exceeds upload_size_limit_bytes") {
+		t.Fatalf("expected size limit error but got: %v", err)
+	}
+	if n := enc.metrics.Counter(encLogExUploadSizeLimitCounterName).Value().(uint64); n != 1 {
+		t.Fatalf("expected size limit counter to be 1 but got %d", n)
+	}
+}
+
+func TestEventBufferOTLP(t *testing.T) {
+	t.Parallel()
+
+	collector := &testCollector{t: t, status: []int{http.StatusServiceUnavailable}}
+	client, ts := setupTestServer(t, defaultOTLPResourcePath, collector.ServeHTTP)
+	defer ts.Close()
+
+	b := newEventBuffer(defaultBufferSizeLimitEvents, client, defaultOTLPResourcePath, defaultUploadSizeLimitBytes).
+		WithFormat(otlpFormat).WithMetrics(metrics.New()).WithLogger(logging.NewNoOpLogger())
+
+	for _, id := range []string{"1", "2", "3"} {
+		b.Push(newTestEvent(t, id, false))
+	}
+
+	// The failed chunk is requeued and retried on the next upload.
+	var httpErr lstat.HTTPError
+	if err := b.Upload(context.Background()); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected upload to fail but got: %v", err)
+	}
+	if err := b.Upload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	records := collector.records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records but got %d", len(records))
+	}
+	for i, record := range records {
+		if id := otlpAttributes(record.Attributes)["opa.decision_id"]; id != fmt.Sprint(i+1) {
+			t.Fatalf("expected decision %d but got %v", i+1, id)
+		}
+	}
+}
+
+func TestPluginOTLP(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	collector := &testCollector{t: t}
+	ts := httptest.NewServer(collector)
+	defer ts.Close()
+
+	manager, err := plugins.New([]byte(fmt.Sprintf(`{"services": {"collector": {"url": %q}}}`, ts.URL)), "test-instance-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := plugins.TriggerManual
+	config, err := NewConfigBuilder().
+		WithBytes([]byte(`{"format": "otlp"}`)).
+		WithServices(manager.Services()).
+		WithTriggerMode(&tr).
+		Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *config.Resource != defaultOTLPResourcePath {
+		t.Fatalf("expected default OTLP resource but got: %v", *config.Resource)
+	}
+
+	plugin := New(config, manager)
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Stop(ctx)
+
+	if err := plugin.Log(ctx, &server.Info{DecisionID: "1", Path: "test/allow"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := plugin.Trigger(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	records := collector.records()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record but got %d", len(records))
+	}
+	attrs := otlpAttributes(records[0].Attributes)
+	if attrs["opa.decision_id"] != "1" || attrs["opa.path"] != "test/allow" {
+		t.Fatalf("unexpected attributes: %v", attrs)
+	}
+}
+
+func TestOTLPConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note     string
+		config   string
+		resource string
+		wantErr  string
+	}{
+		{
+			note:     "default format",
+			config:   `{"service": "example"}`,
+			resource: defaultResourcePath,
+		},
+		{
+			note:     "otlp",
+			config:   `{"service": "example", "format": "otlp"}`,
+			resource: defaultOTLPResourcePath,
+		},
+		{
+			note:     "otlp with resource",
+			config:   `{"service": "example", "format": "otlp", "resource": "/otlp/v1/logs"}`,
+			resource: "/otlp/v1/logs",
+		},
+		{
+			note:    "invalid format",
+			config:  `{"service": "example", "format": "xml"}`,
+			wantErr: `invalid format "xml" in decision_logs`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			config, err := ParseConfig([]byte(tc.config), []string{"example"}, nil)
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "" && *config.Resource != tc.resource:
+				t.Fatalf("expected resource %v but got: %v", tc.resource, *config.Resource)
+			}
+		})
+	}
+}
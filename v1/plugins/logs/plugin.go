@@ -68,6 +68,7 @@ type EventV1 struct {
 	Metrics        map[string]any          `json:"metrics,omitempty"`
 	RequestID      uint64                  `json:"req_id,omitempty"`
 	RequestContext *RequestContext         `json:"request_context,omitempty"`
+	Shadow         map[string]ShadowInfoV1 `json:"shadow,omitempty"`
 
 	inputAST ast.Value
 }
@@ -77,6 +78,15 @@ type BundleInfoV1 struct {
 	Revision string `json:"revision,omitempty"`
 }
 
+// ShadowInfoV1 describes the result of a decision log event re-evaluated
+// against a bundle revision in shadow evaluation.
+type ShadowInfoV1 struct {
+	Revision string `json:"revision,omitempty"`
+	Result   *any   `json:"result,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Diverged bool   `json:"diverged"`
+}
+
 type RequestContext struct {
 	HTTPRequest *HTTPRequestContext `json:"http,omitempty"`
 }
@@ -94,6 +104,27 @@ func (b *BundleInfoV1) AST() ast.Value {
 	return result
 }
 
+// AST returns the ShadowInfoV1 as an AST value
+func (s *ShadowInfoV1) AST() (ast.Value, error) {
+	result := ast.NewObject(
+		ast.Item(ast.InternedStringTerm("diverged"), ast.InternedBooleanTerm(s.Diverged)),
+	)
+	if len(s.Revision) > 0 {
+		result.Insert(ast.InternedStringTerm("revision"), ast.StringTerm(s.Revision))
+	}
+	if s.Result != nil {
+		x, err := roundtripJSONToAST(s.Result)
+		if err != nil {
+			return nil, err
+		}
+		result.Insert(ast.InternedStringTerm("result"), ast.NewTerm(x))
+	}
+	if len(s.Error) > 0 {
+		result.Insert(ast.InternedStringTerm("error"), ast.StringTerm(s.Error))
+	}
+	return result, nil
+}
+
 // AST returns the Rego AST representation for a given EventV1 object.
 // This avoids having to round trip through JSON while applying a decision log
 // mask policy to the event.
@@ -215,6 +246,18 @@ func (e *EventV1) AST() (ast.Value, error) {
 		event.Insert(ast.InternedStringTerm("req_id"), ast.UIntNumberTerm(e.RequestID))
 	}
 
+	if len(e.Shadow) > 0 {
+		shadowObj := ast.NewObject()
+		for k, v := range e.Shadow {
+			x, err := v.AST()
+			if err != nil {
+				return nil, err
+			}
+			shadowObj.Insert(ast.StringTerm(k), ast.NewTerm(x))
+		}
+		event.Insert(ast.InternedStringTerm("shadow"), ast.NewTerm(shadowObj))
+	}
+
 	return event, nil
 }
 
@@ -239,6 +282,7 @@ const (
 	minUploadSizeLimitBytes             = int64(90)         // A single event with a decision ID (69 bytes) + empty gzip file (21 bytes)
 	maxUploadSizeLimitBytes             = int64(4294967296) // about 4GB
 	defaultBufferSizeLimitBytes         = int64(0)          // unlimited
+	defaultDiskFsyncIntervalSeconds     = 1
 	defaultMaskDecisionPath             = "/system/log/mask"
 	defaultDropDecisionPath             = "/system/log/drop"
 	logRateLimitExDropCounterName       = "decision_logs_dropped_rate_limit_exceeded"
@@ -246,21 +290,31 @@ const (
 	logBufferEventDropCounterName       = "decision_logs_dropped_buffer_size_limit_exceeded"
 	logBufferSizeLimitExDropCounterName = "decision_logs_dropped_buffer_size_limit_bytes_exceeded"
 	logEncodingFailureCounterName       = "decision_logs_encoding_failure"
+	logFileWriteFailureCounterName      = "decision_logs_file_write_failure"
 	defaultResourcePath                 = "/logs"
+	defaultOTLPResourcePath             = "/v1/logs"
+	jsonFormat                          = "json"
+	otlpFormat                          = "otlp"
 	sizeBufferType                      = "size"
 	eventBufferType                     = "event"
+	diskBufferType                      = "disk"
+	diskFsyncEvent                      = "event"
+	diskFsyncInterval                   = "interval"
 )
 
 // ReportingConfig represents configuration for the plugin's reporting behaviour.
 type ReportingConfig struct {
-	BufferType            string               `json:"buffer_type,omitempty"`              // toggles how the buffer stores events, defaults to using bytes
-	BufferSizeLimitBytes  *int64               `json:"buffer_size_limit_bytes,omitempty"`  // max size of in-memory size buffer
-	BufferSizeLimitEvents *int64               `json:"buffer_size_limit_events,omitempty"` // max size of in-memory event channel buffer
-	UploadSizeLimitBytes  *int64               `json:"upload_size_limit_bytes,omitempty"`  // max size of upload payload
-	MinDelaySeconds       *int64               `json:"min_delay_seconds,omitempty"`        // min amount of time to wait between successful poll attempts
-	MaxDelaySeconds       *int64               `json:"max_delay_seconds,omitempty"`        // max amount of time to wait between poll attempts
-	MaxDecisionsPerSecond *float64             `json:"max_decisions_per_second,omitempty"` // max number of decision logs to buffer per second
-	Trigger               *plugins.TriggerMode `json:"trigger,omitempty"`                  // trigger mode
+	BufferType                 string               `json:"buffer_type,omitempty"`                   // toggles how the buffer stores events, defaults to using bytes
+	BufferPath                 string               `json:"buffer_path,omitempty"`                   // directory of the disk buffer
+	BufferFsync                *string              `json:"buffer_fsync,omitempty"`                  // when writes to the disk buffer are synced
+	BufferFsyncIntervalSeconds *int64               `json:"buffer_fsync_interval_seconds,omitempty"` // max delay of syncs of the disk buffer
+	BufferSizeLimitBytes       *int64               `json:"buffer_size_limit_bytes,omitempty"`       // max size of in-memory size buffer
+	BufferSizeLimitEvents      *int64               `json:"buffer_size_limit_events,omitempty"`      // max size of in-memory event channel buffer
+	UploadSizeLimitBytes       *int64               `json:"upload_size_limit_bytes,omitempty"`       // max size of upload payload
+	MinDelaySeconds            *int64               `json:"min_delay_seconds,omitempty"`             // min amount of time to wait between successful poll attempts
+	MaxDelaySeconds            *int64               `json:"max_delay_seconds,omitempty"`             // max amount of time to wait between poll attempts
+	MaxDecisionsPerSecond      *float64             `json:"max_decisions_per_second,omitempty"`      // max number of decision logs to buffer per second
+	Trigger                    *plugins.TriggerMode `json:"trigger,omitempty"`                       // trigger mode
 }
 
 type RequestContextConfig struct {
@@ -273,18 +327,22 @@ type HTTPRequestContextConfig struct {
 
 // Config represents the plugin configuration.
 type Config struct {
-	Plugin          *string              `json:"plugin"`
-	Service         string               `json:"service"`
-	PartitionName   string               `json:"partition_name,omitempty"`
-	Reporting       ReportingConfig      `json:"reporting"`
-	RequestContext  RequestContextConfig `json:"request_context"`
-	MaskDecision    *string              `json:"mask_decision"`
-	DropDecision    *string              `json:"drop_decision"`
-	ConsoleLogs     bool                 `json:"console"`
-	Resource        *string              `json:"resource"`
-	NDBuiltinCache  bool                 `json:"nd_builtin_cache,omitempty"`
-	maskDecisionRef ast.Ref
-	dropDecisionRef ast.Ref
+	Plugin            *string              `json:"plugin"`
+	Service           string               `json:"service"`
+	PartitionName     string               `json:"partition_name,omitempty"`
+	Reporting         ReportingConfig      `json:"reporting"`
+	RequestContext    RequestContextConfig `json:"request_context"`
+	MaskDecision      *string              `json:"mask_decision"`
+	DropDecision      *string              `json:"drop_decision"`
+	SampleDecision    *string              `json:"sample_decision"`
+	ConsoleLogs       bool                 `json:"console"`
+	File              *FileConfig          `json:"file,omitempty"`
+	Resource          *string              `json:"resource"`
+	Format            string               `json:"format,omitempty"`
+	NDBuiltinCache    bool                 `json:"nd_builtin_cache,omitempty"`
+	maskDecisionRef   ast.Ref
+	dropDecisionRef   ast.Ref
+	sampleDecisionRef ast.Ref
 }
 
 func (c *Config) validateAndInjectDefaults(services []string, pluginsList []string, trigger *plugins.TriggerMode, l logging.Logger) error {
@@ -297,11 +355,11 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 		if !found {
 			return fmt.Errorf("invalid plugin name %q in decision_logs", *c.Plugin)
 		}
-	} else if c.Service == "" && len(services) != 0 && !c.ConsoleLogs {
+	} else if c.Service == "" && len(services) != 0 && !c.ConsoleLogs && c.File == nil {
 		// For backwards compatibility allow defaulting to the first
-		// service listed, but only if console logging is disabled. If enabled
-		// we can't tell if the deployer wanted to use only console logs or
-		// both console logs and the default service option.
+		// service listed, but only if console and file logging are disabled.
+		// If enabled we can't tell if the deployer wanted to use only local
+		// logs or both local logs and the default service option.
 		c.Service = services[0]
 	} else if c.Service != "" {
 		found := slices.Contains(services, c.Service)
@@ -311,6 +369,12 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 		}
 	}
 
+	if c.File != nil {
+		if err := c.File.validateAndInjectDefaults(); err != nil {
+			return err
+		}
+	}
+
 	t, err := plugins.ValidateAndInjectDefaultsForTriggerMode(trigger, c.Reporting.Trigger)
 	if err != nil {
 		return fmt.Errorf("invalid decision_log config: %w", err)
@@ -365,8 +429,44 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 
 	if c.Reporting.BufferType == "" {
 		c.Reporting.BufferType = sizeBufferType
-	} else if c.Reporting.BufferType != eventBufferType && c.Reporting.BufferType != sizeBufferType {
-		return fmt.Errorf("invalid buffer type %q, expected %q or %q", c.Reporting.BufferType, eventBufferType, sizeBufferType)
+	} else if c.Reporting.BufferType != eventBufferType && c.Reporting.BufferType != sizeBufferType && c.Reporting.BufferType != diskBufferType {
+		return fmt.Errorf("invalid buffer type %q, expected %q, %q or %q", c.Reporting.BufferType, eventBufferType, sizeBufferType, diskBufferType)
+	}
+
+	if c.Reporting.BufferType == diskBufferType {
+		if c.Reporting.BufferPath == "" {
+			return fmt.Errorf("invalid decision_log config, 'buffer_path' is required for the %v buffer type", diskBufferType)
+		}
+		if c.Reporting.BufferSizeLimitEvents != nil {
+			return fmt.Errorf("invalid decision_log config, 'buffer_size_limit_events' isn't supported for the %v buffer type", diskBufferType)
+		}
+
+		fsync := diskFsyncInterval
+		if c.Reporting.BufferFsync != nil {
+			fsync = *c.Reporting.BufferFsync
+		}
+		switch fsync {
+		case diskFsyncEvent:
+			if c.Reporting.BufferFsyncIntervalSeconds != nil {
+				return fmt.Errorf("invalid decision_log config, 'buffer_fsync_interval_seconds' is only supported for the %q 'buffer_fsync' policy", diskFsyncInterval)
+			}
+		case diskFsyncInterval:
+			interval := int64(defaultDiskFsyncIntervalSeconds)
+			if c.Reporting.BufferFsyncIntervalSeconds != nil {
+				if *c.Reporting.BufferFsyncIntervalSeconds <= 0 {
+					return errors.New("invalid decision_log config, 'buffer_fsync_interval_seconds' must be higher than 0")
+				}
+				interval = *c.Reporting.BufferFsyncIntervalSeconds
+			}
+			c.Reporting.BufferFsyncIntervalSeconds = &interval
+		default:
+			return fmt.Errorf("invalid decision_log config, invalid 'buffer_fsync' policy %q, expected %q or %q", fsync, diskFsyncEvent, diskFsyncInterval)
+		}
+		c.Reporting.BufferFsync = &fsync
+	} else if c.Reporting.BufferPath != "" {
+		return fmt.Errorf("invalid decision_log config, 'buffer_path' is only supported for the %v buffer type", diskBufferType)
+	} else if c.Reporting.BufferFsync != nil || c.Reporting.BufferFsyncIntervalSeconds != nil {
+		return fmt.Errorf("invalid decision_log config, 'buffer_fsync' and 'buffer_fsync_interval_seconds' are only supported for the %v buffer type", diskBufferType)
 	}
 
 	if c.Reporting.BufferType == eventBufferType && c.Reporting.BufferSizeLimitBytes != nil {
@@ -419,11 +519,32 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 		return fmt.Errorf("invalid drop_decision in decision_logs: %w", err)
 	}
 
+	if c.SampleDecision == nil {
+		sampleDecision := defaultSampleDecisionPath
+		c.SampleDecision = &sampleDecision
+	}
+
+	c.sampleDecisionRef, err = ref.ParseDataPath(*c.SampleDecision)
+	if err != nil {
+		return fmt.Errorf("invalid sample_decision in decision_logs: %w", err)
+	}
+
+	switch c.Format {
+	case "":
+		c.Format = jsonFormat
+	case jsonFormat, otlpFormat:
+	default:
+		return fmt.Errorf("invalid format %q in decision_logs, expected %q or %q", c.Format, jsonFormat, otlpFormat)
+	}
+
 	if c.PartitionName != "" {
 		resourcePath := fmt.Sprintf("/logs/%v", c.PartitionName)
 		c.Resource = &resourcePath
 	} else if c.Resource == nil {
 		resourcePath := defaultResourcePath
+		if c.Format == otlpFormat {
+			resourcePath = defaultOTLPResourcePath
+		}
 		c.Resource = &resourcePath
 	} else {
 		if _, err := url.Parse(*c.Resource); err != nil {
@@ -436,23 +557,27 @@ func (c *Config) validateAndInjectDefaults(services []string, pluginsList []stri
 
 // Plugin implements decision log buffering and uploading.
 type Plugin struct {
-	manager       *plugins.Manager
-	config        Config
-	runningBuffer string
-	reconfigMtx   sync.RWMutex // reconfigMtx blocks reads/writes on buffer reconfiguration
-	eventBuffer   *eventBuffer
-	buffer        *logBuffer
-	enc           *chunkEncoder
-	mtx           sync.Mutex
-	statusMtx     sync.Mutex
-	stop          chan chan struct{}
-	reconfig      chan reconfigure
-	preparedMask  prepareOnce
-	preparedDrop  prepareOnce
-	limiter       *rate.Limiter
-	metrics       metrics.Metrics
-	logger        logging.Logger
-	status        *lstat.Status
+	manager        *plugins.Manager
+	config         Config
+	runningBuffer  string
+	reconfigMtx    sync.RWMutex // reconfigMtx blocks reads/writes on buffer reconfiguration
+	eventBuffer    *eventBuffer
+	diskBuffer     *diskBuffer
+	buffer         *logBuffer
+	fileSink       *fileSink
+	enc            *chunkEncoder
+	mtx            sync.Mutex
+	statusMtx      sync.Mutex
+	stop           chan chan struct{}
+	reconfig       chan reconfigure
+	preparedMask   prepareOnce
+	preparedDrop   prepareOnce
+	preparedSample prepareOnce
+	sampleStats    sampleStats
+	limiter        *rate.Limiter
+	metrics        metrics.Metrics
+	logger         logging.Logger
+	status         *lstat.Status
 }
 
 type prepareOnce struct {
@@ -549,7 +674,7 @@ func (b *ConfigBuilder) Parse() (*Config, error) {
 		return nil, err
 	}
 
-	if parsedConfig.Plugin == nil && parsedConfig.Service == "" && len(b.services) == 0 && !parsedConfig.ConsoleLogs {
+	if parsedConfig.Plugin == nil && parsedConfig.Service == "" && len(b.services) == 0 && !parsedConfig.ConsoleLogs && parsedConfig.File == nil {
 		// Nothing to validate or inject
 		return nil, nil
 	}
@@ -565,15 +690,16 @@ func (b *ConfigBuilder) Parse() (*Config, error) {
 func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 
 	plugin := &Plugin{
-		manager:      manager,
-		config:       *parsedConfig,
-		stop:         make(chan chan struct{}),
-		enc:          newChunkEncoder(*parsedConfig.Reporting.UploadSizeLimitBytes),
-		reconfig:     make(chan reconfigure),
-		logger:       manager.Logger().WithFields(map[string]any{"plugin": Name}),
-		status:       &lstat.Status{},
-		preparedDrop: *newPrepareOnce(),
-		preparedMask: *newPrepareOnce(),
+		manager:        manager,
+		config:         *parsedConfig,
+		stop:           make(chan chan struct{}),
+		enc:            newChunkEncoder(*parsedConfig.Reporting.UploadSizeLimitBytes).WithFormat(parsedConfig.Format),
+		reconfig:       make(chan reconfigure),
+		logger:         manager.Logger().WithFields(map[string]any{"plugin": Name}),
+		status:         &lstat.Status{},
+		preparedDrop:   *newPrepareOnce(),
+		preparedSample: *newPrepareOnce(),
+		preparedMask:   *newPrepareOnce(),
 	}
 
 	switch parsedConfig.Reporting.BufferType {
@@ -583,11 +709,25 @@ func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 			plugin.manager.Client(plugin.config.Service),
 			*parsedConfig.Resource,
 			*parsedConfig.Reporting.UploadSizeLimitBytes,
-		).WithLogger(plugin.logger).WithMetrics(plugin.metrics)
+		).WithFormat(parsedConfig.Format).WithLogger(plugin.logger).WithMetrics(plugin.metrics)
 		plugin.runningBuffer = eventBufferType
 	case sizeBufferType:
 		plugin.buffer = newLogBuffer(*parsedConfig.Reporting.BufferSizeLimitBytes)
 		plugin.runningBuffer = sizeBufferType
+	case diskBufferType:
+		plugin.diskBuffer = newDiskBuffer(
+			parsedConfig.Reporting.BufferPath,
+			*parsedConfig.Reporting.BufferSizeLimitBytes,
+			plugin.manager.Client(plugin.config.Service),
+			*parsedConfig.Resource,
+			*parsedConfig.Reporting.UploadSizeLimitBytes,
+		).WithFormat(parsedConfig.Format).WithLogger(plugin.logger).WithMetrics(plugin.metrics).
+			WithFsync(*parsedConfig.Reporting.BufferFsync, time.Duration(*parsedConfig.Reporting.BufferFsyncIntervalSeconds)*time.Second)
+		plugin.runningBuffer = diskBufferType
+	}
+
+	if parsedConfig.File != nil {
+		plugin.fileSink = newFileSink(*parsedConfig.File, plugin.logger)
 	}
 
 	if parsedConfig.Reporting.MaxDecisionsPerSecond != nil {
@@ -606,6 +746,9 @@ func New(parsedConfig *Config, manager *plugins.Manager) *Plugin {
 func (p *Plugin) WithMetrics(m metrics.Metrics) *Plugin {
 	p.metrics = m
 	p.enc.WithMetrics(m)
+	if p.diskBuffer != nil {
+		p.diskBuffer.WithMetrics(m)
+	}
 	return p
 }
 
@@ -623,6 +766,16 @@ func Lookup(manager *plugins.Manager) *Plugin {
 // Start starts the plugin.
 func (p *Plugin) Start(_ context.Context) error {
 	p.logger.Info("Starting decision logger.")
+
+	if p.runningBuffer == diskBufferType {
+		if err := p.diskBuffer.Open(); err != nil {
+			return err
+		}
+		if events, _ := p.diskBuffer.Depth(); events > 0 {
+			p.logger.Info("Replaying %d buffered decisions from %v.", events, p.config.Reporting.BufferPath)
+		}
+	}
+
 	go p.loop()
 	p.manager.UpdatePluginStatus(Name, &plugins.Status{State: plugins.StateOK})
 	return nil
@@ -641,6 +794,20 @@ func (p *Plugin) Stop(ctx context.Context) {
 	done := make(chan struct{})
 	p.stop <- done
 	<-done
+
+	p.reconfigMtx.Lock()
+	if p.fileSink != nil {
+		if err := p.fileSink.Close(); err != nil {
+			p.logger.Error("Failed to close decision log file: %v.", err)
+		}
+	}
+	if p.diskBuffer != nil {
+		if err := p.diskBuffer.Close(); err != nil {
+			p.logger.Error("Failed to close decision log buffer: %v.", err)
+		}
+	}
+	p.reconfigMtx.Unlock()
+
 	p.manager.UpdatePluginStatus(Name, &plugins.Status{State: plugins.StateNotReady})
 }
 
@@ -685,6 +852,18 @@ func (p *Plugin) Log(ctx context.Context, decision *server.Info) error {
 		bundles[name] = BundleInfoV1{Revision: info.Revision}
 	}
 
+	var shadow map[string]ShadowInfoV1
+	for name, info := range decision.Shadow {
+		if shadow == nil {
+			shadow = make(map[string]ShadowInfoV1, len(decision.Shadow))
+		}
+		s := ShadowInfoV1{Revision: info.Revision, Result: info.Result, Diverged: info.Diverged}
+		if info.Error != nil {
+			s.Error = info.Error.Error()
+		}
+		shadow[name] = s
+	}
+
 	event := EventV1{
 		Labels:         p.manager.Labels(),
 		DecisionID:     decision.DecisionID,
@@ -701,6 +880,7 @@ func (p *Plugin) Log(ctx context.Context, decision *server.Info) error {
 		RequestedBy:    decision.RemoteAddr,
 		Timestamp:      decision.Timestamp,
 		RequestID:      decision.RequestID,
+		Shadow:         shadow,
 		inputAST:       decision.InputAST,
 	}
 
@@ -744,6 +924,27 @@ func (p *Plugin) Log(ctx context.Context, decision *server.Info) error {
 		event.Error = decision.Error
 	}
 
+	// The sample decision sees the error in addition to the drop decision input.
+	sampleInput := input
+	if event.Error != nil {
+		if obj, ok := input.(ast.Object); ok {
+			if evalErr, err := roundtripJSONToAST(event.Error); err == nil {
+				obj = obj.Copy()
+				obj.Insert(ast.InternedStringTerm("error"), ast.NewTerm(evalErr))
+				sampleInput = obj
+			}
+		}
+	}
+
+	keep, err := p.sampleEvent(ctx, decision.Txn, sampleInput, &event)
+	if err != nil {
+		p.incrMetric(logSampleEvalFailureCounterName)
+		p.logger.Error("Log sample decision failed, keeping event: %v.", err)
+	} else if !keep {
+		p.logger.Debug("Decision log event to path %v sampled out", event.Path)
+		return nil
+	}
+
 	if err := p.maskEvent(ctx, decision.Txn, input, &event); err != nil {
 		// TODO(tsandall): see note below about error handling.
 		p.logger.Error("Log event masking failed: %v.", err)
@@ -756,6 +957,11 @@ func (p *Plugin) Log(ctx context.Context, decision *server.Info) error {
 		}
 	}
 
+	if err := p.writeFileEvent(event); err != nil {
+		p.incrMetric(logFileWriteFailureCounterName)
+		p.logger.Error("Failed to write decision log file: %v.", err)
+	}
+
 	if p.config.Service != "" {
 		p.encodeAndBufferEvent(event)
 	}
@@ -779,6 +985,7 @@ func (p *Plugin) Reconfigure(_ context.Context, config any) {
 
 	p.preparedMask.drop()
 	p.preparedDrop.drop()
+	p.preparedSample.drop()
 
 	<-done
 }
@@ -814,6 +1021,7 @@ func (p *Plugin) Trigger(ctx context.Context) error {
 func (p *Plugin) compilerUpdated(storage.Transaction) {
 	p.preparedMask.drop()
 	p.preparedDrop.drop()
+	p.preparedSample.drop()
 }
 
 func (p *Plugin) loop() {
@@ -894,8 +1102,11 @@ func (p *Plugin) doOneShot(ctx context.Context) error {
 }
 
 func (p *Plugin) oneShot(ctx context.Context) error {
-	if p.runningBuffer == eventBufferType {
+	switch p.runningBuffer {
+	case eventBufferType:
 		return p.eventBuffer.Upload(ctx)
+	case diskBufferType:
+		return p.diskBuffer.Upload(ctx)
 	}
 
 	// Make a local copy of the plugin's encoder and buffer and create
@@ -906,7 +1117,7 @@ func (p *Plugin) oneShot(ctx context.Context) error {
 	oldChunkEnc := p.enc
 	oldBuffer := p.buffer
 	p.buffer = newLogBuffer(*p.config.Reporting.BufferSizeLimitBytes)
-	p.enc = newChunkEncoder(*p.config.Reporting.UploadSizeLimitBytes).WithMetrics(p.metrics).
+	p.enc = newChunkEncoder(*p.config.Reporting.UploadSizeLimitBytes).WithMetrics(p.metrics).WithFormat(p.config.Format).
 		WithSoftLimit(oldChunkEnc.softLimit, oldChunkEnc.softLimitScaleDownExponent, oldChunkEnc.softLimitScaleUpExponent)
 	p.mtx.Unlock()
 
@@ -928,10 +1139,12 @@ func (p *Plugin) oneShot(ctx context.Context) error {
 
 	for bs := oldBuffer.Pop(); bs != nil; bs = oldBuffer.Pop() {
 		if err == nil {
-			err = uploadChunk(ctx, p.manager.Client(p.config.Service), *p.config.Resource, bs)
+			err = uploadChunk(ctx, p.manager.Client(p.config.Service), *p.config.Resource, p.config.Format, bs)
 		}
 		if err != nil {
-			if p.limiter != nil {
+			// OTLP chunks can't be decoded into events again, so they are
+			// requeued as they are.
+			if p.limiter != nil && oldChunkEnc.format != otlpFormat {
 				events, decErr := newChunkDecoder(bs).decode()
 				if decErr != nil {
 					continue
@@ -966,6 +1179,18 @@ func (p *Plugin) reconfigure(ctx context.Context, config any) {
 	p.reconfigMtx.Lock()
 	defer p.reconfigMtx.Unlock()
 
+	if p.fileSink == nil || newConfig.File == nil || !reflect.DeepEqual(p.fileSink.config, *newConfig.File) {
+		if p.fileSink != nil {
+			if err := p.fileSink.Close(); err != nil {
+				p.logger.Error("Failed to close decision log file: %v.", err)
+			}
+			p.fileSink = nil
+		}
+		if newConfig.File != nil {
+			p.fileSink = newFileSink(*newConfig.File, p.logger)
+		}
+	}
+
 	switch newConfig.Reporting.BufferType {
 	case eventBufferType:
 		if p.eventBuffer == nil {
@@ -973,16 +1198,17 @@ func (p *Plugin) reconfigure(ctx context.Context, config any) {
 				*p.config.Reporting.BufferSizeLimitEvents,
 				p.manager.Client(p.config.Service),
 				*p.config.Resource,
-				*p.config.Reporting.UploadSizeLimitBytes).WithLogger(p.logger).WithMetrics(p.metrics)
+				*p.config.Reporting.UploadSizeLimitBytes).WithFormat(p.config.Format).WithLogger(p.logger).WithMetrics(p.metrics)
 		} else {
 			p.eventBuffer.Reconfigure(
 				*p.config.Reporting.BufferSizeLimitEvents,
 				p.manager.Client(p.config.Service),
 				*p.config.Resource,
-				*p.config.Reporting.UploadSizeLimitBytes)
+				*p.config.Reporting.UploadSizeLimitBytes,
+				p.config.Format)
 		}
 
-		if p.runningBuffer == sizeBufferType {
+		if p.runningBuffer != eventBufferType {
 			if err := p.oneShot(ctx); err != nil && !errors.Is(err, &bufferEmpty{}) {
 				p.setStatus(err)
 			}
@@ -990,8 +1216,8 @@ func (p *Plugin) reconfigure(ctx context.Context, config any) {
 
 		p.runningBuffer = eventBufferType
 	case sizeBufferType:
-		if p.runningBuffer == eventBufferType {
-			if err := p.eventBuffer.Upload(ctx); err != nil && !errors.Is(err, &bufferEmpty{}) {
+		if p.runningBuffer != sizeBufferType {
+			if err := p.oneShot(ctx); err != nil && !errors.Is(err, &bufferEmpty{}) {
 				p.setStatus(err)
 			}
 		}
@@ -1001,6 +1227,42 @@ func (p *Plugin) reconfigure(ctx context.Context, config any) {
 		}
 
 		p.runningBuffer = sizeBufferType
+	case diskBufferType:
+		if p.runningBuffer != diskBufferType {
+			if err := p.oneShot(ctx); err != nil && !errors.Is(err, &bufferEmpty{}) {
+				p.setStatus(err)
+			}
+		}
+
+		if p.diskBuffer == nil || p.diskBuffer.dir != p.config.Reporting.BufferPath {
+			if p.diskBuffer != nil {
+				if err := p.diskBuffer.Close(); err != nil {
+					p.logger.Error("Failed to close decision log buffer: %v.", err)
+				}
+			}
+			p.diskBuffer = newDiskBuffer(
+				p.config.Reporting.BufferPath,
+				*p.config.Reporting.BufferSizeLimitBytes,
+				p.manager.Client(p.config.Service),
+				*p.config.Resource,
+				*p.config.Reporting.UploadSizeLimitBytes).WithFormat(p.config.Format).WithLogger(p.logger).WithMetrics(p.metrics).
+				WithFsync(*p.config.Reporting.BufferFsync, time.Duration(*p.config.Reporting.BufferFsyncIntervalSeconds)*time.Second)
+		} else {
+			p.diskBuffer.Reconfigure(
+				*p.config.Reporting.BufferSizeLimitBytes,
+				p.manager.Client(p.config.Service),
+				*p.config.Resource,
+				*p.config.Reporting.UploadSizeLimitBytes,
+				p.config.Format,
+				*p.config.Reporting.BufferFsync,
+				time.Duration(*p.config.Reporting.BufferFsyncIntervalSeconds)*time.Second)
+		}
+
+		if err := p.diskBuffer.Open(); err != nil {
+			p.setStatus(err)
+		}
+
+		p.runningBuffer = diskBufferType
 	}
 }
 
@@ -1018,9 +1280,13 @@ func (p *Plugin) encodeAndBufferEvent(event EventV1) {
 	p.reconfigMtx.RLock()
 	defer p.reconfigMtx.RUnlock()
 
-	if p.runningBuffer == eventBufferType {
+	switch p.runningBuffer {
+	case eventBufferType:
 		p.eventBuffer.Push(&event)
 		return
+	case diskBufferType:
+		p.diskBuffer.Push(&event)
+		return
 	}
 
 	result, err := p.encodeEvent(event)
@@ -1175,10 +1441,15 @@ func (p *Plugin) dropEvent(ctx context.Context, txn storage.Transaction, input a
 	return rs.Allowed(), nil
 }
 
-func uploadChunk(ctx context.Context, client rest.Client, uploadPath string, data []byte) error {
+func uploadChunk(ctx context.Context, client rest.Client, uploadPath string, format string, data []byte) error {
+
+	contentType := "application/json"
+	if format == otlpFormat {
+		contentType = "application/x-protobuf"
+	}
 
 	resp, err := client.
-		WithHeader("Content-Type", "application/json").
+		WithHeader("Content-Type", contentType).
 		WithHeader("Content-Encoding", "gzip").
 		WithBytes(data).
 		Do(ctx, "POST", uploadPath)
@@ -1189,6 +1460,9 @@ func uploadChunk(ctx context.Context, client rest.Client, uploadPath string, dat
 
 	defer util.Close(resp)
 
+	// An OTLP partial success means the collector accepted the request but
+	// rejected some records. Those records must not be retried, so it's
+	// treated like any other successful response.
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return lstat.HTTPError{StatusCode: resp.StatusCode}
 	}
@@ -1212,6 +1486,23 @@ func (p *Plugin) logEvent(event EventV1) error {
 	return nil
 }
 
+func (p *Plugin) writeFileEvent(event EventV1) error {
+	// only blocks when the file sink is being reconfigured
+	p.reconfigMtx.RLock()
+	defer p.reconfigMtx.RUnlock()
+
+	if p.fileSink == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(event); err != nil {
+		return err
+	}
+
+	return p.fileSink.Write(buf.Bytes())
+}
+
 func (p *Plugin) incrMetric(name string) {
 	if p.metrics != nil {
 		p.metrics.Counter(name).Incr()
@@ -1221,6 +1512,10 @@ func (p *Plugin) incrMetric(name string) {
 func (p *Plugin) setStatus(err error) {
 	p.statusMtx.Lock()
 	p.status.SetError(err)
+	if p.runningBuffer == diskBufferType {
+		p.status.BufferEvents, p.status.BufferBytes = p.diskBuffer.Depth()
+	}
+	p.status.Sampling = p.sampleStats.status()
 	oldStatus := p.status
 	p.statusMtx.Unlock()
 
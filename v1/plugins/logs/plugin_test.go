@@ -2913,6 +2913,44 @@ func TestPluginDropErrorHandling(t *testing.T) {
 	}
 }
 
+func TestPluginDiskBufferStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fixture := newTestFixture(t, testFixtureOptions{
+		ExtraConfig: map[string]any{
+			"reporting": map[string]any{
+				"buffer_type": diskBufferType,
+				"buffer_path": t.TempDir(),
+			},
+		},
+	})
+	defer fixture.server.stop()
+
+	fixture.server.ch = make(chan []EventV1, 1)
+	fixture.server.expCode = http.StatusInternalServerError
+
+	for i := range 2 {
+		if err := fixture.plugin.Log(ctx, &server.Info{DecisionID: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fixture.plugin.doOneShot(ctx); err == nil {
+		t.Fatal("expected upload to fail")
+	}
+	<-fixture.server.ch
+
+	fixture.plugin.statusMtx.Lock()
+	status := *fixture.plugin.status
+	fixture.plugin.statusMtx.Unlock()
+
+	if status.BufferEvents != 2 || status.BufferBytes == 0 || status.Code == "" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
 type testFixtureOptions struct {
 	ConsoleLogger                  *test.Logger
 	ReportingBufferType            string
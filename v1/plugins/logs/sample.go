@@ -0,0 +1,209 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	lstat "github.com/IUAD1IY7/opa/v1/plugins/logs/status"
+	"github.com/IUAD1IY7/opa/v1/rego"
+	"github.com/IUAD1IY7/opa/v1/storage"
+)
+
+const (
+	defaultSampleDecisionPath       = "/system/log/sample"
+	logSampleHeadKeptCounterName    = "decision_logs_sampled_head_kept"
+	logSampleHeadDroppedCounterName = "decision_logs_sampled_head_dropped"
+	logSampleTailKeptCounterName    = "decision_logs_sampled_tail_kept"
+	logSampleTailDroppedCounterName = "decision_logs_sampled_tail_dropped"
+	logSampleEvalFailureCounterName = "decision_logs_sample_decision_failure"
+)
+
+// sampleDecision is the result of the sample decision for an event. A
+// priority above zero always keeps the event and a priority below zero always
+// drops it (tail sampling). Otherwise the event is kept with the probability
+// given by rate (head sampling).
+type sampleDecision struct {
+	Rate     float64
+	Priority int
+}
+
+// parseSampleDecision accepts either a number, which is used as the rate, or
+// an object with optional "rate" and "priority" keys.
+func parseSampleDecision(x any) (sampleDecision, error) {
+	d := sampleDecision{Rate: 1}
+
+	switch x := x.(type) {
+	case json.Number:
+		rate, err := parseSampleRate(x)
+		if err != nil {
+			return d, err
+		}
+		d.Rate = rate
+	case map[string]any:
+		if v, ok := x["rate"]; ok {
+			n, ok := v.(json.Number)
+			if !ok {
+				return d, fmt.Errorf("sample rate must be a number, got %T", v)
+			}
+			rate, err := parseSampleRate(n)
+			if err != nil {
+				return d, err
+			}
+			d.Rate = rate
+		}
+		if v, ok := x["priority"]; ok {
+			n, ok := v.(json.Number)
+			if !ok {
+				return d, fmt.Errorf("sample priority must be an integer, got %T", v)
+			}
+			priority, err := n.Int64()
+			if err != nil {
+				return d, fmt.Errorf("sample priority must be an integer, got %v", n)
+			}
+			d.Priority = int(priority)
+		}
+	default:
+		return d, fmt.Errorf("sample decision must be a number or an object, got %T", x)
+	}
+
+	return d, nil
+}
+
+func parseSampleRate(n json.Number) (float64, error) {
+	rate, err := n.Float64()
+	if err != nil || rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("sample rate must be between 0 and 1, got %v", n)
+	}
+	return rate, nil
+}
+
+// sampleKey returns the value used to make the head sampling decision for an
+// event. Events sharing a trace are sampled together, so that all the
+// decisions made during a request are either kept or dropped. Hashing also
+// makes the decision consistent across OPA instances.
+func sampleKey(event *EventV1) string {
+	if event.TraceID != "" {
+		return event.TraceID
+	}
+	return event.DecisionID
+}
+
+// keep returns true if the event should be kept, and whether it was decided
+// by priority rather than rate.
+func (d sampleDecision) keep(event *EventV1) (keep bool, tail bool) {
+	switch {
+	case d.Priority > 0:
+		return true, true
+	case d.Priority < 0:
+		return false, true
+	case d.Rate >= 1:
+		return true, false
+	case d.Rate <= 0:
+		return false, false
+	}
+
+	var x float64
+	if key := sampleKey(event); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		x = float64(binary.BigEndian.Uint64(sum[:8])) / math.MaxUint64
+	} else {
+		x = rand.Float64()
+	}
+
+	return x < d.Rate, false
+}
+
+// sampleStats counts the sampling outcomes reported in the plugin status.
+type sampleStats struct {
+	headKept, headDropped, tailKept, tailDropped atomic.Int64
+}
+
+func (s *sampleStats) record(keep, tail bool) string {
+	switch {
+	case tail && keep:
+		s.tailKept.Add(1)
+		return logSampleTailKeptCounterName
+	case tail:
+		s.tailDropped.Add(1)
+		return logSampleTailDroppedCounterName
+	case keep:
+		s.headKept.Add(1)
+		return logSampleHeadKeptCounterName
+	default:
+		s.headDropped.Add(1)
+		return logSampleHeadDroppedCounterName
+	}
+}
+
+func (s *sampleStats) status() *lstat.SamplingStatus {
+	result := lstat.SamplingStatus{
+		HeadKept:    s.headKept.Load(),
+		HeadDropped: s.headDropped.Load(),
+		TailKept:    s.tailKept.Load(),
+		TailDropped: s.tailDropped.Load(),
+	}
+	if result == (lstat.SamplingStatus{}) {
+		return nil
+	}
+	return &result
+}
+
+// sampleEvent evaluates the sample decision and returns true if the event
+// should be kept. Events are kept if the decision is undefined.
+func (p *Plugin) sampleEvent(ctx context.Context, txn storage.Transaction, input ast.Value, event *EventV1) (bool, error) {
+	pq, err := p.preparedSample.prepareOnce(func() (*rego.PreparedEvalQuery, error) {
+		query := ast.NewBody(ast.NewExpr(ast.NewTerm(p.config.sampleDecisionRef)))
+		r := rego.New(
+			rego.ParsedQuery(query),
+			rego.Compiler(p.manager.GetCompiler()),
+			rego.Store(p.manager.Store),
+			rego.Transaction(txn),
+			rego.Runtime(p.manager.Info),
+			rego.EnablePrintStatements(p.manager.EnablePrintStatements()),
+			rego.PrintHook(p.manager.PrintHook()),
+		)
+
+		pq, err := r.PrepareForEval(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return &pq, nil
+	})
+
+	if err != nil {
+		return true, err
+	}
+
+	rs, err := pq.Eval(
+		ctx,
+		rego.EvalParsedInput(input),
+		rego.EvalTransaction(txn),
+	)
+
+	if err != nil {
+		return true, err
+	} else if len(rs) == 0 {
+		return true, nil
+	}
+
+	d, err := parseSampleDecision(rs[0].Expressions[0].Value)
+	if err != nil {
+		return true, err
+	}
+
+	keep, tail := d.keep(event)
+	p.incrMetric(p.sampleStats.record(keep, tail))
+
+	return keep, nil
+}
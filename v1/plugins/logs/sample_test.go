@@ -0,0 +1,210 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	"github.com/IUAD1IY7/opa/v1/plugins"
+	"github.com/IUAD1IY7/opa/v1/server"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+func TestParseSampleDecision(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		note    string
+		value   string
+		exp     sampleDecision
+		wantErr string
+	}{
+		{
+			note:  "rate",
+			value: `0.25`,
+			exp:   sampleDecision{Rate: 0.25},
+		},
+		{
+			note:  "object",
+			value: `{"rate": 0.5, "priority": 2}`,
+			exp:   sampleDecision{Rate: 0.5, Priority: 2},
+		},
+		{
+			note:  "priority only",
+			value: `{"priority": -1}`,
+			exp:   sampleDecision{Rate: 1, Priority: -1},
+		},
+		{
+			note:    "rate out of range",
+			value:   `1.5`,
+			wantErr: "sample rate must be between 0 and 1",
+		},
+		{
+			note:    "bad priority",
+			value:   `{"priority": 0.5}`,
+			wantErr: "sample priority must be an integer",
+		},
+		{
+			note:    "bad type",
+			value:   `"all"`,
+			wantErr: "sample decision must be a number or an object",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			d, err := parseSampleDecision(util.MustUnmarshalJSON([]byte(tc.value)))
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("expected error containing %q but got: %v", tc.wantErr, err)
+			case tc.wantErr == "" && d != tc.exp:
+				t.Fatalf("expected %+v but got %+v", tc.exp, d)
+			}
+		})
+	}
+}
+
+func TestSampleDecisionKeep(t *testing.T) {
+	t.Parallel()
+
+	d := sampleDecision{Rate: 0.1}
+
+	var kept int
+	for i := range 10000 {
+		event := &EventV1{DecisionID: strconv.Itoa(i)}
+		keep, tail := d.keep(event)
+		if tail {
+			t.Fatal("expected head sampling decision")
+		}
+		if again, _ := d.keep(event); again != keep {
+			t.Fatal("expected sampling decision to be deterministic")
+		}
+		if keep {
+			kept++
+		}
+	}
+	if kept < 800 || kept > 1200 {
+		t.Fatalf("expected about 1000 events to be kept but got %d", kept)
+	}
+
+	// Decisions within a trace are sampled together.
+	var traceKept int
+	for i := range 100 {
+		event := &EventV1{DecisionID: strconv.Itoa(i), TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}
+		if keep, _ := d.keep(event); keep {
+			traceKept++
+		}
+	}
+	if traceKept != 0 && traceKept != 100 {
+		t.Fatalf("expected all or none of the trace to be kept but got %d", traceKept)
+	}
+
+	if keep, tail := (sampleDecision{Rate: 0, Priority: 1}).keep(&EventV1{}); !keep || !tail {
+		t.Fatal("expected priority to keep the event")
+	}
+	if keep, tail := (sampleDecision{Rate: 1, Priority: -1}).keep(&EventV1{}); keep || !tail {
+		t.Fatal("expected priority to drop the event")
+	}
+}
+
+func TestPluginSample(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := inmem.New()
+
+	// Keep all denies and errors, but only 1% of allows.
+	policy := []byte(`package system.log
+
+sample := {"priority": 1} if {
+	not input.result
+} else := 0.01
+`)
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.UpsertPolicy(ctx, txn, "sample.rego", policy)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := plugins.New(nil, "test-instance-id", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "decisions.log")
+	config, err := ParseConfig([]byte(`{"file": {"path": "`+filepath.ToSlash(path)+`"}}`), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := metrics.New()
+	plugin := New(config, manager).WithMetrics(m)
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var allow, deny any = true, false
+	for i := range 1000 {
+		if err := plugin.Log(ctx, &server.Info{DecisionID: "allow-" + strconv.Itoa(i), Results: &allow}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range 10 {
+		if err := plugin.Log(ctx, &server.Info{DecisionID: "deny-" + strconv.Itoa(i), Results: &deny}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range 5 {
+		if err := plugin.Log(ctx, &server.Info{DecisionID: "error-" + strconv.Itoa(i), Error: errors.New("boom")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plugin.Stop(ctx)
+
+	stats := plugin.sampleStats.status()
+	if stats == nil || stats.TailKept != 15 || stats.TailDropped != 0 || stats.HeadKept+stats.HeadDropped != 1000 {
+		t.Fatalf("unexpected sampling stats: %+v", stats)
+	}
+	if stats.HeadKept < 1 || stats.HeadKept > 30 {
+		t.Fatalf("expected about 1%% of allows to be kept but got %d", stats.HeadKept)
+	}
+	if v := m.Counter(logSampleHeadDroppedCounterName).Value().(uint64); v != uint64(stats.HeadDropped) {
+		t.Fatalf("expected head dropped counter to be %d but got %d", stats.HeadDropped, v)
+	}
+
+	lines := readLines(t, path)
+	if int64(len(lines)) != stats.TailKept+stats.HeadKept {
+		t.Fatalf("expected %d events to be logged but got %d", stats.TailKept+stats.HeadKept, len(lines))
+	}
+	var denies int
+	for _, line := range lines {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+		if id := event["decision_id"].(string); !strings.HasPrefix(id, "allow-") {
+			denies++
+		}
+	}
+	if denies != 15 {
+		t.Fatalf("expected all denies and errors to be logged but got %d", denies)
+	}
+}
@@ -25,6 +25,22 @@ type Status struct {
 	Message  string          `json:"message,omitempty"`
 	HTTPCode json.Number     `json:"http_code,omitempty"`
 	Metrics  metrics.Metrics `json:"metrics,omitempty"`
+
+	// BufferEvents and BufferBytes report the depth of the disk buffer.
+	BufferEvents int64 `json:"buffer_events,omitempty"`
+	BufferBytes  int64 `json:"buffer_bytes,omitempty"`
+
+	Sampling *SamplingStatus `json:"sampling,omitempty"`
+}
+
+// SamplingStatus reports the number of events kept and dropped by the sample
+// decision. Head sampling is decided by the sample rate, tail sampling by the
+// sample priority.
+type SamplingStatus struct {
+	HeadKept    int64 `json:"head_kept"`
+	HeadDropped int64 `json:"head_dropped"`
+	TailKept    int64 `json:"tail_kept"`
+	TailDropped int64 `json:"tail_dropped"`
 }
 
 // SetError updates the status object to reflect a failure to upload or
@@ -0,0 +1,60 @@
+package changes
+
+import (
+	"errors"
+
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+var defaultBufferSize = 1024
+
+// Config represents the configuration for the Server.Changes settings
+type Config struct {
+	BufferSize *int `json:"buffer_size,omitempty"` // the number of committed changes kept for resuming streams
+}
+
+// ConfigBuilder assists in the construction of the plugin configuration.
+type ConfigBuilder struct {
+	raw []byte
+}
+
+// NewConfigBuilder returns a new ConfigBuilder to build and parse the server config
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// WithBytes sets the raw server config
+func (b *ConfigBuilder) WithBytes(config []byte) *ConfigBuilder {
+	b.raw = config
+	return b
+}
+
+// Parse returns a valid Config object with defaults injected.
+func (b *ConfigBuilder) Parse() (*Config, error) {
+	if b.raw == nil {
+		defaultConfig := &Config{
+			BufferSize: &defaultBufferSize,
+		}
+		return defaultConfig, nil
+	}
+
+	var result Config
+
+	if err := util.Unmarshal(b.raw, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, result.validateAndInjectDefaults()
+}
+
+func (c *Config) validateAndInjectDefaults() error {
+	if c.BufferSize == nil {
+		c.BufferSize = &defaultBufferSize
+	}
+
+	if *c.BufferSize < 0 {
+		return errors.New("invalid value for server.changes.buffer_size field, should be a non-negative number")
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package changes
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{
+			input:   `{}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"buffer_size": 100}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"buffer_size": 0}`,
+			wantErr: false,
+		},
+		{
+			input:   `{"buffer_size": -1}`,
+			wantErr: true,
+		},
+		{
+			input:   `{"buffer_size": "100"}`,
+			wantErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("TestConfigValidation_case_%d", i), func(t *testing.T) {
+			_, err := NewConfigBuilder().WithBytes([]byte(test.input)).Parse()
+			if err != nil && !test.wantErr {
+				t.Fatalf("Unexpected error: %s", err.Error())
+			}
+			if err == nil && test.wantErr {
+				t.Fatalf("Expected error for input %v", test.input)
+			}
+		})
+	}
+}
+
+func TestConfigValue(t *testing.T) {
+	tests := []struct {
+		input      []byte
+		bufferSize int
+	}{
+		{
+			input:      nil,
+			bufferSize: 1024,
+		},
+		{
+			input:      []byte(`{}`),
+			bufferSize: 1024,
+		},
+		{
+			input:      []byte(`{"buffer_size": 1000}`),
+			bufferSize: 1000,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("TestConfigValue_case_%d", i), func(t *testing.T) {
+			config, err := NewConfigBuilder().WithBytes(test.input).Parse()
+			if err != nil {
+				t.Fatalf("Error building configuration: %s", err.Error())
+			}
+			if *config.BufferSize != test.bufferSize {
+				t.Fatalf("Unexpected buffer size value, expected %v, got %v", test.bufferSize, *config.BufferSize)
+			}
+		})
+	}
+}
@@ -42,6 +42,7 @@ type collectors struct {
 	lastSuccessfulActivation *prometheus.GaugeVec
 	lastSuccessfulDownload   *prometheus.GaugeVec
 	lastSuccessfulRequest    *prometheus.GaugeVec
+	lastRollback             *prometheus.GaugeVec
 	bundleLoadDuration       *prometheus.HistogramVec
 }
 
@@ -104,6 +105,13 @@ func newCollectors(prometheusConfig *PrometheusConfig) *collectors {
 		},
 		[]string{"name"},
 	)
+	lastRollback := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "last_bundle_rollback",
+			Help: "Gauge for the last bundle rollback.",
+		},
+		[]string{"name"},
+	)
 
 	bundleLoadDuration := newBundleLoadDurationCollector(prometheusConfig)
 
@@ -116,6 +124,7 @@ func newCollectors(prometheusConfig *PrometheusConfig) *collectors {
 		lastSuccessfulActivation: lastSuccessfulActivation,
 		lastSuccessfulDownload:   lastSuccessfulDownload,
 		lastSuccessfulRequest:    lastSuccessfulRequest,
+		lastRollback:             lastRollback,
 		bundleLoadDuration:       bundleLoadDuration,
 	}
 }
@@ -169,6 +178,7 @@ func (c *collectors) toList() []prometheus.Collector {
 		c.lastSuccessfulActivation,
 		c.lastSuccessfulDownload,
 		c.lastSuccessfulRequest,
+		c.lastRollback,
 		c.bundleLoadDuration,
 	}
 }
@@ -572,6 +572,7 @@ func (p *Plugin) updatePrometheusMetrics(u *UpdateRequestV1) {
 		p.collectors.pluginStatus.WithLabelValues(name, string(plugin.State)).Set(1)
 	}
 	p.collectors.lastSuccessfulActivation.Reset()
+	p.collectors.lastRollback.Reset()
 	for _, bundle := range u.Bundles {
 		if bundle.Code == "" && !bundle.LastSuccessfulActivation.IsZero() {
 			p.collectors.loaded.WithLabelValues(bundle.Name).Inc()
@@ -582,6 +583,9 @@ func (p *Plugin) updatePrometheusMetrics(u *UpdateRequestV1) {
 		p.collectors.lastSuccessfulDownload.WithLabelValues(bundle.Name).Set(float64(bundle.LastSuccessfulDownload.UnixNano()))
 		p.collectors.lastSuccessfulRequest.WithLabelValues(bundle.Name).Set(float64(bundle.LastSuccessfulRequest.UnixNano()))
 		p.collectors.lastRequest.WithLabelValues(bundle.Name).Set(float64(bundle.LastRequest.UnixNano()))
+		if bundle.Rollback != nil {
+			p.collectors.lastRollback.WithLabelValues(bundle.Name).Set(float64(bundle.Rollback.Time.UnixNano()))
+		}
 
 		if bundle.Metrics != nil {
 			for stage, metric := range bundle.Metrics.All() {
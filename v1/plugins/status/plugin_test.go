@@ -204,11 +204,14 @@ func TestPluginPrometheus(t *testing.T) {
 	if registerMock.Collectors[fixture.plugin.collectors.lastSuccessfulRequest] != true {
 		t.Fatalf("Last Successful Request metric was not registered on prometheus")
 	}
+	if registerMock.Collectors[fixture.plugin.collectors.lastRollback] != true {
+		t.Fatalf("Last Rollback metric was not registered on prometheus")
+	}
 	if registerMock.Collectors[fixture.plugin.collectors.bundleLoadDuration] != true {
 		t.Fatalf("Bundle Load Duration metric was not registered on prometheus")
 	}
-	if len(registerMock.Collectors) != 9 {
-		t.Fatalf("Number of collectors expected (%v), got %v", 9, len(registerMock.Collectors))
+	if len(registerMock.Collectors) != 10 {
+		t.Fatalf("Number of collectors expected (%v), got %v", 10, len(registerMock.Collectors))
 	}
 
 	lastRequestMetricResult := time.UnixMilli(int64(testutil.ToFloat64(fixture.plugin.collectors.lastRequest) / 1e6))
@@ -264,8 +267,8 @@ func TestPluginPrometheus(t *testing.T) {
 	fixture.plugin.Reconfigure(ctx, prometheusReenabledConfig)
 	eventually(t, func() bool { return fixture.plugin.config.Prometheus == true })
 
-	if len(registerMock.Collectors) != 9 {
-		t.Fatalf("Number of collectors expected (%v), got %v", 9, len(registerMock.Collectors))
+	if len(registerMock.Collectors) != 10 {
+		t.Fatalf("Number of collectors expected (%v), got %v", 10, len(registerMock.Collectors))
 	}
 }
 
@@ -67,7 +67,7 @@ func (h *LoggingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var rctx logging.RequestContext
 	rctx.ReqID = atomic.AddUint64(&h.requestID, uint64(1))
 
-	recorder := newRecorder(h.logger, w, r, rctx.ReqID, h.loggingEnabled(logging.Debug))
+	recorder := newRecorder(h.logger, w, r, rctx.ReqID, h.loggingEnabled(logging.Debug) && !isChangesEndpoint(r))
 	t0 := time.Now()
 
 	if h.loggingEnabled(logging.Info) {
@@ -140,6 +140,10 @@ func (h *LoggingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		if h.loggingEnabled(logging.Debug) {
 			switch {
+			case isChangesEndpoint(r):
+				// the changes endpoint streams until the client disconnects
+				fields["resp_body"] = "[streamed payload]"
+
 			case isPprofEndpoint(r):
 				// pprof always sends binary data (protobuf)
 				fields["resp_body"] = "[binary payload]"
@@ -212,6 +216,10 @@ func isCompileEndpoint(req *http.Request) bool {
 	return strings.HasPrefix(req.URL.Path, "/v1/compile")
 }
 
+func isChangesEndpoint(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "/v1/changes")
+}
+
 type recorder struct {
 	logger logging.Logger
 	inner  http.ResponseWriter
@@ -254,6 +262,12 @@ func (r *recorder) WriteHeader(s int) {
 	r.inner.WriteHeader(s)
 }
 
+// Unwrap returns the underlying response writer so that http.ResponseController
+// can flush streamed responses.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.inner
+}
+
 func readBody(r io.ReadCloser) ([]byte, io.ReadCloser, error) {
 	if r == http.NoBody {
 		return nil, r, nil
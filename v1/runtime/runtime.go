@@ -48,6 +48,7 @@ import (
 	"github.com/IUAD1IY7/opa/v1/logging"
 	"github.com/IUAD1IY7/opa/v1/metrics"
 	"github.com/IUAD1IY7/opa/v1/plugins"
+	bundlePlugin "github.com/IUAD1IY7/opa/v1/plugins/bundle"
 	"github.com/IUAD1IY7/opa/v1/plugins/discovery"
 	"github.com/IUAD1IY7/opa/v1/plugins/logs"
 	metrics_config "github.com/IUAD1IY7/opa/v1/plugins/server/metrics"
@@ -55,6 +56,7 @@ import (
 	"github.com/IUAD1IY7/opa/v1/server"
 	"github.com/IUAD1IY7/opa/v1/storage"
 	"github.com/IUAD1IY7/opa/v1/storage/disk"
+	"github.com/IUAD1IY7/opa/v1/storage/sqlite"
 	"github.com/IUAD1IY7/opa/v1/tracing"
 	"github.com/IUAD1IY7/opa/v1/util"
 	"github.com/IUAD1IY7/opa/v1/version"
@@ -95,6 +97,10 @@ type Params struct {
 	// for read-only diagnostic API's (/health, /metrics, etc)
 	DiagnosticAddrs *[]string
 
+	// GRPCAddrs are the listening addresses that the OPA server will bind to
+	// for the gRPC API. The gRPC API is disabled if no addresses are set.
+	GRPCAddrs *[]string
+
 	// H2CEnabled flag controls whether OPA will allow H2C (HTTP/2 cleartext) on
 	// HTTP listeners.
 	H2CEnabled bool
@@ -159,6 +165,11 @@ type Params struct {
 	// PprofEnabled flag controls whether pprof endpoints are enabled
 	PprofEnabled bool
 
+	// DebugAPIEnabled flag controls whether debuggers may attach to decisions
+	// through the Debug API. It requires TLS client authentication or basic
+	// authorization.
+	DebugAPIEnabled bool
+
 	// DecisionIDFactory generates decision IDs to include in API responses
 	// sent by the server (in response to Data API queries.)
 	DecisionIDFactory func() string
@@ -223,6 +234,12 @@ type Params struct {
 	// It can also be enabled via config, and this runtime field takes precedence.
 	DiskStorage *disk.Options
 
+	// SQLiteStorage, if set, will make the runtime instantiate a SQLite-backed
+	// storage implementation (instead of the default, in-memory store).
+	// It can also be enabled via config, and this runtime field takes precedence.
+	// It cannot be used together with DiskStorage.
+	SQLiteStorage *sqlite.Options
+
 	DistributedTracingOpts tracing.Options
 
 	// Check if default Addr is set or the user has changed it.
@@ -247,7 +264,7 @@ type Params struct {
 	// ReadAstValuesFromStore controls whether the storage layer should return AST values when reading from the store.
 	// This is an eager conversion, that comes with an upfront performance cost when updating the store (e.g. bundle updates).
 	// Evaluation performance is affected in that data doesn't need to be converted to AST during evaluation.
-	// Only applicable when using the default in-memory store, and not when used together with the DiskStorage option.
+	// Only applicable when using the default in-memory store, and not when used together with the DiskStorage or SQLiteStorage options.
 	ReadAstValuesFromStore bool
 }
 
@@ -416,12 +433,27 @@ func NewRuntime(ctx context.Context, params Params) (*Runtime, error) {
 		}
 	}
 
-	if params.DiskStorage != nil {
+	if params.SQLiteStorage == nil {
+		params.SQLiteStorage, err = sqlite.OptionsFromConfig(config, params.ID)
+		if err != nil {
+			return nil, fmt.Errorf("parse sqlite store configuration: %w", err)
+		}
+	}
+
+	switch {
+	case params.DiskStorage != nil && params.SQLiteStorage != nil:
+		return nil, errors.New("disk and sqlite stores cannot be used together")
+	case params.DiskStorage != nil:
 		store, err = disk.New(ctx, logger, metrics, *params.DiskStorage)
 		if err != nil {
 			return nil, fmt.Errorf("initialize disk store: %w", err)
 		}
-	} else {
+	case params.SQLiteStorage != nil:
+		store, err = sqlite.New(ctx, *params.SQLiteStorage)
+		if err != nil {
+			return nil, fmt.Errorf("initialize sqlite store: %w", err)
+		}
+	default:
 		store = inmem.NewWithOpts(inmem.OptRoundTripOnWrite(false),
 			inmem.OptReturnASTValuesOnRead(params.ReadAstValuesFromStore))
 	}
@@ -557,9 +589,14 @@ func (rt *Runtime) Serve(ctx context.Context) error {
 		rt.Params.DiagnosticAddrs = &[]string{}
 	}
 
+	if rt.Params.GRPCAddrs == nil {
+		rt.Params.GRPCAddrs = &[]string{}
+	}
+
 	rt.logger.WithFields(map[string]any{
 		"addrs":            *rt.Params.Addrs,
 		"diagnostic-addrs": *rt.Params.DiagnosticAddrs,
+		"grpc-addrs":       *rt.Params.GRPCAddrs,
 	}).Info(serverInitializingMessage)
 
 	if rt.Params.Authorization == server.AuthorizationOff && rt.Params.Authentication == server.AuthenticationToken {
@@ -600,7 +637,9 @@ func (rt *Runtime) Serve(ctx context.Context) error {
 		WithManager(rt.Manager).
 		WithCompilerErrorLimit(rt.Params.ErrorLimit).
 		WithPprofEnabled(rt.Params.PprofEnabled).
+		WithDebugAPIEnabled(rt.Params.DebugAPIEnabled).
 		WithAddresses(*rt.Params.Addrs).
+		WithGRPCAddresses(*rt.Params.GRPCAddrs).
 		WithH2CEnabled(rt.Params.H2CEnabled).
 		// always use the initial values for the certificate and ca pool, reloading behavior is configured below
 		WithCertificate(rt.Params.Certificate).
@@ -672,6 +711,7 @@ func (rt *Runtime) Serve(ctx context.Context) error {
 
 	rt.server.Handler = NewLoggingHandler(rt.logger, rt.server.Handler)
 	rt.server.DiagnosticHandler = NewLoggingHandler(rt.logger, rt.server.DiagnosticHandler)
+	rt.server.GRPCHandler = NewLoggingHandler(rt.logger, rt.server.GRPCHandler)
 
 	rt.setServerStatus(ServerWaitingForPlugins)
 
@@ -749,6 +789,20 @@ func (rt *Runtime) DiagnosticAddrs() []string {
 	return rt.server.DiagnosticAddrs()
 }
 
+// GRPCAddrs returns a list of addresses that the runtime is listening on for
+// the gRPC API (when in server mode). Returns an empty list if it hasn't
+// started listening.
+func (rt *Runtime) GRPCAddrs() []string {
+	rt.serverInitMtx.RLock()
+	defer rt.serverInitMtx.RUnlock()
+
+	if rt.serverStatus < ServerInitialized {
+		return nil
+	}
+
+	return rt.server.GRPCAddrs()
+}
+
 // StartREPL starts the runtime in REPL mode. This function will block the calling goroutine.
 func (rt *Runtime) StartREPL(ctx context.Context) {
 	if err := rt.Manager.Start(ctx); err != nil {
@@ -849,6 +903,11 @@ func (rt *Runtime) decisionIDFactory() string {
 }
 
 func (rt *Runtime) decisionLogger(ctx context.Context, event *server.Info) error {
+	if bp := bundlePlugin.Lookup(rt.Manager); bp != nil && event.Query == "" {
+		bp.RecordDecision(event.Path, event.Error)
+		rt.shadowDecision(bp, event)
+	}
+
 	plugin := logs.Lookup(rt.Manager)
 	if plugin == nil {
 		return nil
@@ -857,6 +916,54 @@ func (rt *Runtime) decisionLogger(ctx context.Context, event *server.Info) error
 	return plugin.Log(ctx, event)
 }
 
+// shadowDecision queues successful data API decisions for re-evaluation
+// against the bundle revisions in shadow evaluation. Once evaluated, the
+// results are logged in a separate event with the ID of the decision.
+func (rt *Runtime) shadowDecision(plugin *bundlePlugin.Plugin, event *server.Info) {
+	if event.Error != nil {
+		return
+	}
+
+	info := server.Info{
+		Revision:           event.Revision,
+		Bundles:            event.Bundles,
+		DecisionID:         event.DecisionID,
+		TraceID:            event.TraceID,
+		SpanID:             event.SpanID,
+		RemoteAddr:         event.RemoteAddr,
+		HTTPRequestContext: event.HTTPRequestContext,
+		Path:               event.Path,
+		Timestamp:          event.Timestamp,
+		RequestID:          event.RequestID,
+	}
+
+	plugin.Shadow(bundlePlugin.ShadowDecision{
+		Path:     event.Path,
+		Input:    event.Input,
+		InputAST: event.InputAST,
+		Result:   event.Results,
+	}, func(results map[string]bundlePlugin.ShadowResult) {
+		logPlugin := logs.Lookup(rt.Manager)
+		if logPlugin == nil {
+			return
+		}
+
+		info.Shadow = make(map[string]server.ShadowInfo, len(results))
+		for name, r := range results {
+			info.Shadow[name] = server.ShadowInfo{
+				Revision: r.Revision,
+				Result:   r.Result,
+				Error:    r.Error,
+				Diverged: r.Diverged,
+			}
+		}
+
+		if err := logPlugin.Log(context.Background(), &info); err != nil {
+			rt.logger.Error("Failed to log shadow evaluation results: %v.", err)
+		}
+	})
+}
+
 func (rt *Runtime) startWatcher(ctx context.Context, paths []string, onReload func(time.Duration, error)) error {
 	watcher, err := rt.getWatcher(paths)
 	if err != nil {
@@ -30,6 +30,8 @@ import (
 
 	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/disk"
+	"github.com/IUAD1IY7/opa/v1/storage/sqlite"
 	"github.com/IUAD1IY7/opa/v1/util"
 	"github.com/IUAD1IY7/opa/v1/util/test"
 )
@@ -1628,3 +1630,24 @@ func TestRuntimeWithExplicitBadMetricConfiguration(t *testing.T) {
 		}
 	})
 }
+
+func TestRuntimeWithSQLiteStorageConfiguration(t *testing.T) {
+	test.WithTempFS(map[string]string{}, func(testDirRoot string) {
+		params := NewParams()
+		params.ConfigOverrides = []string{"storage.sqlite.path=" + filepath.Join(testDirRoot, "opa.db")}
+
+		rt, err := NewRuntime(context.Background(), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := rt.Store.(*sqlite.Store); !ok {
+			t.Fatalf("expected sqlite store but got %T", rt.Store)
+		}
+
+		params.DiskStorage = &disk.Options{Dir: testDirRoot}
+		if _, err := NewRuntime(context.Background(), params); err == nil || err.Error() != "disk and sqlite stores cannot be used together" {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
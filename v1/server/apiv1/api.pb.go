@@ -0,0 +1,1141 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: v1/server/apiv1/api.proto
+
+package apiv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetDataRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Slash-separated path of the document, e.g., "example/allow".
+	Path                string          `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Input               *structpb.Value `protobuf:"bytes,2,opt,name=input,proto3" json:"input,omitempty"`
+	Provenance          bool            `protobuf:"varint,3,opt,name=provenance,proto3" json:"provenance,omitempty"`
+	Metrics             bool            `protobuf:"varint,4,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	Instrument          bool            `protobuf:"varint,5,opt,name=instrument,proto3" json:"instrument,omitempty"`
+	StrictBuiltinErrors bool            `protobuf:"varint,6,opt,name=strict_builtin_errors,json=strictBuiltinErrors,proto3" json:"strict_builtin_errors,omitempty"`
+	// JSON encoded input, set instead of input to keep numbers that can't be
+	// represented as doubles.
+	RawInput []byte `protobuf:"bytes,7,opt,name=raw_input,json=rawInput,proto3" json:"raw_input,omitempty"`
+	// Return the result JSON encoded in raw_result instead of result.
+	RawOutput     bool `protobuf:"varint,8,opt,name=raw_output,json=rawOutput,proto3" json:"raw_output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDataRequest) Reset() {
+	*x = GetDataRequest{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDataRequest) ProtoMessage() {}
+
+func (x *GetDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDataRequest.ProtoReflect.Descriptor instead.
+func (*GetDataRequest) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetDataRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *GetDataRequest) GetInput() *structpb.Value {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *GetDataRequest) GetProvenance() bool {
+	if x != nil {
+		return x.Provenance
+	}
+	return false
+}
+
+func (x *GetDataRequest) GetMetrics() bool {
+	if x != nil {
+		return x.Metrics
+	}
+	return false
+}
+
+func (x *GetDataRequest) GetInstrument() bool {
+	if x != nil {
+		return x.Instrument
+	}
+	return false
+}
+
+func (x *GetDataRequest) GetStrictBuiltinErrors() bool {
+	if x != nil {
+		return x.StrictBuiltinErrors
+	}
+	return false
+}
+
+func (x *GetDataRequest) GetRawInput() []byte {
+	if x != nil {
+		return x.RawInput
+	}
+	return nil
+}
+
+func (x *GetDataRequest) GetRawOutput() bool {
+	if x != nil {
+		return x.RawOutput
+	}
+	return false
+}
+
+type GetDataResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	DecisionId string                 `protobuf:"bytes,1,opt,name=decision_id,json=decisionId,proto3" json:"decision_id,omitempty"`
+	// Not set if the document is undefined.
+	Result     *structpb.Value  `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	Metrics    *structpb.Struct `protobuf:"bytes,3,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	Provenance *structpb.Struct `protobuf:"bytes,4,opt,name=provenance,proto3" json:"provenance,omitempty"`
+	// Set instead of result if raw_output is requested.
+	RawResult     []byte `protobuf:"bytes,5,opt,name=raw_result,json=rawResult,proto3" json:"raw_result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDataResponse) Reset() {
+	*x = GetDataResponse{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDataResponse) ProtoMessage() {}
+
+func (x *GetDataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDataResponse.ProtoReflect.Descriptor instead.
+func (*GetDataResponse) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetDataResponse) GetDecisionId() string {
+	if x != nil {
+		return x.DecisionId
+	}
+	return ""
+}
+
+func (x *GetDataResponse) GetResult() *structpb.Value {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *GetDataResponse) GetMetrics() *structpb.Struct {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *GetDataResponse) GetProvenance() *structpb.Struct {
+	if x != nil {
+		return x.Provenance
+	}
+	return nil
+}
+
+func (x *GetDataResponse) GetRawResult() []byte {
+	if x != nil {
+		return x.RawResult
+	}
+	return nil
+}
+
+type QueryRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Query      string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Input      *structpb.Value        `protobuf:"bytes,2,opt,name=input,proto3" json:"input,omitempty"`
+	Metrics    bool                   `protobuf:"varint,3,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	Instrument bool                   `protobuf:"varint,4,opt,name=instrument,proto3" json:"instrument,omitempty"`
+	// JSON encoded input, set instead of input to keep numbers that can't be
+	// represented as doubles.
+	RawInput []byte `protobuf:"bytes,5,opt,name=raw_input,json=rawInput,proto3" json:"raw_input,omitempty"`
+	// Return the result JSON encoded in raw_result instead of result.
+	RawOutput     bool `protobuf:"varint,6,opt,name=raw_output,json=rawOutput,proto3" json:"raw_output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QueryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetInput() *structpb.Value {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetMetrics() bool {
+	if x != nil {
+		return x.Metrics
+	}
+	return false
+}
+
+func (x *QueryRequest) GetInstrument() bool {
+	if x != nil {
+		return x.Instrument
+	}
+	return false
+}
+
+func (x *QueryRequest) GetRawInput() []byte {
+	if x != nil {
+		return x.RawInput
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetRawOutput() bool {
+	if x != nil {
+		return x.RawOutput
+	}
+	return false
+}
+
+type QueryResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Result  []*structpb.Struct     `protobuf:"bytes,1,rep,name=result,proto3" json:"result,omitempty"`
+	Metrics *structpb.Struct       `protobuf:"bytes,2,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	// The JSON array of results, set instead of result if raw_output is
+	// requested.
+	RawResult     []byte `protobuf:"bytes,3,opt,name=raw_result,json=rawResult,proto3" json:"raw_result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *QueryResponse) GetResult() []*structpb.Struct {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetMetrics() *structpb.Struct {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetRawResult() []byte {
+	if x != nil {
+		return x.RawResult
+	}
+	return nil
+}
+
+type Policy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Raw           string                 `protobuf:"bytes,2,opt,name=raw,proto3" json:"raw,omitempty"`
+	Ast           *structpb.Struct       `protobuf:"bytes,3,opt,name=ast,proto3" json:"ast,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Policy) Reset() {
+	*x = Policy{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Policy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Policy) ProtoMessage() {}
+
+func (x *Policy) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Policy.ProtoReflect.Descriptor instead.
+func (*Policy) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Policy) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Policy) GetRaw() string {
+	if x != nil {
+		return x.Raw
+	}
+	return ""
+}
+
+func (x *Policy) GetAst() *structpb.Struct {
+	if x != nil {
+		return x.Ast
+	}
+	return nil
+}
+
+type ListPoliciesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPoliciesRequest) Reset() {
+	*x = ListPoliciesRequest{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPoliciesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoliciesRequest) ProtoMessage() {}
+
+func (x *ListPoliciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoliciesRequest.ProtoReflect.Descriptor instead.
+func (*ListPoliciesRequest) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{5}
+}
+
+type ListPoliciesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        []*Policy              `protobuf:"bytes,1,rep,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPoliciesResponse) Reset() {
+	*x = ListPoliciesResponse{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPoliciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoliciesResponse) ProtoMessage() {}
+
+func (x *ListPoliciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoliciesResponse.ProtoReflect.Descriptor instead.
+func (*ListPoliciesResponse) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListPoliciesResponse) GetResult() []*Policy {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type GetPolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPolicyRequest) Reset() {
+	*x = GetPolicyRequest{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPolicyRequest) ProtoMessage() {}
+
+func (x *GetPolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPolicyRequest.ProtoReflect.Descriptor instead.
+func (*GetPolicyRequest) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetPolicyRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetPolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *Policy                `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPolicyResponse) Reset() {
+	*x = GetPolicyResponse{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPolicyResponse) ProtoMessage() {}
+
+func (x *GetPolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPolicyResponse.ProtoReflect.Descriptor instead.
+func (*GetPolicyResponse) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetPolicyResponse) GetResult() *Policy {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type PutPolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Raw           string                 `protobuf:"bytes,2,opt,name=raw,proto3" json:"raw,omitempty"`
+	Metrics       bool                   `protobuf:"varint,3,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutPolicyRequest) Reset() {
+	*x = PutPolicyRequest{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutPolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutPolicyRequest) ProtoMessage() {}
+
+func (x *PutPolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutPolicyRequest.ProtoReflect.Descriptor instead.
+func (*PutPolicyRequest) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PutPolicyRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PutPolicyRequest) GetRaw() string {
+	if x != nil {
+		return x.Raw
+	}
+	return ""
+}
+
+func (x *PutPolicyRequest) GetMetrics() bool {
+	if x != nil {
+		return x.Metrics
+	}
+	return false
+}
+
+type PutPolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       *structpb.Struct       `protobuf:"bytes,1,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutPolicyResponse) Reset() {
+	*x = PutPolicyResponse{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutPolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutPolicyResponse) ProtoMessage() {}
+
+func (x *PutPolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutPolicyResponse.ProtoReflect.Descriptor instead.
+func (*PutPolicyResponse) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PutPolicyResponse) GetMetrics() *structpb.Struct {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type DeletePolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Metrics       bool                   `protobuf:"varint,2,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePolicyRequest) Reset() {
+	*x = DeletePolicyRequest{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePolicyRequest) ProtoMessage() {}
+
+func (x *DeletePolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePolicyRequest.ProtoReflect.Descriptor instead.
+func (*DeletePolicyRequest) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeletePolicyRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DeletePolicyRequest) GetMetrics() bool {
+	if x != nil {
+		return x.Metrics
+	}
+	return false
+}
+
+type DeletePolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       *structpb.Struct       `protobuf:"bytes,1,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletePolicyResponse) Reset() {
+	*x = DeletePolicyResponse{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletePolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletePolicyResponse) ProtoMessage() {}
+
+func (x *DeletePolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletePolicyResponse.ProtoReflect.Descriptor instead.
+func (*DeletePolicyResponse) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeletePolicyResponse) GetMetrics() *structpb.Struct {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type CompileRequest struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	Query                    string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Input                    *structpb.Value        `protobuf:"bytes,2,opt,name=input,proto3" json:"input,omitempty"`
+	Unknowns                 []string               `protobuf:"bytes,3,rep,name=unknowns,proto3" json:"unknowns,omitempty"`
+	DisableInlining          []string               `protobuf:"bytes,4,rep,name=disable_inlining,json=disableInlining,proto3" json:"disable_inlining,omitempty"`
+	NondeterministicBuiltins bool                   `protobuf:"varint,5,opt,name=nondeterministic_builtins,json=nondeterministicBuiltins,proto3" json:"nondeterministic_builtins,omitempty"`
+	Metrics                  bool                   `protobuf:"varint,6,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	Instrument               bool                   `protobuf:"varint,7,opt,name=instrument,proto3" json:"instrument,omitempty"`
+	// JSON encoded input, set instead of input to keep numbers that can't be
+	// represented as doubles.
+	RawInput []byte `protobuf:"bytes,8,opt,name=raw_input,json=rawInput,proto3" json:"raw_input,omitempty"`
+	// Return the result JSON encoded in raw_result instead of result.
+	RawOutput     bool `protobuf:"varint,9,opt,name=raw_output,json=rawOutput,proto3" json:"raw_output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompileRequest) Reset() {
+	*x = CompileRequest{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompileRequest) ProtoMessage() {}
+
+func (x *CompileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompileRequest.ProtoReflect.Descriptor instead.
+func (*CompileRequest) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CompileRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *CompileRequest) GetInput() *structpb.Value {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *CompileRequest) GetUnknowns() []string {
+	if x != nil {
+		return x.Unknowns
+	}
+	return nil
+}
+
+func (x *CompileRequest) GetDisableInlining() []string {
+	if x != nil {
+		return x.DisableInlining
+	}
+	return nil
+}
+
+func (x *CompileRequest) GetNondeterministicBuiltins() bool {
+	if x != nil {
+		return x.NondeterministicBuiltins
+	}
+	return false
+}
+
+func (x *CompileRequest) GetMetrics() bool {
+	if x != nil {
+		return x.Metrics
+	}
+	return false
+}
+
+func (x *CompileRequest) GetInstrument() bool {
+	if x != nil {
+		return x.Instrument
+	}
+	return false
+}
+
+func (x *CompileRequest) GetRawInput() []byte {
+	if x != nil {
+		return x.RawInput
+	}
+	return nil
+}
+
+func (x *CompileRequest) GetRawOutput() bool {
+	if x != nil {
+		return x.RawOutput
+	}
+	return false
+}
+
+type CompileResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The partial evaluation result with "queries" and "support" keys.
+	Result  *structpb.Value  `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Metrics *structpb.Struct `protobuf:"bytes,2,opt,name=metrics,proto3" json:"metrics,omitempty"`
+	// Set instead of result if raw_output is requested.
+	RawResult     []byte `protobuf:"bytes,3,opt,name=raw_result,json=rawResult,proto3" json:"raw_result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompileResponse) Reset() {
+	*x = CompileResponse{}
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompileResponse) ProtoMessage() {}
+
+func (x *CompileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_v1_server_apiv1_api_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompileResponse.ProtoReflect.Descriptor instead.
+func (*CompileResponse) Descriptor() ([]byte, []int) {
+	return file_v1_server_apiv1_api_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CompileResponse) GetResult() *structpb.Value {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *CompileResponse) GetMetrics() *structpb.Struct {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *CompileResponse) GetRawResult() []byte {
+	if x != nil {
+		return x.RawResult
+	}
+	return nil
+}
+
+var File_v1_server_apiv1_api_proto protoreflect.FileDescriptor
+
+const file_v1_server_apiv1_api_proto_rawDesc = "" +
+	"\n" +
+	"\x19v1/server/apiv1/api.proto\x12\x06opa.v1\x1a\x1cgoogle/protobuf/struct.proto\"\x9c\x02\n" +
+	"\x0eGetDataRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12,\n" +
+	"\x05input\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x05input\x12\x1e\n" +
+	"\n" +
+	"provenance\x18\x03 \x01(\bR\n" +
+	"provenance\x12\x18\n" +
+	"\ametrics\x18\x04 \x01(\bR\ametrics\x12\x1e\n" +
+	"\n" +
+	"instrument\x18\x05 \x01(\bR\n" +
+	"instrument\x122\n" +
+	"\x15strict_builtin_errors\x18\x06 \x01(\bR\x13strictBuiltinErrors\x12\x1b\n" +
+	"\traw_input\x18\a \x01(\fR\brawInput\x12\x1d\n" +
+	"\n" +
+	"raw_output\x18\b \x01(\bR\trawOutput\"\xed\x01\n" +
+	"\x0fGetDataResponse\x12\x1f\n" +
+	"\vdecision_id\x18\x01 \x01(\tR\n" +
+	"decisionId\x12.\n" +
+	"\x06result\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x06result\x121\n" +
+	"\ametrics\x18\x03 \x01(\v2\x17.google.protobuf.StructR\ametrics\x127\n" +
+	"\n" +
+	"provenance\x18\x04 \x01(\v2\x17.google.protobuf.StructR\n" +
+	"provenance\x12\x1d\n" +
+	"\n" +
+	"raw_result\x18\x05 \x01(\fR\trawResult\"\xc8\x01\n" +
+	"\fQueryRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12,\n" +
+	"\x05input\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x05input\x12\x18\n" +
+	"\ametrics\x18\x03 \x01(\bR\ametrics\x12\x1e\n" +
+	"\n" +
+	"instrument\x18\x04 \x01(\bR\n" +
+	"instrument\x12\x1b\n" +
+	"\traw_input\x18\x05 \x01(\fR\brawInput\x12\x1d\n" +
+	"\n" +
+	"raw_output\x18\x06 \x01(\bR\trawOutput\"\x92\x01\n" +
+	"\rQueryResponse\x12/\n" +
+	"\x06result\x18\x01 \x03(\v2\x17.google.protobuf.StructR\x06result\x121\n" +
+	"\ametrics\x18\x02 \x01(\v2\x17.google.protobuf.StructR\ametrics\x12\x1d\n" +
+	"\n" +
+	"raw_result\x18\x03 \x01(\fR\trawResult\"U\n" +
+	"\x06Policy\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03raw\x18\x02 \x01(\tR\x03raw\x12)\n" +
+	"\x03ast\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x03ast\"\x15\n" +
+	"\x13ListPoliciesRequest\">\n" +
+	"\x14ListPoliciesResponse\x12&\n" +
+	"\x06result\x18\x01 \x03(\v2\x0e.opa.v1.PolicyR\x06result\"\"\n" +
+	"\x10GetPolicyRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\";\n" +
+	"\x11GetPolicyResponse\x12&\n" +
+	"\x06result\x18\x01 \x01(\v2\x0e.opa.v1.PolicyR\x06result\"N\n" +
+	"\x10PutPolicyRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03raw\x18\x02 \x01(\tR\x03raw\x12\x18\n" +
+	"\ametrics\x18\x03 \x01(\bR\ametrics\"F\n" +
+	"\x11PutPolicyResponse\x121\n" +
+	"\ametrics\x18\x01 \x01(\v2\x17.google.protobuf.StructR\ametrics\"?\n" +
+	"\x13DeletePolicyRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\ametrics\x18\x02 \x01(\bR\ametrics\"I\n" +
+	"\x14DeletePolicyResponse\x121\n" +
+	"\ametrics\x18\x01 \x01(\v2\x17.google.protobuf.StructR\ametrics\"\xce\x02\n" +
+	"\x0eCompileRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12,\n" +
+	"\x05input\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x05input\x12\x1a\n" +
+	"\bunknowns\x18\x03 \x03(\tR\bunknowns\x12)\n" +
+	"\x10disable_inlining\x18\x04 \x03(\tR\x0fdisableInlining\x12;\n" +
+	"\x19nondeterministic_builtins\x18\x05 \x01(\bR\x18nondeterministicBuiltins\x12\x18\n" +
+	"\ametrics\x18\x06 \x01(\bR\ametrics\x12\x1e\n" +
+	"\n" +
+	"instrument\x18\a \x01(\bR\n" +
+	"instrument\x12\x1b\n" +
+	"\traw_input\x18\b \x01(\fR\brawInput\x12\x1d\n" +
+	"\n" +
+	"raw_output\x18\t \x01(\bR\trawOutput\"\x93\x01\n" +
+	"\x0fCompileResponse\x12.\n" +
+	"\x06result\x18\x01 \x01(\v2\x16.google.protobuf.ValueR\x06result\x121\n" +
+	"\ametrics\x18\x02 \x01(\v2\x17.google.protobuf.StructR\ametrics\x12\x1d\n" +
+	"\n" +
+	"raw_result\x18\x03 \x01(\fR\trawResult2\xcd\x03\n" +
+	"\x03OPA\x12:\n" +
+	"\aGetData\x12\x16.opa.v1.GetDataRequest\x1a\x17.opa.v1.GetDataResponse\x124\n" +
+	"\x05Query\x12\x14.opa.v1.QueryRequest\x1a\x15.opa.v1.QueryResponse\x12I\n" +
+	"\fListPolicies\x12\x1b.opa.v1.ListPoliciesRequest\x1a\x1c.opa.v1.ListPoliciesResponse\x12@\n" +
+	"\tGetPolicy\x12\x18.opa.v1.GetPolicyRequest\x1a\x19.opa.v1.GetPolicyResponse\x12@\n" +
+	"\tPutPolicy\x12\x18.opa.v1.PutPolicyRequest\x1a\x19.opa.v1.PutPolicyResponse\x12I\n" +
+	"\fDeletePolicy\x12\x1b.opa.v1.DeletePolicyRequest\x1a\x1c.opa.v1.DeletePolicyResponse\x12:\n" +
+	"\aCompile\x12\x16.opa.v1.CompileRequest\x1a\x17.opa.v1.CompileResponseB)Z'github.com/IUAD1IY7/opa/v1/server/apiv1b\x06proto3"
+
+var (
+	file_v1_server_apiv1_api_proto_rawDescOnce sync.Once
+	file_v1_server_apiv1_api_proto_rawDescData []byte
+)
+
+func file_v1_server_apiv1_api_proto_rawDescGZIP() []byte {
+	file_v1_server_apiv1_api_proto_rawDescOnce.Do(func() {
+		file_v1_server_apiv1_api_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_v1_server_apiv1_api_proto_rawDesc), len(file_v1_server_apiv1_api_proto_rawDesc)))
+	})
+	return file_v1_server_apiv1_api_proto_rawDescData
+}
+
+var file_v1_server_apiv1_api_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_v1_server_apiv1_api_proto_goTypes = []any{
+	(*GetDataRequest)(nil),       // 0: opa.v1.GetDataRequest
+	(*GetDataResponse)(nil),      // 1: opa.v1.GetDataResponse
+	(*QueryRequest)(nil),         // 2: opa.v1.QueryRequest
+	(*QueryResponse)(nil),        // 3: opa.v1.QueryResponse
+	(*Policy)(nil),               // 4: opa.v1.Policy
+	(*ListPoliciesRequest)(nil),  // 5: opa.v1.ListPoliciesRequest
+	(*ListPoliciesResponse)(nil), // 6: opa.v1.ListPoliciesResponse
+	(*GetPolicyRequest)(nil),     // 7: opa.v1.GetPolicyRequest
+	(*GetPolicyResponse)(nil),    // 8: opa.v1.GetPolicyResponse
+	(*PutPolicyRequest)(nil),     // 9: opa.v1.PutPolicyRequest
+	(*PutPolicyResponse)(nil),    // 10: opa.v1.PutPolicyResponse
+	(*DeletePolicyRequest)(nil),  // 11: opa.v1.DeletePolicyRequest
+	(*DeletePolicyResponse)(nil), // 12: opa.v1.DeletePolicyResponse
+	(*CompileRequest)(nil),       // 13: opa.v1.CompileRequest
+	(*CompileResponse)(nil),      // 14: opa.v1.CompileResponse
+	(*structpb.Value)(nil),       // 15: google.protobuf.Value
+	(*structpb.Struct)(nil),      // 16: google.protobuf.Struct
+}
+var file_v1_server_apiv1_api_proto_depIdxs = []int32{
+	15, // 0: opa.v1.GetDataRequest.input:type_name -> google.protobuf.Value
+	15, // 1: opa.v1.GetDataResponse.result:type_name -> google.protobuf.Value
+	16, // 2: opa.v1.GetDataResponse.metrics:type_name -> google.protobuf.Struct
+	16, // 3: opa.v1.GetDataResponse.provenance:type_name -> google.protobuf.Struct
+	15, // 4: opa.v1.QueryRequest.input:type_name -> google.protobuf.Value
+	16, // 5: opa.v1.QueryResponse.result:type_name -> google.protobuf.Struct
+	16, // 6: opa.v1.QueryResponse.metrics:type_name -> google.protobuf.Struct
+	16, // 7: opa.v1.Policy.ast:type_name -> google.protobuf.Struct
+	4,  // 8: opa.v1.ListPoliciesResponse.result:type_name -> opa.v1.Policy
+	4,  // 9: opa.v1.GetPolicyResponse.result:type_name -> opa.v1.Policy
+	16, // 10: opa.v1.PutPolicyResponse.metrics:type_name -> google.protobuf.Struct
+	16, // 11: opa.v1.DeletePolicyResponse.metrics:type_name -> google.protobuf.Struct
+	15, // 12: opa.v1.CompileRequest.input:type_name -> google.protobuf.Value
+	15, // 13: opa.v1.CompileResponse.result:type_name -> google.protobuf.Value
+	16, // 14: opa.v1.CompileResponse.metrics:type_name -> google.protobuf.Struct
+	0,  // 15: opa.v1.OPA.GetData:input_type -> opa.v1.GetDataRequest
+	2,  // 16: opa.v1.OPA.Query:input_type -> opa.v1.QueryRequest
+	5,  // 17: opa.v1.OPA.ListPolicies:input_type -> opa.v1.ListPoliciesRequest
+	7,  // 18: opa.v1.OPA.GetPolicy:input_type -> opa.v1.GetPolicyRequest
+	9,  // 19: opa.v1.OPA.PutPolicy:input_type -> opa.v1.PutPolicyRequest
+	11, // 20: opa.v1.OPA.DeletePolicy:input_type -> opa.v1.DeletePolicyRequest
+	13, // 21: opa.v1.OPA.Compile:input_type -> opa.v1.CompileRequest
+	1,  // 22: opa.v1.OPA.GetData:output_type -> opa.v1.GetDataResponse
+	3,  // 23: opa.v1.OPA.Query:output_type -> opa.v1.QueryResponse
+	6,  // 24: opa.v1.OPA.ListPolicies:output_type -> opa.v1.ListPoliciesResponse
+	8,  // 25: opa.v1.OPA.GetPolicy:output_type -> opa.v1.GetPolicyResponse
+	10, // 26: opa.v1.OPA.PutPolicy:output_type -> opa.v1.PutPolicyResponse
+	12, // 27: opa.v1.OPA.DeletePolicy:output_type -> opa.v1.DeletePolicyResponse
+	14, // 28: opa.v1.OPA.Compile:output_type -> opa.v1.CompileResponse
+	22, // [22:29] is the sub-list for method output_type
+	15, // [15:22] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_v1_server_apiv1_api_proto_init() }
+func file_v1_server_apiv1_api_proto_init() {
+	if File_v1_server_apiv1_api_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_v1_server_apiv1_api_proto_rawDesc), len(file_v1_server_apiv1_api_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_v1_server_apiv1_api_proto_goTypes,
+		DependencyIndexes: file_v1_server_apiv1_api_proto_depIdxs,
+		MessageInfos:      file_v1_server_apiv1_api_proto_msgTypes,
+	}.Build()
+	File_v1_server_apiv1_api_proto = out.File
+	file_v1_server_apiv1_api_proto_goTypes = nil
+	file_v1_server_apiv1_api_proto_depIdxs = nil
+}
@@ -0,0 +1,15 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package apiv1 contains the protocol buffer messages and gRPC service
+// definitions of the OPA gRPC API. The API mirrors the REST Data, Query,
+// Policy and Compile APIs.
+//
+// The Go code is generated from api.proto. To regenerate it, run the
+// following command from the repository root:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	  --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//	  v1/server/apiv1/api.proto
+package apiv1
@@ -233,6 +233,9 @@ func expectBody(method string, path []any) bool {
 		} else if len(path) >= 2 {
 			s1 := path[0].(string)
 			s2 := path[1].(string)
+			if s1 == "v1" && s2 == "batch" && len(path) >= 3 {
+				return path[2].(string) == "data"
+			}
 			return dataAPIVersions[s1] && s2 == "data"
 		}
 	}
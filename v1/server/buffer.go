@@ -36,6 +36,16 @@ type Info struct {
 	Metrics            metrics.Metrics
 	Trace              []*topdown.Event
 	RequestID          uint64
+	Shadow             map[string]ShadowInfo
+}
+
+// ShadowInfo contains the result of a decision re-evaluated against a bundle
+// revision in shadow evaluation.
+type ShadowInfo struct {
+	Revision string
+	Result   *any
+	Error    error
+	Diverged bool
 }
 
 // BundleInfo contains information describing a bundle.
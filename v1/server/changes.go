@@ -0,0 +1,509 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	serverChangesPlugin "github.com/IUAD1IY7/opa/v1/plugins/server/changes"
+	"github.com/IUAD1IY7/opa/v1/server/types"
+	"github.com/IUAD1IY7/opa/v1/server/writer"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+const (
+	// changeSubscriberBufferSize is the number of changes that may be queued
+	// for a single Changes API client. Clients that fall further behind are
+	// disconnected and must resume from the last sequence number they saw.
+	changeSubscriberBufferSize = 256
+
+	// changeKeepAliveInterval controls how often comments are written to
+	// idle Server-Sent Event streams so that intermediaries do not close them.
+	changeKeepAliveInterval = 30 * time.Second
+)
+
+// change is the server-side representation of a committed write transaction.
+// Values are serialized when the transaction commits because stores may
+// reuse the underlying objects in later transactions. Values that neither a
+// subscriber nor the resume buffer needs are left out.
+type change struct {
+	seq      uint64
+	txnID    uint64
+	data     []dataChange
+	policies []types.PolicyChangeV1
+	context  map[string]any
+}
+
+type dataChange struct {
+	path    storage.Path
+	removed bool
+	value   json.RawMessage
+}
+
+// changeFeed assigns sequence numbers to committed transactions, retains the
+// most recent ones for resuming clients and fans them out to subscribers.
+type changeFeed struct {
+	commitMtx sync.Mutex // held while a commit is published, serializes subscriptions with it
+	mtx       sync.Mutex
+	seq       uint64
+	buffer    []*change // ring buffer, buffer[(seq-1)%len(buffer)] is the latest change
+	subs      map[chan *change]*changeFilter
+	closed    bool
+}
+
+var errChangesNotBuffered = errors.New("changes are not retained for resuming, server.changes.buffer_size is 0")
+
+type errChangesGap struct {
+	since, oldest, latest uint64
+}
+
+func (e errChangesGap) Error() string {
+	if e.since > e.latest {
+		return fmt.Sprintf("sequence number %d is ahead of latest change %d", e.since, e.latest)
+	}
+	return fmt.Sprintf("changes after sequence number %d are no longer available, oldest available change is %d", e.since, e.oldest)
+}
+
+func newChangeFeed(bufferSize int) *changeFeed {
+	return &changeFeed{
+		buffer: make([]*change, bufferSize),
+		subs:   map[chan *change]*changeFilter{},
+	}
+}
+
+// onCommit is registered as a storage trigger.
+func (f *changeFeed) onCommit(_ context.Context, txn storage.Transaction, event storage.TriggerEvent) {
+	if event.IsZero() {
+		return
+	}
+
+	// Subscriptions wait for the commit to be published, so the values can
+	// be serialized without holding f.mtx and the set of subscribers does
+	// not grow in the meantime.
+	f.commitMtx.Lock()
+	defer f.commitMtx.Unlock()
+
+	f.mtx.Lock()
+	f.seq++
+	seq := f.seq
+	var filters []*changeFilter
+	if len(f.buffer) == 0 {
+		if len(f.subs) == 0 {
+			f.mtx.Unlock()
+			return
+		}
+		for _, filter := range f.subs {
+			filters = append(filters, filter)
+		}
+	}
+	f.mtx.Unlock()
+
+	c := newChange(seq, txn.ID(), event, func(p storage.Path) bool {
+		// Buffered changes may be read by any client later on.
+		if filters == nil {
+			return true
+		}
+		for _, filter := range filters {
+			if filter.covers(p) {
+				return true
+			}
+		}
+		return false
+	})
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if len(f.buffer) > 0 {
+		f.buffer[(c.seq-1)%uint64(len(f.buffer))] = c
+	}
+
+	for ch := range f.subs {
+		select {
+		case ch <- c:
+		default:
+			// The subscriber is lagging behind. Closing the channel ends the
+			// stream so that the client can resume from its last position.
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the buffered changes after since together with a channel
+// that receives all subsequently committed changes matching filter. If since
+// is nil only subsequent changes are returned.
+func (f *changeFeed) subscribe(since *uint64, filter *changeFilter) ([]*change, chan *change, error) {
+	f.commitMtx.Lock()
+	defer f.commitMtx.Unlock()
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.closed {
+		return nil, nil, errors.New("server is shutting down")
+	}
+
+	var backlog []*change
+
+	if since != nil && *since != f.seq {
+		if len(f.buffer) == 0 {
+			return nil, nil, errChangesNotBuffered
+		}
+		oldest := f.seq + 1
+		if n := uint64(len(f.buffer)); n > 0 {
+			oldest = max(f.seq, n) - n + 1
+		}
+		if *since > f.seq || *since+1 < oldest {
+			return nil, nil, errChangesGap{since: *since, oldest: oldest, latest: f.seq}
+		}
+		for seq := *since + 1; seq <= f.seq; seq++ {
+			backlog = append(backlog, f.buffer[(seq-1)%uint64(len(f.buffer))])
+		}
+	}
+
+	ch := make(chan *change, changeSubscriberBufferSize)
+	f.subs[ch] = filter
+
+	return backlog, ch, nil
+}
+
+func (f *changeFeed) unsubscribe(ch chan *change) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if _, ok := f.subs[ch]; ok {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// close ends all streams and rejects new subscriptions.
+func (f *changeFeed) close() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.closed = true
+	for ch := range f.subs {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// newChange converts the event into a change. Writes to paths for which
+// wanted returns false are dropped without being serialized.
+func newChange(seq, txnID uint64, event storage.TriggerEvent, wanted func(storage.Path) bool) *change {
+	c := &change{
+		seq:   seq,
+		txnID: txnID,
+	}
+
+	for _, e := range event.Data {
+		if !wanted(e.Path) {
+			continue
+		}
+		dc := dataChange{path: e.Path, removed: e.Removed}
+		if !e.Removed {
+			bs, err := json.Marshal(e.Data)
+			if err != nil {
+				// Base documents are always JSON serializable; fall back to
+				// null rather than dropping the change.
+				bs = []byte("null")
+			}
+			dc.value = bs
+		}
+		c.data = append(c.data, dc)
+	}
+
+	for _, e := range event.Policy {
+		pc := types.PolicyChangeV1{ID: e.ID, Op: types.ChangeOpUpsertV1}
+		if e.Removed {
+			pc.Op = types.ChangeOpRemoveV1
+		} else {
+			pc.Raw = string(e.Data)
+		}
+		c.policies = append(c.policies, pc)
+	}
+
+	event.Context.Range(func(k, v any) bool {
+		key, ok := k.(string)
+		if !ok {
+			return true
+		}
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return true
+		}
+		if c.context == nil {
+			c.context = map[string]any{}
+		}
+		c.context[key] = json.RawMessage(bs)
+		return true
+	})
+
+	return c
+}
+
+// changeFilter selects the parts of a change that a client is interested in.
+type changeFilter struct {
+	paths    []storage.Path
+	policies bool
+}
+
+func newChangeFilter(paths []string, policies bool) *changeFilter {
+	f := &changeFilter{policies: policies}
+
+	for _, s := range paths {
+		// The path always starts with a slash, so parsing cannot fail.
+		p, _ := storage.ParsePathEscaped("/" + strings.Trim(s, "/"))
+		f.paths = append(f.paths, p)
+	}
+
+	// Drop paths covered by other paths so that changes are reported once.
+	var result []storage.Path
+	for i, p := range f.paths {
+		covered := false
+		for j, q := range f.paths {
+			if i != j && p.HasPrefix(q) && (len(q) < len(p) || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			result = append(result, p)
+		}
+	}
+	f.paths = result
+
+	return f
+}
+
+// covers returns true if changes to p are reported by the filter.
+func (f *changeFilter) covers(p storage.Path) bool {
+	if len(f.paths) == 0 {
+		return true
+	}
+	for _, q := range f.paths {
+		if p.HasPrefix(q) || q.HasPrefix(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the change as seen by the client or nil if nothing in the
+// change matches the filter. Writes to ancestors of a filtered path are
+// narrowed to the filtered path.
+func (f *changeFilter) apply(c *change) (*types.ChangeV1, error) {
+	result := &types.ChangeV1{
+		Seq:     c.seq,
+		TxnID:   c.txnID,
+		Context: c.context,
+	}
+
+	for _, dc := range c.data {
+		if len(f.paths) == 0 {
+			result.Data = append(result.Data, dc.toV1())
+			continue
+		}
+		for _, p := range f.paths {
+			if dc.path.HasPrefix(p) {
+				result.Data = append(result.Data, dc.toV1())
+				break
+			}
+			if p.HasPrefix(dc.path) {
+				narrowed, err := dc.narrow(p)
+				if err != nil {
+					return nil, err
+				}
+				result.Data = append(result.Data, narrowed.toV1())
+			}
+		}
+	}
+
+	if f.policies {
+		result.Policies = c.policies
+	}
+
+	if len(result.Data) == 0 && len(result.Policies) == 0 {
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// narrow returns the change to the descendant path p implied by dc.
+func (dc dataChange) narrow(p storage.Path) (dataChange, error) {
+	result := dataChange{path: p, removed: true}
+	if dc.removed {
+		return result, nil
+	}
+
+	var value any
+	if err := util.UnmarshalJSON(dc.value, &value); err != nil {
+		return result, err
+	}
+
+	for _, key := range p[len(dc.path):] {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[key]; !ok {
+				return result, nil
+			}
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return result, nil
+			}
+			value = v[idx]
+		default:
+			return result, nil
+		}
+	}
+
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return result, err
+	}
+
+	result.removed = false
+	result.value = bs
+
+	return result, nil
+}
+
+func (dc dataChange) toV1() types.DataChangeV1 {
+	if dc.removed {
+		return types.DataChangeV1{Path: dc.path.String(), Op: types.ChangeOpRemoveV1}
+	}
+	return types.DataChangeV1{Path: dc.path.String(), Op: types.ChangeOpUpsertV1, Value: dc.value}
+}
+
+func (s *Server) initChangeFeed() error {
+	var raw []byte
+	if s.manager.Config.Server != nil {
+		raw = s.manager.Config.Server.Changes
+	}
+
+	config, err := serverChangesPlugin.NewConfigBuilder().WithBytes(raw).Parse()
+	if err != nil {
+		return err
+	}
+
+	s.changes = newChangeFeed(*config.BufferSize)
+
+	return nil
+}
+
+func (s *Server) v1ChangesGet(w http.ResponseWriter, r *http.Request) {
+	var since *uint64
+
+	sinceStr := r.URL.Query().Get(types.ParamSinceV1)
+	if sinceStr == "" {
+		sinceStr = r.Header.Get("Last-Event-ID")
+	}
+	if sinceStr != "" {
+		n, err := strconv.ParseUint(sinceStr, 10, 64)
+		if err != nil {
+			writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, fmt.Errorf("invalid %v parameter: %v", types.ParamSinceV1, sinceStr))
+			return
+		}
+		since = &n
+	}
+
+	policies := true
+	if _, ok := r.URL.Query()[types.ParamPoliciesV1]; ok {
+		policies = getBoolParam(r.URL, types.ParamPoliciesV1, true)
+	}
+
+	filter := newChangeFilter(r.URL.Query()[types.ParamPathV1], policies)
+
+	backlog, ch, err := s.changes.subscribe(since, filter)
+	if err != nil {
+		var gap errChangesGap
+		if errors.As(err, &gap) || errors.Is(err, errChangesNotBuffered) {
+			writer.ErrorString(w, http.StatusGone, types.CodeResourceNotFound, err)
+			return
+		}
+		writer.ErrorString(w, http.StatusServiceUnavailable, types.CodeInternal, err)
+		return
+	}
+	defer s.changes.unsubscribe(ch)
+
+	sse := strings.Contains(r.Header.Get("Accept"), types.MediaTypeEventStreamV1)
+
+	if sse {
+		w.Header().Set("Content-Type", types.MediaTypeEventStreamV1)
+	} else {
+		w.Header().Set("Content-Type", types.MediaTypeNDJSONV1)
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+
+	write := func(c *change) error {
+		v, err := filter.apply(c)
+		if err != nil || v == nil {
+			return err
+		}
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if sse {
+			_, err = fmt.Fprintf(w, "id: %d\ndata: %s\n\n", v.Seq, bs)
+		} else {
+			_, err = w.Write(append(bs, '\n'))
+		}
+		return err
+	}
+
+	for _, c := range backlog {
+		if err := write(c); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(changeKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case c, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := write(c); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if !sse {
+				continue
+			}
+			if _, err := w.Write([]byte(":\n\n")); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
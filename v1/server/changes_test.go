@@ -0,0 +1,354 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/server/types"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+type changesClient struct {
+	t    *testing.T
+	resp *http.Response
+	scan *bufio.Scanner
+}
+
+func newChangesClient(t *testing.T, f *fixture, query string, header http.Header) *changesClient {
+	t.Helper()
+
+	srv := httptest.NewServer(f.server.Handler)
+	t.Cleanup(srv.Close)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/changes"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return &changesClient{t: t, resp: resp, scan: bufio.NewScanner(resp.Body)}
+}
+
+// next returns the next non-empty line of the stream.
+func (c *changesClient) next() string {
+	c.t.Helper()
+
+	line := make(chan string)
+	go func() {
+		for c.scan.Scan() {
+			if s := c.scan.Text(); s != "" {
+				line <- s
+				return
+			}
+		}
+		close(line)
+	}()
+
+	select {
+	case s, ok := <-line:
+		if !ok {
+			c.t.Fatal("stream ended unexpectedly")
+		}
+		return s
+	case <-time.After(10 * time.Second):
+		c.t.Fatal("timed out waiting for change")
+	}
+	return ""
+}
+
+func (c *changesClient) nextChange() types.ChangeV1 {
+	c.t.Helper()
+
+	var change types.ChangeV1
+	if err := util.UnmarshalJSON([]byte(c.next()), &change); err != nil {
+		c.t.Fatal(err)
+	}
+	return change
+}
+
+func writeChange(t *testing.T, f *fixture, op storage.PatchOp, path string, value any) {
+	t.Helper()
+
+	if err := storage.WriteOne(context.Background(), f.server.store, op, storage.MustParsePath(path), value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectDataChanges(t *testing.T, change types.ChangeV1, exp ...types.DataChangeV1) {
+	t.Helper()
+
+	if len(change.Data) != len(exp) {
+		t.Fatalf("expected %d data changes but got: %+v", len(exp), change)
+	}
+	for i := range exp {
+		if change.Data[i].Path != exp[i].Path || change.Data[i].Op != exp[i].Op {
+			t.Fatalf("expected %+v but got %+v", exp[i], change.Data[i])
+		}
+		if exp[i].Value == nil {
+			continue
+		}
+		var a, b any
+		if err := util.UnmarshalJSON(change.Data[i].Value, &a); err != nil {
+			t.Fatal(err)
+		}
+		if err := util.UnmarshalJSON(exp[i].Value, &b); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("expected value %s but got %s", exp[i].Value, change.Data[i].Value)
+		}
+	}
+}
+
+func TestChangesStream(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	c := newChangesClient(t, f, "", nil)
+
+	if ct := c.resp.Header.Get("Content-Type"); ct != types.MediaTypeNDJSONV1 {
+		t.Fatalf("unexpected content type: %v", ct)
+	}
+
+	writeChange(t, f, storage.AddOp, "/users", map[string]any{"alice": map[string]any{"admin": true}})
+	writeChange(t, f, storage.RemoveOp, "/users/alice", nil)
+
+	ctx := context.Background()
+	if err := storage.Txn(ctx, f.server.store, storage.WriteParams, func(txn storage.Transaction) error {
+		return f.server.store.UpsertPolicy(ctx, txn, "test", []byte("package test\n\np := 1"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	change := c.nextChange()
+	if change.Seq != 1 || change.TxnID == 0 {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	expectDataChanges(t, change, types.DataChangeV1{Path: "/users", Op: types.ChangeOpUpsertV1, Value: json.RawMessage(`{"alice": {"admin": true}}`)})
+
+	change = c.nextChange()
+	if change.Seq != 2 {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	expectDataChanges(t, change, types.DataChangeV1{Path: "/users/alice", Op: types.ChangeOpRemoveV1})
+
+	change = c.nextChange()
+	exp := []types.PolicyChangeV1{{ID: "test", Op: types.ChangeOpUpsertV1, Raw: "package test\n\np := 1"}}
+	if change.Seq != 3 || len(change.Data) != 0 || !reflect.DeepEqual(change.Policies, exp) {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+}
+
+func TestChangesEventStream(t *testing.T) {
+	t.Parallel()
+
+	f := newFixtureWithConfig(t, `{"server": {"changes": {"buffer_size": 10}}}`)
+
+	writeChange(t, f, storage.AddOp, "/a", 1)
+	writeChange(t, f, storage.AddOp, "/b", 2)
+
+	c := newChangesClient(t, f, "", http.Header{
+		"Accept":        {types.MediaTypeEventStreamV1},
+		"Last-Event-ID": {"1"},
+	})
+
+	if ct := c.resp.Header.Get("Content-Type"); ct != types.MediaTypeEventStreamV1 {
+		t.Fatalf("unexpected content type: %v", ct)
+	}
+
+	if id := c.next(); id != "id: 2" {
+		t.Fatalf("unexpected event id: %v", id)
+	}
+	data, ok := strings.CutPrefix(c.next(), "data: ")
+	if !ok {
+		t.Fatal("expected data field")
+	}
+
+	var change types.ChangeV1
+	if err := util.UnmarshalJSON([]byte(data), &change); err != nil {
+		t.Fatal(err)
+	}
+	expectDataChanges(t, change, types.DataChangeV1{Path: "/b", Op: types.ChangeOpUpsertV1, Value: json.RawMessage(`2`)})
+}
+
+func TestChangesResume(t *testing.T) {
+	t.Parallel()
+
+	f := newFixtureWithConfig(t, `{"server": {"changes": {"buffer_size": 2}}}`)
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		writeChange(t, f, storage.AddOp, path, true)
+	}
+
+	c := newChangesClient(t, f, "?since=1", nil)
+
+	for _, exp := range []string{"/b", "/c"} {
+		expectDataChanges(t, c.nextChange(), types.DataChangeV1{Path: exp, Op: types.ChangeOpUpsertV1})
+	}
+
+	writeChange(t, f, storage.AddOp, "/d", true)
+
+	change := c.nextChange()
+	if change.Seq != 4 {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	expectDataChanges(t, change, types.DataChangeV1{Path: "/d", Op: types.ChangeOpUpsertV1})
+
+	tests := []struct {
+		note   string
+		query  string
+		status int
+	}{
+		{
+			note:   "evicted",
+			query:  "?since=1",
+			status: http.StatusGone,
+		},
+		{
+			note:   "ahead",
+			query:  "?since=100",
+			status: http.StatusGone,
+		},
+		{
+			note:   "invalid",
+			query:  "?since=x",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := newChangesClient(t, f, tc.query, nil)
+			if c.resp.StatusCode != tc.status {
+				t.Fatalf("expected status %d but got %d", tc.status, c.resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestChangesResumeNotBuffered(t *testing.T) {
+	t.Parallel()
+
+	f := newFixtureWithConfig(t, `{"server": {"changes": {"buffer_size": 0}}}`)
+
+	writeChange(t, f, storage.AddOp, "/a", true)
+	writeChange(t, f, storage.AddOp, "/b", true)
+
+	c := newChangesClient(t, f, "?since=1", nil)
+	if c.resp.StatusCode != http.StatusGone {
+		t.Fatalf("expected status %d but got %d", http.StatusGone, c.resp.StatusCode)
+	}
+
+	// Nothing was missed, so the stream can continue.
+	c = newChangesClient(t, f, "?since=2", nil)
+	if c.resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d but got %d", http.StatusOK, c.resp.StatusCode)
+	}
+}
+
+func TestChangeFeedSkipsUnwantedValues(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := inmem.New()
+	feed := newChangeFeed(0)
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		_, err := store.Register(ctx, txn, storage.TriggerConfig{OnCommit: feed.onCommit})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, ch, err := feed.subscribe(nil, newChangeFilter([]string{"/a/b"}, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/x", "/a"} {
+		if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath(path), map[string]any{"b": 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if c := <-ch; c.seq != 1 || len(c.data) != 0 {
+		t.Fatalf("expected write to /x to be dropped but got %+v", c.data)
+	}
+	if c := <-ch; c.seq != 2 || len(c.data) != 1 || string(c.data[0].value) != `{"b":1}` {
+		t.Fatalf("expected write to /a but got %+v", c.data)
+	}
+}
+
+func TestChangesFilter(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	c := newChangesClient(t, f, "?path=/a/b&path=/a/b/c&path=x&policies=false", nil)
+
+	ctx := context.Background()
+	if err := storage.Txn(ctx, f.server.store, storage.WriteParams, func(txn storage.Transaction) error {
+		return f.server.store.UpsertPolicy(ctx, txn, "test", []byte("package test\n\np := 1"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	writeChange(t, f, storage.AddOp, "/a", map[string]any{"b": map[string]any{"c": 1}, "d": 2})
+	writeChange(t, f, storage.AddOp, "/a/d", 3)
+	writeChange(t, f, storage.AddOp, "/a/b/e", 4)
+	writeChange(t, f, storage.AddOp, "/a", map[string]any{})
+	writeChange(t, f, storage.AddOp, "/x", []any{"y"})
+
+	change := c.nextChange()
+	if change.Seq != 2 {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	expectDataChanges(t, change, types.DataChangeV1{Path: "/a/b", Op: types.ChangeOpUpsertV1, Value: json.RawMessage(`{"c": 1}`)})
+	expectDataChanges(t, c.nextChange(), types.DataChangeV1{Path: "/a/b/e", Op: types.ChangeOpUpsertV1, Value: json.RawMessage(`4`)})
+	expectDataChanges(t, c.nextChange(), types.DataChangeV1{Path: "/a/b", Op: types.ChangeOpRemoveV1})
+	expectDataChanges(t, c.nextChange(), types.DataChangeV1{Path: "/x", Op: types.ChangeOpUpsertV1, Value: json.RawMessage(`["y"]`)})
+}
+
+func TestChangesShutdown(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+	c := newChangesClient(t, f, "", nil)
+
+	if err := f.server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for c.scan.Scan() { //nolint:revive
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected stream to end on shutdown")
+	}
+}
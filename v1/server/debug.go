@@ -0,0 +1,310 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IUAD1IY7/opa/internal/dap"
+	storageUtils "github.com/IUAD1IY7/opa/internal/storage"
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/debug"
+	"github.com/IUAD1IY7/opa/v1/rego"
+	"github.com/IUAD1IY7/opa/v1/server/types"
+	"github.com/IUAD1IY7/opa/v1/server/writer"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/topdown/builtins"
+)
+
+const (
+	// debugUpgradeProtocol is the protocol that Debug API connections are
+	// upgraded to: the Debug Adapter Protocol, framed as over stdio.
+	debugUpgradeProtocol = "dap"
+
+	// defaultDebugAttachTimeout is how long an attached debugger waits for a
+	// matching decision unless it sets a timeout of its own.
+	defaultDebugAttachTimeout = 5 * time.Minute
+)
+
+// debugCapture is the request of an attached debugger for the next decision
+// matching its path and input filter.
+type debugCapture struct {
+	path   string
+	filter ast.Body
+	ch     chan *capturedDecision
+}
+
+// capturedDecision is a decision handed to an attached debugger, with the
+// compiler and a snapshot of the store it was made with.
+type capturedDecision struct {
+	path     string
+	input    ast.Value
+	compiler *ast.Compiler
+	store    storage.Store
+	ndbCache builtins.NDBCache
+}
+
+// debugAPI holds the connections of the attached debuggers, and the decisions
+// they wait for.
+type debugAPI struct {
+	ctx      context.Context // cancelled when the server shuts down
+	cancel   context.CancelFunc
+	mtx      sync.Mutex
+	captures []*debugCapture
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+func newDebugAPI() *debugAPI {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &debugAPI{ctx: ctx, cancel: cancel, conns: map[net.Conn]struct{}{}}
+}
+
+func (d *debugAPI) addConn(conn net.Conn) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.closed {
+		return errors.New("server is shutting down")
+	}
+	d.conns[conn] = struct{}{}
+	return nil
+}
+
+func (d *debugAPI) removeConn(conn net.Conn) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	delete(d.conns, conn)
+}
+
+func (d *debugAPI) addCapture(c *debugCapture) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.captures = append(d.captures, c)
+}
+
+// claim removes the capture, returning false if it was already removed.
+func (d *debugAPI) claim(c *debugCapture) bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	i := slices.Index(d.captures, c)
+	if i < 0 {
+		return false
+	}
+	d.captures = slices.Delete(d.captures, i, i+1)
+	return true
+}
+
+func (d *debugAPI) pending() []*debugCapture {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return slices.Clone(d.captures)
+}
+
+// close disconnects all debuggers and rejects new ones.
+func (d *debugAPI) close() {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.closed = true
+	d.cancel()
+	for conn := range d.conns {
+		conn.Close()
+	}
+}
+
+func (s *Server) v1DebugGet(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), debugUpgradeProtocol) {
+		w.Header().Set("Upgrade", debugUpgradeProtocol)
+		writer.Error(w, http.StatusUpgradeRequired, types.NewErrorV1(types.CodeInvalidParameter, "connection must be upgraded to %q", debugUpgradeProtocol))
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writer.ErrorString(w, http.StatusInternalServerError, types.CodeInternal, fmt.Errorf("cannot upgrade connection: %w", err))
+		return
+	}
+	defer conn.Close()
+
+	if err := s.debug.addConn(conn); err != nil {
+		return
+	}
+	defer s.debug.removeConn(conn)
+
+	// Debug sessions last longer than any request, the deadlines set by the
+	// http server don't apply.
+	_ = conn.SetDeadline(time.Time{})
+
+	if _, err := fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: %s\r\n\r\n", debugUpgradeProtocol); err != nil {
+		return
+	}
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	logger := s.manager.Logger()
+	logger.Info("Debugger attached from %v.", conn.RemoteAddr())
+
+	// The reader may have buffered messages sent along with the request.
+	client := struct {
+		io.Reader
+		io.Writer
+	}{rw, conn}
+
+	if err := dap.NewServer(client, logger).WithAttach(s.attachDebugger).Serve(s.debug.ctx); err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.Error("Debugger from %v failed: %v", conn.RemoteAddr(), err)
+	}
+
+	logger.Info("Debugger from %v detached.", conn.RemoteAddr())
+}
+
+// attachDebugger waits for the next decision matching the arguments, and
+// launches a debug session re-evaluating it.
+func (s *Server) attachDebugger(ctx context.Context, d debug.Debugger, args dap.AttachArguments) (debug.Session, error) {
+	c := &debugCapture{
+		path: strings.Trim(args.Path, "/"),
+		ch:   make(chan *capturedDecision, 1),
+	}
+
+	if args.InputFilter != "" {
+		filter, err := ast.ParseBodyWithOpts(args.InputFilter, s.manager.ParserOptions())
+		if err != nil {
+			return nil, fmt.Errorf("invalid input filter: %w", err)
+		}
+		c.filter = filter
+	}
+
+	timeout := defaultDebugAttachTimeout
+	if args.Timeout > 0 {
+		timeout = time.Duration(args.Timeout) * time.Second
+	}
+
+	s.debug.addCapture(c)
+	defer s.debug.claim(c)
+
+	var decision *capturedDecision
+	select {
+	case decision = <-c.ch:
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("no decision matching the path and input filter within %v", timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	ref, err := stringPathToDataRef(decision.path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.manager.Logger().Info("Debugging decision %v.", ref)
+
+	launcher, ok := d.(debug.DecisionLauncher)
+	if !ok {
+		return nil, errors.New("debugging decisions is not supported by the debugger")
+	}
+
+	return launcher.LaunchDecision(ctx, debug.LaunchDecisionProperties{
+		LaunchProperties: args.LaunchProperties(),
+		Query:            ref.String(),
+		Input:            decision.input,
+		Compiler:         decision.compiler,
+		Store:            decision.store,
+		NDBuiltinCache:   decision.ndbCache,
+	})
+}
+
+// captureDecision hands the decision to the attached debuggers waiting for a
+// decision matching it, if any. Non-deterministic built-in functions are
+// called again when the decision is debugged, unless their results were
+// recorded in the cache.
+func (s *Server) captureDecision(ctx context.Context, txn storage.Transaction, urlPath string, input ast.Value, ndbCache builtins.NDBCache) {
+	if s.debug == nil {
+		return
+	}
+
+	path := strings.Trim(urlPath, "/")
+	compiler := s.getCompiler()
+
+	var store storage.Store
+
+	for _, c := range s.debug.pending() {
+		if c.path != "" && c.path != path {
+			continue
+		}
+		if c.filter != nil && !s.debugFilterMatches(ctx, txn, compiler, c.filter, input) {
+			continue
+		}
+		if !s.debug.claim(c) {
+			continue
+		}
+
+		if store == nil {
+			var err error
+			if store, err = storageUtils.Snapshot(ctx, s.store, txn); err != nil {
+				s.manager.Logger().Error("Failed to capture decision for debugger: %v", err)
+				s.debug.addCapture(c)
+				return
+			}
+		}
+
+		c.ch <- &capturedDecision{
+			path:     path,
+			input:    input,
+			compiler: compiler,
+			store:    store,
+			ndbCache: copyNDBCache(ndbCache),
+		}
+	}
+}
+
+// debugFilterMatches returns true if the input filter is true, i.e. if none of
+// the expressions of some result of the filter is false.
+func (s *Server) debugFilterMatches(ctx context.Context, txn storage.Transaction, compiler *ast.Compiler, filter ast.Body, input ast.Value) bool {
+	rs, err := rego.New(
+		rego.ParsedQuery(filter),
+		rego.Compiler(compiler),
+		rego.Store(s.store),
+		rego.Transaction(txn),
+		rego.ParsedInput(input),
+	).Eval(ctx)
+	if err != nil {
+		s.manager.Logger().Debug("Failed to evaluate input filter of debugger: %v", err)
+		return false
+	}
+
+	for _, r := range rs {
+		if !slices.ContainsFunc(r.Expressions, func(ev *rego.ExpressionValue) bool { return ev.Value == false }) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyNDBCache copies the cache for each debugger, as the debugged decision
+// records the results of non-deterministic built-in functions it calls anew.
+func copyNDBCache(cache builtins.NDBCache) builtins.NDBCache {
+	if cache == nil {
+		return nil
+	}
+	result := make(builtins.NDBCache, len(cache))
+	for name, obj := range cache {
+		result[name] = obj.Copy()
+	}
+	return result
+}
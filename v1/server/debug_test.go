@@ -0,0 +1,259 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IUAD1IY7/opa/v1/plugins"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+)
+
+type debugClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+}
+
+type debugMessage struct {
+	Type    string          `json:"type"`
+	Command string          `json:"command"`
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Event   string          `json:"event"`
+	Body    json.RawMessage `json:"body"`
+}
+
+func newDebugClient(t *testing.T, url, token string) *debugClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	fmt.Fprintf(conn, "GET /v1/debug HTTP/1.1\r\nHost: opa\r\nConnection: Upgrade\r\nUpgrade: dap\r\nAuthorization: Bearer %s\r\n\r\n", token)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected connection to be upgraded but got: %v", resp.Status)
+	}
+
+	return &debugClient{t: t, conn: conn, r: r}
+}
+
+func (c *debugClient) send(command string, args any) {
+	c.t.Helper()
+
+	c.seq++
+	bs, err := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(bs), bs); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// next returns the next message of the given type, skipping any other.
+func (c *debugClient) next(typ, name string) debugMessage {
+	c.t.Helper()
+
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+			c.t.Fatal(err)
+		}
+		header, err := textproto.NewReader(c.r).ReadMIMEHeader()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		n, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		bs := make([]byte, n)
+		if _, err := io.ReadFull(c.r, bs); err != nil {
+			c.t.Fatal(err)
+		}
+
+		var msg debugMessage
+		if err := json.Unmarshal(bs, &msg); err != nil {
+			c.t.Fatal(err)
+		}
+		if msg.Type == typ && (msg.Command == name || msg.Event == name) {
+			return msg
+		}
+	}
+}
+
+func newDebugFixture(t *testing.T) (*fixture, *httptest.Server) {
+	t.Helper()
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]any{"min": 1})
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := store.UpsertPolicy(ctx, txn, "authz.rego", []byte(`package system.authz
+
+default allow := false
+
+allow if input.identity == "bob"`)); err != nil {
+			return err
+		}
+		return store.UpsertPolicy(ctx, txn, "test.rego", []byte(`package test
+
+p if input.x > data.min`))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFixtureWithStore(t, store, func(s *Server) {
+		s.WithAuthentication(AuthenticationToken).WithAuthorization(AuthorizationBasic).WithDebugAPIEnabled(true)
+	})
+
+	srv := httptest.NewServer(f.server.Handler)
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { f.server.debug.close() })
+
+	return f, srv
+}
+
+func TestDebugAPIRequiresAuthentication(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := inmem.New()
+	m, err := plugins.New([]byte{}, "test", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New().WithStore(store).WithManager(m).WithAuthentication(AuthenticationToken).WithDebugAPIEnabled(true).Init(ctx)
+	if err == nil || !strings.Contains(err.Error(), "debug API requires authentication") {
+		t.Fatalf("expected authentication error but got: %v", err)
+	}
+}
+
+func TestDebugAPIRequests(t *testing.T) {
+	t.Parallel()
+
+	_, srv := newDebugFixture(t)
+
+	tests := []struct {
+		note    string
+		token   string
+		upgrade string
+		exp     int
+	}{
+		{note: "unauthorized", token: "alice", upgrade: "dap", exp: http.StatusUnauthorized},
+		{note: "not upgraded", token: "bob", exp: http.StatusUpgradeRequired},
+		{note: "other protocol", token: "bob", upgrade: "websocket", exp: http.StatusUpgradeRequired},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/debug", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			if tc.upgrade != "" {
+				req.Header.Set("Connection", "Upgrade")
+				req.Header.Set("Upgrade", tc.upgrade)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.exp {
+				t.Fatalf("expected status %v but got %v", tc.exp, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestDebugAPIAttach(t *testing.T) {
+	t.Parallel()
+
+	f, srv := newDebugFixture(t)
+	c := newDebugClient(t, srv.URL, "bob")
+
+	c.send("attach", map[string]any{"path": "/test/p", "inputFilter": "input.x == 2", "timeout": 10})
+
+	// Wait for the debugger to wait for a decision.
+	for len(f.server.debug.pending()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, input := range []string{`{"x": 1}`, `{"x": 2}`, `{"x": 3}`} {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/data/test/p", strings.NewReader(`{"input": `+input+`}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer bob")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	if resp := c.next("response", "attach"); !resp.Success {
+		t.Fatalf("failed to attach: %v", resp.Message)
+	}
+	c.next("event", "initialized")
+
+	// The decision is debugged with the data it was made with.
+	writeChange(t, f, storage.ReplaceOp, "/min", 5)
+
+	c.send("configurationDone", nil)
+
+	var output struct {
+		Output string `json:"output"`
+	}
+	if err := json.Unmarshal(c.next("event", "output").Body, &output); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.Output, `"value": true`) || !strings.Contains(output.Output, `"text": "data.test.p"`) {
+		t.Fatalf("expected data.test.p to be true but got: %v", output.Output)
+	}
+
+	c.next("event", "terminated")
+}
+
+func TestDebugAPIAttachTimeout(t *testing.T) {
+	t.Parallel()
+
+	_, srv := newDebugFixture(t)
+	c := newDebugClient(t, srv.URL, "bob")
+
+	c.send("attach", map[string]any{"path": "test/p", "timeout": 1})
+
+	if resp := c.next("response", "attach"); resp.Success || !strings.Contains(resp.Message, "no decision matching") {
+		t.Fatalf("expected attach to time out but got: %+v", resp)
+	}
+}
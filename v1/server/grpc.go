@@ -0,0 +1,869 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/IUAD1IY7/opa/v1/ast"
+	"github.com/IUAD1IY7/opa/v1/logging"
+	"github.com/IUAD1IY7/opa/v1/metrics"
+	serverDecodingPlugin "github.com/IUAD1IY7/opa/v1/plugins/server/decoding"
+	"github.com/IUAD1IY7/opa/v1/rego"
+	"github.com/IUAD1IY7/opa/v1/server/apiv1"
+	"github.com/IUAD1IY7/opa/v1/server/types"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/topdown"
+	"github.com/IUAD1IY7/opa/v1/topdown/builtins"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+// GRPCAddrs returns a list of addresses that the gRPC API is listening on.
+// If the server hasn't been started it will not return an address.
+func (s *Server) GRPCAddrs() []string {
+	addrs := make([]string, 0, len(s.grpcListeners))
+	for _, l := range s.grpcListeners {
+		addrs = append(addrs, l.Addr().String())
+	}
+	return addrs
+}
+
+// getGRPCListeners binds the gRPC API to the configured addresses and returns
+// the loops that serve it. If a certificate is configured, connections are
+// secured with the same TLS configuration as the HTTPS listeners.
+func (s *Server) getGRPCListeners() ([]Loop, error) {
+	if len(s.grpcAddrs) == 0 {
+		return nil, nil
+	}
+
+	maxLength, err := s.getMaxRequestLength()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.grpcInterceptor),
+		grpc.MaxRecvMsgSize(maxLength),
+	}
+	if s.cert != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.getTLSConfig("h2"))))
+	}
+
+	s.grpcServer = grpc.NewServer(opts...)
+	apiv1.RegisterOPAServer(s.grpcServer, &grpcServer{s: s})
+	grpc_health_v1.RegisterHealthServer(s.grpcServer, &grpcHealthServer{s: s})
+
+	loops := make([]Loop, 0, len(s.grpcAddrs))
+
+	for _, addr := range s.grpcAddrs {
+		parsedURL, err := parseURL(addr, false)
+		if err != nil {
+			return nil, err
+		}
+
+		var l net.Listener
+		switch parsedURL.Scheme {
+		case "unix":
+			socketPath := parsedURL.Host + parsedURL.Path
+			// Remove domain socket file in case it already exists.
+			os.Remove(socketPath)
+			l, err = net.Listen("unix", socketPath)
+		case "http":
+			l, err = net.Listen("tcp", parsedURL.Host)
+		default:
+			err = fmt.Errorf("invalid url scheme %q", parsedURL.Scheme)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s.grpcListeners = append(s.grpcListeners, l)
+		loops = append(loops, func() error { return s.grpcServer.Serve(l) })
+	}
+
+	return loops, nil
+}
+
+func (s *Server) getMaxRequestLength() (int, error) {
+	var decodingRawConfig json.RawMessage
+	if serverConfig := s.manager.Config.Server; serverConfig != nil {
+		decodingRawConfig = serverConfig.Decoding
+	}
+	decodingConfig, err := serverDecodingPlugin.NewConfigBuilder().WithBytes(decodingRawConfig).Parse()
+	if err != nil {
+		return 0, err
+	}
+	return int(*decodingConfig.MaxLength), nil
+}
+
+func (s *Server) shutdownGRPC(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// initGRPCRouter returns the handler that invokes gRPC calls after they have
+// been authenticated and authorized. Calls are routed like requests to the
+// equivalent REST endpoints, so that they are instrumented with the same
+// labels.
+func (s *Server) initGRPCRouter() http.Handler {
+	router := http.NewServeMux()
+	router.Handle("GET /health", s.instrumentHandler(s.grpcInvoke, PromHandlerHealth))
+	router.Handle("POST /v1/data/{path...}", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Data))
+	router.Handle("POST /v1/data", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Data))
+	router.Handle("GET /v1/policies", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Policies))
+	router.Handle("DELETE /v1/policies/{path...}", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Policies))
+	router.Handle("GET /v1/policies/{path...}", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Policies))
+	router.Handle("PUT /v1/policies/{path...}", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Policies))
+	router.Handle("POST /v1/query", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Query))
+	router.Handle("POST /v1/compile", s.instrumentHandler(s.grpcInvoke, PromHandlerV1Compile))
+	return router
+}
+
+type grpcCallKey struct{}
+
+// grpcCall is a unary gRPC call that is passed through s.GRPCHandler.
+type grpcCall struct {
+	req     any
+	handler grpc.UnaryHandler
+	invoked bool
+	resp    any
+	err     error
+}
+
+// grpcInterceptor passes calls through s.GRPCHandler, so that they are
+// authenticated, authorized, instrumented and logged like requests to the
+// equivalent REST endpoints.
+func (s *Server) grpcInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	call := &grpcCall{req: req, handler: handler}
+
+	r, err := s.grpcRequest(context.WithValue(ctx, grpcCallKey{}, call), info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &grpcResponseWriter{header: http.Header{}}
+	s.GRPCHandler.ServeHTTP(w, r)
+
+	if !call.invoked {
+		return nil, w.err()
+	}
+
+	return call.resp, call.err
+}
+
+func (*Server) grpcInvoke(w http.ResponseWriter, r *http.Request) {
+	call := r.Context().Value(grpcCallKey{}).(*grpcCall)
+	call.invoked = true
+	call.resp, call.err = call.handler(r.Context(), call.req)
+	call.err = grpcError(call.err)
+	w.WriteHeader(grpcHTTPStatus(status.Code(call.err)))
+}
+
+// grpcRequest returns the REST request that is equivalent to the gRPC call.
+// The request carries the call's metadata as headers and, if the connection
+// is secured with TLS, the connection state.
+func (s *Server) grpcRequest(ctx context.Context, method string, req any) (*http.Request, error) {
+	var httpMethod, path string
+	var body []byte
+	params := url.Values{}
+
+	setParam := func(name string, value bool) {
+		if value {
+			params.Set(name, "true")
+		}
+	}
+
+	switch req := req.(type) {
+	case *apiv1.GetDataRequest:
+		httpMethod, path = http.MethodPost, "/v1/data"
+		if p := strings.Trim(req.Path, "/"); p != "" {
+			path += "/" + p
+		}
+		setParam(types.ParamProvenanceV1, req.Provenance)
+		setParam(types.ParamMetricsV1, req.Metrics)
+		setParam(types.ParamInstrumentV1, req.Instrument)
+		setParam(types.ParamStrictBuiltinErrors, req.StrictBuiltinErrors)
+		// The authorizer provides the input to the authorization policy.
+		if s.authorization != AuthorizationOff && (req.Input != nil || req.RawInput != nil) {
+			input := req.RawInput
+			if input == nil {
+				var err error
+				if input, err = protojson.Marshal(req.Input); err != nil {
+					return nil, status.Error(codes.InvalidArgument, err.Error())
+				}
+			} else if !json.Valid(input) {
+				return nil, status.Error(codes.InvalidArgument, "invalid raw_input")
+			}
+			body = append(append([]byte(`{"input":`), input...), '}')
+		}
+	case *apiv1.QueryRequest:
+		httpMethod, path = http.MethodPost, "/v1/query"
+		setParam(types.ParamMetricsV1, req.Metrics)
+		setParam(types.ParamInstrumentV1, req.Instrument)
+	case *apiv1.ListPoliciesRequest:
+		httpMethod, path = http.MethodGet, "/v1/policies"
+	case *apiv1.GetPolicyRequest:
+		httpMethod, path = http.MethodGet, "/v1/policies/"+req.Id
+	case *apiv1.PutPolicyRequest:
+		httpMethod, path = http.MethodPut, "/v1/policies/"+req.Id
+		setParam(types.ParamMetricsV1, req.Metrics)
+		body = []byte(req.Raw)
+	case *apiv1.DeletePolicyRequest:
+		httpMethod, path = http.MethodDelete, "/v1/policies/"+req.Id
+		setParam(types.ParamMetricsV1, req.Metrics)
+	case *apiv1.CompileRequest:
+		httpMethod, path = http.MethodPost, "/v1/compile"
+		setParam(types.ParamMetricsV1, req.Metrics)
+		setParam(types.ParamInstrumentV1, req.Instrument)
+	case *grpc_health_v1.HealthCheckRequest:
+		httpMethod, path = http.MethodGet, "/health"
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "method %v not implemented", method)
+	}
+
+	u := url.URL{Path: path, RawQuery: params.Encode()}
+
+	r, err := http.NewRequestWithContext(ctx, httpMethod, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, vs := range md {
+			if strings.HasPrefix(k, ":") {
+				continue
+			}
+			for _, v := range vs {
+				r.Header.Add(k, v)
+			}
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			r.RemoteAddr = p.Addr.String()
+		}
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			r.TLS = &tlsInfo.State
+		}
+	}
+
+	return r, nil
+}
+
+// grpcResponseWriter records responses written by s.GRPCHandler for calls
+// that were not invoked, e.g., because they were not authorized.
+type grpcResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *grpcResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcResponseWriter) Write(bs []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(bs)
+}
+
+func (w *grpcResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *grpcResponseWriter) err() error {
+	var resp struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(w.body.Bytes(), &resp); err != nil || resp.Code == "" {
+		return status.Errorf(codes.Internal, "call was not invoked: %v", http.StatusText(w.status))
+	}
+	return grpcError(&types.ErrorV1{Code: resp.Code, Message: resp.Message})
+}
+
+var grpcCodes = map[string]codes.Code{
+	types.CodeInternal:          codes.Internal,
+	types.CodeEvaluation:        codes.Internal,
+	types.CodeUnauthorized:      codes.PermissionDenied,
+	types.CodeInvalidParameter:  codes.InvalidArgument,
+	types.CodeInvalidOperation:  codes.FailedPrecondition,
+	types.CodeResourceNotFound:  codes.NotFound,
+	types.CodeResourceConflict:  codes.Aborted,
+	types.CodeUndefinedDocument: codes.NotFound,
+}
+
+// grpcError converts err into a gRPC status error. Errors that are not
+// *types.ErrorV1 are classified like the REST API classifies them.
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var errV1 *types.ErrorV1
+	switch {
+	case errors.As(err, &errV1):
+	case types.IsBadRequest(err), storage.IsInvalidPatch(err):
+		errV1 = types.NewErrorV1(types.CodeInvalidParameter, "%v", err)
+	case storage.IsWriteConflictError(err):
+		errV1 = types.NewErrorV1(types.CodeResourceConflict, "%v", err)
+	case topdown.IsError(err):
+		errV1 = types.NewErrorV1(types.CodeInternal, types.MsgEvaluationError).WithError(err)
+	case storage.IsNotFound(err):
+		errV1 = types.NewErrorV1(types.CodeResourceNotFound, "%v", err)
+	default:
+		errV1 = types.NewErrorV1(types.CodeInternal, "%v", err)
+	}
+
+	code, ok := grpcCodes[errV1.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	msg := errV1.Message
+	if len(errV1.Errors) > 0 {
+		errs := make([]string, len(errV1.Errors))
+		for i := range errV1.Errors {
+			errs[i] = errV1.Errors[i].Error()
+		}
+		msg += ": " + strings.Join(errs, "; ")
+	}
+
+	return status.Error(code, msg)
+}
+
+// grpcHTTPStatus returns the HTTP status that the REST API responds with for
+// errors that are reported with code.
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.NotFound, codes.Aborted:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// grpcServer implements the OPA gRPC service.
+type grpcServer struct {
+	apiv1.UnimplementedOPAServer
+	s *Server
+}
+
+func (g *grpcServer) GetData(ctx context.Context, req *apiv1.GetDataRequest) (*apiv1.GetDataResponse, error) {
+	s := g.s
+
+	var m metrics.Metrics = metrics.NoOp()
+	if s.logger != nil || req.Metrics || req.Instrument {
+		m = metrics.New()
+	}
+
+	m.Timer(metrics.ServerHandler).Start()
+
+	decisionID := s.generateDecisionID()
+	ctx = logging.WithDecisionID(ctx, decisionID)
+	annotateSpan(ctx, decisionID)
+
+	m.Timer(metrics.RegoInputParse).Start()
+
+	input, goInput, err := grpcInput(req.Input, req.RawInput)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Timer(metrics.RegoInputParse).Stop()
+
+	txn, err := s.store.NewTransaction(ctx, storage.TransactionParams{Context: storage.NewContext().WithMetrics(m)})
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	var logger decisionLogger
+	var br bundleRevisions
+
+	if s.logger != nil || req.Provenance {
+		br, err = getRevisions(ctx, s.store, txn)
+		if err != nil {
+			return nil, err
+		}
+		if s.logger != nil {
+			logger = s.getDecisionLogger(br)
+		}
+	}
+
+	var ndbCache builtins.NDBCache
+	if s.ndbCacheEnabled {
+		ndbCache = builtins.NDBCache{}
+	}
+
+	urlPath := strings.Trim(req.Path, "/")
+
+	// Prepared queries are shared with the REST API.
+	pqID := "v1DataPost::"
+	if req.StrictBuiltinErrors {
+		pqID = "v1DataPost::strict-builtin-errors::"
+	}
+	pqID += urlPath
+	preparedQuery, ok := s.getCachedPreparedEvalQuery(pqID, m)
+	if !ok {
+		opts := []func(*rego.Rego){
+			rego.Compiler(s.getCompiler()),
+			rego.Store(s.store),
+		}
+
+		for _, r := range s.manager.GetWasmResolvers() {
+			for _, entrypoint := range r.Entrypoints() {
+				opts = append(opts, rego.Resolver(entrypoint, r))
+			}
+		}
+
+		rego, err := s.makeRego(ctx, req.StrictBuiltinErrors, txn, input, urlPath, m, req.Instrument, nil, opts)
+		if err != nil {
+			_ = logger.Log(ctx, txn, urlPath, "", goInput, input, nil, ndbCache, err, m)
+			return nil, err
+		}
+
+		pq, err := rego.PrepareForEval(ctx)
+		if err != nil {
+			_ = logger.Log(ctx, txn, urlPath, "", goInput, input, nil, ndbCache, err, m)
+			return nil, err
+		}
+		preparedQuery = &pq
+		s.preparedEvalQueries.Insert(pqID, preparedQuery)
+	}
+
+	rs, err := preparedQuery.Eval(ctx,
+		rego.EvalTransaction(txn),
+		rego.EvalParsedInput(input),
+		rego.EvalMetrics(m),
+		rego.EvalInterQueryBuiltinCache(s.interQueryBuiltinCache),
+		rego.EvalInterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
+		rego.EvalInstrument(req.Instrument),
+		rego.EvalNDBuiltinCache(ndbCache),
+	)
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	if err != nil {
+		_ = logger.Log(ctx, txn, urlPath, "", goInput, input, nil, ndbCache, err, m)
+		return nil, err
+	}
+
+	resp := &apiv1.GetDataResponse{DecisionId: decisionID}
+
+	var result *any
+	if len(rs) > 0 {
+		result = &rs[0].Expressions[0].Value
+		if req.RawOutput {
+			resp.RawResult, err = json.Marshal(*result)
+		} else {
+			resp.Result, err = structpb.NewValue(*result)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Metrics || req.Instrument {
+		if resp.Metrics, err = structpb.NewStruct(m.All()); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Provenance {
+		if resp.Provenance, err = grpcStruct(s.getProvenance(br)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := logger.Log(ctx, txn, urlPath, "", goInput, input, result, ndbCache, nil, m); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (g *grpcServer) Query(ctx context.Context, req *apiv1.QueryRequest) (*apiv1.QueryResponse, error) {
+	s := g.s
+
+	m := metrics.New()
+	m.Timer(metrics.ServerHandler).Start()
+
+	decisionID := s.generateDecisionID()
+	ctx = logging.WithDecisionID(ctx, decisionID)
+	annotateSpan(ctx, decisionID)
+
+	parsedQuery, err := validateQuery(req.Query, s.manager.ParserOptions())
+	if err != nil {
+		if errs, ok := err.(ast.Errors); ok {
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, types.MsgParseQueryError).WithASTErrors(errs)
+		}
+		return nil, err
+	}
+
+	input, goInput, err := grpcInput(req.Input, req.RawInput)
+	if err != nil {
+		return nil, err
+	}
+
+	txn, err := s.store.NewTransaction(ctx, storage.TransactionParams{Context: storage.NewContext().WithMetrics(m)})
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	br, err := getRevisions(ctx, s.store, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.execQuery(ctx, br, txn, parsedQuery, input, goInput, m, types.ExplainOffV1, req.Metrics, req.Instrument, false)
+	if err != nil {
+		if errs, ok := err.(ast.Errors); ok {
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileQueryError).WithASTErrors(errs)
+		}
+		return nil, err
+	}
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	resp := &apiv1.QueryResponse{}
+
+	if req.RawOutput {
+		if resp.RawResult, err = json.Marshal(results.Result); err != nil {
+			return nil, err
+		}
+	} else {
+		resp.Result = make([]*structpb.Struct, len(results.Result))
+		for i := range results.Result {
+			if resp.Result[i], err = structpb.NewStruct(results.Result[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if req.Metrics || req.Instrument {
+		if resp.Metrics, err = structpb.NewStruct(m.All()); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (g *grpcServer) ListPolicies(ctx context.Context, _ *apiv1.ListPoliciesRequest) (*apiv1.ListPoliciesResponse, error) {
+	s := g.s
+
+	txn, err := s.store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	// Only return policies from the store, the compiler
+	// may contain additional partially compiled modules.
+	ids, err := s.store.ListPolicies(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &apiv1.ListPoliciesResponse{Result: make([]*apiv1.Policy, 0, len(ids))}
+
+	for _, id := range ids {
+		policy, err := g.getPolicy(ctx, txn, id)
+		if err != nil {
+			return nil, err
+		}
+		resp.Result = append(resp.Result, policy)
+	}
+
+	return resp, nil
+}
+
+func (g *grpcServer) GetPolicy(ctx context.Context, req *apiv1.GetPolicyRequest) (*apiv1.GetPolicyResponse, error) {
+	s := g.s
+
+	txn, err := s.store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	policy, err := g.getPolicy(ctx, txn, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.GetPolicyResponse{Result: policy}, nil
+}
+
+func (g *grpcServer) getPolicy(ctx context.Context, txn storage.Transaction, id string) (*apiv1.Policy, error) {
+	bs, err := g.s.store.GetPolicy(ctx, txn, id)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &apiv1.Policy{Id: id, Raw: string(bs)}
+
+	if module := g.s.getCompiler().Modules[id]; module != nil {
+		if policy.Ast, err = grpcStruct(module); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+func (g *grpcServer) PutPolicy(ctx context.Context, req *apiv1.PutPolicyRequest) (*apiv1.PutPolicyResponse, error) {
+	m := metrics.New()
+
+	if err := g.s.upsertPolicy(ctx, req.Id, []byte(req.Raw), m); err != nil {
+		return nil, err
+	}
+
+	resp := &apiv1.PutPolicyResponse{}
+
+	if req.Metrics {
+		var err error
+		if resp.Metrics, err = structpb.NewStruct(m.All()); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (g *grpcServer) DeletePolicy(ctx context.Context, req *apiv1.DeletePolicyRequest) (*apiv1.DeletePolicyResponse, error) {
+	m := metrics.New()
+
+	if err := g.s.deletePolicy(ctx, req.Id, m); err != nil {
+		return nil, err
+	}
+
+	resp := &apiv1.DeletePolicyResponse{}
+
+	if req.Metrics {
+		var err error
+		if resp.Metrics, err = structpb.NewStruct(m.All()); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (g *grpcServer) Compile(ctx context.Context, req *apiv1.CompileRequest) (*apiv1.CompileResponse, error) {
+	s := g.s
+
+	m := metrics.New()
+	m.Timer(metrics.ServerHandler).Start()
+	m.Timer(metrics.RegoQueryParse).Start()
+
+	query, err := ast.ParseBodyWithOpts(req.Query, s.manager.ParserOptions())
+	if err != nil {
+		if errs, ok := err.(ast.Errors); ok {
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, types.MsgParseQueryError).WithASTErrors(errs)
+		}
+		return nil, types.NewErrorV1(types.CodeInvalidParameter, "%v: %v", types.MsgParseQueryError, err)
+	} else if len(query) == 0 {
+		return nil, types.NewErrorV1(types.CodeInvalidParameter, "missing required 'query' value")
+	}
+
+	input, _, err := grpcInput(req.Input, req.RawInput)
+	if err != nil {
+		return nil, err
+	}
+
+	var unknowns []*ast.Term
+	for _, u := range req.Unknowns {
+		term, err := ast.ParseTerm(u)
+		if err != nil {
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, "error(s) occurred while parsing unknowns: %v", err)
+		}
+		unknowns = append(unknowns, term)
+	}
+
+	m.Timer(metrics.RegoQueryParse).Stop()
+
+	txn, err := s.store.NewTransaction(ctx, storage.TransactionParams{Context: storage.NewContext().WithMetrics(m)})
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	eval := rego.New(
+		rego.Compiler(s.getCompiler()),
+		rego.Store(s.store),
+		rego.Transaction(txn),
+		rego.ParsedQuery(query),
+		rego.ParsedInput(input),
+		rego.ParsedUnknowns(unknowns),
+		rego.DisableInlining(req.DisableInlining),
+		rego.NondeterministicBuiltins(req.NondeterministicBuiltins),
+		rego.Instrument(req.Instrument),
+		rego.Metrics(m),
+		rego.Runtime(s.runtime),
+		rego.UnsafeBuiltins(unsafeBuiltinsMap),
+		rego.InterQueryBuiltinCache(s.interQueryBuiltinCache),
+		rego.InterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
+		rego.PrintHook(s.manager.PrintHook()),
+	)
+
+	pq, err := eval.Partial(ctx)
+	if err != nil {
+		if errs, ok := err.(ast.Errors); ok {
+			return nil, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(errs)
+		}
+		return nil, err
+	}
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	resp := &apiv1.CompileResponse{}
+
+	result := types.PartialEvaluationResultV1{Queries: pq.Queries, Support: pq.Support}
+	if req.RawOutput {
+		resp.RawResult, err = json.Marshal(result)
+	} else {
+		resp.Result, err = grpcValue(result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Metrics || req.Instrument {
+		if resp.Metrics, err = structpb.NewStruct(m.All()); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// grpcHealthServer implements the standard gRPC health service. The overall
+// health corresponds to GET /health. The "bundles" and "plugins" services
+// correspond to GET /health?bundles and GET /health?plugins respectively.
+type grpcHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	s *Server
+}
+
+func (h *grpcHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	var includeBundleStatus, includePluginStatus bool
+
+	switch req.Service {
+	case "", apiv1.OPA_ServiceDesc.ServiceName:
+	case "bundles":
+		includeBundleStatus = true
+	case "plugins":
+		includePluginStatus = true
+	default:
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+
+	resp := &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}
+	if err := h.s.checkHealth(ctx, includeBundleStatus, includePluginStatus, nil); err != nil {
+		resp.Status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	return resp, nil
+}
+
+// grpcInput returns the input of a request, given either as a Value or JSON
+// encoded. Numbers in a Value are doubles, so only JSON encoded input keeps
+// integers above 2^53 intact.
+func grpcInput(v *structpb.Value, raw []byte) (ast.Value, *any, error) {
+	if v != nil && raw != nil {
+		return nil, nil, types.NewErrorV1(types.CodeInvalidParameter, "input and raw_input are mutually exclusive")
+	}
+
+	var x any
+	switch {
+	case raw != nil:
+		if err := util.UnmarshalJSON(raw, &x); err != nil {
+			return nil, nil, types.NewErrorV1(types.CodeInvalidParameter, "error(s) occurred while parsing raw_input: %v", err)
+		}
+	case v != nil:
+		x = v.AsInterface()
+	default:
+		return nil, nil, nil
+	}
+
+	input, err := ast.InterfaceToValue(x)
+	if err != nil {
+		return nil, nil, types.NewErrorV1(types.CodeInvalidParameter, "error(s) occurred while converting input: %v", err)
+	}
+	return input, &x, nil
+}
+
+// grpcValue converts x into a Value using its JSON representation.
+func grpcValue(x any) (*structpb.Value, error) {
+	v := &structpb.Value{}
+	return v, grpcUnmarshal(x, v)
+}
+
+// grpcStruct converts x into a Struct using its JSON representation.
+func grpcStruct(x any) (*structpb.Struct, error) {
+	v := &structpb.Struct{}
+	return v, grpcUnmarshal(x, v)
+}
+
+func grpcUnmarshal(x any, m proto.Message) error {
+	bs, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(bs, m)
+}
@@ -0,0 +1,339 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/IUAD1IY7/opa/v1/server/apiv1"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+)
+
+func newGRPCFixture(t *testing.T, opts ...any) (*fixture, *grpc.ClientConn) {
+	t.Helper()
+
+	opts = append(opts, func(s *Server) {
+		s.WithGRPCAddresses([]string{"localhost:0"})
+	})
+	f := newFixture(t, opts...)
+
+	loops, err := f.server.getGRPCListeners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, loop := range loops {
+		go func() {
+			_ = loop()
+		}()
+	}
+
+	conn, err := grpc.NewClient(f.server.GRPCAddrs()[0], grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := f.server.Shutdown(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return f, conn
+}
+
+func expectGRPCCode(t *testing.T, err error, code codes.Code, msg string) {
+	t.Helper()
+
+	if s, _ := status.FromError(err); s.Code() != code || !strings.Contains(s.Message(), msg) {
+		t.Fatalf("expected %v error containing %q but got: %v", code, msg, err)
+	}
+}
+
+func TestGRPCDataAndPolicies(t *testing.T) {
+	t.Parallel()
+
+	var decisions []*Info
+	f, conn := newGRPCFixture(t, func(s *Server) {
+		s.WithDecisionIDFactory(func() string { return "xyz" })
+		s.WithDecisionLoggerWithErr(func(_ context.Context, info *Info) error {
+			decisions = append(decisions, info)
+			return nil
+		})
+	})
+	client := apiv1.NewOPAClient(conn)
+	ctx := context.Background()
+
+	module := `package test
+
+allow if input.user == "alice"
+
+users := data.users`
+
+	if _, err := client.PutPolicy(ctx, &apiv1.PutPolicyRequest{Id: "test", Raw: module}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.WriteOne(ctx, f.server.store, storage.AddOp, storage.MustParsePath("/users"), []any{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := structpb.NewValue(map[string]any{"user": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/allow", Input: input, Metrics: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.DecisionId != "xyz" || !resp.Result.GetBoolValue() || resp.Metrics == nil {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+
+	if len(decisions) != 1 || decisions[0].Path != "test/allow" || !reflect.DeepEqual(*decisions[0].Input, map[string]any{"user": "alice"}) {
+		t.Fatalf("unexpected decisions: %v", decisions)
+	}
+
+	resp, err = client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/allow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result != nil {
+		t.Fatalf("expected undefined result but got: %v", resp.Result)
+	}
+
+	resp, err = client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []any{"alice"}; !reflect.DeepEqual(resp.Result.AsInterface(), exp) {
+		t.Fatalf("expected %v but got %v", exp, resp.Result.AsInterface())
+	}
+
+	query, err := client.Query(ctx, &apiv1.QueryRequest{Query: "x := data.users[_]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(query.Result) != 1 || query.Result[0].AsMap()["x"] != "alice" {
+		t.Fatalf("unexpected query result: %v", query.Result)
+	}
+
+	_, err = client.Query(ctx, &apiv1.QueryRequest{Query: "x :="})
+	expectGRPCCode(t, err, codes.InvalidArgument, "error(s) occurred while parsing query")
+
+	list, err := client.ListPolicies(ctx, &apiv1.ListPoliciesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Result) != 1 || list.Result[0].Id != "test" || list.Result[0].Raw != module || list.Result[0].Ast == nil {
+		t.Fatalf("unexpected policies: %v", list.Result)
+	}
+
+	_, err = client.PutPolicy(ctx, &apiv1.PutPolicyRequest{Id: "bad", Raw: "package bad\n\np if { x }"})
+	expectGRPCCode(t, err, codes.InvalidArgument, "var x is unsafe")
+
+	if _, err := client.DeletePolicy(ctx, &apiv1.DeletePolicyRequest{Id: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetPolicy(ctx, &apiv1.GetPolicyRequest{Id: "test"})
+	expectGRPCCode(t, err, codes.NotFound, "storage_not_found_error")
+}
+
+func TestGRPCLargeIntegers(t *testing.T) {
+	t.Parallel()
+
+	_, conn := newGRPCFixture(t)
+	client := apiv1.NewOPAClient(conn)
+	ctx := context.Background()
+
+	if _, err := client.PutPolicy(ctx, &apiv1.PutPolicyRequest{Id: "test", Raw: "package test\n\nid := input.id"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Numbers in a Value are doubles, so 2^53 + 1 can't be represented.
+	input, err := structpb.NewValue(map[string]any{"id": 9007199254740993})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/id", Input: input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result.GetNumberValue() != 9007199254740992 {
+		t.Fatalf("expected rounded result but got: %v", resp.Result)
+	}
+
+	raw := []byte(`{"id": 9007199254740993}`)
+
+	resp, err = client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/id", RawInput: raw, RawOutput: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result != nil || string(resp.RawResult) != "9007199254740993" {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+
+	query, err := client.Query(ctx, &apiv1.QueryRequest{Query: "x := input.id", RawInput: raw, RawOutput: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(query.RawResult) != `[{"x":9007199254740993}]` {
+		t.Fatalf("unexpected query result: %s", query.RawResult)
+	}
+
+	_, err = client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/id", Input: input, RawInput: raw})
+	expectGRPCCode(t, err, codes.InvalidArgument, "input and raw_input are mutually exclusive")
+
+	_, err = client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/id", RawInput: []byte("{")})
+	expectGRPCCode(t, err, codes.InvalidArgument, "error(s) occurred while parsing raw_input")
+}
+
+func TestGRPCCompile(t *testing.T) {
+	t.Parallel()
+
+	_, conn := newGRPCFixture(t)
+	client := apiv1.NewOPAClient(conn)
+	ctx := context.Background()
+
+	module := `package test
+
+allow if input.x > 1`
+
+	if _, err := client.PutPolicy(ctx, &apiv1.PutPolicyRequest{Id: "test", Raw: module}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Compile(ctx, &apiv1.CompileRequest{Query: "data.test.allow == true", Unknowns: []string{"input"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := resp.Result.GetStructValue().AsMap()["queries"].([]any)
+	if len(queries) != 1 {
+		t.Fatalf("expected one query but got: %v", queries)
+	}
+
+	_, err = client.Compile(ctx, &apiv1.CompileRequest{Query: ""})
+	expectGRPCCode(t, err, codes.InvalidArgument, "missing required 'query' value")
+}
+
+func TestGRPCAuthorization(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := inmem.New()
+
+	authzPolicy := `package system.authz
+
+default allow := false
+
+allow if {
+	input.identity == "bob"
+	input.path == ["v1", "data", "test", "allow"]
+	input.body.input.user == "alice"
+}
+
+allow if {
+	input.identity == "bob"
+	input.path == ["health"]
+}`
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := store.UpsertPolicy(ctx, txn, "authz", []byte(authzPolicy)); err != nil {
+			return err
+		}
+		return store.UpsertPolicy(ctx, txn, "test", []byte("package test\n\nallow := true"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, conn := newGRPCFixture(t, func(s *Server) {
+		s.WithStore(store).WithAuthentication(AuthenticationToken).WithAuthorization(AuthorizationBasic)
+	})
+	client := apiv1.NewOPAClient(conn)
+	health := grpc_health_v1.NewHealthClient(conn)
+
+	bob := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer bob")
+
+	alice, err := structpb.NewValue(map[string]any{"user": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.GetData(bob, &apiv1.GetDataRequest{Path: "test/allow", Input: alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Result.GetBoolValue() {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+
+	resp, err = client.GetData(bob, &apiv1.GetDataRequest{Path: "test/allow", RawInput: []byte(`{"user": "alice"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Result.GetBoolValue() {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+
+	_, err = client.GetData(ctx, &apiv1.GetDataRequest{Path: "test/allow", Input: alice})
+	expectGRPCCode(t, err, codes.PermissionDenied, "request rejected by administrative policy")
+
+	_, err = client.GetData(bob, &apiv1.GetDataRequest{Path: "test/allow"})
+	expectGRPCCode(t, err, codes.PermissionDenied, "request rejected by administrative policy")
+
+	_, err = client.ListPolicies(bob, &apiv1.ListPoliciesRequest{})
+	expectGRPCCode(t, err, codes.PermissionDenied, "request rejected by administrative policy")
+
+	check, err := health.Check(bob, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("unexpected health status: %v", check.Status)
+	}
+
+	_, err = health.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	expectGRPCCode(t, err, codes.PermissionDenied, "request rejected by administrative policy")
+}
+
+func TestGRPCHealth(t *testing.T) {
+	t.Parallel()
+
+	_, conn := newGRPCFixture(t)
+	health := grpc_health_v1.NewHealthClient(conn)
+	ctx := context.Background()
+
+	for _, service := range []string{"", "opa.v1.OPA", "bundles", "plugins"} {
+		resp, err := health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+			t.Fatalf("expected %q to be serving but got %v", service, resp.Status)
+		}
+	}
+
+	_, err := health.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	expectGRPCCode(t, err, codes.NotFound, "unknown service")
+}
@@ -19,10 +19,12 @@ import (
 	"net/http/pprof"
 	"net/url"
 	"os"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	serverDecodingPlugin "github.com/IUAD1IY7/opa/v1/plugins/server/decoding"
@@ -34,9 +36,12 @@ import (
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 
+	"google.golang.org/grpc"
+
 	"github.com/IUAD1IY7/opa/internal/json/patch"
 	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/filter"
 	"github.com/IUAD1IY7/opa/v1/logging"
 	"github.com/IUAD1IY7/opa/v1/metrics"
 	"github.com/IUAD1IY7/opa/v1/plugins"
@@ -85,11 +90,15 @@ const (
 	// Set of handlers for use in the "handler" dimension of the duration metric.
 	PromHandlerV0Data     = "v0/data"
 	PromHandlerV1Data     = "v1/data"
+	PromHandlerV1Batch    = "v1/batch/data"
 	PromHandlerV1Query    = "v1/query"
 	PromHandlerV1Policies = "v1/policies"
 	PromHandlerV1Compile  = "v1/compile"
 	PromHandlerV1Config   = "v1/config"
 	PromHandlerV1Status   = "v1/status"
+	PromHandlerV1Changes  = "v1/changes"
+	PromHandlerV1Bundles  = "v1/bundles"
+	PromHandlerV1Debug    = "v1/debug"
 	PromHandlerIndex      = "index"
 	PromHandlerCatch      = "catchall"
 	PromHandlerHealth     = "health"
@@ -111,9 +120,14 @@ type Server struct {
 	Handler           http.Handler
 	DiagnosticHandler http.Handler
 
+	// GRPCHandler authenticates, authorizes and instruments calls to the gRPC
+	// API. Calls are represented as requests to the equivalent REST endpoints.
+	GRPCHandler http.Handler
+
 	router                      *http.ServeMux
 	addrs                       []string
 	diagAddrs                   []string
+	grpcAddrs                   []string
 	h2cEnabled                  bool
 	authentication              AuthenticationScheme
 	authorization               AuthorizationScheme
@@ -137,8 +151,11 @@ type Server struct {
 	logger                      func(context.Context, *Info) error
 	errLimit                    int
 	pprofEnabled                bool
+	debugAPIEnabled             bool
 	runtime                     *ast.Term
 	httpListeners               []httpListener
+	grpcServer                  *grpc.Server
+	grpcListeners               []net.Listener
 	metrics                     Metrics
 	defaultDecisionPath         string
 	interQueryBuiltinCache      iCache.InterQueryCache
@@ -148,6 +165,8 @@ type Server struct {
 	ndbCacheEnabled             bool
 	unixSocketPerm              *string
 	cipherSuites                *[]uint16
+	changes                     *changeFeed
+	debug                       *debugAPI
 }
 
 // Metrics defines the interface that the server requires for recording HTTP
@@ -186,8 +205,19 @@ func New() *Server {
 // Init initializes the server. This function MUST be called before starting any loops
 // from s.Listeners().
 func (s *Server) Init(ctx context.Context) (*Server, error) {
+	if s.debugAPIEnabled {
+		if s.authentication != AuthenticationTLS && s.authorization != AuthorizationBasic {
+			return nil, errors.New("debug API requires authentication: enable TLS client authentication or basic authorization")
+		}
+		s.debug = newDebugAPI()
+	}
+
 	s.initRouters(ctx)
 
+	if err := s.initChangeFeed(); err != nil {
+		return nil, err
+	}
+
 	txn, err := s.store.NewTransaction(ctx, storage.WriteParams)
 	if err != nil {
 		return nil, err
@@ -203,6 +233,13 @@ func (s *Server) Init(ctx context.Context) (*Server, error) {
 		return nil, err
 	}
 
+	// Register triggers so that committed changes are streamed to clients of
+	// the Changes API.
+	if _, err := s.store.Register(ctx, txn, storage.TriggerConfig{OnCommit: s.changes.onCommit}); err != nil {
+		s.store.Abort(ctx, txn)
+		return nil, err
+	}
+
 	s.partials = map[string]rego.PartialResult{}
 	s.preparedEvalQueries = newCache(pqMaxCacheSize)
 	s.defaultDecisionPath = s.generateDefaultDecisionPath()
@@ -216,6 +253,7 @@ func (s *Server) Init(ctx context.Context) (*Server, error) {
 		return nil, err
 	}
 	s.DiagnosticHandler = s.initHandlerAuthn(s.DiagnosticHandler)
+	s.GRPCHandler = s.initHandlerAuthn(s.GRPCHandler)
 
 	s.Handler, err = s.initHandlerDecodingLimits(s.Handler)
 	if err != nil {
@@ -226,18 +264,37 @@ func (s *Server) Init(ctx context.Context) (*Server, error) {
 }
 
 // Shutdown will attempt to gracefully shutdown each of the http servers
-// currently in use by the OPA Server. If any exceed the deadline specified
-// by the context an error will be returned.
+// and the gRPC server currently in use by the OPA Server. If any exceed the
+// deadline specified by the context an error will be returned.
 func (s *Server) Shutdown(ctx context.Context) error {
+	// End open Changes API streams, otherwise the http servers would wait
+	// for them until the deadline is exceeded.
+	if s.changes != nil {
+		s.changes.close()
+	}
+
+	// Disconnect attached debuggers, their connections aren't tracked by the
+	// http servers.
+	if s.debug != nil {
+		s.debug.close()
+	}
+
 	errChan := make(chan error)
 	for _, srvr := range s.httpListeners {
 		go func(s httpListener) {
 			errChan <- s.Shutdown(ctx)
 		}(srvr)
 	}
+	n := len(s.httpListeners)
+	if s.grpcServer != nil {
+		go func() {
+			errChan <- s.shutdownGRPC(ctx)
+		}()
+		n++
+	}
 	// wait until each server has finished shutting down
 	var errorList []error
-	for range s.httpListeners {
+	for range n {
 		err := <-errChan
 		if err != nil {
 			errorList = append(errorList, err)
@@ -267,6 +324,13 @@ func (s *Server) WithDiagnosticAddresses(addrs []string) *Server {
 	return s
 }
 
+// WithGRPCAddresses sets the listening addresses that the server will bind
+// the gRPC API to. The gRPC API is disabled if no addresses are set.
+func (s *Server) WithGRPCAddresses(addrs []string) *Server {
+	s.grpcAddrs = addrs
+	return s
+}
+
 // WithAuthentication sets authentication scheme to use on the server.
 func (s *Server) WithAuthentication(scheme AuthenticationScheme) *Server {
 	s.authentication = scheme
@@ -345,6 +409,14 @@ func (s *Server) WithPprofEnabled(pprofEnabled bool) *Server {
 	return s
 }
 
+// WithDebugAPIEnabled sets whether the Debug API is enabled, letting
+// debuggers attach to decisions. It requires TLS client authentication or the
+// basic authorization policy.
+func (s *Server) WithDebugAPIEnabled(enabled bool) *Server {
+	s.debugAPIEnabled = enabled
+	return s
+}
+
 // WithH2CEnabled sets whether h2c ("HTTP/2 cleartext") is enabled for the http listener
 func (s *Server) WithH2CEnabled(enabled bool) *Server {
 	s.h2cEnabled = enabled
@@ -443,7 +515,12 @@ func (s *Server) Listeners() ([]Loop, error) {
 		}
 	}
 
-	return loops, nil
+	grpcLoops, err := s.getGRPCListeners()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(loops, grpcLoops...), nil
 }
 
 // Addrs returns a list of addresses that the server is listening on.
@@ -639,8 +716,27 @@ func (s *Server) getListenerForHTTPSServer(u *url.URL, h http.Handler, t httpLis
 		return nil, nil, errors.New("TLS certificate required but not supplied")
 	}
 
-	tlsConfig := tls.Config{
+	httpsServer := http.Server{
+		Addr:      u.Host,
+		Handler:   h,
+		TLSConfig: s.getTLSConfig(),
+	}
+
+	l := newHTTPListener(&httpsServer, t)
+
+	httpsLoop := func() error { return l.ListenAndServeTLS("", "") }
+
+	return httpsLoop, l, nil
+}
+
+// getTLSConfig returns the TLS configuration for listeners. The configuration
+// is reloaded for every connection, so that changes to the certificate and
+// certificate pool take effect. The given application protocols are offered
+// during ALPN negotiation.
+func (s *Server) getTLSConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
 		GetCertificate: s.getCertificate,
+		NextProtos:     nextProtos,
 		// GetConfigForClient is used to ensure that a fresh config is provided containing the latest cert pool.
 		// This is not required, but appears to be how connect time updates config should be done:
 		// https://github.com/golang/go/issues/16066#issuecomment-250606132
@@ -651,6 +747,7 @@ func (s *Server) getListenerForHTTPSServer(u *url.URL, h http.Handler, t httpLis
 			cfg := &tls.Config{
 				GetCertificate: s.getCertificate,
 				ClientCAs:      s.certPool,
+				NextProtos:     nextProtos,
 			}
 
 			if s.authentication == AuthenticationTLS {
@@ -670,18 +767,6 @@ func (s *Server) getListenerForHTTPSServer(u *url.URL, h http.Handler, t httpLis
 			return cfg, nil
 		},
 	}
-
-	httpsServer := http.Server{
-		Addr:      u.Host,
-		Handler:   h,
-		TLSConfig: &tlsConfig,
-	}
-
-	l := newHTTPListener(&httpsServer, t)
-
-	httpsLoop := func() error { return l.ListenAndServeTLS("", "") }
-
-	return httpsLoop, l, nil
 }
 
 func (s *Server) getListenerForUNIXSocket(u *url.URL, h http.Handler, t httpListenerType) (Loop, httpListener, error) {
@@ -834,6 +919,10 @@ func (s *Server) initRouters(ctx context.Context) {
 		mainRouter.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
 	}
 
+	if s.debug != nil {
+		mainRouter.Handle("GET /v1/debug", s.instrumentHandler(s.v1DebugGet, PromHandlerV1Debug))
+	}
+
 	// Only the main mainRouter gets the OPA API's (data, policies, query, etc)
 	mainRouter.Handle("POST /v0/data/{path...}", s.instrumentHandler(s.v0DataPost, PromHandlerV0Data))
 	mainRouter.Handle("POST /v0/data", s.instrumentHandler(s.v0DataPost, PromHandlerV0Data))
@@ -846,6 +935,8 @@ func (s *Server) initRouters(ctx context.Context) {
 	mainRouter.Handle("PATCH /v1/data", s.instrumentHandler(s.v1DataPatch, PromHandlerV1Data))
 	mainRouter.Handle("POST /v1/data/{path...}", s.instrumentHandler(s.v1DataPost, PromHandlerV1Data))
 	mainRouter.Handle("POST /v1/data", s.instrumentHandler(s.v1DataPost, PromHandlerV1Data))
+	mainRouter.Handle("POST /v1/batch/data/{path...}", s.instrumentHandler(s.v1BatchDataPost, PromHandlerV1Batch))
+	mainRouter.Handle("POST /v1/batch/data", s.instrumentHandler(s.v1BatchDataPost, PromHandlerV1Batch))
 	mainRouter.Handle("GET /v1/policies", s.instrumentHandler(s.v1PoliciesList, PromHandlerV1Policies))
 	mainRouter.Handle("DELETE /v1/policies/{path...}", s.instrumentHandler(s.v1PoliciesDelete, PromHandlerV1Policies))
 	mainRouter.Handle("GET /v1/policies/{path...}", s.instrumentHandler(s.v1PoliciesGet, PromHandlerV1Policies))
@@ -855,6 +946,9 @@ func (s *Server) initRouters(ctx context.Context) {
 	mainRouter.Handle("POST /v1/compile", s.instrumentHandler(s.v1CompilePost, PromHandlerV1Compile))
 	mainRouter.Handle("GET /v1/config", s.instrumentHandler(s.v1ConfigGet, PromHandlerV1Config))
 	mainRouter.Handle("GET /v1/status", s.instrumentHandler(s.v1StatusGet, PromHandlerV1Status))
+	mainRouter.Handle("GET /v1/changes", s.instrumentHandler(s.v1ChangesGet, PromHandlerV1Changes))
+	mainRouter.Handle("POST /v1/bundles/{name}/promote", s.instrumentHandler(s.v1BundlePromotePost, PromHandlerV1Bundles))
+	mainRouter.Handle("POST /v1/bundles/{name}/reject", s.instrumentHandler(s.v1BundleRejectPost, PromHandlerV1Bundles))
 	mainRouter.Handle("POST /{$}", s.instrumentHandler(s.unversionedPost, PromHandlerIndex))
 	mainRouter.Handle("GET /{$}", s.instrumentHandler(s.indexGet, PromHandlerIndex))
 
@@ -863,14 +957,20 @@ func (s *Server) initRouters(ctx context.Context) {
 	mainRouter.Handle("/v0/data", s.methodNotAllowedHandler())
 	mainRouter.Handle("/v1/data/{path...}", s.methodNotAllowedHandler())
 	mainRouter.Handle("/v1/data", s.methodNotAllowedHandler())
+	mainRouter.Handle("/v1/batch/data/{path...}", s.methodNotAllowedHandler())
+	mainRouter.Handle("/v1/batch/data", s.methodNotAllowedHandler())
 	mainRouter.Handle("/v1/policies", s.methodNotAllowedHandler())
 	mainRouter.Handle("/v1/policies/{path...}", s.methodNotAllowedHandler())
 	mainRouter.Handle("/v1/query/{path...}", s.methodNotAllowedHandler())
 	mainRouter.Handle("/v1/query", s.methodNotAllowedHandler())
+	mainRouter.Handle("/v1/changes", s.methodNotAllowedHandler())
+	mainRouter.Handle("/v1/bundles/{name}/promote", s.methodNotAllowedHandler())
+	mainRouter.Handle("/v1/bundles/{name}/reject", s.methodNotAllowedHandler())
 
 	// Add authorization handler in the end so that it can run first
 	s.Handler = handlerAuthz
 	s.DiagnosticHandler = handlerAuthzDiag
+	s.GRPCHandler = s.initHandlerAuthz(s.initGRPCRouter())
 }
 
 func (s *Server) instrumentHandler(handler func(http.ResponseWriter, *http.Request), label string) http.Handler {
@@ -1114,6 +1214,8 @@ func (s *Server) v0QueryPath(w http.ResponseWriter, r *http.Request, urlPath str
 
 	m.Timer(metrics.ServerHandler).Stop()
 
+	s.captureDecision(ctx, txn, urlPath, input, ndbCache)
+
 	// Handle results.
 	if err != nil {
 		_ = logger.Log(ctx, txn, urlPath, "", goInput, input, nil, ndbCache, err, m)
@@ -1223,10 +1325,15 @@ func (s *Server) unversionedGetHealth(w http.ResponseWriter, r *http.Request) {
 		excludePluginMap[name] = struct{}{}
 	}
 
+	writeHealthResponse(w, s.checkHealth(ctx, includeBundleStatus, includePluginStatus, excludePluginMap))
+}
+
+// checkHealth returns an error if the server cannot evaluate policies or, if
+// requested, if bundles have not been activated or plugins are not up.
+func (s *Server) checkHealth(ctx context.Context, includeBundleStatus, includePluginStatus bool, excludePluginMap map[string]struct{}) error {
 	// Ensure the server can evaluate a simple query
 	if !s.canEval(ctx) {
-		writeHealthResponse(w, errors.New("unable to perform evaluation"))
-		return
+		return errors.New("unable to perform evaluation")
 	}
 
 	pluginStatuses := s.manager.PluginStatus()
@@ -1236,28 +1343,21 @@ func (s *Server) unversionedGetHealth(w http.ResponseWriter, r *http.Request) {
 	// normal bundles that are configured.
 	if includeBundleStatus && !s.bundlesReady(pluginStatuses) {
 		// For backwards compatibility we don't return a payload with statuses for the bundle endpoint
-		writeHealthResponse(w, errors.New("one or more bundles are not activated"))
-		return
+		return errors.New("one or more bundles are not activated")
 	}
 
 	if includePluginStatus {
 		// Ensure that all plugins (if requested to be included in the result) have an OK status.
-		hasErr := false
 		for name, status := range pluginStatuses {
 			if _, exclude := excludePluginMap[name]; exclude {
 				continue
 			}
 			if status != nil && status.State != plugins.StateOK {
-				hasErr = true
-				break
+				return errors.New("one or more plugins are not up")
 			}
 		}
-		if hasErr {
-			writeHealthResponse(w, errors.New("one or more plugins are not up"))
-			return
-		}
 	}
-	writeHealthResponse(w, nil)
+	return nil
 }
 
 func (s *Server) unversionedGetHealthWithPolicy(w http.ResponseWriter, r *http.Request) {
@@ -1344,6 +1444,7 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	explainMode := getExplain(r.URL, types.ExplainOffV1)
 	includeInstrumentation := getBoolParam(r.URL, types.ParamInstrumentV1, true)
+	render := getFilterRenderer(r)
 
 	m := metrics.New()
 	m.Timer(metrics.ServerHandler).Start()
@@ -1364,6 +1465,14 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 
 	m.Timer(metrics.RegoQueryParse).Stop()
 
+	if render != nil {
+		if err := filter.Check(s.getCompiler(), request.Query, request.Unknowns); err != nil {
+			m.Timer(metrics.ServerHandler).Stop()
+			writer.Error(w, http.StatusBadRequest, filterErrorV1(err))
+			return
+		}
+	}
+
 	c := storage.NewContext().WithMetrics(m)
 	txn, err := s.store.NewTransaction(ctx, storage.TransactionParams{Context: c})
 	if err != nil {
@@ -1408,6 +1517,23 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var i any = types.PartialEvaluationResultV1{
+		Queries: pq.Queries,
+		Support: pq.Support,
+	}
+
+	if render != nil {
+		f, err := filter.Translate(pq)
+		if err == nil {
+			i, err = render(f)
+		}
+		if err != nil {
+			m.Timer(metrics.ServerHandler).Stop()
+			writer.Error(w, http.StatusBadRequest, filterErrorV1(err))
+			return
+		}
+	}
+
 	m.Timer(metrics.ServerHandler).Stop()
 
 	result := types.CompileResponseV1{}
@@ -1420,11 +1546,6 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 		result.Explanation = s.getExplainResponse(explainMode, *buf, pretty(r))
 	}
 
-	var i any = types.PartialEvaluationResultV1{
-		Queries: pq.Queries,
-		Support: pq.Support,
-	}
-
 	result.Result = &i
 
 	writer.JSONOK(w, result, pretty(r))
@@ -1544,6 +1665,8 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 
 	m.Timer(metrics.ServerHandler).Stop()
 
+	s.captureDecision(ctx, txn, urlPath, input, ndbCache)
+
 	// Handle results.
 	if err != nil {
 		_ = logger.Log(ctx, txn, urlPath, "", goInput, input, nil, ndbCache, err, m)
@@ -1766,6 +1889,8 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 
 	m.Timer(metrics.ServerHandler).Stop()
 
+	s.captureDecision(ctx, txn, urlPath, input, ndbCache)
+
 	// Handle results.
 	if err != nil {
 		_ = logger.Log(ctx, txn, urlPath, "", goInput, input, nil, ndbCache, err, m)
@@ -1818,6 +1943,226 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 	writer.JSONOK(w, result, pretty(r))
 }
 
+func (s *Server) v1BatchDataPost(w http.ResponseWriter, r *http.Request) {
+	m := s.getMetrics(r)
+	m.Timer(metrics.ServerHandler).Start()
+
+	ctx := r.Context()
+
+	m.Timer(metrics.RegoInputParse).Start()
+
+	inputs, err := readInputBatchPostV1(r)
+	m.Timer(metrics.RegoInputParse).Stop()
+	if err != nil {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+
+	txn, err := s.store.NewTransaction(ctx, storage.TransactionParams{Context: storage.NewContext().WithMetrics(m)})
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	provenance := getBoolParam(r.URL, types.ParamProvenanceV1, true)
+
+	var logger decisionLogger
+	var br bundleRevisions
+
+	if s.logger != nil || provenance {
+		br, err = getRevisions(ctx, s.store, txn)
+		if err != nil {
+			writer.ErrorAuto(w, err)
+			return
+		}
+		if s.logger != nil {
+			logger = s.getDecisionLogger(br)
+		}
+	}
+
+	urlPath := escapedPathValue(r, "path")
+
+	strictBuiltinErrors := getBoolParam(r.URL, types.ParamStrictBuiltinErrors, true)
+	includeInstrumentation := getBoolParam(r.URL, types.ParamInstrumentV1, true)
+	includeMetrics := getBoolParam(r.URL, types.ParamMetricsV1, true)
+
+	pqID := "v1BatchDataPost::"
+	if strictBuiltinErrors {
+		pqID = "v1BatchDataPost::strict-builtin-errors::"
+	}
+	pqID += urlPath
+	preparedQuery, ok := s.getCachedPreparedEvalQuery(pqID, m)
+	if !ok {
+		opts := []func(*rego.Rego){
+			rego.Compiler(s.getCompiler()),
+			rego.Store(s.store),
+		}
+
+		for _, r := range s.manager.GetWasmResolvers() {
+			for _, entrypoint := range r.Entrypoints() {
+				opts = append(opts, rego.Resolver(entrypoint, r))
+			}
+		}
+
+		rego, err := s.makeRego(ctx, strictBuiltinErrors, txn, nil, urlPath, m, includeInstrumentation, nil, opts)
+		if err != nil {
+			writer.ErrorAuto(w, err)
+			return
+		}
+
+		pq, err := rego.PrepareForEval(ctx)
+		if err != nil {
+			writer.ErrorAuto(w, err)
+			return
+		}
+		preparedQuery = &pq
+		s.preparedEvalQueries.Insert(pqID, preparedQuery)
+	}
+
+	ids := make([]string, 0, len(inputs))
+	for id := range inputs {
+		ids = append(ids, id)
+	}
+
+	responses := make([]types.BatchDataResponseItemV1, len(ids))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			responses[i] = s.evalBatchItem(ctx, preparedQuery, txn, logger, urlPath, inputs[id], includeMetrics, includeInstrumentation)
+			if responses[i].Error != nil {
+				failed.Store(true)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	result := types.BatchDataResponseV1{
+		Responses: make(map[string]types.BatchDataResponseItemV1, len(ids)),
+	}
+
+	for i, id := range ids {
+		result.Responses[id] = responses[i]
+	}
+
+	if includeMetrics || includeInstrumentation {
+		result.Metrics = m.All()
+	}
+
+	if provenance {
+		result.Provenance = s.getProvenance(br)
+	}
+
+	if failed.Load() {
+		writer.JSON(w, http.StatusMultiStatus, result, pretty(r))
+		return
+	}
+
+	writer.JSONOK(w, result, pretty(r))
+}
+
+// evalBatchItem evaluates a single input of a batch request. Every input is
+// evaluated with its own decision ID, metrics and non-deterministic builtin
+// cache, and is logged as a separate decision.
+func (s *Server) evalBatchItem(
+	ctx context.Context,
+	pq *rego.PreparedEvalQuery,
+	txn storage.Transaction,
+	logger decisionLogger,
+	urlPath string,
+	goInput *any,
+	includeMetrics bool,
+	includeInstrumentation bool,
+) types.BatchDataResponseItemV1 {
+	decisionID := s.generateDecisionID()
+	ctx = logging.WithDecisionID(ctx, decisionID)
+
+	item := types.BatchDataResponseItemV1{
+		DecisionID: decisionID,
+	}
+
+	m := metrics.New()
+	m.Timer(metrics.ServerHandler).Start()
+
+	var input ast.Value
+	if goInput != nil {
+		var err error
+		m.Timer(metrics.RegoInputParse).Start()
+		input, err = ast.InterfaceToValue(*goInput)
+		m.Timer(metrics.RegoInputParse).Stop()
+		if err != nil {
+			item.Error = types.NewErrorV1(types.CodeInvalidParameter, "error(s) occurred while converting input: %v", err)
+			return item
+		}
+	}
+
+	var ndbCache builtins.NDBCache
+	if s.ndbCacheEnabled {
+		ndbCache = builtins.NDBCache{}
+	}
+
+	rs, err := pq.Eval(ctx,
+		rego.EvalTransaction(txn),
+		rego.EvalParsedInput(input),
+		rego.EvalMetrics(m),
+		rego.EvalInterQueryBuiltinCache(s.interQueryBuiltinCache),
+		rego.EvalInterQueryBuiltinValueCache(s.interQueryBuiltinValueCache),
+		rego.EvalInstrument(includeInstrumentation),
+		rego.EvalNDBuiltinCache(ndbCache),
+	)
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	s.captureDecision(ctx, txn, urlPath, input, ndbCache)
+
+	if includeMetrics || includeInstrumentation {
+		item.Metrics = m.All()
+	}
+
+	if err != nil {
+		_ = logger.Log(ctx, txn, urlPath, "", goInput, input, nil, ndbCache, err, m)
+		item.Error = batchErrorV1(err)
+		return item
+	}
+
+	if len(rs) > 0 {
+		item.Result = &rs[0].Expressions[0].Value
+	}
+
+	if err := logger.Log(ctx, txn, urlPath, "", goInput, input, item.Result, ndbCache, nil, m); err != nil {
+		item.Result = nil
+		item.Error = batchErrorV1(err)
+	}
+
+	return item
+}
+
+// batchErrorV1 converts err into an error response for a single batch item.
+// Status codes are not available per item, so only the error code is set.
+func batchErrorV1(err error) *types.ErrorV1 {
+	switch {
+	case types.IsBadRequest(err):
+		return types.NewErrorV1(types.CodeInvalidParameter, "%v", err)
+	case topdown.IsError(err):
+		return types.NewErrorV1(types.CodeInternal, types.MsgEvaluationError).WithError(err)
+	default:
+		return types.NewErrorV1(types.CodeInternal, "%v", err)
+	}
+}
+
 func escapedPathValue(r *http.Request, key string) string {
 	pathValue := r.PathValue(key)
 	escaped := r.URL.EscapedPath()
@@ -1974,23 +2319,39 @@ func (s *Server) v1PoliciesDelete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("path")
 
 	m := metrics.New()
+
+	if err := s.deletePolicy(r.Context(), id, m); err != nil {
+		writePolicyError(w, err)
+		return
+	}
+
+	resp := types.PolicyDeleteResponseV1{}
+	if includeMetrics(r) {
+		resp.Metrics = m.All()
+	}
+
+	writer.JSONOK(w, resp, pretty(r))
+}
+
+// deletePolicy removes the policy module with the given id if the remaining
+// modules compile. Errors caused by the request are returned as *types.ErrorV1.
+func (s *Server) deletePolicy(ctx context.Context, id string, m metrics.Metrics) error {
 	params := storage.WriteParams
 	params.Context = storage.NewContext().WithMetrics(m)
-	txn, err := s.store.NewTransaction(r.Context(), params)
+	txn, err := s.store.NewTransaction(ctx, params)
 	if err != nil {
-		writer.ErrorAuto(w, err)
-		return
+		return err
 	}
 
-	if err := s.checkPolicyIDScope(r.Context(), txn, id); err != nil {
-		s.abortAuto(r.Context(), txn, w, err)
-		return
+	if err := s.checkPolicyIDScope(ctx, txn, id); err != nil {
+		s.store.Abort(ctx, txn)
+		return err
 	}
 
-	modules, err := s.loadModules(r.Context(), txn)
+	modules, err := s.loadModules(ctx, txn)
 	if err != nil {
-		s.abortAuto(r.Context(), txn, w, err)
-		return
+		s.store.Abort(ctx, txn)
+		return err
 	}
 
 	delete(modules, id)
@@ -2000,30 +2361,27 @@ func (s *Server) v1PoliciesDelete(w http.ResponseWriter, r *http.Request) {
 	m.Timer(metrics.RegoModuleCompile).Start()
 
 	if c.Compile(modules); c.Failed() {
-		s.abort(r.Context(), txn, func() {
-			writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidOperation, types.MsgCompileModuleError).WithASTErrors(c.Errors))
-		})
-		return
+		s.store.Abort(ctx, txn)
+		return types.NewErrorV1(types.CodeInvalidOperation, types.MsgCompileModuleError).WithASTErrors(c.Errors)
 	}
 
 	m.Timer(metrics.RegoModuleCompile).Stop()
 
-	if err := s.store.DeletePolicy(r.Context(), txn, id); err != nil {
-		s.abortAuto(r.Context(), txn, w, err)
-		return
+	if err := s.store.DeletePolicy(ctx, txn, id); err != nil {
+		s.store.Abort(ctx, txn)
+		return err
 	}
 
-	if err := s.store.Commit(r.Context(), txn); err != nil {
-		writer.ErrorAuto(w, err)
-		return
-	}
+	return s.store.Commit(ctx, txn)
+}
 
-	resp := types.PolicyDeleteResponseV1{}
-	if includeMetrics(r) {
-		resp.Metrics = m.All()
+// writePolicyError writes err as returned by upsertPolicy or deletePolicy.
+func writePolicyError(w http.ResponseWriter, err error) {
+	if errV1, ok := err.(*types.ErrorV1); ok {
+		writer.Error(w, http.StatusBadRequest, errV1)
+		return
 	}
-
-	writer.JSONOK(w, resp, pretty(r))
+	writer.ErrorAuto(w, err)
 }
 
 func (s *Server) v1PoliciesGet(w http.ResponseWriter, r *http.Request) {
@@ -2110,32 +2468,44 @@ func (s *Server) v1PoliciesPut(w http.ResponseWriter, r *http.Request) {
 
 	m.Timer("server_read_bytes").Stop()
 
+	if err := s.upsertPolicy(ctx, id, buf, m); err != nil {
+		writePolicyError(w, err)
+		return
+	}
+
+	resp := types.PolicyPutResponseV1{}
+
+	if includeMetrics {
+		resp.Metrics = m.All()
+	}
+
+	writer.JSONOK(w, resp, pretty(r))
+}
+
+// upsertPolicy creates or updates the policy module with the given id if the
+// resulting set of modules compiles. Errors caused by the request are returned
+// as *types.ErrorV1.
+func (s *Server) upsertPolicy(ctx context.Context, id string, buf []byte, m metrics.Metrics) error {
 	params := storage.WriteParams
 	params.Context = storage.NewContext().WithMetrics(m)
 	txn, err := s.store.NewTransaction(ctx, params)
 	if err != nil {
-		writer.ErrorAuto(w, err)
-		return
+		return err
 	}
 
 	if err := s.checkPolicyIDScope(ctx, txn, id); err != nil && !storage.IsNotFound(err) {
-		s.abortAuto(ctx, txn, w, err)
-		return
+		s.store.Abort(ctx, txn)
+		return err
 	}
 
 	if bs, err := s.store.GetPolicy(ctx, txn, id); err != nil {
 		if !storage.IsNotFound(err) {
-			s.abortAuto(ctx, txn, w, err)
-			return
+			s.store.Abort(ctx, txn)
+			return err
 		}
 	} else if bytes.Equal(buf, bs) {
 		s.store.Abort(ctx, txn)
-		resp := types.PolicyPutResponseV1{}
-		if includeMetrics {
-			resp.Metrics = m.All()
-		}
-		writer.JSONOK(w, resp, pretty(r))
-		return
+		return nil
 	}
 
 	m.Timer(metrics.RegoModuleParse).Start()
@@ -2146,28 +2516,26 @@ func (s *Server) v1PoliciesPut(w http.ResponseWriter, r *http.Request) {
 		s.store.Abort(ctx, txn)
 		switch err := err.(type) {
 		case ast.Errors:
-			writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(err))
+			return types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(err)
 		default:
-			writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+			return types.NewErrorV1(types.CodeInvalidParameter, "%v", err)
 		}
-		return
 	}
 
 	if parsedMod == nil {
 		s.store.Abort(ctx, txn)
-		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "empty module"))
-		return
+		return types.NewErrorV1(types.CodeInvalidParameter, "empty module")
 	}
 
 	if err := s.checkPolicyPackageScope(ctx, txn, parsedMod.Package); err != nil {
-		s.abortAuto(ctx, txn, w, err)
-		return
+		s.store.Abort(ctx, txn)
+		return err
 	}
 
 	modules, err := s.loadModules(ctx, txn)
 	if err != nil {
-		s.abortAuto(ctx, txn, w, err)
-		return
+		s.store.Abort(ctx, txn)
+		return err
 	}
 
 	modules[id] = parsedMod
@@ -2180,31 +2548,18 @@ func (s *Server) v1PoliciesPut(w http.ResponseWriter, r *http.Request) {
 	m.Timer(metrics.RegoModuleCompile).Start()
 
 	if c.Compile(modules); c.Failed() {
-		s.abort(ctx, txn, func() {
-			writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(c.Errors))
-		})
-		return
+		s.store.Abort(ctx, txn)
+		return types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(c.Errors)
 	}
 
 	m.Timer(metrics.RegoModuleCompile).Stop()
 
 	if err := s.store.UpsertPolicy(ctx, txn, id, buf); err != nil {
-		s.abortAuto(ctx, txn, w, err)
-		return
-	}
-
-	if err := s.store.Commit(ctx, txn); err != nil {
-		writer.ErrorAuto(w, err)
-		return
-	}
-
-	resp := types.PolicyPutResponseV1{}
-
-	if includeMetrics {
-		resp.Metrics = m.All()
+		s.store.Abort(ctx, txn)
+		return err
 	}
 
-	writer.JSONOK(w, resp, pretty(r))
+	return s.store.Commit(ctx, txn)
 }
 
 func (s *Server) v1QueryGet(w http.ResponseWriter, r *http.Request) {
@@ -2362,6 +2717,36 @@ func (s *Server) v1StatusGet(w http.ResponseWriter, r *http.Request) {
 	writer.JSONOK(w, types.StatusResponseV1{Result: &st}, pretty(r))
 }
 
+func (s *Server) v1BundlePromotePost(w http.ResponseWriter, r *http.Request) {
+	s.settleBundleShadow(w, r, (*bundlePlugin.Plugin).Promote)
+}
+
+func (s *Server) v1BundleRejectPost(w http.ResponseWriter, r *http.Request) {
+	s.settleBundleShadow(w, r, (*bundlePlugin.Plugin).Reject)
+}
+
+func (s *Server) settleBundleShadow(w http.ResponseWriter, r *http.Request, settle func(*bundlePlugin.Plugin, context.Context, string) (*bundlePlugin.ShadowStatus, error)) {
+	p := bundlePlugin.Lookup(s.manager)
+	if p == nil {
+		writer.ErrorString(w, http.StatusInternalServerError, types.CodeInternal, errors.New("bundle plugin not enabled"))
+		return
+	}
+
+	name := r.PathValue("name")
+
+	st, err := settle(p, r.Context(), name)
+	if errors.Is(err, bundlePlugin.ErrNoShadow) {
+		writer.Error(w, http.StatusNotFound, types.NewErrorV1(types.CodeResourceNotFound, "bundle %q has no revision in shadow evaluation", name))
+		return
+	} else if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	var result any = st
+	writer.JSONOK(w, types.BundleShadowResponseV1{Result: &result}, pretty(r))
+}
+
 func (s *Server) checkPolicyIDScope(ctx context.Context, txn storage.Transaction, id string) error {
 
 	bs, err := s.store.GetPolicy(ctx, txn, id)
@@ -2897,6 +3282,50 @@ func readInputPostV1(r *http.Request) (ast.Value, *any, error) {
 	return v, request.Input, err
 }
 
+func readInputBatchPostV1(r *http.Request) (map[string]*any, error) {
+
+	parsed, ok := authorizer.GetBodyOnContext(r.Context())
+	if ok {
+		obj, ok := parsed.(map[string]any)
+		if !ok {
+			return nil, errors.New("body must be an object")
+		}
+		inputs, ok := obj["inputs"].(map[string]any)
+		if !ok {
+			return nil, errors.New("body must contain an 'inputs' object")
+		}
+		result := make(map[string]*any, len(inputs))
+		for id, input := range inputs {
+			result[id] = &input
+		}
+		return result, nil
+	}
+
+	var request types.BatchDataRequestV1
+
+	bodyBytes, err := util.ReadMaybeCompressedBody(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress the body: %w", err)
+	}
+
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		if err := util.Unmarshal(bodyBytes, &request); err != nil {
+			return nil, fmt.Errorf("body contains malformed inputs: %w", err)
+		}
+	} else {
+		dec := util.NewJSONDecoder(bytes.NewBuffer(bodyBytes))
+		if err := dec.Decode(&request); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("body contains malformed inputs: %w", err)
+		}
+	}
+
+	if request.Inputs == nil {
+		return nil, errors.New("body must contain an 'inputs' object")
+	}
+
+	return request.Inputs, nil
+}
+
 type compileRequest struct {
 	Query    ast.Body
 	Input    ast.Value
@@ -2909,6 +3338,53 @@ type compileRequestOptions struct {
 	NondeterminsiticBuiltins bool
 }
 
+// filterRenderer renders a data filter in one of the formats supported by the
+// Compile API.
+type filterRenderer func(*filter.Filter) (any, error)
+
+func sqlRenderer(d filter.Dialect) filterRenderer {
+	return func(f *filter.Filter) (any, error) {
+		return f.SQL(d)
+	}
+}
+
+var filterRenderers = map[string]filterRenderer{
+	types.MediaTypeSQLPostgresV1: sqlRenderer(filter.Postgres),
+	types.MediaTypeSQLMySQLV1:    sqlRenderer(filter.MySQL),
+	types.MediaTypeSQLSQLiteV1:   sqlRenderer(filter.SQLite),
+	types.MediaTypeUCASTV1: func(f *filter.Filter) (any, error) {
+		return types.DataFilterResultV1{Query: f.UCAST()}, nil
+	},
+	types.MediaTypeMongoDBV1: func(f *filter.Filter) (any, error) {
+		return f.MongoDB()
+	},
+}
+
+// getFilterRenderer returns the renderer for the data filter format requested
+// by the client through the Accept header, if any.
+func getFilterRenderer(r *http.Request) filterRenderer {
+	for _, accept := range r.Header.Values("Accept") {
+		for mediaType := range strings.SplitSeq(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if render, ok := filterRenderers[strings.TrimSpace(mediaType)]; ok {
+				return render
+			}
+		}
+	}
+	return nil
+}
+
+func filterErrorV1(err error) *types.ErrorV1 {
+	errV1 := types.NewErrorV1(types.CodeInvalidParameter, types.MsgDataFilterTranslationError)
+	if errs, ok := err.(filter.Errors); ok {
+		for _, e := range errs {
+			errV1 = errV1.WithError(e)
+		}
+		return errV1
+	}
+	return errV1.WithError(err)
+}
+
 func readInputCompilePostV1(reqBytes []byte, queryParserOptions ast.ParserOptions) (*compileRequest, *types.ErrorV1) {
 	var request types.CompileRequestV1
 
@@ -34,6 +34,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -1255,6 +1256,98 @@ func TestCompileV1UnsafeBuiltin(t *testing.T) {
 	}
 }
 
+func TestCompileV1DataFilters(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+
+	err := f.v1(http.MethodPut, "/policies/test", `package test
+
+allow if {
+	input.fruits.name == input.name
+	input.fruits.price < 10
+}
+
+deny if upper(input.fruits.name) == "APPLE"`, 200, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		note   string
+		accept string
+		query  string
+		code   int
+		resp   string
+	}{
+		{
+			note:   "postgres",
+			accept: types.MediaTypeSQLPostgresV1,
+			query:  "data.test.allow == true",
+			code:   200,
+			resp:   `{"result": {"query": "WHERE fruits.name = $1 AND fruits.price < $2", "args": ["banana", 10]}}`,
+		},
+		{
+			note:   "mysql with parameters",
+			accept: types.MediaTypeSQLMySQLV1 + "; charset=utf-8",
+			query:  "data.test.allow == true",
+			code:   200,
+			resp:   `{"result": {"query": "WHERE fruits.name = ? AND fruits.price < ?", "args": ["banana", 10]}}`,
+		},
+		{
+			note:   "ucast",
+			accept: types.MediaTypeUCASTV1,
+			query:  "data.test.allow == true",
+			code:   200,
+			resp: `{"result": {"query": {"type": "compound", "operator": "and", "value": [
+				{"type": "field", "operator": "eq", "field": "fruits.name", "value": "banana"},
+				{"type": "field", "operator": "lt", "field": "fruits.price", "value": 10}
+			]}}}`,
+		},
+		{
+			note:   "mongodb",
+			accept: types.MediaTypeMongoDBV1,
+			query:  "data.test.allow == true",
+			code:   200,
+			resp: `{"result": {"collection": "fruits", "filter": {"$and": [
+				{"name": {"$eq": "banana"}},
+				{"price": {"$lt": 10}}
+			]}}}`,
+		},
+		{
+			note:   "untranslatable",
+			accept: types.MediaTypeSQLSQLiteV1,
+			query:  "data.test.deny == true",
+			code:   400,
+			resp: `{
+				"code": "invalid_parameter",
+				"message": "error(s) occurred while translating partial evaluation results",
+				"errors": [
+					{
+						"code": "data_filter_translation_error",
+						"message": "call to upper cannot be translated",
+						"location": {"file": "test", "row": 8, "col": 9}
+					}
+				]
+			}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			req := newReqV1(http.MethodPost, "/compile?instrument=false", fmt.Sprintf(`{
+				"query": %q,
+				"input": {"name": "banana"},
+				"unknowns": ["input.fruits"]
+			}`, tc.query))
+			req.Header.Set("Accept", tc.accept)
+			if err := f.executeRequest(req, tc.code, tc.resp); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
 func TestDataV1Redirection(t *testing.T) {
 	t.Parallel()
 
@@ -4331,6 +4424,120 @@ func TestDecisionLoggingWithHTTPRequestContext(t *testing.T) {
 	}
 }
 
+func TestBatchDataV1(t *testing.T) {
+	t.Parallel()
+
+	f := newFixture(t)
+
+	var mu sync.Mutex
+	var nextID int
+	decisions := map[string]*Info{}
+
+	f.server = f.server.WithDecisionIDFactory(func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		nextID++
+		return strconv.Itoa(nextID)
+	}).WithDecisionLoggerWithErr(func(_ context.Context, info *Info) error {
+		mu.Lock()
+		defer mu.Unlock()
+		decisions[info.DecisionID] = info
+		return nil
+	})
+
+	if err := f.v1(http.MethodPut, "/policies/test", `package test
+
+default allow := false
+
+allow if input.user == "alice"
+
+ratio := 10 / input.x`, 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req := newReqV1(http.MethodPost, "/batch/data/test/allow", `{"inputs": {
+		"a": {"user": "alice"},
+		"b": {"user": "bob"}
+	}}`)
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if f.recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200 but got %v: %v", f.recorder.Code, f.recorder.Body)
+	}
+
+	var resp types.BatchDataResponseV1
+	if err := util.NewJSONDecoder(f.recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Responses) != 2 {
+		t.Fatalf("expected 2 responses but got %v", resp.Responses)
+	}
+
+	for id, exp := range map[string]bool{"a": true, "b": false} {
+		item := resp.Responses[id]
+		if item.Result == nil || *item.Result != exp {
+			t.Errorf("expected result %v for %v but got %v", exp, id, item.Result)
+		}
+		info, ok := decisions[item.DecisionID]
+		if !ok {
+			t.Fatalf("expected decision %v for %v to be logged", item.DecisionID, id)
+		}
+		if info.Path != "test/allow" || info.Input == nil || info.Results == nil || *info.Results != exp {
+			t.Errorf("unexpected decision log for %v: %+v", id, info)
+		}
+	}
+
+	if resp.Responses["a"].DecisionID == resp.Responses["b"].DecisionID {
+		t.Fatal("expected unique decision IDs")
+	}
+
+	// Errors are reported per input.
+	req = newReqV1(http.MethodPost, "/batch/data/test/ratio?strict-builtin-errors", `{"inputs": {
+		"ok": {"x": 2},
+		"fail": {"x": 0}
+	}}`)
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if f.recorder.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207 but got %v: %v", f.recorder.Code, f.recorder.Body)
+	}
+
+	var partial struct {
+		Responses map[string]struct {
+			DecisionID string `json:"decision_id"`
+			Result     any    `json:"result"`
+			Error      *struct {
+				Code string `json:"code"`
+			} `json:"error"`
+		} `json:"responses"`
+	}
+	if err := util.NewJSONDecoder(f.recorder.Body).Decode(&partial); err != nil {
+		t.Fatal(err)
+	}
+
+	if item := partial.Responses["ok"]; item.Error != nil || item.Result != json.Number("5") {
+		t.Errorf("unexpected response for ok: %+v", item)
+	}
+
+	if item := partial.Responses["fail"]; item.Error == nil || item.Error.Code != types.CodeInternal || item.DecisionID == "" {
+		t.Errorf("expected error for fail but got %+v", item)
+	}
+
+	if len(decisions) != 4 {
+		t.Fatalf("expected 4 decisions but got %d", len(decisions))
+	}
+
+	if err := f.v1(http.MethodPost, "/batch/data/test/allow", `{"input": {}}`, 400, `{
+		"code": "invalid_parameter",
+		"message": "body must contain an 'inputs' object"
+	}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDecisionLogging(t *testing.T) {
 	t.Parallel()
 
@@ -83,6 +83,7 @@ const (
 	MsgPluginConfigError          = "error(s) occurred while configuring plugin(s)"
 	MsgDecodingLimitError         = "request body too large"
 	MsgDecodingGzipLimitError     = "compressed request body too large"
+	MsgDataFilterTranslationError = "error(s) occurred while translating partial evaluation results"
 )
 
 // PatchV1 models a single patch operation against a document.
@@ -154,6 +155,29 @@ type DataResponseV1 struct {
 	Warning     *Warning      `json:"warning,omitempty"`
 }
 
+// BatchDataRequestV1 models the request message for batched Data API POST
+// operations. Inputs are keyed by caller-defined identifiers.
+type BatchDataRequestV1 struct {
+	Inputs map[string]*any `json:"inputs"`
+}
+
+// BatchDataResponseV1 models the response message for batched Data API POST
+// operations. Responses are keyed by the identifiers of the request inputs.
+type BatchDataResponseV1 struct {
+	Responses  map[string]BatchDataResponseItemV1 `json:"responses"`
+	Provenance *ProvenanceV1                      `json:"provenance,omitempty"`
+	Metrics    MetricsV1                          `json:"metrics,omitempty"`
+}
+
+// BatchDataResponseItemV1 models the result of evaluating a single input of a
+// batched Data API POST operation.
+type BatchDataResponseItemV1 struct {
+	DecisionID string    `json:"decision_id,omitempty"`
+	Metrics    MetricsV1 `json:"metrics,omitempty"`
+	Result     *any      `json:"result,omitempty"`
+	Error      *ErrorV1  `json:"error,omitempty"`
+}
+
 // Warning models DataResponse warnings
 type Warning struct {
 	Code    string `json:"code,omitempty"`
@@ -386,6 +410,22 @@ type CompileResponseV1 struct {
 	Metrics     MetricsV1 `json:"metrics,omitempty"`
 }
 
+// Media types that can be requested through the Accept header of Compile API
+// requests to translate partial evaluation results into data filters.
+const (
+	MediaTypeSQLPostgresV1 = "application/vnd.opa.sql.postgresql+json"
+	MediaTypeSQLMySQLV1    = "application/vnd.opa.sql.mysql+json"
+	MediaTypeSQLSQLiteV1   = "application/vnd.opa.sql.sqlite+json"
+	MediaTypeUCASTV1       = "application/vnd.opa.ucast+json"
+	MediaTypeMongoDBV1     = "application/vnd.opa.mongodb+json"
+)
+
+// DataFilterResultV1 models the result of Compile API operations that
+// translate partial evaluation results into structured data filters.
+type DataFilterResultV1 struct {
+	Query any `json:"query"`
+}
+
 // PartialEvaluationResultV1 represents the output of partial evaluation and is
 // included in Compile API responses.
 type PartialEvaluationResultV1 struct {
@@ -409,11 +449,55 @@ type StatusResponseV1 struct {
 	Result *any `json:"result,omitempty"`
 }
 
+// BundleShadowResponseV1 models the response message for the bundle shadow
+// promotion and rejection API operations.
+type BundleShadowResponseV1 struct {
+	Result *any `json:"result,omitempty"`
+}
+
 // HealthResponseV1 models the response message for Health API operations.
 type HealthResponseV1 struct {
 	Error string `json:"error,omitempty"`
 }
 
+// ChangeV1 models a single committed transaction streamed by the Changes API.
+// Seq is assigned by the server and increases by one with every committed
+// write transaction.
+type ChangeV1 struct {
+	Seq      uint64           `json:"seq"`
+	TxnID    uint64           `json:"txn_id"`
+	Data     []DataChangeV1   `json:"data,omitempty"`
+	Policies []PolicyChangeV1 `json:"policies,omitempty"`
+	Context  map[string]any   `json:"context,omitempty"`
+}
+
+// DataChangeV1 models a change to a base data document.
+type DataChangeV1 struct {
+	Path  string          `json:"path"`
+	Op    string          `json:"op"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// PolicyChangeV1 models a change to a policy module.
+type PolicyChangeV1 struct {
+	ID  string `json:"id"`
+	Op  string `json:"op"`
+	Raw string `json:"raw,omitempty"`
+}
+
+// Operations reported by the Changes API.
+const (
+	ChangeOpUpsertV1 = "upsert"
+	ChangeOpRemoveV1 = "remove"
+)
+
+// Media types that can be requested through the Accept header of Changes API
+// requests.
+const (
+	MediaTypeNDJSONV1      = "application/x-ndjson"
+	MediaTypeEventStreamV1 = "text/event-stream"
+)
+
 const (
 	// ParamQueryV1 defines the name of the HTTP URL parameter that specifies
 	// values for the request query.
@@ -469,6 +553,20 @@ const (
 	// ParamStrictBuiltinErrors names the HTTP URL parameter that indicates the client
 	// wants built-in function errors to be treated as fatal.
 	ParamStrictBuiltinErrors = "strict-builtin-errors"
+
+	// ParamSinceV1 defines the name of the HTTP URL parameter that specifies
+	// the sequence number of the last change seen by the client of the
+	// Changes API.
+	ParamSinceV1 = "since"
+
+	// ParamPathV1 defines the name of the HTTP URL parameter that specifies
+	// the path prefixes the client of the Changes API is interested in.
+	ParamPathV1 = "path"
+
+	// ParamPoliciesV1 defines the name of the HTTP URL parameter that
+	// indicates whether the client of the Changes API wants to receive policy
+	// changes.
+	ParamPoliciesV1 = "policies"
 )
 
 // BadRequestErr represents an error condition raised if the caller passes
@@ -90,6 +90,19 @@ func (ctx *Context) Put(key, value any) {
 	ctx.values[key] = value
 }
 
+// Range calls f sequentially for each key/value pair in the context. If f
+// returns false, Range stops the iteration.
+func (ctx *Context) Range(f func(key, value any) bool) {
+	if ctx == nil {
+		return
+	}
+	for k, v := range ctx.values {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
 var metricsKey = struct{}{}
 
 // WithMetrics allows passing metrics via the Context.
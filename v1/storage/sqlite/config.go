@@ -0,0 +1,56 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/IUAD1IY7/opa/v1/config"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+type cfg struct {
+	Path       string `json:"path"`
+	AutoCreate bool   `json:"auto_create"`
+}
+
+var ErrMissingPath = errors.New("missing database path")
+
+// OptionsFromConfig parses the passed config, extracts the SQLite storage
+// settings, validates it, and returns a *Options struct pointer on success.
+func OptionsFromConfig(raw []byte, id string) (*Options, error) {
+	parsedConfig, err := config.ParseConfig(raw, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedConfig.Storage == nil || len(parsedConfig.Storage.SQLite) == 0 {
+		return nil, nil
+	}
+
+	var c cfg
+	if err := util.Unmarshal(parsedConfig.Storage.SQLite, &c); err != nil {
+		return nil, err
+	}
+
+	if c.Path == "" {
+		return nil, ErrMissingPath
+	}
+
+	dir := filepath.Dir(c.Path)
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) && c.AutoCreate {
+			err = os.MkdirAll(dir, 0700) // overwrite err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("directory %v invalid: %w", dir, err)
+		}
+	}
+
+	return &Options{Path: c.Path}, nil
+}
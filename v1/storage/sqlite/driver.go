@@ -0,0 +1,14 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+// driverName is the name of the database/sql driver used to open the
+// database. The driver is implemented in pure Go, so the store can be built
+// with CGO_ENABLED=0 like the rest of OPA.
+const driverName = "sqlite"
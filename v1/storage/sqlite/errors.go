@@ -0,0 +1,38 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/IUAD1IY7/opa/v1/storage"
+)
+
+const rootMustBeObjectMsg = "root must be object"
+const rootCannotBeRemovedMsg = "root cannot be removed"
+
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*storage.Error); ok {
+		return err
+	}
+	return &storage.Error{Code: storage.InternalErr, Message: err.Error()}
+}
+
+func invalidPatchError(f string, a ...any) *storage.Error {
+	return &storage.Error{
+		Code:    storage.InvalidPatchErr,
+		Message: fmt.Sprintf(f, a...),
+	}
+}
+
+func corruptKeyError(key string) *storage.Error {
+	return &storage.Error{
+		Code:    storage.InternalErr,
+		Message: fmt.Sprintf("corrupt key: %s", key),
+	}
+}
@@ -0,0 +1,452 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package sqlite provides a SQLite-based implementation of the storage.Store
+// interface.
+//
+// The sqlite.Store implementation persists policies and data in a single
+// SQLite database file. Data is exploded into one row per leaf document: every
+// value that is not an object is stored in the "data" table under the path
+// that refers to it (e.g., /users/alice/roles), and objects are represented
+// implicitly by the rows under their path. Empty objects are stored as "{}" so
+// they can be told apart from missing documents. Paths are stored in escaped
+// form (see storage.Path.String) and values are stored as JSON, so the
+// database can be inspected and queried with standard SQLite tooling:
+//
+//	SELECT path, json_extract(value, '$') FROM data WHERE path LIKE '/users/%';
+//
+// Policy modules are stored in the "policies" table with one row per module.
+//
+// Reads of a document rebuild the document from the rows under its path, so
+// reads of large subtrees (e.g., a read against the entirety of /data) are
+// more expensive than reads of individual leaf documents. Arrays are stored
+// as a single row, so writes to an array element rewrite the entire array.
+//
+// Only one write transaction may be open at a time. Read transactions see a
+// consistent snapshot of the database and are blocked while a write
+// transaction commits, like the other storage.Store implementations.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+const (
+	// supportedSchemaVersion represents the version of the database schema
+	// supported by this OPA.
+	supportedSchemaVersion = "1"
+
+	schemaVersionKey = "schema_version"
+)
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS metadata (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS data (path TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+	`CREATE TABLE IF NOT EXISTS policies (id TEXT PRIMARY KEY, module TEXT NOT NULL)`,
+}
+
+// Options contains parameters that configure the SQLite-based store.
+type Options struct {
+	Path string // specifies the database file to store data in
+}
+
+// Store provides a SQLite-based implementation of the storage.Store interface.
+type Store struct {
+	db       *sql.DB              // underlying database
+	xid      uint64               // next transaction id
+	rmu      sync.RWMutex         // reader-writer lock
+	wmu      sync.Mutex           // writer lock
+	triggers map[*handle]struct{} // registered triggers
+}
+
+// New returns a new SQLite-based store based on the provided options. The
+// database file is created if it does not exist.
+func New(ctx context.Context, opts Options) (*Store, error) {
+	if opts.Path == "" {
+		return nil, &storage.Error{
+			Code:    storage.InternalErr,
+			Message: "database path must be set",
+		}
+	}
+
+	db, err := sql.Open(driverName, opts.Path)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	store := &Store{
+		db:       db,
+		triggers: map[*handle]struct{}{},
+	}
+
+	if err := store.init(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close closes the underlying database.
+func (db *Store) Close(context.Context) error {
+	return wrapError(db.db.Close())
+}
+
+func (db *Store) init(ctx context.Context) error {
+
+	// WAL mode allows readers to proceed while a write transaction is open.
+	if _, err := db.db.ExecContext(ctx, `PRAGMA journal_mode=WAL`); err != nil {
+		return wrapError(err)
+	}
+
+	for _, stmt := range schema {
+		if _, err := db.db.ExecContext(ctx, stmt); err != nil {
+			return wrapError(err)
+		}
+	}
+
+	var version string
+	err := db.db.QueryRowContext(ctx, `SELECT value FROM metadata WHERE key = ?`, schemaVersionKey).Scan(&version)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err = db.db.ExecContext(ctx, `INSERT INTO metadata (key, value) VALUES (?, ?)`, schemaVersionKey, supportedSchemaVersion)
+		return wrapError(err)
+	case err != nil:
+		return wrapError(err)
+	case version != supportedSchemaVersion:
+		return &storage.Error{
+			Code:    storage.InternalErr,
+			Message: fmt.Sprintf("unsupported schema version: %v (want %v)", version, supportedSchemaVersion),
+		}
+	}
+
+	return nil
+}
+
+// NewTransaction implements the storage.Store interface.
+func (db *Store) NewTransaction(ctx context.Context, params ...storage.TransactionParams) (storage.Transaction, error) {
+	var write bool
+	var context *storage.Context
+
+	if len(params) > 0 {
+		write = params[0].Write
+		context = params[0].Context
+	}
+
+	xid := atomic.AddUint64(&db.xid, uint64(1))
+	if write {
+		db.wmu.Lock() // only one concurrent write txn
+	} else {
+		db.rmu.RLock()
+	}
+
+	underlying, err := db.begin(ctx)
+	if err != nil {
+		if write {
+			db.wmu.Unlock()
+		} else {
+			db.rmu.RUnlock()
+		}
+		return nil, err
+	}
+
+	return newTransaction(xid, write, underlying, context, db), nil
+}
+
+// begin starts a transaction on the underlying database. The transaction
+// outlives the call that created it, so it must not be rolled back when ctx
+// is canceled.
+func (db *Store) begin(ctx context.Context) (*sql.Tx, error) {
+	tx, err := db.db.BeginTx(context.WithoutCancel(ctx), nil)
+	return tx, wrapError(err)
+}
+
+// Truncate implements the storage.Store interface. This method must be called within a transaction.
+func (db *Store) Truncate(ctx context.Context, txn storage.Transaction, params storage.TransactionParams, it storage.Iterator) error {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return err
+	}
+
+	mergedData := map[string]any{}
+
+	for {
+		var update *storage.Update
+
+		update, err = it.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return wrapError(err)
+		}
+
+		if update.IsPolicy {
+			if err := underlying.UpsertPolicy(ctx, strings.TrimLeft(update.Path.String(), "/"), update.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var value any
+		if err := util.Unmarshal(update.Value, &value); err != nil {
+			return wrapError(err)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		obj, err := mktree(update.Path, value)
+		if err != nil {
+			return err
+		}
+
+		merged, ok := inmem.InterfaceMaps(mergedData, obj)
+		if !ok {
+			return fmt.Errorf("failed to insert data file from path %s", update.Path)
+		}
+		mergedData = merged
+	}
+
+	// For backwards compatibility, check if `RootOverwrite` was configured.
+	if params.RootOverwrite {
+		return underlying.Write(ctx, storage.AddOp, storage.Path{}, mergedData)
+	}
+
+	for _, root := range params.BasePaths {
+		newPath, ok := storage.ParsePathEscaped("/" + root)
+		if !ok {
+			return fmt.Errorf("storage path invalid: %v", newPath)
+		}
+
+		if value, ok := lookup(newPath, mergedData); ok {
+			if len(newPath) > 0 {
+				if err := storage.MakeDir(ctx, db, txn, newPath[:len(newPath)-1]); err != nil {
+					return err
+				}
+			}
+			if err := underlying.Write(ctx, storage.AddOp, newPath, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Commit implements the storage.Store interface.
+func (db *Store) Commit(ctx context.Context, txn storage.Transaction) error {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return err
+	}
+	if underlying.write {
+		db.rmu.Lock() // blocks until all readers are done
+		defer db.wmu.Unlock()
+		defer db.rmu.Unlock()
+
+		event, err := underlying.Commit(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(db.triggers) == 0 {
+			return nil
+		}
+
+		readOnly, err := db.begin(ctx)
+		if err != nil {
+			return err
+		}
+		xid := atomic.AddUint64(&db.xid, uint64(1))
+		readTxn := newTransaction(xid, false, readOnly, nil, db)
+		for h := range db.triggers {
+			h.cb(ctx, readTxn, event)
+		}
+		readTxn.Abort(ctx)
+	} else { // committing read txn
+		underlying.Abort(ctx)
+		db.rmu.RUnlock()
+	}
+	return nil
+}
+
+// Abort implements the storage.Store interface.
+func (db *Store) Abort(ctx context.Context, txn storage.Transaction) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		panic(err)
+	}
+	underlying.Abort(ctx)
+	if underlying.write {
+		db.wmu.Unlock()
+	} else {
+		db.rmu.RUnlock()
+	}
+}
+
+// ListPolicies implements the storage.Policy interface.
+func (db *Store) ListPolicies(ctx context.Context, txn storage.Transaction) ([]string, error) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return underlying.ListPolicies(ctx)
+}
+
+// GetPolicy implements the storage.Policy interface.
+func (db *Store) GetPolicy(ctx context.Context, txn storage.Transaction, id string) ([]byte, error) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return underlying.GetPolicy(ctx, id)
+}
+
+// UpsertPolicy implements the storage.Policy interface.
+func (db *Store) UpsertPolicy(ctx context.Context, txn storage.Transaction, id string, bs []byte) error {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return err
+	}
+	return underlying.UpsertPolicy(ctx, id, bs)
+}
+
+// DeletePolicy implements the storage.Policy interface.
+func (db *Store) DeletePolicy(ctx context.Context, txn storage.Transaction, id string) error {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return err
+	}
+	if _, err := underlying.GetPolicy(ctx, id); err != nil {
+		return err
+	}
+	return underlying.DeletePolicy(ctx, id)
+}
+
+// Register implements the storage.Trigger interface.
+func (db *Store) Register(_ context.Context, txn storage.Transaction, config storage.TriggerConfig) (storage.TriggerHandle, error) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	if !underlying.write {
+		return nil, &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "triggers must be registered with a write transaction",
+		}
+	}
+	h := &handle{db: db, cb: config.OnCommit}
+	db.triggers[h] = struct{}{}
+	return h, nil
+}
+
+// Read implements the storage.Store interface.
+func (db *Store) Read(ctx context.Context, txn storage.Transaction, path storage.Path) (any, error) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return underlying.Read(ctx, path)
+}
+
+// Write implements the storage.Store interface.
+func (db *Store) Write(ctx context.Context, txn storage.Transaction, op storage.PatchOp, path storage.Path, value any) error {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return err
+	}
+	val := util.Reference(value)
+	if err := util.RoundTrip(val); err != nil {
+		return wrapError(err)
+	}
+	return underlying.Write(ctx, op, path, *val)
+}
+
+func (db *Store) underlying(txn storage.Transaction) (*transaction, error) {
+	underlying, ok := txn.(*transaction)
+	if !ok {
+		return nil, &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: fmt.Sprintf("unexpected transaction type %T", txn),
+		}
+	}
+	if underlying.db != db {
+		return nil, &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "unknown transaction",
+		}
+	}
+	if underlying.stale {
+		return nil, &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "stale transaction",
+		}
+	}
+	return underlying, nil
+}
+
+type handle struct {
+	db *Store
+	cb func(context.Context, storage.Transaction, storage.TriggerEvent)
+}
+
+func (h *handle) Unregister(_ context.Context, txn storage.Transaction) {
+	underlying, err := h.db.underlying(txn)
+	if err != nil {
+		panic(err)
+	}
+	if !underlying.write {
+		panic(&storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "triggers must be unregistered with a write transaction",
+		})
+	}
+	delete(h.db.triggers, h)
+}
+
+func mktree(path storage.Path, value any) (map[string]any, error) {
+	if len(path) == 0 {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil, invalidPatchError(rootMustBeObjectMsg)
+		}
+		return obj, nil
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		value = map[string]any{path[i]: value}
+	}
+	return map[string]any{path[0]: value}, nil
+}
+
+func lookup(path storage.Path, data map[string]any) (any, bool) {
+	if len(path) == 0 {
+		return data, true
+	}
+	for i := range len(path) - 1 {
+		value, ok := data[path[i]]
+		if !ok {
+			return nil, false
+		}
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		data = obj
+	}
+	value, ok := data[path[len(path)-1]]
+	return value, ok
+}
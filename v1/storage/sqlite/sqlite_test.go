@@ -0,0 +1,542 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/IUAD1IY7/opa/internal/file/archive"
+	"github.com/IUAD1IY7/opa/v1/bundle"
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/inmem"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+const fixture = `{
+	"a": [1, 2, 3, 4],
+	"b": {"v1": "hello", "v2": "goodbye"},
+	"c": [{"x": [true, false, "foo"], "y": [null, 3.14]}],
+	"d": {"e": {"f": {}}},
+	"g": {"a/b": {"c%d": 1}, "": 2}
+}`
+
+func newStore(t *testing.T, path string) *Store {
+	t.Helper()
+
+	ctx := context.Background()
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "opa.db")
+	}
+
+	store, err := New(ctx, Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := store.Close(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return store
+}
+
+func TestReadWrite(t *testing.T) {
+	tests := []struct {
+		note  string
+		op    storage.PatchOp
+		path  string
+		value string
+		read  string
+	}{
+		{"add root", storage.AddOp, "/", `{"a": [1]}`, "/"},
+		{"add", storage.AddOp, "/newroot", `{"a": [[1]]}`, "/newroot"},
+		{"add empty object", storage.AddOp, "/newroot", `{}`, "/"},
+		{"add under empty object", storage.AddOp, "/d/e/f/x", `1`, "/d"},
+		{"add obj", storage.AddOp, "/b/vNew", `"x"`, "/b"},
+		{"add obj (existing)", storage.AddOp, "/b/v2", `{"x": {"y": 1}}`, "/b"},
+		{"add arr", storage.AddOp, "/a/1", `"x"`, "/a"},
+		{"add obj/arr/obj", storage.AddOp, "/c/0/z", `"x"`, "/c"},
+		{"append arr", storage.AddOp, "/a/-", `"x"`, "/a"},
+		{"append obj/arr", storage.AddOp, "/c/0/x/3", `"x"`, "/c/0/x"},
+		{"add escaped", storage.AddOp, "/g/a%2Fb/c%25d", `2`, "/g"},
+		{"remove", storage.RemoveOp, "/a", ``, "/"},
+		{"remove obj", storage.RemoveOp, "/b/v2", ``, "/b"},
+		{"remove last", storage.RemoveOp, "/d/e/f", ``, "/d"},
+		{"remove arr", storage.RemoveOp, "/c/0/x/1", ``, "/c"},
+		{"replace root", storage.ReplaceOp, "/", `{"a": [1]}`, "/"},
+		{"replace", storage.ReplaceOp, "/a", `1`, "/"},
+		{"replace obj", storage.ReplaceOp, "/b", `{"v3": [1]}`, "/b"},
+		{"replace arr", storage.ReplaceOp, "/a/1", `999`, "/a"},
+		{"read leaf", storage.AddOp, "/b/v1", `"x"`, "/b/v1"},
+		{"read inside leaf", storage.AddOp, "/b/v1", `"x"`, "/c/0/y/1"},
+		{"err: bad root type", storage.AddOp, "/", `[1, 2, 3]`, ""},
+		{"err: remove root", storage.RemoveOp, "/", ``, ""},
+		{"err: add arr (non-integer)", storage.AddOp, "/a/foo", `1`, ""},
+		{"err: add arr (out of range)", storage.AddOp, "/a/5", `1`, ""},
+		{"err: add missing parent", storage.AddOp, "/dead/beef", `1`, ""},
+		{"err: add under empty object", storage.AddOp, "/d/e/f/x/y", `1`, ""},
+		{"err: add non-coll", storage.AddOp, "/a/1/2", `1`, ""},
+		{"err: append arr (non-array)", storage.AddOp, "/b/v1/-", `1`, ""},
+		{"err: remove missing", storage.RemoveOp, "/dead/beef", ``, ""},
+		{"err: remove obj (missing)", storage.RemoveOp, "/b/deadbeef", ``, ""},
+		{"err: remove arr (append)", storage.RemoveOp, "/a/-", ``, ""},
+		{"err: replace missing", storage.ReplaceOp, "/deadbeef", `1`, ""},
+		{"err: replace under empty object", storage.ReplaceOp, "/d/e/f/x", `1`, ""},
+		{"err: read missing", storage.AddOp, "/b/v1", `"x"`, "/b/deadbeef"},
+		{"err: read inside scalar", storage.AddOp, "/b/v1", `"x"`, "/b/v1/x"},
+	}
+
+	ctx := context.Background()
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var data map[string]any
+			if err := util.UnmarshalJSON([]byte(fixture), &data); err != nil {
+				t.Fatal(err)
+			}
+
+			store := newStore(t, "")
+			if err := storage.WriteOne(ctx, store, storage.AddOp, storage.Path{}, data); err != nil {
+				t.Fatal(err)
+			}
+
+			expStore := inmem.NewFromObjectWithOpts(data, inmem.OptRoundTripOnWrite(true))
+
+			var value any
+			if tc.value != "" {
+				if err := util.UnmarshalJSON([]byte(tc.value), &value); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			path, ok := storage.ParsePathEscaped(tc.path)
+			if !ok {
+				t.Fatalf("bad path: %v", tc.path)
+			}
+
+			expErr := storage.WriteOne(ctx, expStore, tc.op, path, value)
+			err := storage.WriteOne(ctx, store, tc.op, path, value)
+			if !reflect.DeepEqual(err, expErr) {
+				t.Fatalf("expected write error %v but got %v", expErr, err)
+			}
+
+			if tc.read == "" {
+				return
+			}
+
+			path, _ = storage.ParsePathEscaped(tc.read)
+
+			exp, expErr := storage.ReadOne(ctx, expStore, path)
+			result, err := storage.ReadOne(ctx, store, path)
+			if !reflect.DeepEqual(err, expErr) {
+				t.Fatalf("expected read error %v but got %v", expErr, err)
+			}
+			if !reflect.DeepEqual(result, exp) {
+				t.Fatalf("expected %v but got %v", exp, result)
+			}
+		})
+	}
+}
+
+func TestTxnWrites(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t, "")
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/users"), map[string]any{"alice": map[string]any{"roles": []any{"admin"}}}); err != nil {
+			return err
+		}
+		if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/users/alice/roles/-"), "dev"); err != nil {
+			return err
+		}
+		result, err := store.Read(ctx, txn, storage.MustParsePath("/users/alice/roles"))
+		if err != nil {
+			return err
+		}
+		if exp := []any{"admin", "dev"}; !reflect.DeepEqual(result, exp) {
+			t.Fatalf("expected %v but got %v", exp, result)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Aborted writes are not visible.
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	if err := store.Write(ctx, txn, storage.RemoveOp, storage.MustParsePath("/users/alice"), nil); err != nil {
+		t.Fatal(err)
+	}
+	store.Abort(ctx, txn)
+
+	result, err := storage.ReadOne(ctx, store, storage.MustParsePath("/users/alice/roles/1"))
+	if err != nil || result != "dev" {
+		t.Fatalf("expected dev but got %v (err: %v)", result, err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/x"), 1); !storage.IsInvalidTransaction(err) {
+		t.Fatalf("expected invalid transaction error but got: %v", err)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "opa.db")
+
+	store, err := New(ctx, Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := store.UpsertPolicy(ctx, txn, "test.rego", []byte("package test")); err != nil {
+			return err
+		}
+		return store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/users"), map[string]any{
+			"alice": map[string]any{"roles": []any{"admin"}},
+			"bob":   map[string]any{},
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The database can be inspected with standard tooling.
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, `SELECT path, value FROM data ORDER BY path`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result [][2]string
+	for rows.Next() {
+		var row [2]string
+		if err := rows.Scan(&row[0], &row[1]); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, row)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := [][2]string{{"/users/alice/roles", `["admin"]`}, {"/users/bob", `{}`}}
+	if !reflect.DeepEqual(result, exp) {
+		t.Fatalf("expected rows %v but got %v", exp, result)
+	}
+
+	store = newStore(t, path)
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	data, err := store.Read(ctx, txn, storage.Path{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := map[string]any{"users": map[string]any{"alice": map[string]any{"roles": []any{"admin"}}, "bob": map[string]any{}}}; !reflect.DeepEqual(data, exp) {
+		t.Fatalf("expected %v but got %v", exp, data)
+	}
+
+	bs, err := store.GetPolicy(ctx, txn, "test.rego")
+	if err != nil || string(bs) != "package test" {
+		t.Fatalf("unexpected policy %q (err: %v)", bs, err)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "opa.db")
+
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range append(schema, `INSERT INTO metadata (key, value) VALUES ('schema_version', '2')`) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(ctx, Options{Path: path})
+	if err == nil || err.Error() != "storage_internal_error: unsupported schema version: 2 (want 1)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t, "")
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+
+	if err := store.UpsertPolicy(ctx, txn, "b.rego", []byte("package b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpsertPolicy(ctx, txn, "a.rego", []byte("package a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpsertPolicy(ctx, txn, "a.rego", []byte("package a.b")); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"a.rego", "b.rego"}; !slices.Equal(ids, exp) {
+		t.Fatalf("expected %v but got %v", exp, ids)
+	}
+
+	bs, err := store.GetPolicy(ctx, txn, "a.rego")
+	if err != nil || string(bs) != "package a.b" {
+		t.Fatalf("unexpected policy %q (err: %v)", bs, err)
+	}
+
+	if err := store.DeletePolicy(ctx, txn, "b.rego"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.GetPolicy(ctx, txn, "b.rego"); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got: %v", err)
+	}
+
+	if err := store.DeletePolicy(ctx, txn, "b.rego"); !storage.IsNotFound(err) {
+		t.Fatalf("expected not found error but got: %v", err)
+	}
+
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if err := store.UpsertPolicy(ctx, txn, "c.rego", []byte("package c")); !storage.IsInvalidTransaction(err) {
+		t.Fatalf("expected invalid transaction error but got: %v", err)
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t, "")
+
+	var events []storage.TriggerEvent
+	var reads []any
+
+	params := storage.WriteParams
+	params.Context = storage.NewContext()
+	params.Context.Put("foo", "bar")
+
+	var handle storage.TriggerHandle
+
+	if err := storage.Txn(ctx, store, params, func(txn storage.Transaction) error {
+		var err error
+		handle, err = store.Register(ctx, txn, storage.TriggerConfig{
+			OnCommit: func(ctx context.Context, txn storage.Transaction, event storage.TriggerEvent) {
+				result, err := store.Read(ctx, txn, storage.MustParsePath("/a"))
+				if err != nil {
+					t.Error(err)
+				}
+				reads = append(reads, result)
+				events = append(events, event)
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/a"), "x"); err != nil {
+			return err
+		}
+		return store.UpsertPolicy(ctx, txn, "test.rego", []byte("package test"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected one event but got: %v", events)
+	}
+
+	event := events[0]
+	if !event.DataChanged() || !event.PolicyChanged() || event.Context.Get("foo") != "bar" {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	if exp := []storage.DataEvent{{Path: storage.MustParsePath("/a"), Data: "x"}}; !reflect.DeepEqual(event.Data, exp) {
+		t.Fatalf("expected %v but got %v", exp, event.Data)
+	}
+	if exp := []any{"x"}; !reflect.DeepEqual(reads, exp) {
+		t.Fatalf("expected trigger reads %v but got %v", exp, reads)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	handle.Unregister(ctx, txn)
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/b"), "y"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("expected no more events but got: %v", events)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if _, err := store.Register(ctx, txn, storage.TriggerConfig{}); !storage.IsInvalidTransaction(err) {
+		t.Fatalf("expected invalid transaction error but got: %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t, "")
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/a"), map[string]any{"old": true}); err != nil {
+			return err
+		}
+		return store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/other"), map[string]any{"keep": true})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	archiveFiles := map[string]string{
+		"/a/b/c/data.json":   "[1,2,3]",
+		"/a/b/d/data.json":   `{"e": true}`,
+		"/a/x/y/data.json":   `{"z": 1}`,
+		"/a/b/y/data.yaml":   `foo: 1`,
+		"/policy.rego":       "package foo\n p = 1",
+		"/roles/policy.rego": "package bar\n p = 1",
+	}
+
+	var files [][2]string
+	for name, content := range archiveFiles {
+		files = append(files, [2]string{name, content})
+	}
+
+	buf := archive.MustWriteTarGz(files)
+	b, err := bundle.NewReader(buf).WithLazyLoadingMode(true).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iterator := bundle.NewIterator(b.Raw)
+
+	params := storage.WriteParams
+	params.BasePaths = []string{"a"}
+
+	txn := storage.NewTransactionOrDie(ctx, store, params)
+	if err := store.Truncate(ctx, txn, params, iterator); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	result, err := store.Read(ctx, txn, storage.Path{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exp any
+	if err := util.UnmarshalJSON([]byte(`{
+		"a": {"b": {"c": [1, 2, 3], "d": {"e": true}, "y": {"foo": 1}}, "x": {"y": {"z": 1}}},
+		"other": {"keep": true}
+	}`), &exp); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(result, exp) {
+		t.Fatalf("expected %v but got %v", exp, result)
+	}
+
+	ids, err := store.ListPolicies(ctx, txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"policy.rego", "roles/policy.rego"}; !slices.Equal(ids, exp) {
+		t.Fatalf("expected %v but got %v", exp, ids)
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		note   string
+		config string
+		exp    *Options
+		err    error
+	}{
+		{
+			note:   "no storage section",
+			config: `{}`,
+		},
+		{
+			note:   "path",
+			config: `{"storage": {"sqlite": {"path": "` + filepath.Join(dir, "opa.db") + `"}}}`,
+			exp:    &Options{Path: filepath.Join(dir, "opa.db")},
+		},
+		{
+			note:   "auto_create",
+			config: `{"storage": {"sqlite": {"path": "` + filepath.Join(dir, "sub", "opa.db") + `", "auto_create": true}}}`,
+			exp:    &Options{Path: filepath.Join(dir, "sub", "opa.db")},
+		},
+		{
+			note:   "missing directory",
+			config: `{"storage": {"sqlite": {"path": "` + filepath.Join(dir, "missing", "opa.db") + `"}}}`,
+			err:    errors.New("directory " + filepath.Join(dir, "missing") + " invalid: stat " + filepath.Join(dir, "missing") + ": no such file or directory"),
+		},
+		{
+			note:   "missing path",
+			config: `{"storage": {"sqlite": {}}}`,
+			err:    ErrMissingPath,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			opts, err := OptionsFromConfig([]byte(tc.config), "id")
+			if tc.err != nil {
+				if err == nil || err.Error() != tc.err.Error() {
+					t.Fatalf("expected error %v but got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(opts, tc.exp) {
+				t.Fatalf("expected %v but got %v", tc.exp, opts)
+			}
+		})
+	}
+}
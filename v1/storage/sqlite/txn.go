@@ -0,0 +1,464 @@
+// Copyright 2025 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/IUAD1IY7/opa/v1/storage"
+	"github.com/IUAD1IY7/opa/v1/storage/internal/errors"
+	"github.com/IUAD1IY7/opa/v1/storage/internal/ptr"
+	"github.com/IUAD1IY7/opa/v1/util"
+)
+
+type transaction struct {
+	underlying *sql.Tx              // handle for the underlying database transaction
+	db         *Store               // handle for the database this transaction was created on
+	xid        uint64               // unique id for this transaction
+	stale      bool                 // bit to indicate if the transaction was already aborted/committed
+	write      bool                 // bit to indicate if the transaction may perform writes
+	event      storage.TriggerEvent // constructed as we go, supplied by the caller to be included in triggers
+}
+
+func newTransaction(xid uint64, write bool, underlying *sql.Tx, context *storage.Context, db *Store) *transaction {
+	return &transaction{
+		underlying: underlying,
+		db:         db,
+		xid:        xid,
+		write:      write,
+		event: storage.TriggerEvent{
+			Context: context,
+		},
+	}
+}
+
+func (txn *transaction) ID() uint64 {
+	return txn.xid
+}
+
+func (txn *transaction) Commit(context.Context) (storage.TriggerEvent, error) {
+	txn.stale = true
+	return txn.event, wrapError(txn.underlying.Commit())
+}
+
+func (txn *transaction) Abort(context.Context) {
+	txn.stale = true
+	_ = txn.underlying.Rollback()
+}
+
+func (txn *transaction) Read(ctx context.Context, path storage.Path) (any, error) {
+	i, value, ok, err := txn.readLeaf(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		// Nest the value under its path so that errors refer to the full path.
+		for j := i - 1; j >= 0; j-- {
+			value = map[string]any{path[j]: value}
+		}
+		return ptr.Ptr(value, path)
+	}
+
+	result, err := txn.readChildren(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		if len(path) == 0 {
+			return map[string]any{}, nil
+		}
+		return nil, errors.NewNotFoundError(path)
+	}
+
+	return result, nil
+}
+
+// readLeaf returns the value of the row stored at path or at one of its
+// ancestors. The returned index is the length of the path the row is stored
+// at.
+func (txn *transaction) readLeaf(ctx context.Context, path storage.Path) (int, any, bool, error) {
+	if len(path) == 0 {
+		return 0, nil, false, nil
+	}
+
+	keys := make([]any, len(path))
+	for i := range path {
+		keys[i] = path[:i+1].String()
+	}
+
+	query := `SELECT path, value FROM data WHERE path IN (?` + strings.Repeat(`, ?`, len(keys)-1) + `)`
+
+	var key, bs string
+	if err := txn.underlying.QueryRowContext(ctx, query, keys...).Scan(&key, &bs); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, false, nil
+		}
+		return 0, nil, false, wrapError(err)
+	}
+
+	value, err := deserialize(bs)
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	return strings.Count(key, "/"), value, true, nil
+}
+
+// readChildren rebuilds the object stored at path from the rows under it. If
+// there are no rows under path, nil is returned.
+func (txn *transaction) readChildren(ctx context.Context, path storage.Path) (map[string]any, error) {
+	lo, hi := childRange(path)
+	rows, err := txn.underlying.QueryContext(ctx, `SELECT path, value FROM data WHERE path >= ? AND path < ?`, lo, hi)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+
+	var result map[string]any
+
+	for rows.Next() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var key, bs string
+		if err := rows.Scan(&key, &bs); err != nil {
+			return nil, wrapError(err)
+		}
+
+		value, err := deserialize(bs)
+		if err != nil {
+			return nil, err
+		}
+
+		if result == nil {
+			result = map[string]any{}
+		}
+
+		node := result
+		segments := strings.Split(key[len(lo):], "/")
+		for i, segment := range segments {
+			segment, err := url.PathUnescape(segment)
+			if err != nil {
+				return nil, corruptKeyError(key)
+			}
+
+			if i == len(segments)-1 {
+				node[segment] = value
+				break
+			}
+
+			child, ok := node[segment]
+			if !ok {
+				child = map[string]any{}
+				node[segment] = child
+			}
+			childObj, ok := child.(map[string]any)
+			if !ok {
+				return nil, corruptKeyError(key)
+			}
+			node = childObj
+		}
+	}
+
+	return result, wrapError(rows.Err())
+}
+
+func (txn *transaction) hasChildren(ctx context.Context, path storage.Path) (bool, error) {
+	lo, hi := childRange(path)
+	var exists bool
+	err := txn.underlying.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM data WHERE path >= ? AND path < ?)`, lo, hi).Scan(&exists)
+	return exists, wrapError(err)
+}
+
+func (txn *transaction) Write(ctx context.Context, op storage.PatchOp, path storage.Path, value any) error {
+	if !txn.write {
+		return &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "data write during read transaction",
+		}
+	}
+
+	if err := txn.apply(ctx, op, path, value); err != nil {
+		return err
+	}
+
+	txn.event.Data = append(txn.event.Data, storage.DataEvent{
+		Path:    path,
+		Data:    value,
+		Removed: op == storage.RemoveOp,
+	})
+
+	return nil
+}
+
+func (txn *transaction) apply(ctx context.Context, op storage.PatchOp, path storage.Path, value any) error {
+	if len(path) == 0 {
+		if op == storage.RemoveOp {
+			return invalidPatchError(rootCannotBeRemovedMsg)
+		}
+		if _, ok := value.(map[string]any); !ok {
+			return invalidPatchError(rootMustBeObjectMsg)
+		}
+		if err := txn.delete(ctx, path); err != nil {
+			return err
+		}
+		return txn.insert(ctx, path, value)
+	}
+
+	i, leaf, ok, err := txn.readLeaf(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	// The path refers to a location inside of a stored value, so the stored
+	// value is patched and written back.
+	if ok && i < len(path) {
+		modified, err := patch(leaf, op, path, i, value)
+		if err != nil {
+			return err
+		}
+		if err := txn.delete(ctx, path[:i]); err != nil {
+			return err
+		}
+		return txn.insert(ctx, path[:i], modified)
+	}
+
+	if !ok {
+		exists, err := txn.hasChildren(ctx, path)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if op != storage.AddOp {
+				return errors.NewNotFoundError(path)
+			}
+			// Parents that are stored values or empty objects are found by
+			// readLeaf, so the only remaining parents are non-empty objects.
+			if parent := path[:len(path)-1]; len(parent) > 0 {
+				if exists, err := txn.hasChildren(ctx, parent); err != nil {
+					return err
+				} else if !exists {
+					return errors.NewNotFoundError(path)
+				}
+			}
+		}
+	}
+
+	if err := txn.delete(ctx, path); err != nil {
+		return err
+	}
+
+	if op != storage.RemoveOp {
+		return txn.insert(ctx, path, value)
+	}
+
+	// Removing the last value of an object leaves an empty object behind.
+	parent := path[:len(path)-1]
+	if len(parent) == 0 {
+		return nil
+	}
+	if exists, err := txn.hasChildren(ctx, parent); err != nil || exists {
+		return err
+	}
+	return txn.insert(ctx, parent, map[string]any{})
+}
+
+// delete removes the rows stored at and under path.
+func (txn *transaction) delete(ctx context.Context, path storage.Path) error {
+	lo, hi := childRange(path)
+	_, err := txn.underlying.ExecContext(ctx, `DELETE FROM data WHERE path = ? OR (path >= ? AND path < ?)`, path.String(), lo, hi)
+	return wrapError(err)
+}
+
+// insert writes value at path. Non-empty objects are exploded into one row
+// per leaf document.
+func (txn *transaction) insert(ctx context.Context, path storage.Path, value any) error {
+	if obj, ok := value.(map[string]any); ok && (len(obj) > 0 || len(path) == 0) {
+		for k, v := range obj {
+			if err := txn.insert(ctx, append(path[:len(path):len(path)], k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	bs, err := serialize(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = txn.underlying.ExecContext(ctx, `INSERT INTO data (path, value) VALUES (?, ?)`, path.String(), bs)
+	return wrapError(err)
+}
+
+func (txn *transaction) ListPolicies(ctx context.Context) ([]string, error) {
+	rows, err := txn.underlying.QueryContext(ctx, `SELECT id FROM policies ORDER BY id`)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer rows.Close()
+
+	var result []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, wrapError(err)
+		}
+		result = append(result, id)
+	}
+
+	return result, wrapError(rows.Err())
+}
+
+func (txn *transaction) GetPolicy(ctx context.Context, id string) ([]byte, error) {
+	var module string
+	err := txn.underlying.QueryRowContext(ctx, `SELECT module FROM policies WHERE id = ?`, id).Scan(&module)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundErrorf("policy id %q", id)
+		}
+		return nil, wrapError(err)
+	}
+	return []byte(module), nil
+}
+
+func (txn *transaction) UpsertPolicy(ctx context.Context, id string, bs []byte) error {
+	if !txn.write {
+		return &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "policy write during read transaction",
+		}
+	}
+	if _, err := txn.underlying.ExecContext(ctx,
+		`INSERT INTO policies (id, module) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET module = excluded.module`,
+		id, string(bs)); err != nil {
+		return wrapError(err)
+	}
+	txn.event.Policy = append(txn.event.Policy, storage.PolicyEvent{
+		ID:   id,
+		Data: bs,
+	})
+	return nil
+}
+
+func (txn *transaction) DeletePolicy(ctx context.Context, id string) error {
+	if !txn.write {
+		return &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "policy write during read transaction",
+		}
+	}
+	if _, err := txn.underlying.ExecContext(ctx, `DELETE FROM policies WHERE id = ?`, id); err != nil {
+		return wrapError(err)
+	}
+	txn.event.Policy = append(txn.event.Policy, storage.PolicyEvent{
+		ID:      id,
+		Removed: true,
+	})
+	return nil
+}
+
+// childRange returns the bounds of the keys of the rows stored under path.
+// Escaped path segments never contain '/', and '0' is the character following
+// '/', so all keys prefixed with "<path>/" fall into the range.
+func childRange(path storage.Path) (string, string) {
+	prefix := "/"
+	if len(path) > 0 {
+		prefix = path.String() + "/"
+	}
+	return prefix, prefix[:len(prefix)-1] + "0"
+}
+
+func serialize(value any) (string, error) {
+	bs, err := json.Marshal(value)
+	return string(bs), wrapError(err)
+}
+
+func deserialize(bs string) (any, error) {
+	var result any
+	d := util.NewJSONDecoder(bytes.NewBufferString(bs))
+	return result, wrapError(d.Decode(&result))
+}
+
+// patch applies the operation to the value stored at path[:idx]. The value
+// was freshly deserialized, so it is modified in place.
+func patch(data any, op storage.PatchOp, path storage.Path, idx int, value any) (any, error) {
+	key := path[idx]
+	last := idx == len(path)-1
+
+	switch x := data.(type) {
+	case map[string]any:
+		child, ok := x[key]
+		if !last {
+			if !ok {
+				return nil, errors.NewNotFoundError(path)
+			}
+			modified, err := patch(child, op, path, idx+1, value)
+			if err != nil {
+				return nil, err
+			}
+			x[key] = modified
+			return x, nil
+		}
+		switch op {
+		case storage.RemoveOp:
+			if !ok {
+				return nil, errors.NewNotFoundError(path)
+			}
+			delete(x, key)
+		case storage.ReplaceOp:
+			if !ok {
+				return nil, errors.NewNotFoundError(path)
+			}
+			x[key] = value
+		default:
+			x[key] = value
+		}
+		return x, nil
+
+	case []any:
+		if last && (key == "-" || key == strconv.Itoa(len(x))) {
+			if op != storage.AddOp {
+				return nil, invalidPatchError("%v: invalid patch path", path)
+			}
+			return append(x, value), nil
+		}
+
+		pos, err := ptr.ValidateArrayIndex(x, key, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !last {
+			modified, err := patch(x[pos], op, path, idx+1, value)
+			if err != nil {
+				return nil, err
+			}
+			x[pos] = modified
+			return x, nil
+		}
+
+		switch op {
+		case storage.AddOp:
+			return slices.Insert(x, pos, value), nil
+		case storage.RemoveOp:
+			return slices.Delete(x, pos, pos+1), nil
+		default:
+			x[pos] = value
+			return x, nil
+		}
+	}
+
+	return nil, errors.NewNotFoundError(path)
+}
@@ -5,11 +5,15 @@
 package tester
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/cover"
@@ -285,21 +289,66 @@ type JSONCoverageReporter struct {
 	Output    io.Writer
 	Threshold float64
 	Verbose   bool
+	Merge     []cover.Report // reports of other test runs to merge coverage with
 }
 
 // Report prints the test report to the reporter's output. If any tests fail or
 // encounter errors, this function returns an error.
 func (r JSONCoverageReporter) Report(ch chan *Result) error {
+	report, err := coverageReport(ch, r)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(r.Output)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// LCOVCoverageReporter reports coverage in the LCOV tracefile format.
+type LCOVCoverageReporter JSONCoverageReporter
+
+// Report prints the test report to the reporter's output. If any tests fail or
+// encounter errors, this function returns an error.
+func (r LCOVCoverageReporter) Report(ch chan *Result) error {
+	report, err := coverageReport(ch, JSONCoverageReporter(r))
+	if err != nil {
+		return err
+	}
+	return cover.WriteLCOV(r.Output, report, r.Modules)
+}
+
+// CoberturaCoverageReporter reports coverage in the Cobertura XML format.
+type CoberturaCoverageReporter JSONCoverageReporter
+
+// Report prints the test report to the reporter's output. If any tests fail or
+// encounter errors, this function returns an error.
+func (r CoberturaCoverageReporter) Report(ch chan *Result) error {
+	report, err := coverageReport(ch, JSONCoverageReporter(r))
+	if err != nil {
+		return err
+	}
+	return cover.WriteCobertura(r.Output, report, r.Modules)
+}
+
+// coverageReport returns the coverage report of the test run, merged with the
+// reports to merge. It returns an error if any tests fail or encounter errors,
+// or if the coverage is less than the threshold.
+func coverageReport(ch chan *Result, r JSONCoverageReporter) (cover.Report, error) {
 	for tr := range ch {
 		if !tr.Pass() {
 			if tr.Error != nil {
-				return tr.Error
+				return cover.Report{}, tr.Error
 			}
-			return errors.New(tr.String())
+			return cover.Report{}, errors.New(tr.String())
 		}
 	}
 	report := r.Cover.Report(r.Modules)
 
+	if len(r.Merge) > 0 {
+		report = cover.Merge(append([]cover.Report{report}, r.Merge...)...)
+	}
+
 	if report.Coverage < r.Threshold {
 		err := cover.CoverageThresholdError{
 			Coverage:  report.Coverage,
@@ -310,12 +359,286 @@ func (r JSONCoverageReporter) Report(ch chan *Result) error {
 			err.Report = &report
 		}
 
-		return &err
+		return cover.Report{}, &err
 	}
 
-	encoder := json.NewEncoder(r.Output)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(report)
+	return report, nil
+}
+
+// JUnitReporter reports test results in the JUnit XML format. Each package is
+// reported as a test suite.
+type JUnitReporter struct {
+	Output    io.Writer
+	Verbose   bool
+	LocalVars bool
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+
+	duration time.Duration
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+// Report prints the test report to the reporter's output.
+func (r JUnitReporter) Report(ch chan *Result) error {
+	report := junitTestSuites{Suites: []*junitTestSuite{}}
+	suites := map[string]*junitTestSuite{}
+	var duration time.Duration
+
+	for tr := range ch {
+		suite, ok := suites[tr.Package]
+		if !ok {
+			suite = &junitTestSuite{Name: tr.Package}
+			suites[tr.Package] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+
+		tc := junitTestCase{
+			Name:      tr.Name,
+			Classname: tr.Package,
+			Time:      seconds(tr.Duration),
+		}
+		if len(tr.Output) > 0 {
+			tc.SystemOut = &junitOutput{Text: string(tr.Output)}
+		}
+		if tr.Location != nil {
+			tc.File = tr.Location.File
+			tc.Line = tr.Location.Row
+		}
+
+		switch {
+		case tr.Skip:
+			tc.Skipped = &junitMessage{Message: tr.skipReason()}
+			suite.Skipped++
+		case tr.Error != nil:
+			tc.Error = &junitMessage{Message: tr.Error.Error()}
+			suite.Errors++
+		case tr.Fail:
+			details, err := failureDetails(tr, r.Verbose, r.LocalVars)
+			if err != nil {
+				return err
+			}
+			tc.Failure = &junitMessage{Message: tr.string(false), Text: details}
+			suite.Failures++
+		}
+
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		suite.duration += tr.Duration
+		duration += tr.Duration
+	}
+
+	for _, suite := range report.Suites {
+		suite.Time = seconds(suite.duration)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+	}
+	report.Time = seconds(duration)
+
+	bs, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.Output, "%s%s\n", xml.Header, bs)
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// TAPReporter reports test results in the Test Anything Protocol (TAP) version
+// 14 format. Each package is reported as a subtest, and every test has a YAML
+// diagnostic block with its duration, and the details of its failure.
+type TAPReporter struct {
+	Output    io.Writer
+	Verbose   bool
+	LocalVars bool
+}
+
+// Report prints the test report to the reporter's output.
+func (r TAPReporter) Report(ch chan *Result) error {
+	var packages []string
+	results := map[string][]*Result{}
+
+	for tr := range ch {
+		if _, ok := results[tr.Package]; !ok {
+			packages = append(packages, tr.Package)
+		}
+		results[tr.Package] = append(results[tr.Package], tr)
+	}
+
+	w := &tapWriter{w: r.Output}
+	w.printf("TAP version 14\n")
+	w.printf("1..%d\n", len(packages))
+
+	for i, pkg := range packages {
+		sub := &tapWriter{w: newIndentingWriter(r.Output, 4)}
+		w.printf("# Subtest: %s\n", pkg)
+		sub.printf("1..%d\n", len(results[pkg]))
+
+		ok := true
+		for j, tr := range results[pkg] {
+			if err := r.report(sub, j+1, tr); err != nil {
+				return err
+			}
+			ok = ok && (tr.Pass() || tr.Skip)
+		}
+
+		w.point(ok, i+1, pkg, "")
+		if w.err != nil {
+			return w.err
+		}
+		if sub.err != nil {
+			return sub.err
+		}
+	}
+
+	return w.err
+}
+
+func (r TAPReporter) report(w *tapWriter, n int, tr *Result) error {
+	var directive string
+	if tr.Skip {
+		directive = "SKIP " + tr.skipReason()
+	}
+	w.point(tr.Pass() || tr.Skip, n, tr.Name, directive)
+
+	var diag [][2]string
+	diag = append(diag, [2]string{"duration_ms", strconv.FormatFloat(float64(tr.Duration)/float64(time.Millisecond), 'f', 3, 64)})
+
+	if tr.Location != nil {
+		diag = append(diag, [2]string{"file", tapString(tr.Location.File)}, [2]string{"line", strconv.Itoa(tr.Location.Row)})
+	}
+
+	switch {
+	case tr.Error != nil:
+		diag = append(diag, [2]string{"severity", "error"}, [2]string{"message", tapString(tr.Error.Error())})
+	case tr.Fail:
+		details, err := failureDetails(tr, r.Verbose, r.LocalVars)
+		if err != nil {
+			return err
+		}
+		diag = append(diag, [2]string{"severity", "fail"}, [2]string{"message", tapString(tr.string(false))})
+		if details != "" {
+			diag = append(diag, [2]string{"trace", tapString(details)})
+		}
+	}
+
+	if len(tr.Output) > 0 {
+		diag = append(diag, [2]string{"output", tapString(string(tr.Output))})
+	}
+
+	w.printf("  ---\n")
+	for _, kv := range diag {
+		w.printf("  %s: %s\n", kv[0], kv[1])
+	}
+	w.printf("  ...\n")
+
+	return w.err
+}
+
+// tapWriter writes TAP lines, and keeps the first error it encounters.
+type tapWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *tapWriter) printf(format string, a ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, a...)
+	}
+}
+
+func (w *tapWriter) point(ok bool, n int, description, directive string) {
+	status := "ok"
+	if !ok {
+		status = "not ok"
+	}
+	// Escape the characters TAP gives a meaning to in descriptions.
+	description = strings.NewReplacer("\\", "\\\\", "#", "\\#").Replace(description)
+	if directive != "" {
+		w.printf("%s %d - %s # %s\n", status, n, description, directive)
+	} else {
+		w.printf("%s %d - %s\n", status, n, description)
+	}
+}
+
+// tapString quotes s as a YAML string. JSON strings are valid YAML.
+func tapString(s string) string {
+	bs, _ := json.Marshal(s)
+	return string(bs)
+}
+
+// failureDetails returns the details of a failed test: its failing sub-results
+// and the failure trace, if the test was traced.
+func failureDetails(tr *Result, verbose bool, localVars bool) (string, error) {
+	var buf bytes.Buffer
+
+	if len(tr.SubResults) > 0 {
+		if err := printFailure(&buf, tr.Trace, verbose, false, localVars); err != nil {
+			return "", err
+		}
+
+		for fullName, sr := range tr.SubResults.Iter {
+			if !sr.Fail || len(sr.SubResults) > 0 {
+				continue
+			}
+
+			var w io.Writer = &buf
+			for _, n := range fullName {
+				_, _ = fmt.Fprintf(w, "%s: %s\n", n, sr.outcome())
+				w = newIndentingWriter(w)
+			}
+
+			if err := printFailure(w, sr.Trace, false, true, localVars); err != nil {
+				return "", err
+			}
+		}
+	} else if err := printFailure(&buf, tr.Trace, verbose, true, localVars); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
 }
 
 type indentingWriter struct {
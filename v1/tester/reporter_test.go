@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/IUAD1IY7/opa/v1/ast"
 	"github.com/IUAD1IY7/opa/v1/topdown"
@@ -1492,3 +1493,154 @@ func resultsChan(ts []*Result) chan *Result {
 	}()
 	return ch
 }
+
+func getCIReporterResults() []*Result {
+	failTrace := getFakeTraceEventsFor(ast.MustParseExpr("true = false"), func(e *topdown.Event) {
+		e.QueryID = 1
+		e.Location = &ast.Location{File: "policy1.rego", Row: 4, Text: []byte("true = false")}
+	})
+
+	return []*Result{
+		{
+			Package:  "data.foo.bar",
+			Name:     "test_baz",
+			Duration: 1500 * time.Microsecond,
+			Location: &ast.Location{File: "policy1.rego", Row: 3},
+		},
+		{
+			Package:  "data.foo.bar",
+			Name:     "test_corge",
+			Fail:     true,
+			Duration: 2 * time.Millisecond,
+			Trace:    failTrace,
+			Location: &ast.Location{File: "policy1.rego", Row: 4},
+			Output:   []byte("fake print output\n"),
+		},
+		{
+			Package:  "data.foo.bar",
+			Name:     "test_qux",
+			Error:    errors.New("some err"),
+			Location: &ast.Location{File: "policy1.rego", Row: 7},
+		},
+		{
+			Package:  "data.foo.bar",
+			Name:     "todo_test_qux",
+			Skip:     true,
+			Location: &ast.Location{File: "policy1.rego", Row: 9},
+		},
+		{
+			Package:  "data.foo.baz",
+			Name:     "test_cases",
+			Fail:     true,
+			Location: &ast.Location{File: "policy2.rego", Row: 3},
+			SubResults: SubResultMap{
+				"one": {Name: "one"},
+				"two": {Name: "two", Fail: true},
+			},
+		},
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+	ts := getCIReporterResults()
+
+	r := JUnitReporter{Output: &buf}
+	ch := resultsChan(ts)
+	if err := r.Report(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="5" failures="2" errors="1" skipped="1" time="0.004">
+  <testsuite name="data.foo.bar" tests="4" failures="1" errors="1" skipped="1" time="0.004">
+    <testcase name="test_baz" classname="data.foo.bar" file="policy1.rego" line="3" time="0.002"></testcase>
+    <testcase name="test_corge" classname="data.foo.bar" file="policy1.rego" line="4" time="0.002">
+      <failure message="data.foo.bar.test_corge: FAIL (2ms)"><![CDATA[policy1.rego:4:
+    true = false]]></failure>
+      <system-out><![CDATA[fake print output
+]]></system-out>
+    </testcase>
+    <testcase name="test_qux" classname="data.foo.bar" file="policy1.rego" line="7" time="0.000">
+      <error message="some err"></error>
+    </testcase>
+    <testcase name="todo_test_qux" classname="data.foo.bar" file="policy1.rego" line="9" time="0.000">
+      <skipped message="todo test (todo_test_ prefix)"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="data.foo.baz" tests="1" failures="1" errors="0" skipped="0" time="0.000">
+    <testcase name="test_cases" classname="data.foo.baz" file="policy2.rego" line="3" time="0.000">
+      <failure message="data.foo.baz.test_cases: FAIL (0s)"><![CDATA[two: FAIL]]></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+	if exp != buf.String() {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+	}
+}
+
+func TestTAPReporter(t *testing.T) {
+	var buf bytes.Buffer
+	ts := getCIReporterResults()
+
+	r := TAPReporter{Output: &buf}
+	ch := resultsChan(ts)
+	if err := r.Report(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `TAP version 14
+1..2
+# Subtest: data.foo.bar
+    1..4
+    ok 1 - test_baz
+      ---
+      duration_ms: 1.500
+      file: "policy1.rego"
+      line: 3
+      ...
+    not ok 2 - test_corge
+      ---
+      duration_ms: 2.000
+      file: "policy1.rego"
+      line: 4
+      severity: fail
+      message: "data.foo.bar.test_corge: FAIL (2ms)"
+      trace: "policy1.rego:4:\n    true = false"
+      output: "fake print output\n"
+      ...
+    not ok 3 - test_qux
+      ---
+      duration_ms: 0.000
+      file: "policy1.rego"
+      line: 7
+      severity: error
+      message: "some err"
+      ...
+    ok 4 - todo_test_qux # SKIP todo test (todo_test_ prefix)
+      ---
+      duration_ms: 0.000
+      file: "policy1.rego"
+      line: 9
+      ...
+not ok 1 - data.foo.bar
+# Subtest: data.foo.baz
+    1..1
+    not ok 1 - test_cases
+      ---
+      duration_ms: 0.000
+      file: "policy2.rego"
+      line: 3
+      severity: fail
+      message: "data.foo.baz.test_cases: FAIL (0s)"
+      trace: "two: FAIL"
+      ...
+not ok 2 - data.foo.baz
+`
+
+	if exp != buf.String() {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+	}
+}
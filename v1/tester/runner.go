@@ -198,6 +198,11 @@ func (r *Result) outcome() string {
 	return "ERROR"
 }
 
+// skipReason returns the reason the test was skipped.
+func (r *Result) skipReason() string {
+	return fmt.Sprintf("todo test (%v prefix)", SkipTestPrefix)
+}
+
 func (sr *SubResult) String() string {
 	return fmt.Sprintf("%v: %v", sr.Name, sr.outcome())
 }
@@ -372,7 +377,8 @@ func (r *Runner) SetRuntime(term *ast.Term) *Runner {
 	return r
 }
 
-// SetTimeout sets the timeout for the individual test cases
+// SetTimeout sets the timeout for the individual test cases. A zero timeout
+// disables it, e.g. for tests evaluated step by step by a debugger.
 func (r *Runner) SetTimeout(timout time.Duration) *Runner {
 	r.timeout = timout
 	return r
@@ -557,7 +563,10 @@ func (r *Runner) runTests(ctx context.Context, txn storage.Transaction, enablePr
 						}
 
 						tr, stop := func() (*Result, bool) {
-							runCtx, cancel := context.WithTimeout(ctx, r.timeout)
+							runCtx, cancel := ctx, context.CancelFunc(func() {})
+							if r.timeout > 0 {
+								runCtx, cancel = context.WithTimeout(ctx, r.timeout)
+							}
 							defer cancel()
 							return runFunc(runCtx, txn, module, rule)
 						}()